@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/audit"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/db"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/env"
+)
+
+// Walks the audit log hash chain and reports every break found.
+// Exits with status 1 when the chain is not intact.
+func main() {
+	cfg, err := env.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	database, err := db.Connect(
+		cfg.DB.URL,
+		cfg.DB.MaxOpenConns,
+		cfg.DB.MaxIdleConns,
+		cfg.DB.MaxIdleTime,
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer database.Close()
+
+	auditRepo := audit.NewAuditRepository(database, db.QueryDuration)
+	auditService := audit.NewAuditService(auditRepo)
+
+	report, err := auditService.Verify(context.Background())
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		log.Fatal(err)
+	}
+
+	if len(report.Breaks) > 0 {
+		fmt.Fprintf(os.Stderr, "audit chain has %d break(s)\n", len(report.Breaks))
+		database.Close()
+		os.Exit(1)
+	}
+}
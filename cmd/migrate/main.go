@@ -0,0 +1,190 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/DevVictor19/pic-pay-challenge/cmd/migrate/migrations"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/db"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/env"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/migrator"
+)
+
+const usage = `usage: migrate <command> [args]
+
+commands:
+  up             apply every pending migration
+  down N         roll back the last N migrations
+  status         list migrations and whether they are applied
+  version        print the current version
+  force V        set the version to V without running anything, clearing
+                 the dirty flag (0 means no migration applied)
+  create NAME    add an empty up/down pair to -dir
+`
+
+// Applies the migrations embedded in the binary. Runs holding a Postgres
+// advisory lock, so concurrent deploys cannot race each other.
+func main() {
+	dir := flag.String("dir", "./cmd/migrate/migrations", "directory new migrations are created in")
+	flag.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if args[0] == "create" {
+		if len(args) != 2 {
+			flag.Usage()
+			os.Exit(2)
+		}
+		if err := create(*dir, args[1]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if err := run(ctx, args); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(ctx context.Context, args []string) error {
+	cfg, err := env.Load()
+	if err != nil {
+		return err
+	}
+
+	database, err := db.Connect(
+		cfg.DB.URL,
+		cfg.DB.MaxOpenConns,
+		cfg.DB.MaxIdleConns,
+		cfg.DB.MaxIdleTime,
+	)
+	if err != nil {
+		return err
+	}
+	defer database.Close()
+
+	m, err := migrator.New(database, migrations.FS)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case "up":
+		n, err := m.Up(ctx)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("applied %d migration(s)\n", n)
+
+	case "down":
+		n, err := intArg(args)
+		if err != nil {
+			return err
+		}
+		reverted, err := m.Down(ctx, n)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("reverted %d migration(s)\n", reverted)
+
+	case "status":
+		status, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, s := range status {
+			state := "pending"
+			if s.Applied {
+				state = "applied"
+			}
+			fmt.Printf("%06d  %-8s %s\n", s.Version, state, s.Name)
+		}
+
+	case "version":
+		version, dirty, err := m.Version(ctx)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			fmt.Printf("%d (dirty)\n", version)
+		} else {
+			fmt.Println(version)
+		}
+
+	case "force":
+		version, err := intArg(args)
+		if err != nil {
+			return err
+		}
+		if version < 0 {
+			return fmt.Errorf("version must not be negative")
+		}
+		if err := m.Force(ctx, uint(version)); err != nil {
+			return err
+		}
+		fmt.Printf("forced version %d\n", version)
+
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	return nil
+}
+
+func intArg(args []string) (int, error) {
+	if len(args) != 2 {
+		return 0, fmt.Errorf("%s takes exactly one number", args[0])
+	}
+	n, err := strconv.Atoi(args[1])
+	if err != nil {
+		return 0, fmt.Errorf("%s: %q is not a number", args[0], args[1])
+	}
+	return n, nil
+}
+
+// create adds the next "<version>_<name>.up.sql" and ".down.sql" to dir.
+func create(dir, name string) error {
+	name = strings.ReplaceAll(strings.TrimSpace(name), " ", "_")
+	if name == "" {
+		return fmt.Errorf("migration name must not be empty")
+	}
+
+	existing, err := migrator.Load(os.DirFS(dir))
+	if err != nil {
+		return err
+	}
+
+	var next uint = 1
+	if len(existing) > 0 {
+		next = existing[len(existing)-1].Version + 1
+	}
+
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(dir, fmt.Sprintf("%06d_%s.%s.sql", next, name, direction))
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		f.Close()
+		fmt.Println(path)
+	}
+
+	return nil
+}
@@ -0,0 +1,13 @@
+DELETE FROM users WHERE role::text = 'admin';
+
+ALTER TABLE IF EXISTS users
+DROP CONSTRAINT IF EXISTS cpf_or_cnpj_required;
+
+ALTER TABLE IF EXISTS users
+ADD CONSTRAINT cpf_or_cnpj_required
+CHECK (cpf IS NOT NULL OR cnpj IS NOT NULL);
+
+ALTER TYPE user_role RENAME TO user_role_old;
+CREATE TYPE user_role AS ENUM ('common', 'shopkeeper');
+ALTER TABLE users ALTER COLUMN role TYPE user_role USING role::text::user_role;
+DROP TYPE user_role_old;
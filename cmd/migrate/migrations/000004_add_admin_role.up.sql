@@ -0,0 +1,8 @@
+ALTER TYPE user_role ADD VALUE IF NOT EXISTS 'admin';
+
+ALTER TABLE IF EXISTS users
+DROP CONSTRAINT IF EXISTS cpf_or_cnpj_required;
+
+ALTER TABLE IF EXISTS users
+ADD CONSTRAINT cpf_or_cnpj_required
+CHECK (role::text = 'admin' OR cpf IS NOT NULL OR cnpj IS NOT NULL);
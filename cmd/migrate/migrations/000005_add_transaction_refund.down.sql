@@ -0,0 +1,12 @@
+DELETE FROM transactions WHERE type::text = 'refund';
+
+DROP INDEX IF EXISTS transactions_refund_of_idx;
+
+ALTER TABLE IF EXISTS transactions
+DROP COLUMN IF EXISTS refunded_at,
+DROP COLUMN IF EXISTS refund_of;
+
+ALTER TYPE transaction_type RENAME TO transaction_type_old;
+CREATE TYPE transaction_type AS ENUM ('payment_received', 'payment_sent');
+ALTER TABLE transactions ALTER COLUMN type TYPE transaction_type USING type::text::transaction_type;
+DROP TYPE transaction_type_old;
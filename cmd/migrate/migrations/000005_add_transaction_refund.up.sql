@@ -0,0 +1,7 @@
+ALTER TYPE transaction_type ADD VALUE IF NOT EXISTS 'refund';
+
+ALTER TABLE IF EXISTS transactions
+ADD COLUMN IF NOT EXISTS refund_of INTEGER REFERENCES transactions(id),
+ADD COLUMN IF NOT EXISTS refunded_at TIMESTAMP WITH TIME ZONE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS transactions_refund_of_idx ON transactions(refund_of);
@@ -0,0 +1,12 @@
+DROP TRIGGER IF EXISTS audit_logs_no_truncate ON audit_logs;
+DROP TRIGGER IF EXISTS audit_logs_no_modify ON audit_logs;
+DROP FUNCTION IF EXISTS audit_logs_append_only();
+
+ALTER TABLE IF EXISTS audit_logs
+DROP COLUMN IF EXISTS hash,
+DROP COLUMN IF EXISTS prev_hash,
+DROP COLUMN IF EXISTS after,
+DROP COLUMN IF EXISTS before,
+DROP COLUMN IF EXISTS request_id,
+DROP COLUMN IF EXISTS ip,
+ALTER COLUMN details TYPE JSONB USING details::jsonb;
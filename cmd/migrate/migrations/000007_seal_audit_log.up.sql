@@ -0,0 +1,25 @@
+ALTER TABLE IF EXISTS audit_logs
+ALTER COLUMN details TYPE JSON USING details::json,
+ADD COLUMN IF NOT EXISTS ip TEXT,
+ADD COLUMN IF NOT EXISTS request_id TEXT,
+ADD COLUMN IF NOT EXISTS before JSON,
+ADD COLUMN IF NOT EXISTS after JSON,
+ADD COLUMN IF NOT EXISTS prev_hash CHAR(64),
+ADD COLUMN IF NOT EXISTS hash CHAR(64) UNIQUE;
+
+CREATE OR REPLACE FUNCTION audit_logs_append_only()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_logs is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_logs_no_modify ON audit_logs;
+CREATE TRIGGER audit_logs_no_modify
+BEFORE UPDATE OR DELETE ON audit_logs
+FOR EACH ROW EXECUTE FUNCTION audit_logs_append_only();
+
+DROP TRIGGER IF EXISTS audit_logs_no_truncate ON audit_logs;
+CREATE TRIGGER audit_logs_no_truncate
+BEFORE TRUNCATE ON audit_logs
+FOR EACH STATEMENT EXECUTE FUNCTION audit_logs_append_only();
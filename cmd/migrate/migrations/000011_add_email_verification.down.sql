@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS email_verification_tokens;
+
+ALTER TABLE IF EXISTS users
+DROP COLUMN IF EXISTS email_verified_at;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS api_key_nonces;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS signed_requests;
@@ -0,0 +1,10 @@
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS signed_requests BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS api_key_nonces (
+    api_key_id INTEGER NOT NULL REFERENCES api_keys(id) ON DELETE CASCADE,
+    nonce VARCHAR(128) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (api_key_id, nonce)
+);
+
+CREATE INDEX IF NOT EXISTS api_key_nonces_expires_at_idx ON api_key_nonces(expires_at);
@@ -0,0 +1,25 @@
+ALTER TABLE IF EXISTS transactions
+DROP CONSTRAINT IF EXISTS transactions_payee_id_fkey,
+ADD CONSTRAINT transactions_payee_id_fkey
+FOREIGN KEY (payee_id) REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE IF EXISTS transactions
+DROP CONSTRAINT IF EXISTS transactions_payer_id_fkey,
+ADD CONSTRAINT transactions_payer_id_fkey
+FOREIGN KEY (payer_id) REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE IF EXISTS wallets
+DROP CONSTRAINT IF EXISTS wallets_user_id_fkey,
+ADD CONSTRAINT wallets_user_id_fkey
+FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE;
+
+ALTER TABLE IF EXISTS users
+DROP CONSTRAINT IF EXISTS cpf_or_cnpj_required;
+
+-- closed accounts have no document left, so existing rows are not checked
+ALTER TABLE IF EXISTS users
+ADD CONSTRAINT cpf_or_cnpj_required
+CHECK (role::text = 'admin' OR cpf IS NOT NULL OR cnpj IS NOT NULL) NOT VALID;
+
+ALTER TABLE IF EXISTS users
+DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,27 @@
+ALTER TABLE IF EXISTS users
+ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+-- closed accounts keep their row, with personal data wiped, so the ledger
+-- still points at them
+ALTER TABLE IF EXISTS users
+DROP CONSTRAINT IF EXISTS cpf_or_cnpj_required;
+
+ALTER TABLE IF EXISTS users
+ADD CONSTRAINT cpf_or_cnpj_required
+CHECK (role::text = 'admin' OR deleted_at IS NOT NULL OR cpf IS NOT NULL OR cnpj IS NOT NULL);
+
+-- deleting a user must never take the financial history with it
+ALTER TABLE IF EXISTS wallets
+DROP CONSTRAINT IF EXISTS wallets_user_id_fkey,
+ADD CONSTRAINT wallets_user_id_fkey
+FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT;
+
+ALTER TABLE IF EXISTS transactions
+DROP CONSTRAINT IF EXISTS transactions_payer_id_fkey,
+ADD CONSTRAINT transactions_payer_id_fkey
+FOREIGN KEY (payer_id) REFERENCES users(id) ON DELETE RESTRICT;
+
+ALTER TABLE IF EXISTS transactions
+DROP CONSTRAINT IF EXISTS transactions_payee_id_fkey,
+ADD CONSTRAINT transactions_payee_id_fkey
+FOREIGN KEY (payee_id) REFERENCES users(id) ON DELETE RESTRICT;
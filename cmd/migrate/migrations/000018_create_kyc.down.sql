@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS transactions_payer_id_created_at_idx;
+
+DROP TABLE IF EXISTS kyc_documents;
+
+ALTER TABLE IF EXISTS users
+DROP COLUMN IF EXISTS trade_name,
+DROP COLUMN IF EXISTS legal_name,
+DROP COLUMN IF EXISTS kyc_level;
+
+DROP TYPE IF EXISTS kyc_level;
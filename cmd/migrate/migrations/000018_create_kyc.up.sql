@@ -0,0 +1,27 @@
+DROP TYPE IF EXISTS kyc_level;
+CREATE TYPE kyc_level AS ENUM ('none', 'basic', 'full');
+
+ALTER TABLE IF EXISTS users
+ADD COLUMN IF NOT EXISTS kyc_level kyc_level NOT NULL DEFAULT 'none',
+ADD COLUMN IF NOT EXISTS legal_name VARCHAR(255),
+ADD COLUMN IF NOT EXISTS trade_name VARCHAR(255);
+
+CREATE TABLE IF NOT EXISTS kyc_documents (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    type VARCHAR(32) NOT NULL,
+    storage_key VARCHAR(255) NOT NULL UNIQUE,
+    content_type VARCHAR(64) NOT NULL,
+    size BIGINT NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    rejection_reason VARCHAR(500),
+    reviewed_by INTEGER REFERENCES users(id) ON DELETE RESTRICT,
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS kyc_documents_user_id_idx ON kyc_documents(user_id);
+CREATE INDEX IF NOT EXISTS kyc_documents_status_idx ON kyc_documents(status, created_at);
+
+-- the daily transfer limit sums the payer's recent payments on every transfer
+CREATE INDEX IF NOT EXISTS transactions_payer_id_created_at_idx ON transactions(payer_id, created_at);
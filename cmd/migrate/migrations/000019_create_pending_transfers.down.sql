@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS transactions_payer_id_payee_id_idx;
+
+DROP TABLE IF EXISTS pending_transfers;
@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS pending_transfers (
+    id SERIAL PRIMARY KEY,
+    payer_id INTEGER NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    payee_id INTEGER NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    amount BIGINT NOT NULL CHECK (amount > 0),
+    description TEXT,
+    reasons TEXT[] NOT NULL,
+    status VARCHAR(16) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'approved', 'rejected')),
+    transaction_id INTEGER REFERENCES transactions(id) ON DELETE RESTRICT,
+    rejection_reason VARCHAR(500),
+    reviewed_by INTEGER REFERENCES users(id) ON DELETE RESTRICT,
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS pending_transfers_status_idx ON pending_transfers(status, created_at);
+
+-- the new payee rule looks up earlier payments between the same two users
+CREATE INDEX IF NOT EXISTS transactions_payer_id_payee_id_idx ON transactions(payer_id, payee_id);
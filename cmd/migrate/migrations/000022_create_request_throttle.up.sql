@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS request_throttles (
+    key VARCHAR(340) PRIMARY KEY,
+    hits INTEGER NOT NULL,
+    window_started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_hit_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
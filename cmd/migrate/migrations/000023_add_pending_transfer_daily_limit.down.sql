@@ -0,0 +1,2 @@
+ALTER TABLE IF EXISTS pending_transfers
+DROP COLUMN IF EXISTS daily_limit;
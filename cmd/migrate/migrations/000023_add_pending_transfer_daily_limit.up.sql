@@ -0,0 +1,3 @@
+-- the payer's daily limit when the transfer was held, applied again on approval
+ALTER TABLE IF EXISTS pending_transfers
+ADD COLUMN IF NOT EXISTS daily_limit BIGINT NOT NULL DEFAULT 0;
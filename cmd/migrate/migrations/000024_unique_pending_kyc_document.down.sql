@@ -0,0 +1 @@
+DROP INDEX IF EXISTS kyc_documents_pending_type_idx;
@@ -0,0 +1,10 @@
+-- keep only the newest of any duplicate pending uploads so the index can be built
+UPDATE kyc_documents d
+SET status = 'rejected', rejection_reason = 'superseded by a newer upload'
+WHERE d.status = 'pending' AND EXISTS (
+    SELECT 1 FROM kyc_documents n
+    WHERE n.user_id = d.user_id AND n.type = d.type AND n.status = 'pending' AND n.id > d.id
+);
+
+-- one pending document per user and type, even under concurrent uploads
+CREATE UNIQUE INDEX IF NOT EXISTS kyc_documents_pending_type_idx ON kyc_documents(user_id, type) WHERE status = 'pending';
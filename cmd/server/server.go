@@ -1,11 +1,36 @@
 package main
 
 import (
+	"flag"
 	"log"
+	"os"
 
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/env"
 	"github.com/DevVictor19/pic-pay-challenge/internal/infra/server"
 )
 
 func main() {
-	log.Fatal(server.Start())
+	loader := env.NewLoader(flag.CommandLine)
+	migrateOnStart := flag.Bool("migrate-on-start", false, "apply pending migrations before serving")
+	printConfig := flag.Bool("print-config", false, "print the effective configuration, secrets redacted, and exit")
+	flag.Parse()
+
+	cfg, err := loader.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if *printConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	err = server.Run(cfg, server.Options{
+		MigrateOnStart: *migrateOnStart,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
 }
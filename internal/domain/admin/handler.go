@@ -0,0 +1,195 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/transaction"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+type AdminHandler struct {
+	adminService AdminService
+}
+
+func (h *AdminHandler) SearchUsers(w http.ResponseWriter, r *http.Request) error {
+	actor := r.Context().Value(utils.UserKey).(*user.User)
+
+	query := r.URL.Query()
+	filter := user.SearchFilter{
+		Query:  query.Get("q"),
+		Role:   user.UserRole(query.Get("role")),
+		Limit:  queryInt(r, "limit"),
+		Offset: queryInt(r, "offset"),
+	}
+
+	users, err := h.adminService.SearchUsers(r.Context(), actor.ID, filter)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, users)
+}
+
+func (h *AdminHandler) GetWallet(w http.ResponseWriter, r *http.Request) error {
+	actor := r.Context().Value(utils.UserKey).(*user.User)
+
+	userID, err := pathID(r, "userID")
+	if err != nil {
+		return err
+	}
+
+	wall, err := h.adminService.GetWallet(r.Context(), actor.ID, userID)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, wall)
+}
+
+func (h *AdminHandler) ListTransactions(w http.ResponseWriter, r *http.Request) error {
+	actor := r.Context().Value(utils.UserKey).(*user.User)
+
+	userID, err := pathID(r, "userID")
+	if err != nil {
+		return err
+	}
+
+	transactions, err := h.adminService.ListTransactions(
+		r.Context(),
+		actor.ID,
+		userID,
+		queryInt(r, "limit"),
+		queryInt(r, "offset"),
+	)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, transactions)
+}
+
+func (h *AdminHandler) FreezeAccount(w http.ResponseWriter, r *http.Request) error {
+	actor := r.Context().Value(utils.UserKey).(*user.User)
+
+	userID, err := pathID(r, "userID")
+	if err != nil {
+		return err
+	}
+
+	if err := h.adminService.FreezeAccount(r.Context(), actor.ID, userID); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *AdminHandler) UnfreezeAccount(w http.ResponseWriter, r *http.Request) error {
+	actor := r.Context().Value(utils.UserKey).(*user.User)
+
+	userID, err := pathID(r, "userID")
+	if err != nil {
+		return err
+	}
+
+	if err := h.adminService.UnfreezeAccount(r.Context(), actor.ID, userID); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *AdminHandler) RefundTransaction(w http.ResponseWriter, r *http.Request) error {
+	actor := r.Context().Value(utils.UserKey).(*user.User)
+
+	transactionID, err := pathID(r, "transactionID")
+	if err != nil {
+		return err
+	}
+
+	refund, err := h.adminService.RefundTransaction(r.Context(), actor.ID, transactionID)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJSON(w, http.StatusCreated, refund)
+}
+
+func (h *AdminHandler) ListPendingTransfers(w http.ResponseWriter, r *http.Request) error {
+	pending, err := h.adminService.ListPendingTransfers(r.Context(), queryInt(r, "limit"), queryInt(r, "offset"))
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, pending)
+}
+
+func (h *AdminHandler) ApprovePendingTransfer(w http.ResponseWriter, r *http.Request) error {
+	actor := r.Context().Value(utils.UserKey).(*user.User)
+
+	id, err := pathID(r, "transferID")
+	if err != nil {
+		return err
+	}
+
+	approved, err := h.adminService.ApprovePendingTransfer(r.Context(), actor.ID, id)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, approved)
+}
+
+func (h *AdminHandler) RejectPendingTransfer(w http.ResponseWriter, r *http.Request) error {
+	actor := r.Context().Value(utils.UserKey).(*user.User)
+
+	id, err := pathID(r, "transferID")
+	if err != nil {
+		return err
+	}
+
+	var body transaction.RejectPendingDTO
+	if err := utils.ReadJSON(w, r, &body); err != nil {
+		return apperror.NewHttpError(http.StatusBadRequest, "error parsing body request")
+	}
+
+	if err := utils.Validate.Struct(body); err != nil {
+		return apperror.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	rejected, err := h.adminService.RejectPendingTransfer(r.Context(), actor.ID, id, body.Reason)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, rejected)
+}
+
+func pathID(r *http.Request, param string) (int, error) {
+	id, err := strconv.Atoi(chi.URLParam(r, param))
+	if err != nil || id <= 0 {
+		return 0, apperror.NewHttpError(http.StatusBadRequest, "invalid "+param)
+	}
+	return id, nil
+}
+
+func queryInt(r *http.Request, key string) int {
+	val, err := strconv.Atoi(r.URL.Query().Get(key))
+	if err != nil {
+		return 0
+	}
+	return val
+}
+
+func NewAdminHandler(adminService AdminService) *AdminHandler {
+	return &AdminHandler{
+		adminService,
+	}
+}
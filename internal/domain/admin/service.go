@@ -0,0 +1,242 @@
+package admin
+
+import (
+	"context"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/audit"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/transaction"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/wallet"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/db"
+)
+
+type AdminService interface {
+	SearchUsers(ctx context.Context, actorID int, filter user.SearchFilter) ([]user.User, error)
+	GetWallet(ctx context.Context, actorID, userID int) (*wallet.Wallet, error)
+	ListTransactions(ctx context.Context, actorID, userID, limit, offset int) ([]transaction.Transaction, error)
+	FreezeAccount(ctx context.Context, actorID, userID int) error
+	UnfreezeAccount(ctx context.Context, actorID, userID int) error
+	RefundTransaction(ctx context.Context, actorID, transactionID int) (*transaction.Transaction, error)
+	ListPendingTransfers(ctx context.Context, limit, offset int) ([]transaction.PendingTransfer, error)
+	ApprovePendingTransfer(ctx context.Context, actorID, id int) (*transaction.PendingTransfer, error)
+	RejectPendingTransfer(ctx context.Context, actorID, id int, reason string) (*transaction.PendingTransfer, error)
+}
+
+type adminSvc struct {
+	userService        user.UserService
+	wallService        wallet.WalletService
+	transactionService transaction.TransactionService
+	auditService       audit.AuditService
+	fingerprinter      audit.Fingerprinter
+	transactor         db.Transactor
+}
+
+func (s *adminSvc) SearchUsers(ctx context.Context, actorID int, filter user.SearchFilter) ([]user.User, error) {
+	users, err := s.userService.Search(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.auditService.Record(ctx, audit.RecordDTO{
+		ActorID: &actorID,
+		Action:  audit.AdminUserSearch,
+		Details: map[string]any{
+			// the query is often an email or a name
+			"query_fingerprint": s.fingerprinter.Fingerprint(filter.Query),
+			"role":              filter.Role,
+			"limit":             filter.Limit,
+			"offset":            filter.Offset,
+			"results":           len(users),
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (s *adminSvc) GetWallet(ctx context.Context, actorID, userID int) (*wallet.Wallet, error) {
+	wall, err := s.wallService.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.auditService.Record(ctx, audit.RecordDTO{
+		ActorID:    &actorID,
+		Action:     audit.AdminWalletView,
+		TargetType: "user",
+		TargetID:   &userID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return wall, nil
+}
+
+func (s *adminSvc) ListTransactions(ctx context.Context, actorID, userID, limit, offset int) ([]transaction.Transaction, error) {
+	if _, err := s.userService.FindByID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	transactions, err := s.transactionService.ListByUser(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.auditService.Record(ctx, audit.RecordDTO{
+		ActorID:    &actorID,
+		Action:     audit.AdminTransactionsView,
+		TargetType: "user",
+		TargetID:   &userID,
+		Details: map[string]any{
+			"limit":  limit,
+			"offset": offset,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
+}
+
+func (s *adminSvc) FreezeAccount(ctx context.Context, actorID, userID int) error {
+	return s.setAccountActive(ctx, actorID, userID, false)
+}
+
+func (s *adminSvc) UnfreezeAccount(ctx context.Context, actorID, userID int) error {
+	return s.setAccountActive(ctx, actorID, userID, true)
+}
+
+func (s *adminSvc) setAccountActive(ctx context.Context, actorID, userID int, active bool) error {
+	before, err := s.wallService.FindByUserID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.wallService.SetActive(ctx, userID, active); err != nil {
+		return err
+	}
+
+	after := *before
+	after.Active = active
+
+	action := audit.AdminAccountFreeze
+	if active {
+		action = audit.AdminAccountUnfreeze
+	}
+
+	return s.auditService.Record(ctx, audit.RecordDTO{
+		ActorID:    &actorID,
+		Action:     action,
+		TargetType: "user",
+		TargetID:   &userID,
+		Before:     before,
+		After:      after,
+	})
+}
+
+func (s *adminSvc) RefundTransaction(ctx context.Context, actorID, transactionID int) (*transaction.Transaction, error) {
+	before, err := s.transactionService.FindByID(ctx, transactionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the refund and its audit entry are stored together, or not at all
+	var refund *transaction.Transaction
+	err = s.transactor.WithTx(ctx, func(ctx context.Context) error {
+		refund, err = s.transactionService.Refund(ctx, transactionID)
+		if err != nil {
+			return err
+		}
+
+		return s.auditService.Record(ctx, audit.RecordDTO{
+			ActorID:    &actorID,
+			Action:     audit.AdminTransactionRefund,
+			TargetType: "transaction",
+			TargetID:   &transactionID,
+			Before:     before,
+			After:      refund,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return refund, nil
+}
+
+func (s *adminSvc) ListPendingTransfers(ctx context.Context, limit, offset int) ([]transaction.PendingTransfer, error) {
+	return s.transactionService.ListPending(ctx, limit, offset)
+}
+
+func (s *adminSvc) ApprovePendingTransfer(ctx context.Context, actorID, id int) (*transaction.PendingTransfer, error) {
+	var approved *transaction.PendingTransfer
+	err := s.transactor.WithTx(ctx, func(ctx context.Context) error {
+		var wallets transaction.TransferWallets
+		var err error
+		approved, wallets, err = s.transactionService.ApprovePending(ctx, actorID, id)
+		if err != nil {
+			return err
+		}
+
+		return s.auditService.Record(ctx, audit.RecordDTO{
+			ActorID:    &actorID,
+			Action:     audit.AdminTransferApprove,
+			TargetType: "pending_transfer",
+			TargetID:   &id,
+			Details:    map[string]any{"transaction_id": approved.TransactionID, "reasons": approved.Reasons},
+			Before:     wallets,
+			After:      wallets.Moved(approved.Amount),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return approved, nil
+}
+
+func (s *adminSvc) RejectPendingTransfer(ctx context.Context, actorID, id int, reason string) (*transaction.PendingTransfer, error) {
+	var rejected *transaction.PendingTransfer
+	err := s.transactor.WithTx(ctx, func(ctx context.Context) error {
+		var err error
+		rejected, err = s.transactionService.RejectPending(ctx, actorID, id, reason)
+		if err != nil {
+			return err
+		}
+
+		return s.auditService.Record(ctx, audit.RecordDTO{
+			ActorID:    &actorID,
+			Action:     audit.AdminTransferReject,
+			TargetType: "pending_transfer",
+			TargetID:   &id,
+			Details:    map[string]any{"reason": reason, "reasons": rejected.Reasons},
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return rejected, nil
+}
+
+func NewAdminService(
+	usrSvc user.UserService,
+	wSvc wallet.WalletService,
+	tSvc transaction.TransactionService,
+	audSvc audit.AuditService,
+	fingerprinter audit.Fingerprinter,
+	transactor db.Transactor) AdminService {
+
+	return &adminSvc{
+		userService:        usrSvc,
+		wallService:        wSvc,
+		transactionService: tSvc,
+		auditService:       audSvc,
+		fingerprinter:      fingerprinter,
+		transactor:         transactor,
+	}
+}
@@ -0,0 +1,209 @@
+package admin
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/audit"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/transaction"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/wallet"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var testFingerprinter = audit.NewFingerprinter("audit-test")
+
+func TestAdminService_SearchUsers(t *testing.T) {
+	t.Run("should search users and record the action", func(t *testing.T) {
+		userServiceMock := new(user.MockUserService)
+		auditServiceMock := new(audit.MockAuditService)
+
+		filter := user.SearchFilter{Query: "john"}
+		userServiceMock.On("Search", mock.Anything, filter).Return([]user.User{{ID: 2}}, nil)
+		auditServiceMock.On("Record", mock.Anything, mock.MatchedBy(func(dto audit.RecordDTO) bool {
+			details := dto.Details.(map[string]any)
+			_, hasQuery := details["query"]
+			return dto.Action == audit.AdminUserSearch && *dto.ActorID == 1 &&
+				!hasQuery && details["query_fingerprint"] == testFingerprinter.Fingerprint("john")
+		})).Return(nil)
+
+		service := NewAdminService(userServiceMock, nil, nil, auditServiceMock, testFingerprinter, &db.MockTransactor{})
+
+		users, err := service.SearchUsers(context.Background(), 1, filter)
+
+		assert.NoError(t, err)
+		assert.Len(t, users, 1)
+
+		userServiceMock.AssertExpectations(t)
+		auditServiceMock.AssertExpectations(t)
+	})
+
+	t.Run("should fail if the audit log cannot be written", func(t *testing.T) {
+		userServiceMock := new(user.MockUserService)
+		auditServiceMock := new(audit.MockAuditService)
+
+		userServiceMock.On("Search", mock.Anything, mock.Anything).Return([]user.User{}, nil)
+		auditServiceMock.On("Record", mock.Anything, mock.Anything).Return(errors.New("db fail"))
+
+		service := NewAdminService(userServiceMock, nil, nil, auditServiceMock, testFingerprinter, &db.MockTransactor{})
+
+		users, err := service.SearchUsers(context.Background(), 1, user.SearchFilter{})
+
+		assert.Error(t, err)
+		assert.Nil(t, users)
+	})
+}
+
+func TestAdminService_FreezeAccount(t *testing.T) {
+	t.Run("should not record the action if the wallet is not found", func(t *testing.T) {
+		wallServiceMock := new(wallet.MockWalletService)
+		auditServiceMock := new(audit.MockAuditService)
+
+		wallServiceMock.On("FindByUserID", mock.Anything, 2).
+			Return(nil, apperror.NewHttpError(http.StatusNotFound, "wallet not found"))
+
+		service := NewAdminService(nil, wallServiceMock, nil, auditServiceMock, testFingerprinter, &db.MockTransactor{})
+
+		err := service.FreezeAccount(context.Background(), 1, 2)
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusNotFound, httpError.Code)
+		auditServiceMock.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should freeze the wallet and record the action", func(t *testing.T) {
+		wallServiceMock := new(wallet.MockWalletService)
+		auditServiceMock := new(audit.MockAuditService)
+
+		wallServiceMock.On("FindByUserID", mock.Anything, 2).Return(&wallet.Wallet{UserID: 2, Active: true}, nil)
+		wallServiceMock.On("SetActive", mock.Anything, 2, false).Return(nil)
+		auditServiceMock.On("Record", mock.Anything, mock.MatchedBy(func(dto audit.RecordDTO) bool {
+			return dto.Action == audit.AdminAccountFreeze &&
+				*dto.TargetID == 2 &&
+				dto.Before.(*wallet.Wallet).Active &&
+				!dto.After.(wallet.Wallet).Active
+		})).Return(nil)
+
+		service := NewAdminService(nil, wallServiceMock, nil, auditServiceMock, testFingerprinter, &db.MockTransactor{})
+
+		err := service.FreezeAccount(context.Background(), 1, 2)
+
+		assert.NoError(t, err)
+		wallServiceMock.AssertExpectations(t)
+		auditServiceMock.AssertExpectations(t)
+	})
+}
+
+func TestAdminService_UnfreezeAccount(t *testing.T) {
+	t.Run("should unfreeze the wallet and record the action", func(t *testing.T) {
+		wallServiceMock := new(wallet.MockWalletService)
+		auditServiceMock := new(audit.MockAuditService)
+
+		wallServiceMock.On("FindByUserID", mock.Anything, 2).Return(&wallet.Wallet{UserID: 2}, nil)
+		wallServiceMock.On("SetActive", mock.Anything, 2, true).Return(nil)
+		auditServiceMock.On("Record", mock.Anything, mock.MatchedBy(func(dto audit.RecordDTO) bool {
+			return dto.Action == audit.AdminAccountUnfreeze
+		})).Return(nil)
+
+		service := NewAdminService(nil, wallServiceMock, nil, auditServiceMock, testFingerprinter, &db.MockTransactor{})
+
+		err := service.UnfreezeAccount(context.Background(), 1, 2)
+
+		assert.NoError(t, err)
+		wallServiceMock.AssertExpectations(t)
+		auditServiceMock.AssertExpectations(t)
+	})
+}
+
+func TestAdminService_RefundTransaction(t *testing.T) {
+	t.Run("should refund and record the action", func(t *testing.T) {
+		transactionServiceMock := new(transaction.MockTransactionService)
+		auditServiceMock := new(audit.MockAuditService)
+
+		original := &transaction.Transaction{ID: 3, Amount: 500, Type: transaction.PaymentSent}
+		refund := &transaction.Transaction{ID: 10, Amount: 500, Type: transaction.Refund}
+		transactionServiceMock.On("FindByID", mock.Anything, 3).Return(original, nil)
+		transactionServiceMock.On("Refund", mock.Anything, 3).Return(refund, nil)
+		auditServiceMock.On("Record", mock.Anything, mock.MatchedBy(func(dto audit.RecordDTO) bool {
+			return dto.Action == audit.AdminTransactionRefund &&
+				*dto.TargetID == 3 &&
+				dto.Before == original &&
+				dto.After == refund
+		})).Return(nil)
+
+		service := NewAdminService(nil, nil, transactionServiceMock, auditServiceMock, testFingerprinter, &db.MockTransactor{})
+
+		result, err := service.RefundTransaction(context.Background(), 1, 3)
+
+		assert.NoError(t, err)
+		assert.Equal(t, refund, result)
+		transactionServiceMock.AssertExpectations(t)
+		auditServiceMock.AssertExpectations(t)
+	})
+
+	t.Run("should not record the action if refund fails", func(t *testing.T) {
+		transactionServiceMock := new(transaction.MockTransactionService)
+		auditServiceMock := new(audit.MockAuditService)
+
+		transactionServiceMock.On("FindByID", mock.Anything, 3).Return(&transaction.Transaction{ID: 3}, nil)
+		transactionServiceMock.On("Refund", mock.Anything, 3).
+			Return(nil, apperror.NewHttpError(http.StatusConflict, "transaction already refunded"))
+
+		service := NewAdminService(nil, nil, transactionServiceMock, auditServiceMock, testFingerprinter, &db.MockTransactor{})
+
+		result, err := service.RefundTransaction(context.Background(), 1, 3)
+
+		assert.Error(t, err)
+		assert.Nil(t, result)
+		auditServiceMock.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+	})
+}
+
+func TestAdminService_ApprovePendingTransfer(t *testing.T) {
+	t.Run("should approve and record the action", func(t *testing.T) {
+		transactionServiceMock := new(transaction.MockTransactionService)
+		auditServiceMock := new(audit.MockAuditService)
+
+		transactionID := 10
+		approved := &transaction.PendingTransfer{ID: 4, Amount: 100, Status: transaction.ReviewApproved, TransactionID: &transactionID}
+		wallets := transaction.TransferWallets{
+			Payer: transaction.WalletState{UserID: 2, Active: true, Balance: 500},
+			Payee: transaction.WalletState{UserID: 3, Active: true, Balance: 0},
+		}
+		transactionServiceMock.On("ApprovePending", mock.Anything, 1, 4).Return(approved, wallets, nil)
+		auditServiceMock.On("Record", mock.Anything, mock.MatchedBy(func(dto audit.RecordDTO) bool {
+			after, ok := dto.After.(transaction.TransferWallets)
+			return dto.Action == audit.AdminTransferApprove && *dto.ActorID == 1 && *dto.TargetID == 4 &&
+				dto.Before == wallets && ok && after.Payer.Balance == 400 && after.Payee.Balance == 100
+		})).Return(nil)
+
+		service := NewAdminService(nil, nil, transactionServiceMock, auditServiceMock, testFingerprinter, &db.MockTransactor{})
+
+		result, err := service.ApprovePendingTransfer(context.Background(), 1, 4)
+
+		assert.NoError(t, err)
+		assert.Equal(t, approved, result)
+		auditServiceMock.AssertExpectations(t)
+	})
+
+	t.Run("should not record the action if approval fails", func(t *testing.T) {
+		transactionServiceMock := new(transaction.MockTransactionService)
+		auditServiceMock := new(audit.MockAuditService)
+
+		transactionServiceMock.On("ApprovePending", mock.Anything, 1, 4).
+			Return(nil, transaction.TransferWallets{}, apperror.NewHttpError(http.StatusUnprocessableEntity, "insufficient balance"))
+
+		service := NewAdminService(nil, nil, transactionServiceMock, auditServiceMock, testFingerprinter, &db.MockTransactor{})
+
+		_, err := service.ApprovePendingTransfer(context.Background(), 1, 4)
+
+		assert.Error(t, err)
+		auditServiceMock.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,13 @@
+package apikey
+
+type CreateDTO struct {
+	Name           string  `json:"name" validate:"required,max=100"`
+	Scopes         []Scope `json:"scopes" validate:"required,min=1,dive,oneof=payments:read payment_requests:write"`
+	SignedRequests bool    `json:"signed_requests"`
+}
+
+type CreatedDTO struct {
+	APIKey
+	Key           string `json:"key"`
+	SigningSecret string `json:"signing_secret"`
+}
@@ -0,0 +1,69 @@
+package apikey
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+type APIKeyHandler struct {
+	apiKeyService APIKeyService
+}
+
+func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) error {
+	usr := r.Context().Value(utils.UserKey).(*user.User)
+
+	var body CreateDTO
+	if err := utils.ReadJSON(w, r, &body); err != nil {
+		return apperror.NewHttpError(http.StatusBadRequest, "error parsing body request")
+	}
+
+	if err := utils.Validate.Struct(body); err != nil {
+		return apperror.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	key, err := h.apiKeyService.Create(r.Context(), usr.ID, body)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJSON(w, http.StatusCreated, key)
+}
+
+func (h *APIKeyHandler) List(w http.ResponseWriter, r *http.Request) error {
+	usr := r.Context().Value(utils.UserKey).(*user.User)
+
+	keys, err := h.apiKeyService.List(r.Context(), usr.ID)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, keys)
+}
+
+func (h *APIKeyHandler) Revoke(w http.ResponseWriter, r *http.Request) error {
+	usr := r.Context().Value(utils.UserKey).(*user.User)
+
+	id, err := strconv.Atoi(chi.URLParam(r, "keyID"))
+	if err != nil || id <= 0 {
+		return apperror.NewHttpError(http.StatusBadRequest, "invalid keyID")
+	}
+
+	if err := h.apiKeyService.Revoke(r.Context(), usr.ID, id); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func NewAPIKeyHandler(apiKeyService APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService,
+	}
+}
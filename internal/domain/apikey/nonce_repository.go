@@ -0,0 +1,61 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type NonceRepository interface {
+	Use(ctx context.Context, keyID int, nonce string, expiresAt time.Time) (bool, error)
+	Purge(ctx context.Context, before time.Time) error
+}
+
+type nonceRepo struct {
+	database     *sql.DB
+	queryTimeout time.Duration
+}
+
+// Use stores the nonce of a signed request. It returns false when the key
+// already used the same nonce and it has not expired yet.
+func (r *nonceRepo) Use(ctx context.Context, keyID int, nonce string, expiresAt time.Time) (bool, error) {
+	query := `
+		INSERT INTO api_key_nonces (api_key_id, nonce, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (api_key_id, nonce) DO UPDATE
+		SET expires_at = EXCLUDED.expires_at
+		WHERE api_key_nonces.expires_at < NOW()
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	res, err := r.database.ExecContext(ctx, query, keyID, nonce, expiresAt)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+func (r *nonceRepo) Purge(ctx context.Context, before time.Time) error {
+	query := `DELETE FROM api_key_nonces WHERE expires_at < $1`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := r.database.ExecContext(ctx, query, before)
+	return err
+}
+
+func NewNonceRepository(database *sql.DB, qt time.Duration) NonceRepository {
+	return &nonceRepo{
+		database:     database,
+		queryTimeout: qt,
+	}
+}
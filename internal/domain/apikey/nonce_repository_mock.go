@@ -0,0 +1,22 @@
+package apikey
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockNonceRepository struct {
+	mock.Mock
+}
+
+func (m *MockNonceRepository) Use(ctx context.Context, keyID int, nonce string, expiresAt time.Time) (bool, error) {
+	args := m.Called(ctx, keyID, nonce, expiresAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockNonceRepository) Purge(ctx context.Context, before time.Time) error {
+	args := m.Called(ctx, before)
+	return args.Error(0)
+}
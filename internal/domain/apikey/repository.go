@@ -0,0 +1,203 @@
+package apikey
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/db"
+	"github.com/lib/pq"
+)
+
+type APIKeyRepository interface {
+	Save(ctx context.Context, k APIKey) (int, error)
+	ListByUser(ctx context.Context, userID int) ([]APIKey, error)
+	FindByHash(ctx context.Context, hash string) (*APIKey, error)
+	Revoke(ctx context.Context, id, userID int, revokedAt time.Time) (bool, error)
+	// RevokeByUser joins the transaction on ctx, if any.
+	RevokeByUser(ctx context.Context, userID int, revokedAt time.Time) error
+	Touch(ctx context.Context, id int, usedAt time.Time) error
+}
+
+type apiKeyRepo struct {
+	database     *sql.DB
+	queryTimeout time.Duration
+}
+
+func (r *apiKeyRepo) Save(ctx context.Context, k APIKey) (int, error) {
+	query := `
+		INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, signed_requests, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var id int
+	err := r.database.QueryRowContext(
+		ctx,
+		query,
+		k.UserID,
+		k.Name,
+		k.Prefix,
+		k.KeyHash,
+		pq.Array(scopeStrings(k.Scopes)),
+		k.SignedRequests,
+		k.CreatedAt,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *apiKeyRepo) ListByUser(ctx context.Context, userID int) ([]APIKey, error) {
+	query := `
+		SELECT id, user_id, name, prefix, key_hash, scopes, signed_requests, last_used_at, revoked_at, created_at
+		FROM api_keys
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	rows, err := r.database.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		k, err := scanKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *k)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+func (r *apiKeyRepo) FindByHash(ctx context.Context, hash string) (*APIKey, error) {
+	query := `
+		SELECT id, user_id, name, prefix, key_hash, scopes, signed_requests, last_used_at, revoked_at, created_at
+		FROM api_keys
+		WHERE key_hash = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	k, err := scanKey(r.database.QueryRowContext(ctx, query, hash))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return k, nil
+}
+
+// Revoke marks the key as revoked if it belongs to userID. It returns false
+// when there is no such active key.
+func (r *apiKeyRepo) Revoke(ctx context.Context, id, userID int, revokedAt time.Time) (bool, error) {
+	query := `
+		UPDATE api_keys
+		SET revoked_at = $3
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	res, err := r.database.ExecContext(ctx, query, id, userID, revokedAt)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+func (r *apiKeyRepo) RevokeByUser(ctx context.Context, userID int, revokedAt time.Time) error {
+	query := `
+		UPDATE api_keys
+		SET revoked_at = $2
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := db.Conn(ctx, r.database).ExecContext(ctx, query, userID, revokedAt)
+	return err
+}
+
+func (r *apiKeyRepo) Touch(ctx context.Context, id int, usedAt time.Time) error {
+	query := `UPDATE api_keys SET last_used_at = $2 WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := r.database.ExecContext(ctx, query, id, usedAt)
+	return err
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanKey(row rowScanner) (*APIKey, error) {
+	var k APIKey
+	var scopes []string
+	err := row.Scan(
+		&k.ID,
+		&k.UserID,
+		&k.Name,
+		&k.Prefix,
+		&k.KeyHash,
+		pq.Array(&scopes),
+		&k.SignedRequests,
+		&k.LastUsedAt,
+		&k.RevokedAt,
+		&k.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, s := range scopes {
+		k.Scopes = append(k.Scopes, Scope(s))
+	}
+
+	return &k, nil
+}
+
+func scopeStrings(scopes []Scope) []string {
+	out := make([]string, len(scopes))
+	for i, s := range scopes {
+		out[i] = string(s)
+	}
+	return out
+}
+
+func NewAPIKeyRepository(database *sql.DB, qt time.Duration) APIKeyRepository {
+	return &apiKeyRepo{
+		database:     database,
+		queryTimeout: qt,
+	}
+}
@@ -0,0 +1,48 @@
+package apikey
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAPIKeyRepository struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyRepository) Save(ctx context.Context, k APIKey) (int, error) {
+	args := m.Called(ctx, k)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) ListByUser(ctx context.Context, userID int) ([]APIKey, error) {
+	args := m.Called(ctx, userID)
+	if k, ok := args.Get(0).([]APIKey); ok {
+		return k, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) FindByHash(ctx context.Context, hash string) (*APIKey, error) {
+	args := m.Called(ctx, hash)
+	if k, ok := args.Get(0).(*APIKey); ok {
+		return k, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) Revoke(ctx context.Context, id, userID int, revokedAt time.Time) (bool, error) {
+	args := m.Called(ctx, id, userID, revokedAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockAPIKeyRepository) RevokeByUser(ctx context.Context, userID int, revokedAt time.Time) error {
+	args := m.Called(ctx, userID, revokedAt)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyRepository) Touch(ctx context.Context, id int, usedAt time.Time) error {
+	args := m.Called(ctx, id, usedAt)
+	return args.Error(0)
+}
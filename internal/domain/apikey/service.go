@@ -0,0 +1,186 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/audit"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
+	"github.com/DevVictor19/pic-pay-challenge/pkg/signature"
+)
+
+const (
+	keyPrefix  = "pk_"
+	prefixSize = len(keyPrefix) + 8
+	// last_used_at is only written once per window to avoid a database write
+	// on every authenticated request.
+	touchInterval = time.Minute
+	// expired nonces are deleted by the request that finds the last purge
+	// older than this, instead of by a separate job.
+	noncePurgeInterval = time.Minute * 10
+)
+
+var ErrInvalidAPIKey = errors.New("invalid api key")
+
+type APIKeyService interface {
+	Create(ctx context.Context, userID int, dto CreateDTO) (*CreatedDTO, error)
+	List(ctx context.Context, userID int) ([]APIKey, error)
+	Revoke(ctx context.Context, userID, id int) error
+	// RevokeAll revokes every key of a closing account.
+	RevokeAll(ctx context.Context, userID int) error
+	Authenticate(ctx context.Context, key string) (*APIKey, error)
+	SigningSecret(k *APIKey) string
+	UseNonce(ctx context.Context, keyID int, nonce string, expiresAt time.Time) (bool, error)
+}
+
+type apiKeySvc struct {
+	apiKeyRepo   APIKeyRepository
+	nonceRepo    NonceRepository
+	auditService audit.AuditService
+	signingKey   string
+	lastPurge    atomic.Int64
+}
+
+func (s *apiKeySvc) Create(ctx context.Context, userID int, dto CreateDTO) (*CreatedDTO, error) {
+	key, err := generateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	k := APIKey{
+		UserID:         userID,
+		Name:           dto.Name,
+		Prefix:         key[:prefixSize],
+		KeyHash:        hashKey(key),
+		Scopes:         dto.Scopes,
+		SignedRequests: dto.SignedRequests,
+		CreatedAt:      time.Now(),
+	}
+
+	k.ID, err = s.apiKeyRepo.Save(ctx, k)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.auditService.Record(ctx, audit.RecordDTO{
+		ActorID:    &userID,
+		Action:     audit.APIKeyCreate,
+		TargetType: "api_key",
+		TargetID:   &k.ID,
+		Details:    map[string]any{"name": k.Name, "scopes": k.Scopes},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &CreatedDTO{APIKey: k, Key: key, SigningSecret: s.SigningSecret(&k)}, nil
+}
+
+func (s *apiKeySvc) List(ctx context.Context, userID int) ([]APIKey, error) {
+	return s.apiKeyRepo.ListByUser(ctx, userID)
+}
+
+func (s *apiKeySvc) Revoke(ctx context.Context, userID, id int) error {
+	revoked, err := s.apiKeyRepo.Revoke(ctx, id, userID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	if !revoked {
+		return apperror.NewHttpError(http.StatusNotFound, "api key not found")
+	}
+
+	return s.auditService.Record(ctx, audit.RecordDTO{
+		ActorID:    &userID,
+		Action:     audit.APIKeyRevoke,
+		TargetType: "api_key",
+		TargetID:   &id,
+	})
+}
+
+func (s *apiKeySvc) RevokeAll(ctx context.Context, userID int) error {
+	return s.apiKeyRepo.RevokeByUser(ctx, userID, time.Now())
+}
+
+// Authenticate resolves a raw key to its active APIKey and records its use.
+func (s *apiKeySvc) Authenticate(ctx context.Context, key string) (*APIKey, error) {
+	if !strings.HasPrefix(key, keyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	k, err := s.apiKeyRepo.FindByHash(ctx, hashKey(key))
+	if err != nil {
+		return nil, err
+	}
+
+	if k == nil || k.IsRevoked() {
+		return nil, ErrInvalidAPIKey
+	}
+
+	now := time.Now()
+	if k.LastUsedAt == nil || now.Sub(*k.LastUsedAt) >= touchInterval {
+		if err := s.apiKeyRepo.Touch(ctx, k.ID, now); err != nil {
+			slog.Error("failed to record api key usage", "err", err.Error(), "api_key_id", k.ID)
+		} else {
+			k.LastUsedAt = &now
+		}
+	}
+
+	return k, nil
+}
+
+// SigningSecret derives the HMAC secret of a key from the server signing key,
+// so it never has to be stored. Changing the signing key invalidates the
+// secrets of every existing key.
+func (s *apiKeySvc) SigningSecret(k *APIKey) string {
+	return signature.Compute(s.signingKey, "api_key:"+strconv.Itoa(k.ID))
+}
+
+func (s *apiKeySvc) UseNonce(ctx context.Context, keyID int, nonce string, expiresAt time.Time) (bool, error) {
+	now := time.Now()
+	last := s.lastPurge.Load()
+	if now.Unix()-last >= int64(noncePurgeInterval.Seconds()) && s.lastPurge.CompareAndSwap(last, now.Unix()) {
+		if err := s.nonceRepo.Purge(ctx, now); err != nil {
+			slog.Error("failed to purge api key nonces", "err", err.Error())
+		}
+	}
+
+	return s.nonceRepo.Use(ctx, keyID, nonce, expiresAt)
+}
+
+func generateKey() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return keyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func NewAPIKeyService(
+	apiKeyRepo APIKeyRepository,
+	nonceRepo NonceRepository,
+	auditSvc audit.AuditService,
+	signingKey string) APIKeyService {
+
+	return &apiKeySvc{
+		apiKeyRepo:   apiKeyRepo,
+		nonceRepo:    nonceRepo,
+		auditService: auditSvc,
+		signingKey:   signingKey,
+	}
+}
@@ -0,0 +1,56 @@
+package apikey
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAPIKeyService struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyService) Create(ctx context.Context, userID int, dto CreateDTO) (*CreatedDTO, error) {
+	args := m.Called(ctx, userID, dto)
+	if k, ok := args.Get(0).(*CreatedDTO); ok {
+		return k, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAPIKeyService) List(ctx context.Context, userID int) ([]APIKey, error) {
+	args := m.Called(ctx, userID)
+	if k, ok := args.Get(0).([]APIKey); ok {
+		return k, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAPIKeyService) Revoke(ctx context.Context, userID, id int) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyService) RevokeAll(ctx context.Context, userID int) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyService) Authenticate(ctx context.Context, key string) (*APIKey, error) {
+	args := m.Called(ctx, key)
+	if k, ok := args.Get(0).(*APIKey); ok {
+		return k, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockAPIKeyService) SigningSecret(k *APIKey) string {
+	args := m.Called(k)
+	return args.String(0)
+}
+
+func (m *MockAPIKeyService) UseNonce(ctx context.Context, keyID int, nonce string, expiresAt time.Time) (bool, error) {
+	args := m.Called(ctx, keyID, nonce, expiresAt)
+	return args.Bool(0), args.Error(1)
+}
@@ -0,0 +1,170 @@
+package apikey
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/audit"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const signingKey = "test-signing-key"
+
+func TestAPIKeyService_Create(t *testing.T) {
+	t.Run("should store only the hash and return the key once", func(t *testing.T) {
+		mockRepo := new(MockAPIKeyRepository)
+		mockAudit := new(audit.MockAuditService)
+
+		var saved APIKey
+		mockRepo.On("Save", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) { saved = args.Get(1).(APIKey) }).
+			Return(7, nil)
+		mockAudit.On("Record", mock.Anything, mock.MatchedBy(func(dto audit.RecordDTO) bool {
+			return dto.Action == audit.APIKeyCreate && *dto.ActorID == 3 && *dto.TargetID == 7
+		})).Return(nil)
+
+		created, err := NewAPIKeyService(mockRepo, nil, mockAudit, signingKey).Create(context.Background(), 3, CreateDTO{
+			Name:   "backend",
+			Scopes: []Scope{ScopePaymentsRead},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 7, created.ID)
+		assert.True(t, strings.HasPrefix(created.Key, keyPrefix))
+		assert.Equal(t, created.Key[:prefixSize], saved.Prefix)
+		assert.Equal(t, hashKey(created.Key), saved.KeyHash)
+		assert.Equal(t, 3, saved.UserID)
+		assert.NotEmpty(t, created.SigningSecret)
+		mockAudit.AssertExpectations(t)
+	})
+}
+
+func TestAPIKeyService_Revoke(t *testing.T) {
+	t.Run("should return not found for keys of other users", func(t *testing.T) {
+		mockRepo := new(MockAPIKeyRepository)
+		mockAudit := new(audit.MockAuditService)
+		mockRepo.On("Revoke", mock.Anything, 7, 3, mock.Anything).Return(false, nil)
+
+		err := NewAPIKeyService(mockRepo, nil, mockAudit, signingKey).Revoke(context.Background(), 3, 7)
+
+		var httpError *apperror.HttpError
+		require.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusNotFound, httpError.Code)
+		mockAudit.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should audit the revocation", func(t *testing.T) {
+		mockRepo := new(MockAPIKeyRepository)
+		mockAudit := new(audit.MockAuditService)
+		mockRepo.On("Revoke", mock.Anything, 7, 3, mock.Anything).Return(true, nil)
+		mockAudit.On("Record", mock.Anything, mock.MatchedBy(func(dto audit.RecordDTO) bool {
+			return dto.Action == audit.APIKeyRevoke && *dto.TargetID == 7
+		})).Return(nil)
+
+		err := NewAPIKeyService(mockRepo, nil, mockAudit, signingKey).Revoke(context.Background(), 3, 7)
+
+		require.NoError(t, err)
+		mockAudit.AssertExpectations(t)
+	})
+}
+
+func TestAPIKeyService_Authenticate(t *testing.T) {
+	key := keyPrefix + "secret"
+
+	t.Run("should reject keys without the prefix", func(t *testing.T) {
+		mockRepo := new(MockAPIKeyRepository)
+
+		_, err := NewAPIKeyService(mockRepo, nil, nil, signingKey).Authenticate(context.Background(), "secret")
+
+		assert.ErrorIs(t, err, ErrInvalidAPIKey)
+		mockRepo.AssertNotCalled(t, "FindByHash", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should reject unknown keys", func(t *testing.T) {
+		mockRepo := new(MockAPIKeyRepository)
+		mockRepo.On("FindByHash", mock.Anything, hashKey(key)).Return(nil, nil)
+
+		_, err := NewAPIKeyService(mockRepo, nil, nil, signingKey).Authenticate(context.Background(), key)
+
+		assert.ErrorIs(t, err, ErrInvalidAPIKey)
+	})
+
+	t.Run("should reject revoked keys", func(t *testing.T) {
+		mockRepo := new(MockAPIKeyRepository)
+		revokedAt := time.Now()
+		mockRepo.On("FindByHash", mock.Anything, hashKey(key)).Return(&APIKey{ID: 1, RevokedAt: &revokedAt}, nil)
+
+		_, err := NewAPIKeyService(mockRepo, nil, nil, signingKey).Authenticate(context.Background(), key)
+
+		assert.ErrorIs(t, err, ErrInvalidAPIKey)
+		mockRepo.AssertNotCalled(t, "Touch", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should record the last use", func(t *testing.T) {
+		mockRepo := new(MockAPIKeyRepository)
+		mockRepo.On("FindByHash", mock.Anything, hashKey(key)).Return(&APIKey{ID: 1}, nil)
+		mockRepo.On("Touch", mock.Anything, 1, mock.Anything).Return(nil)
+
+		k, err := NewAPIKeyService(mockRepo, nil, nil, signingKey).Authenticate(context.Background(), key)
+
+		require.NoError(t, err)
+		assert.NotNil(t, k.LastUsedAt)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should not write the last use on every request", func(t *testing.T) {
+		mockRepo := new(MockAPIKeyRepository)
+		lastUsedAt := time.Now().Add(-time.Second)
+		mockRepo.On("FindByHash", mock.Anything, hashKey(key)).Return(&APIKey{ID: 1, LastUsedAt: &lastUsedAt}, nil)
+
+		_, err := NewAPIKeyService(mockRepo, nil, nil, signingKey).Authenticate(context.Background(), key)
+
+		require.NoError(t, err)
+		mockRepo.AssertNotCalled(t, "Touch", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should still authenticate if recording the use fails", func(t *testing.T) {
+		mockRepo := new(MockAPIKeyRepository)
+		mockRepo.On("FindByHash", mock.Anything, hashKey(key)).Return(&APIKey{ID: 1}, nil)
+		mockRepo.On("Touch", mock.Anything, 1, mock.Anything).Return(errors.New("db down"))
+
+		k, err := NewAPIKeyService(mockRepo, nil, nil, signingKey).Authenticate(context.Background(), key)
+
+		require.NoError(t, err)
+		assert.Nil(t, k.LastUsedAt)
+	})
+}
+
+func TestAPIKeyService_SigningSecret(t *testing.T) {
+	svc := NewAPIKeyService(nil, nil, nil, signingKey)
+
+	assert.Equal(t, svc.SigningSecret(&APIKey{ID: 1}), svc.SigningSecret(&APIKey{ID: 1}))
+	assert.NotEqual(t, svc.SigningSecret(&APIKey{ID: 1}), svc.SigningSecret(&APIKey{ID: 2}))
+	assert.NotEqual(t, svc.SigningSecret(&APIKey{ID: 1}), NewAPIKeyService(nil, nil, nil, "other").SigningSecret(&APIKey{ID: 1}))
+}
+
+func TestAPIKeyService_UseNonce(t *testing.T) {
+	t.Run("should purge expired nonces at most once per interval", func(t *testing.T) {
+		mockNonces := new(MockNonceRepository)
+		mockNonces.On("Purge", mock.Anything, mock.Anything).Return(nil).Once()
+		mockNonces.On("Use", mock.Anything, 1, mock.Anything, mock.Anything).Return(true, nil)
+		svc := NewAPIKeyService(nil, mockNonces, nil, signingKey)
+
+		fresh, err := svc.UseNonce(context.Background(), 1, "a", time.Now())
+		require.NoError(t, err)
+		assert.True(t, fresh)
+
+		_, err = svc.UseNonce(context.Background(), 1, "b", time.Now())
+		require.NoError(t, err)
+
+		mockNonces.AssertNumberOfCalls(t, "Purge", 1)
+		mockNonces.AssertNumberOfCalls(t, "Use", 2)
+	})
+}
@@ -0,0 +1,21 @@
+package audit
+
+import "context"
+
+type metadataKey struct{}
+
+// Metadata carries request scoped information that is attached to every
+// entry recorded while handling that request.
+type Metadata struct {
+	IP        string
+	RequestID string
+}
+
+func WithMetadata(ctx context.Context, md Metadata) context.Context {
+	return context.WithValue(ctx, metadataKey{}, md)
+}
+
+func MetadataFromContext(ctx context.Context) Metadata {
+	md, _ := ctx.Value(metadataKey{}).(Metadata)
+	return md
+}
@@ -0,0 +1,22 @@
+package audit
+
+type RecordDTO struct {
+	ActorID    *int
+	Action     Action
+	TargetType string
+	TargetID   *int
+	Details    any
+	Before     any
+	After      any
+}
+
+type ChainBreak struct {
+	EntryID int    `json:"entry_id"`
+	Reason  string `json:"reason"`
+}
+
+type VerifyReport struct {
+	Checked  int          `json:"checked"`
+	Unsealed int          `json:"unsealed"`
+	Breaks   []ChainBreak `json:"breaks"`
+}
@@ -0,0 +1,104 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Action string
+
+const (
+	AuthSignup               Action = "auth.signup"
+	AuthLoginSuccess         Action = "auth.login.success"
+	AuthLoginFailure         Action = "auth.login.failure"
+	AuthMFAFailure           Action = "auth.mfa.failure"
+	AuthAccountLocked        Action = "auth.account.locked"
+	AuthAccountUnlocked      Action = "auth.account.unlocked"
+	AuthRefreshReuse         Action = "auth.refresh.reuse"
+	AuthLogout               Action = "auth.logout"
+	AuthLogoutAll            Action = "auth.logout_all"
+	AuthPasswordResetRequest Action = "auth.password_reset.request"
+	AuthPasswordReset        Action = "auth.password_reset.complete"
+	AuthEmailVerified        Action = "auth.email.verified"
+	AuthPasswordChange       Action = "auth.password_change"
+	UserProfileUpdate        Action = "user.profile.update"
+	UserAccountClose         Action = "user.account.close"
+	UserDataExport           Action = "user.data.export"
+	KYCDocumentUpload        Action = "kyc.document.upload"
+	KYCDocumentApprove       Action = "kyc.document.approve"
+	KYCDocumentReject        Action = "kyc.document.reject"
+	KYCDocumentView          Action = "kyc.document.view"
+	APIKeyCreate             Action = "apikey.create"
+	APIKeyRevoke             Action = "apikey.revoke"
+	AdminUserSearch          Action = "admin.user.search"
+	AdminWalletView          Action = "admin.wallet.view"
+	AdminTransactionsView    Action = "admin.transactions.view"
+	AdminAccountFreeze       Action = "admin.account.freeze"
+	AdminAccountUnfreeze     Action = "admin.account.unfreeze"
+	AdminTransactionRefund   Action = "admin.transaction.refund"
+	AdminTransferApprove     Action = "admin.transfer.approve"
+	AdminTransferReject      Action = "admin.transfer.reject"
+	TransferCreate           Action = "transfer.create"
+	TransferHeld             Action = "transfer.held"
+)
+
+// Entry is a single append-only audit record. Hash is computed over every
+// other field plus PrevHash, chaining each entry to the one before it.
+type Entry struct {
+	ID         int             `json:"id"`
+	ActorID    *int            `json:"actor_id"`
+	Action     Action          `json:"action"`
+	TargetType string          `json:"target_type,omitempty"`
+	TargetID   *int            `json:"target_id,omitempty"`
+	IP         string          `json:"ip,omitempty"`
+	RequestID  string          `json:"request_id,omitempty"`
+	Details    json.RawMessage `json:"details,omitempty"`
+	Before     json.RawMessage `json:"before,omitempty"`
+	After      json.RawMessage `json:"after,omitempty"`
+	PrevHash   string          `json:"prev_hash"`
+	Hash       string          `json:"hash"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+func (e *Entry) Validate() error {
+	if strings.TrimSpace(string(e.Action)) == "" {
+		return errors.New("action is required")
+	}
+	if e.ActorID != nil && *e.ActorID <= 0 {
+		return errors.New("actor id must be greater than 0")
+	}
+	return nil
+}
+
+// ComputeHash returns the hex encoded SHA-256 of the entry content and its
+// PrevHash. It must only depend on values that survive a database round trip.
+func (e *Entry) ComputeHash() string {
+	fields := []string{
+		e.PrevHash,
+		optionalInt(e.ActorID),
+		string(e.Action),
+		e.TargetType,
+		optionalInt(e.TargetID),
+		e.IP,
+		e.RequestID,
+		string(e.Details),
+		string(e.Before),
+		string(e.After),
+		e.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+func optionalInt(v *int) string {
+	if v == nil {
+		return ""
+	}
+	return strconv.Itoa(*v)
+}
@@ -0,0 +1,31 @@
+package audit
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Fingerprinter stands in for personal data, such as emails and documents, in
+// audit entries. audit_logs is append-only, so whatever is written there
+// outlives the account it belongs to. A keyed hash still lets whoever holds
+// the key and knows the value find its entries.
+type Fingerprinter interface {
+	Fingerprint(value string) string
+}
+
+type fingerprinter struct {
+	key []byte
+}
+
+// Fingerprint ignores case and surrounding spaces, as emails are matched.
+func (f *fingerprinter) Fingerprint(value string) string {
+	mac := hmac.New(sha256.New, f.key)
+	mac.Write([]byte(strings.ToLower(strings.TrimSpace(value))))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func NewFingerprinter(key string) Fingerprinter {
+	return &fingerprinter{[]byte(key)}
+}
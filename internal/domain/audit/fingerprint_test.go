@@ -0,0 +1,26 @@
+package audit
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprinter_Fingerprint(t *testing.T) {
+	f := NewFingerprinter("key")
+
+	t.Run("should not contain the value", func(t *testing.T) {
+		fp := f.Fingerprint("john@example.com")
+
+		assert.Len(t, fp, 64)
+		assert.NotContains(t, fp, "john")
+	})
+
+	t.Run("should match emails regardless of case and spaces", func(t *testing.T) {
+		assert.Equal(t, f.Fingerprint("john@example.com"), f.Fingerprint(" John@Example.com "))
+	})
+
+	t.Run("should depend on the key", func(t *testing.T) {
+		assert.NotEqual(t, f.Fingerprint("john@example.com"), NewFingerprinter("other").Fingerprint("john@example.com"))
+	})
+}
@@ -0,0 +1,149 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/db"
+)
+
+// chainLockKey serializes appends so every entry sees the hash of the one
+// inserted right before it.
+const chainLockKey = 7_201_100
+
+type AuditRepository interface {
+	// Append joins the transaction on ctx, if any, so an entry is stored
+	// together with the change it records, or not at all.
+	Append(ctx context.Context, e Entry) (int, error)
+	Walk(ctx context.Context, fn func(e Entry) error) error
+}
+
+type auditRepo struct {
+	database     *sql.DB
+	queryTimeout time.Duration
+}
+
+func (r *auditRepo) Append(ctx context.Context, e Entry) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var entryID int
+	err := db.RunInTx(ctx, r.database, func(tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, chainLockKey); err != nil {
+			return err
+		}
+
+		var prevHash sql.NullString
+		err := tx.QueryRowContext(ctx, `
+			SELECT hash FROM audit_logs
+			WHERE hash IS NOT NULL
+			ORDER BY id DESC
+			LIMIT 1
+		`).Scan(&prevHash)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		e.PrevHash = prevHash.String
+		e.Hash = e.ComputeHash()
+
+		query := `
+			INSERT INTO audit_logs (
+				actor_id, action, target_type, target_id, ip, request_id,
+				details, before, after, prev_hash, hash, created_at
+			)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			RETURNING id
+		`
+
+		return tx.QueryRowContext(
+			ctx,
+			query,
+			e.ActorID,
+			e.Action,
+			e.TargetType,
+			e.TargetID,
+			e.IP,
+			e.RequestID,
+			nullableJSON(e.Details),
+			nullableJSON(e.Before),
+			nullableJSON(e.After),
+			e.PrevHash,
+			e.Hash,
+			e.CreatedAt,
+		).Scan(&entryID)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return entryID, nil
+}
+
+// Walk streams every entry in insertion order. It does not apply the query
+// timeout because a full walk grows with the size of the log.
+func (r *auditRepo) Walk(ctx context.Context, fn func(e Entry) error) error {
+	query := `
+		SELECT id, actor_id, action, COALESCE(target_type, ''), target_id, COALESCE(ip, ''),
+			COALESCE(request_id, ''), details, before, after, COALESCE(prev_hash, ''),
+			COALESCE(hash, ''), created_at
+		FROM audit_logs
+		ORDER BY id
+	`
+
+	rows, err := r.database.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e Entry
+		var details, before, after []byte
+
+		err := rows.Scan(
+			&e.ID,
+			&e.ActorID,
+			&e.Action,
+			&e.TargetType,
+			&e.TargetID,
+			&e.IP,
+			&e.RequestID,
+			&details,
+			&before,
+			&after,
+			&e.PrevHash,
+			&e.Hash,
+			&e.CreatedAt,
+		)
+		if err != nil {
+			return err
+		}
+
+		e.Details = details
+		e.Before = before
+		e.After = after
+
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+func nullableJSON(raw []byte) any {
+	if len(raw) == 0 {
+		return nil
+	}
+	return string(raw)
+}
+
+func NewAuditRepository(database *sql.DB, qt time.Duration) AuditRepository {
+	return &auditRepo{
+		database:     database,
+		queryTimeout: qt,
+	}
+}
@@ -0,0 +1,28 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAuditRepository struct {
+	mock.Mock
+}
+
+func (m *MockAuditRepository) Append(ctx context.Context, e Entry) (int, error) {
+	args := m.Called(ctx, e)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockAuditRepository) Walk(ctx context.Context, fn func(e Entry) error) error {
+	args := m.Called(ctx, fn)
+	if entries, ok := args.Get(0).([]Entry); ok {
+		for _, e := range entries {
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+	}
+	return args.Error(1)
+}
@@ -0,0 +1,104 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+type AuditService interface {
+	Record(ctx context.Context, dto RecordDTO) error
+	Verify(ctx context.Context) (*VerifyReport, error)
+}
+
+type auditSvc struct {
+	auditRepo AuditRepository
+}
+
+func (s *auditSvc) Record(ctx context.Context, dto RecordDTO) error {
+	md := MetadataFromContext(ctx)
+
+	entry := Entry{
+		ActorID:    dto.ActorID,
+		Action:     dto.Action,
+		TargetType: dto.TargetType,
+		TargetID:   dto.TargetID,
+		IP:         md.IP,
+		RequestID:  md.RequestID,
+		// postgres keeps microsecond precision, so the hash must too
+		CreatedAt: time.Now().UTC().Truncate(time.Microsecond),
+	}
+
+	var err error
+	if entry.Details, err = marshalOptional(dto.Details); err != nil {
+		return err
+	}
+	if entry.Before, err = marshalOptional(dto.Before); err != nil {
+		return err
+	}
+	if entry.After, err = marshalOptional(dto.After); err != nil {
+		return err
+	}
+
+	if err := entry.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := s.auditRepo.Append(ctx, entry); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Verify walks the whole log and reports every entry whose link to the
+// previous entry or whose own content hash does not match. Entries written
+// before the chain existed have no hash and are only counted.
+func (s *auditSvc) Verify(ctx context.Context) (*VerifyReport, error) {
+	report := &VerifyReport{Breaks: []ChainBreak{}}
+
+	var prevHash string
+	err := s.auditRepo.Walk(ctx, func(e Entry) error {
+		if e.Hash == "" {
+			report.Unsealed++
+			return nil
+		}
+
+		report.Checked++
+
+		if e.PrevHash != prevHash {
+			report.Breaks = append(report.Breaks, ChainBreak{
+				EntryID: e.ID,
+				Reason:  "prev_hash does not match the previous entry",
+			})
+		}
+
+		if e.ComputeHash() != e.Hash {
+			report.Breaks = append(report.Breaks, ChainBreak{
+				EntryID: e.ID,
+				Reason:  "content does not match the stored hash",
+			})
+		}
+
+		prevHash = e.Hash
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return report, nil
+}
+
+func marshalOptional(v any) (json.RawMessage, error) {
+	if v == nil {
+		return nil, nil
+	}
+	return json.Marshal(v)
+}
+
+func NewAuditService(auditRepo AuditRepository) AuditService {
+	return &auditSvc{
+		auditRepo,
+	}
+}
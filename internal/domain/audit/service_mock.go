@@ -0,0 +1,25 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockAuditService struct {
+	mock.Mock
+}
+
+func (m *MockAuditService) Record(ctx context.Context, dto RecordDTO) error {
+	args := m.Called(ctx, dto)
+	return args.Error(0)
+}
+
+func (m *MockAuditService) Verify(ctx context.Context) (*VerifyReport, error) {
+	args := m.Called(ctx)
+	r, ok := args.Get(0).(*VerifyReport)
+	if !ok && args.Get(0) != nil {
+		panic("expected *VerifyReport or nil")
+	}
+	return r, args.Error(1)
+}
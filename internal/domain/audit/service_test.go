@@ -0,0 +1,146 @@
+package audit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAuditService_Record(t *testing.T) {
+	t.Run("should return error if action is empty", func(t *testing.T) {
+		mockRepo := new(MockAuditRepository)
+		service := NewAuditService(mockRepo)
+
+		err := service.Record(context.Background(), RecordDTO{})
+
+		assert.Error(t, err)
+		mockRepo.AssertNotCalled(t, "Append", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should return error if db fails", func(t *testing.T) {
+		mockRepo := new(MockAuditRepository)
+		mockRepo.On("Append", mock.Anything, mock.Anything).Return(0, errors.New("db fail"))
+
+		service := NewAuditService(mockRepo)
+
+		err := service.Record(context.Background(), RecordDTO{Action: AdminUserSearch})
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "db fail")
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should save the entry with snapshots and request metadata", func(t *testing.T) {
+		mockRepo := new(MockAuditRepository)
+
+		var entry Entry
+		mockRepo.On("Append", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				entry = args.Get(1).(Entry)
+			}).Return(1, nil)
+
+		service := NewAuditService(mockRepo)
+
+		ctx := WithMetadata(context.Background(), Metadata{IP: "10.0.0.1", RequestID: "req-1"})
+		actorID, targetID := 1, 2
+		err := service.Record(ctx, RecordDTO{
+			ActorID:    &actorID,
+			Action:     AdminAccountFreeze,
+			TargetType: "user",
+			TargetID:   &targetID,
+			Details:    map[string]any{"reason": "fraud"},
+			Before:     map[string]bool{"active": true},
+			After:      map[string]bool{"active": false},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, &actorID, entry.ActorID)
+		assert.Equal(t, AdminAccountFreeze, entry.Action)
+		assert.Equal(t, "10.0.0.1", entry.IP)
+		assert.Equal(t, "req-1", entry.RequestID)
+		assert.JSONEq(t, `{"reason":"fraud"}`, string(entry.Details))
+		assert.JSONEq(t, `{"active":true}`, string(entry.Before))
+		assert.JSONEq(t, `{"active":false}`, string(entry.After))
+		assert.Equal(t, entry.CreatedAt, entry.CreatedAt.Truncate(time.Microsecond))
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestAuditService_Verify(t *testing.T) {
+	seal := func(prev string, id int, action Action) Entry {
+		e := Entry{ID: id, Action: action, PrevHash: prev, CreatedAt: time.Unix(int64(id), 0)}
+		e.Hash = e.ComputeHash()
+		return e
+	}
+
+	t.Run("should report no breaks for an intact chain", func(t *testing.T) {
+		first := seal("", 1, AuthSignup)
+		second := seal(first.Hash, 2, AuthLoginSuccess)
+
+		mockRepo := new(MockAuditRepository)
+		mockRepo.On("Walk", mock.Anything, mock.Anything).
+			Return([]Entry{{ID: 0, Action: AdminUserSearch}, first, second}, nil)
+
+		service := NewAuditService(mockRepo)
+
+		report, err := service.Verify(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 2, report.Checked)
+		assert.Equal(t, 1, report.Unsealed)
+		assert.Empty(t, report.Breaks)
+	})
+
+	t.Run("should report tampered content and broken links", func(t *testing.T) {
+		first := seal("", 1, AuthSignup)
+		second := seal(first.Hash, 2, AuthLoginSuccess)
+		third := seal(second.Hash, 3, AuthLoginFailure)
+
+		second.Action = AdminTransactionRefund
+
+		mockRepo := new(MockAuditRepository)
+		mockRepo.On("Walk", mock.Anything, mock.Anything).
+			Return([]Entry{first, second, third}, nil)
+
+		service := NewAuditService(mockRepo)
+
+		report, err := service.Verify(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, report.Checked)
+		assert.Equal(t, []ChainBreak{{EntryID: 2, Reason: "content does not match the stored hash"}}, report.Breaks)
+	})
+
+	t.Run("should report a removed entry", func(t *testing.T) {
+		first := seal("", 1, AuthSignup)
+		second := seal(first.Hash, 2, AuthLoginSuccess)
+		third := seal(second.Hash, 3, AuthLoginFailure)
+
+		mockRepo := new(MockAuditRepository)
+		mockRepo.On("Walk", mock.Anything, mock.Anything).
+			Return([]Entry{first, third}, nil)
+
+		service := NewAuditService(mockRepo)
+
+		report, err := service.Verify(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, []ChainBreak{{EntryID: 3, Reason: "prev_hash does not match the previous entry"}}, report.Breaks)
+	})
+
+	t.Run("should return error if db fails", func(t *testing.T) {
+		mockRepo := new(MockAuditRepository)
+		mockRepo.On("Walk", mock.Anything, mock.Anything).Return(nil, errors.New("db fail"))
+
+		service := NewAuditService(mockRepo)
+
+		report, err := service.Verify(context.Background())
+
+		assert.Error(t, err)
+		assert.Nil(t, report)
+	})
+}
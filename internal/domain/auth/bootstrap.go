@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/tracing"
+)
+
+// AdminBootstrapper creates the first admin account. It only needs the user
+// lookup and the password hasher, so it runs before the other services exist.
+type AdminBootstrapper interface {
+	BootstrapAdmin(ctx context.Context, dto user.AdminUserDTO) error
+}
+
+type adminBootstrapper struct {
+	userService    user.UserService
+	passwordHasher PasswordHasher
+}
+
+// BootstrapAdmin creates the first admin account if it does not exist yet.
+// It is safe to call on every startup.
+func (b *adminBootstrapper) BootstrapAdmin(ctx context.Context, dto user.AdminUserDTO) error {
+	ctx, span := tracing.Start(ctx, "adminBootstrapper.BootstrapAdmin")
+	defer span.End()
+
+	existing, err := b.userService.FindByEmail(ctx, dto.Email)
+	if err != nil {
+		var httpError *apperror.HttpError
+		if ok := errors.As(err, &httpError); !ok {
+			return err
+		}
+	}
+
+	if existing != nil {
+		if existing.Role != user.Admin {
+			return errors.New("bootstrap admin email already in use by a non admin user")
+		}
+		return nil
+	}
+
+	hashed, err := b.passwordHasher.Hash(ctx, dto.Password)
+	if err != nil {
+		return err
+	}
+
+	_, err = b.userService.CreateAdmin(ctx, user.AdminUserDTO{
+		Fullname: dto.Fullname,
+		Email:    dto.Email,
+		Password: hashed,
+	})
+
+	return err
+}
+
+func NewAdminBootstrapper(usrSvc user.UserService, hasher PasswordHasher) AdminBootstrapper {
+	return &adminBootstrapper{
+		userService:    usrSvc,
+		passwordHasher: hasher,
+	}
+}
@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestAdminBootstrapper_BootstrapAdmin(t *testing.T) {
+	dto := user.AdminUserDTO{
+		Fullname: "Root Admin",
+		Email:    "admin@picpay.com",
+		Password: "password123",
+	}
+
+	t.Run("should do nothing if admin already exists", func(t *testing.T) {
+		userServiceMock := new(user.MockUserService)
+		userServiceMock.On("FindByEmail", mock.Anything, dto.Email).
+			Return(&user.User{ID: 1, Role: user.Admin}, nil)
+
+		hasherMock := new(MockPasswordHasher)
+
+		service := NewAdminBootstrapper(userServiceMock, hasherMock)
+
+		err := service.BootstrapAdmin(context.Background(), dto)
+
+		assert.NoError(t, err)
+		userServiceMock.AssertNotCalled(t, "CreateAdmin", mock.Anything, mock.Anything)
+		hasherMock.AssertNotCalled(t, "Hash", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should fail if email belongs to a non admin user", func(t *testing.T) {
+		userServiceMock := new(user.MockUserService)
+		userServiceMock.On("FindByEmail", mock.Anything, dto.Email).
+			Return(&user.User{ID: 1, Role: user.Common}, nil)
+
+		service := NewAdminBootstrapper(userServiceMock, new(MockPasswordHasher))
+
+		err := service.BootstrapAdmin(context.Background(), dto)
+
+		assert.Error(t, err)
+		userServiceMock.AssertNotCalled(t, "CreateAdmin", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should create the admin with a hashed password", func(t *testing.T) {
+		userServiceMock := new(user.MockUserService)
+		userServiceMock.On("FindByEmail", mock.Anything, dto.Email).
+			Return(nil, apperror.NewHttpError(http.StatusNotFound, "user not found"))
+		userServiceMock.On("CreateAdmin", mock.Anything, user.AdminUserDTO{
+			Fullname: dto.Fullname,
+			Email:    dto.Email,
+			Password: "hashed",
+		}).Return(1, nil)
+
+		hasherMock := new(MockPasswordHasher)
+		hasherMock.On("Hash", mock.Anything, dto.Password).Return("hashed", nil)
+
+		service := NewAdminBootstrapper(userServiceMock, hasherMock)
+
+		err := service.BootstrapAdmin(context.Background(), dto)
+
+		assert.NoError(t, err)
+		userServiceMock.AssertExpectations(t)
+		hasherMock.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// BreachChecker tells whether a password appears in a known data breach.
+type BreachChecker interface {
+	IsBreached(password string) (bool, error)
+}
+
+// fileBreachChecker looks passwords up in an offline copy of the Have I Been
+// Pwned list, split by k-anonymity prefix: dir holds one "<PREFIX>.txt" file
+// per 5 hex character prefix of the SHA-1 hash, each line being
+// "<35 character suffix>:<count>", the same format the range API returns.
+// A missing prefix file means no password with that prefix is listed.
+type fileBreachChecker struct {
+	dir string
+}
+
+func (c *fileBreachChecker) IsBreached(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	f, err := os.Open(filepath.Join(c.dir, prefix+".txt"))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if strings.EqualFold(line, suffix) {
+			return true, nil
+		}
+	}
+
+	return false, scanner.Err()
+}
+
+func NewFileBreachChecker(dir string) BreachChecker {
+	return &fileBreachChecker{dir}
+}
@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileBreachChecker_IsBreached(t *testing.T) {
+	dir := t.TempDir()
+
+	// sha1("password") = 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+	content := "003D68EB55068C33ACE09247EE4C639306B:3\r\n1E4C9B93F3F0682250B6CF8331B7EE68FD8:9659365\r\n"
+	err := os.WriteFile(filepath.Join(dir, "5BAA6.txt"), []byte(content), 0o600)
+	assert.NoError(t, err)
+
+	checker := NewFileBreachChecker(dir)
+
+	t.Run("should find a listed password", func(t *testing.T) {
+		breached, err := checker.IsBreached("password")
+
+		assert.NoError(t, err)
+		assert.True(t, breached)
+	})
+
+	t.Run("should not flag a password missing from its prefix file", func(t *testing.T) {
+		// shares no suffix with the lines above even if the prefix matched
+		breached, err := checker.IsBreached("correct horse battery staple")
+
+		assert.NoError(t, err)
+		assert.False(t, breached)
+	})
+}
@@ -1,14 +1,61 @@
 package auth
 
 type SignupDTO struct {
-	Fullname string  `json:"fullname" validate:"required,max=100"`
-	CPF      *string `json:"cpf,omitempty" validate:"omitempty,len=11"`
-	CNPJ     *string `json:"cnpj,omitempty" validate:"omitempty,len=14"`
-	Email    string  `json:"email" validate:"required,email,max=100"`
-	Password string  `json:"password" validate:"required,gte=6,max=100"`
+	Fullname  string  `json:"fullname" validate:"required,max=100"`
+	CPF       *string `json:"cpf,omitempty" validate:"omitempty,max=14"`
+	CNPJ      *string `json:"cnpj,omitempty" validate:"omitempty,max=18"`
+	LegalName *string `json:"legal_name,omitempty" validate:"omitempty,max=255"`
+	TradeName *string `json:"trade_name,omitempty" validate:"omitempty,max=255"`
+	Email     string  `json:"email" validate:"required,email,max=100"`
+	Password  string  `json:"password" validate:"required,max=100"`
 }
 
 type LoginDTO struct {
 	Email    string `json:"email" validate:"required,email,max=100"`
 	Password string `json:"password" validate:"required,gte=6,max=100"`
 }
+
+type RefreshDTO struct {
+	RefreshToken string `json:"refresh_token" validate:"required,max=200"`
+}
+
+type TokenPairDTO struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// LoginResultDTO carries either a session or, when MFARequired is set, the
+// token to complete the login with a second factor.
+type LoginResultDTO struct {
+	*TokenPairDTO
+	MFARequired bool   `json:"mfa_required,omitempty"`
+	MFAToken    string `json:"mfa_token,omitempty"`
+}
+
+type MFAVerifyDTO struct {
+	MFAToken string `json:"mfa_token" validate:"required,max=200"`
+	Code     string `json:"code" validate:"required,max=32"`
+}
+
+type LogoutDTO struct {
+	RefreshToken *string `json:"refresh_token,omitempty" validate:"omitempty,max=200"`
+}
+
+type ForgotPasswordDTO struct {
+	Email string `json:"email" validate:"required,email,max=100"`
+}
+
+type ResetPasswordDTO struct {
+	Token    string `json:"token" validate:"required,max=200"`
+	Password string `json:"password" validate:"required,max=100"`
+}
+
+type ChangePasswordDTO struct {
+	CurrentPassword string `json:"current_password" validate:"required,max=100"`
+	NewPassword     string `json:"new_password" validate:"required,max=100"`
+}
+
+type CloseAccountDTO struct {
+	Password string `json:"password" validate:"required,max=100"`
+}
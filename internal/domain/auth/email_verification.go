@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+)
+
+var ErrInvalidVerificationToken = errors.New("invalid email verification token")
+
+// ResendThrottle limits how often verification emails can be requested for the
+// same user: at most Max emails per Window, and never twice within Cooldown.
+type ResendThrottle struct {
+	Cooldown time.Duration
+	Window   time.Duration
+	Max      int
+}
+
+type EmailVerificationService interface {
+	Issue(ctx context.Context, userID int) (string, error)
+	Consume(ctx context.Context, token string) (userID int, err error)
+	CanResend(ctx context.Context, userID int) (bool, error)
+}
+
+type emailVerificationSvc struct {
+	verificationRepo EmailVerificationRepository
+	throttleRepo     RequestThrottleRepository
+	ttl              time.Duration
+	throttle         ResendThrottle
+}
+
+// Issue creates a new verification token for the user. Older tokens still
+// pending are invalidated, so only the most recent email can be used.
+func (s *emailVerificationSvc) Issue(ctx context.Context, userID int) (string, error) {
+	if err := s.verificationRepo.InvalidateByUser(ctx, userID); err != nil {
+		return "", err
+	}
+
+	token, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	err = s.verificationRepo.Save(ctx, EmailVerificationToken{
+		UserID:    userID,
+		TokenHash: hashToken(token),
+		ExpiresAt: now.Add(s.ttl),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func (s *emailVerificationSvc) Consume(ctx context.Context, token string) (int, error) {
+	stored, err := s.verificationRepo.Consume(ctx, hashToken(token), time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	if stored == nil {
+		return 0, ErrInvalidVerificationToken
+	}
+
+	return stored.UserID, nil
+}
+
+// CanResend counts a resend against the user and reports whether it is under
+// the limits. Checking and counting is one step, so parallel requests cannot
+// all pass on the same remaining slot.
+func (s *emailVerificationSvc) CanResend(ctx context.Context, userID int) (bool, error) {
+	return s.throttleRepo.Hit(ctx, "verify:user:"+strconv.Itoa(userID), time.Now(), s.throttle)
+}
+
+func NewEmailVerificationService(
+	verificationRepo EmailVerificationRepository,
+	throttleRepo RequestThrottleRepository,
+	ttl time.Duration,
+	throttle ResendThrottle) EmailVerificationService {
+
+	return &emailVerificationSvc{verificationRepo, throttleRepo, ttl, throttle}
+}
@@ -0,0 +1,26 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockEmailVerificationService struct {
+	mock.Mock
+}
+
+func (m *MockEmailVerificationService) Issue(ctx context.Context, userID int) (string, error) {
+	args := m.Called(ctx, userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockEmailVerificationService) Consume(ctx context.Context, token string) (int, error) {
+	args := m.Called(ctx, token)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockEmailVerificationService) CanResend(ctx context.Context, userID int) (bool, error) {
+	args := m.Called(ctx, userID)
+	return args.Bool(0), args.Error(1)
+}
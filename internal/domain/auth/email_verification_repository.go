@@ -0,0 +1,94 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type EmailVerificationToken struct {
+	ID        int
+	UserID    int
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+type EmailVerificationRepository interface {
+	Save(ctx context.Context, t EmailVerificationToken) error
+	Consume(ctx context.Context, hash string, now time.Time) (*EmailVerificationToken, error)
+	InvalidateByUser(ctx context.Context, userID int) error
+}
+
+type emailVerificationRepo struct {
+	database     *sql.DB
+	queryTimeout time.Duration
+}
+
+func (r *emailVerificationRepo) Save(ctx context.Context, t EmailVerificationToken) error {
+	query := `
+		INSERT INTO email_verification_tokens (user_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := r.database.ExecContext(ctx, query, t.UserID, t.TokenHash, t.ExpiresAt, t.CreatedAt)
+	return err
+}
+
+// Consume marks an unused, unexpired token as used and returns it. Checking and
+// marking happen in a single statement, so a token can never be used twice.
+func (r *emailVerificationRepo) Consume(ctx context.Context, hash string, now time.Time) (*EmailVerificationToken, error) {
+	query := `
+		UPDATE email_verification_tokens
+		SET used_at = $2
+		WHERE token_hash = $1 AND used_at IS NULL AND expires_at > $2
+		RETURNING id, user_id, token_hash, expires_at, used_at, created_at
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var t EmailVerificationToken
+	err := r.database.QueryRowContext(ctx, query, hash, now).Scan(
+		&t.ID,
+		&t.UserID,
+		&t.TokenHash,
+		&t.ExpiresAt,
+		&t.UsedAt,
+		&t.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+func (r *emailVerificationRepo) InvalidateByUser(ctx context.Context, userID int) error {
+	query := `
+		UPDATE email_verification_tokens
+		SET used_at = NOW()
+		WHERE user_id = $1 AND used_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := r.database.ExecContext(ctx, query, userID)
+	return err
+}
+
+func NewEmailVerificationRepository(database *sql.DB, qt time.Duration) EmailVerificationRepository {
+	return &emailVerificationRepo{
+		database:     database,
+		queryTimeout: qt,
+	}
+}
@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockEmailVerificationRepository struct {
+	mock.Mock
+}
+
+func (m *MockEmailVerificationRepository) Save(ctx context.Context, t EmailVerificationToken) error {
+	args := m.Called(ctx, t)
+	return args.Error(0)
+}
+
+func (m *MockEmailVerificationRepository) Consume(ctx context.Context, hash string, now time.Time) (*EmailVerificationToken, error) {
+	args := m.Called(ctx, hash, now)
+	if t, ok := args.Get(0).(*EmailVerificationToken); ok {
+		return t, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockEmailVerificationRepository) InvalidateByUser(ctx context.Context, userID int) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var testThrottle = ResendThrottle{Cooldown: time.Minute, Window: time.Hour, Max: 3}
+
+func TestEmailVerificationService_Issue(t *testing.T) {
+	t.Run("should invalidate pending tokens and store only the hash", func(t *testing.T) {
+		mockRepo := new(MockEmailVerificationRepository)
+		mockRepo.On("InvalidateByUser", mock.Anything, 1).Return(nil).Once()
+
+		var stored EmailVerificationToken
+		mockRepo.On("Save", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				stored = args.Get(1).(EmailVerificationToken)
+			}).Return(nil)
+
+		service := NewEmailVerificationService(mockRepo, nil, time.Hour*24, testThrottle)
+
+		token, err := service.Issue(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, hashToken(token), stored.TokenHash)
+		assert.WithinDuration(t, time.Now().Add(time.Hour*24), stored.ExpiresAt, time.Second)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestEmailVerificationService_Consume(t *testing.T) {
+	t.Run("should reject tokens that are unknown, used or expired", func(t *testing.T) {
+		mockRepo := new(MockEmailVerificationRepository)
+		mockRepo.On("Consume", mock.Anything, hashToken("used"), mock.Anything).Return(nil, nil)
+
+		service := NewEmailVerificationService(mockRepo, nil, time.Hour*24, testThrottle)
+
+		_, err := service.Consume(context.Background(), "used")
+
+		assert.ErrorIs(t, err, ErrInvalidVerificationToken)
+	})
+}
+
+func TestEmailVerificationService_CanResend(t *testing.T) {
+	t.Run("should count the resend against the user", func(t *testing.T) {
+		mockThrottle := new(MockRequestThrottleRepository)
+		mockThrottle.On("Hit", mock.Anything, "verify:user:1", mock.Anything, testThrottle).Return(true, nil).Once()
+
+		service := NewEmailVerificationService(nil, mockThrottle, time.Hour*24, testThrottle)
+
+		ok, err := service.CanResend(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		mockThrottle.AssertExpectations(t)
+	})
+
+	t.Run("should deny a resend over the limits", func(t *testing.T) {
+		mockThrottle := new(MockRequestThrottleRepository)
+		mockThrottle.On("Hit", mock.Anything, "verify:user:1", mock.Anything, testThrottle).Return(false, nil)
+
+		service := NewEmailVerificationService(nil, mockThrottle, time.Hour*24, testThrottle)
+
+		ok, err := service.CanResend(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+}
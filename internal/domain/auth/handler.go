@@ -1,10 +1,14 @@
 package auth
 
 import (
+	"errors"
+	"io"
 	"net/http"
 
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
 	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
 	"github.com/DevVictor19/pic-pay-challenge/internal/infra/utils"
+	"github.com/golang-jwt/jwt/v5"
 )
 
 type AuthHandler struct {
@@ -44,16 +48,247 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) error {
 		return apperror.NewHttpError(http.StatusBadRequest, err.Error())
 	}
 
-	token, err := authService.Login(r.Context(), body)
+	tokens, err := authService.Login(r.Context(), body)
 	if err != nil {
 		return err
 	}
 
-	return utils.WriteJSON(w, http.StatusOK, map[string]string{
-		"token": token,
+	return utils.WriteJSON(w, http.StatusOK, tokens)
+}
+
+func (h *AuthHandler) VerifyMFA(w http.ResponseWriter, r *http.Request) error {
+	var body MFAVerifyDTO
+	if err := utils.ReadJSON(w, r, &body); err != nil {
+		return apperror.NewHttpError(http.StatusBadRequest, "error parsing body request")
+	}
+
+	if err := utils.Validate.Struct(body); err != nil {
+		return apperror.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	tokens, err := h.authService.VerifyMFA(r.Context(), body)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, tokens)
+}
+
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) error {
+	authService := h.authService
+
+	var body RefreshDTO
+	if err := utils.ReadJSON(w, r, &body); err != nil {
+		return apperror.NewHttpError(http.StatusBadRequest, "error parsing body request")
+	}
+
+	if err := utils.Validate.Struct(body); err != nil {
+		return apperror.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	tokens, err := authService.Refresh(r.Context(), body)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, tokens)
+}
+
+func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) error {
+	authService := h.authService
+
+	usr := r.Context().Value(utils.UserKey).(*user.User)
+	claims := r.Context().Value(utils.ClaimsKey).(jwt.MapClaims)
+
+	jti, _ := claims["jti"].(string)
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return apperror.NewHttpError(http.StatusUnauthorized, "token is missing exp")
+	}
+
+	var body LogoutDTO
+	if err := utils.ReadJSON(w, r, &body); err != nil && !errors.Is(err, io.EOF) {
+		return apperror.NewHttpError(http.StatusBadRequest, "error parsing body request")
+	}
+
+	if err := utils.Validate.Struct(body); err != nil {
+		return apperror.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := authService.Logout(r.Context(), usr.ID, jti, exp.Time, body); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *AuthHandler) LogoutAll(w http.ResponseWriter, r *http.Request) error {
+	usr := r.Context().Value(utils.UserKey).(*user.User)
+
+	if err := h.authService.LogoutAll(r.Context(), usr.ID); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *AuthHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) error {
+	var body ForgotPasswordDTO
+	if err := utils.ReadJSON(w, r, &body); err != nil {
+		return apperror.NewHttpError(http.StatusBadRequest, "error parsing body request")
+	}
+
+	if err := utils.Validate.Struct(body); err != nil {
+		return apperror.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.authService.ForgotPassword(r.Context(), body); err != nil {
+		return err
+	}
+
+	return utils.WriteJSON(w, http.StatusAccepted, map[string]string{
+		"message": "if the email is registered, a reset link was sent to it",
 	})
 }
 
+func (h *AuthHandler) ResetPassword(w http.ResponseWriter, r *http.Request) error {
+	var body ResetPasswordDTO
+	if err := utils.ReadJSON(w, r, &body); err != nil {
+		return apperror.NewHttpError(http.StatusBadRequest, "error parsing body request")
+	}
+
+	if err := utils.Validate.Struct(body); err != nil {
+		return apperror.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.authService.ResetPassword(r.Context(), body); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *AuthHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) error {
+	token := r.URL.Query().Get("token")
+	if token == "" || len(token) > 200 {
+		return apperror.NewHttpError(http.StatusBadRequest, "token query param is required")
+	}
+
+	if err := h.authService.VerifyEmail(r.Context(), token); err != nil {
+		return err
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "email verified"})
+}
+
+func (h *AuthHandler) Unlock(w http.ResponseWriter, r *http.Request) error {
+	token := r.URL.Query().Get("token")
+	if token == "" || len(token) > 200 {
+		return apperror.NewHttpError(http.StatusBadRequest, "token query param is required")
+	}
+
+	if err := h.authService.Unlock(r.Context(), token); err != nil {
+		return err
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "account unlocked"})
+}
+
+func (h *AuthHandler) ResendVerification(w http.ResponseWriter, r *http.Request) error {
+	usr := r.Context().Value(utils.UserKey).(*user.User)
+
+	if err := h.authService.ResendVerification(r.Context(), usr); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+
+	return nil
+}
+
+func (h *AuthHandler) UpdateProfile(w http.ResponseWriter, r *http.Request) error {
+	usr := r.Context().Value(utils.UserKey).(*user.User)
+
+	var body user.UpdateProfileDTO
+	if err := utils.ReadJSON(w, r, &body); err != nil {
+		return apperror.NewHttpError(http.StatusBadRequest, "error parsing body request")
+	}
+
+	if err := utils.Validate.Struct(body); err != nil {
+		return apperror.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	updated, err := h.authService.UpdateProfile(r.Context(), usr, body)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, updated)
+}
+
+func (h *AuthHandler) ChangePassword(w http.ResponseWriter, r *http.Request) error {
+	usr := r.Context().Value(utils.UserKey).(*user.User)
+
+	var body ChangePasswordDTO
+	if err := utils.ReadJSON(w, r, &body); err != nil {
+		return apperror.NewHttpError(http.StatusBadRequest, "error parsing body request")
+	}
+
+	if err := utils.Validate.Struct(body); err != nil {
+		return apperror.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.authService.ChangePassword(r.Context(), usr, body); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *AuthHandler) CloseAccount(w http.ResponseWriter, r *http.Request) error {
+	usr := r.Context().Value(utils.UserKey).(*user.User)
+
+	var body CloseAccountDTO
+	if err := utils.ReadJSON(w, r, &body); err != nil {
+		return apperror.NewHttpError(http.StatusBadRequest, "error parsing body request")
+	}
+
+	if err := utils.Validate.Struct(body); err != nil {
+		return apperror.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	if err := h.authService.CloseAccount(r.Context(), usr, body); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+type JWKSHandler struct {
+	jwtService JWTService
+}
+
+func (h *JWKSHandler) Get(w http.ResponseWriter, r *http.Request) error {
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	return utils.WriteJSON(w, http.StatusOK, h.jwtService.JWKS())
+}
+
+func NewJWKSHandler(jwtService JWTService) *JWKSHandler {
+	return &JWKSHandler{
+		jwtService,
+	}
+}
+
 func NewAuthHandler(authService AuthService) *AuthHandler {
 	return &AuthHandler{
 		authService,
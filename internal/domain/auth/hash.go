@@ -1,24 +1,193 @@
 package auth
 
-import "golang.org/x/crypto/bcrypt"
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
 
-type BcryptService interface {
-	Hash(pwd string) (string, error)
-	Compare(pwd, hash string) bool
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+type HashAlgorithm string
+
+const (
+	Argon2id HashAlgorithm = "argon2id"
+	Bcrypt   HashAlgorithm = "bcrypt"
+)
+
+const (
+	argon2SaltSize = 16
+	argon2KeySize  = 32
+)
+
+var errMalformedHash = errors.New("malformed password hash")
+
+// HashParams selects the algorithm used for new hashes and its cost. Argon2
+// memory is in KiB.
+type HashParams struct {
+	Algorithm     HashAlgorithm
+	BcryptCost    int
+	Argon2Time    uint32
+	Argon2Memory  uint32
+	Argon2Threads uint8
 }
 
-type bcryptSvc struct{}
+// PasswordHasher hashes passwords in a self-describing format: bcrypt hashes
+// carry their cost and argon2id hashes use the PHC string format
+// ("$argon2id$v=19$m=...,t=...,p=...$salt$key"). Hashes made with any
+// supported algorithm can be compared, so the configured one can change
+// without invalidating stored passwords.
+//
+// Hash and Compare wait for a free slot when the concurrency limit is reached
+// and give up with the context's error once it is done.
+type PasswordHasher interface {
+	Hash(ctx context.Context, pwd string) (string, error)
+	Compare(ctx context.Context, pwd, hash string) (bool, error)
+	// NeedsRehash reports whether hash was made with another algorithm or
+	// parameters than the ones currently configured.
+	NeedsRehash(hash string) bool
+}
 
-func (s *bcryptSvc) Hash(pwd string) (string, error) {
-	bytes, err := bcrypt.GenerateFromPassword([]byte(pwd), 14)
-	return string(bytes), err
+// passwordHasher bounds how many hashes run at once. Each one takes a CPU
+// core for a noticeable time, so without a limit a burst of logins starves
+// every other request.
+type passwordHasher struct {
+	params HashParams
+	slots  chan struct{}
 }
 
-func (s *bcryptSvc) Compare(pwd, hash string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(pwd))
-	return err == nil
+func (h *passwordHasher) Hash(ctx context.Context, pwd string) (string, error) {
+	if err := h.acquire(ctx); err != nil {
+		return "", err
+	}
+	defer h.release()
+
+	if h.params.Algorithm == Bcrypt {
+		bytes, err := bcrypt.GenerateFromPassword([]byte(pwd), h.params.BcryptCost)
+		return string(bytes), err
+	}
+
+	salt := make([]byte, argon2SaltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(pwd), salt, h.params.Argon2Time, h.params.Argon2Memory, h.params.Argon2Threads, argon2KeySize)
+
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		h.params.Argon2Memory,
+		h.params.Argon2Time,
+		h.params.Argon2Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h *passwordHasher) Compare(ctx context.Context, pwd, hash string) (bool, error) {
+	if err := h.acquire(ctx); err != nil {
+		return false, err
+	}
+	defer h.release()
+
+	if !strings.HasPrefix(hash, "$argon2id$") {
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(pwd))
+		return err == nil, nil
+	}
+
+	params, salt, key, err := decodeArgon2(hash)
+	if err != nil {
+		return false, nil
+	}
+
+	other := argon2.IDKey([]byte(pwd), salt, params.Argon2Time, params.Argon2Memory, params.Argon2Threads, uint32(len(key)))
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
 }
 
-func NewBcryptService() BcryptService {
-	return &bcryptSvc{}
+func (h *passwordHasher) NeedsRehash(hash string) bool {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		if h.params.Algorithm != Argon2id {
+			return true
+		}
+
+		params, _, _, err := decodeArgon2(hash)
+		return err != nil ||
+			params.Argon2Time != h.params.Argon2Time ||
+			params.Argon2Memory != h.params.Argon2Memory ||
+			params.Argon2Threads != h.params.Argon2Threads
+	}
+
+	if h.params.Algorithm != Bcrypt {
+		return true
+	}
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	return err != nil || cost != h.params.BcryptCost
+}
+
+func (h *passwordHasher) acquire(ctx context.Context) error {
+	if h.slots == nil {
+		return nil
+	}
+
+	select {
+	case h.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (h *passwordHasher) release() {
+	if h.slots != nil {
+		<-h.slots
+	}
+}
+
+func decodeArgon2(hash string) (HashParams, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 {
+		return HashParams{}, nil, nil, errMalformedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return HashParams{}, nil, nil, errMalformedHash
+	}
+
+	params := HashParams{Algorithm: Argon2id}
+	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Argon2Memory, &params.Argon2Time, &params.Argon2Threads)
+	if err != nil || params.Argon2Memory == 0 || params.Argon2Time == 0 || params.Argon2Threads == 0 {
+		return HashParams{}, nil, nil, errMalformedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return HashParams{}, nil, nil, errMalformedHash
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return HashParams{}, nil, nil, errMalformedHash
+	}
+
+	return params, salt, key, nil
+}
+
+// NewPasswordHasher returns a hasher producing hashes with params and running
+// at most maxConcurrent hashes at a time. Zero or less means no limit.
+func NewPasswordHasher(params HashParams, maxConcurrent int) PasswordHasher {
+	var slots chan struct{}
+	if maxConcurrent > 0 {
+		slots = make(chan struct{}, maxConcurrent)
+	}
+
+	return &passwordHasher{params, slots}
 }
@@ -1,20 +1,27 @@
 package auth
 
 import (
+	"context"
+
 	"github.com/stretchr/testify/mock"
 )
 
-// MockBcryptService é o mock para a interface BcryptService
-type MockBcryptService struct {
+// MockPasswordHasher é o mock para a interface PasswordHasher
+type MockPasswordHasher struct {
 	mock.Mock
 }
 
-func (m *MockBcryptService) Hash(pwd string) (string, error) {
-	args := m.Called(pwd)
+func (m *MockPasswordHasher) Hash(ctx context.Context, pwd string) (string, error) {
+	args := m.Called(ctx, pwd)
 	return args.String(0), args.Error(1)
 }
 
-func (m *MockBcryptService) Compare(pwd, hash string) bool {
-	args := m.Called(pwd, hash)
+func (m *MockPasswordHasher) Compare(ctx context.Context, pwd, hash string) (bool, error) {
+	args := m.Called(ctx, pwd, hash)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockPasswordHasher) NeedsRehash(hash string) bool {
+	args := m.Called(hash)
 	return args.Bool(0)
 }
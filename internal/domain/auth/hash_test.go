@@ -0,0 +1,207 @@
+package auth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// cheap parameters so the tests stay fast
+var (
+	testArgon2Params = HashParams{Algorithm: Argon2id, Argon2Time: 1, Argon2Memory: 64, Argon2Threads: 1}
+	testBcryptParams = HashParams{Algorithm: Bcrypt, BcryptCost: bcrypt.MinCost}
+)
+
+func TestPasswordHasher_Hash(t *testing.T) {
+	for _, params := range []HashParams{testArgon2Params, testBcryptParams} {
+		t.Run("should round trip with "+string(params.Algorithm), func(t *testing.T) {
+			hasher := NewPasswordHasher(params, 0)
+
+			hashed, err := hasher.Hash(context.Background(), "password")
+			require.NoError(t, err)
+
+			assert.True(t, compare(t, hasher, "password", hashed))
+			assert.False(t, compare(t, hasher, "wrong", hashed))
+			assert.False(t, hasher.NeedsRehash(hashed))
+		})
+	}
+
+	t.Run("should encode argon2id parameters in the hash", func(t *testing.T) {
+		hashed, err := NewPasswordHasher(testArgon2Params, 0).Hash(context.Background(), "password")
+		require.NoError(t, err)
+
+		assert.True(t, strings.HasPrefix(hashed, "$argon2id$v=19$m=64,t=1,p=1$"))
+	})
+
+	t.Run("should salt every hash", func(t *testing.T) {
+		hasher := NewPasswordHasher(testArgon2Params, 0)
+
+		a, err := hasher.Hash(context.Background(), "password")
+		require.NoError(t, err)
+		b, err := hasher.Hash(context.Background(), "password")
+		require.NoError(t, err)
+
+		assert.NotEqual(t, a, b)
+	})
+}
+
+func TestPasswordHasher_Compare(t *testing.T) {
+	t.Run("should compare hashes of any supported algorithm", func(t *testing.T) {
+		legacy, err := NewPasswordHasher(testBcryptParams, 0).Hash(context.Background(), "password")
+		require.NoError(t, err)
+
+		assert.True(t, compare(t, NewPasswordHasher(testArgon2Params, 0), "password", legacy))
+	})
+
+	t.Run("should reject malformed hashes", func(t *testing.T) {
+		hasher := NewPasswordHasher(testArgon2Params, 0)
+
+		for _, hashed := range []string{
+			"",
+			"$argon2id$v=19$m=64,t=1,p=1$salt",
+			"$argon2id$v=18$m=64,t=1,p=1$c2FsdA$a2V5",
+			"$argon2id$v=19$m=64,t=1,p=0$c2FsdA$a2V5",
+			"$argon2id$v=19$m=64,t=1,p=1$!!!$a2V5",
+		} {
+			assert.False(t, compare(t, hasher, "password", hashed), hashed)
+		}
+	})
+
+	t.Run("should never run more compares than the limit", func(t *testing.T) {
+		hashed, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+		assert.NoError(t, err)
+
+		hasher := NewPasswordHasher(testBcryptParams, 2).(*passwordHasher)
+
+		var running, peak atomic.Int32
+		var wg sync.WaitGroup
+
+		for range 8 {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+
+				assert.NoError(t, hasher.acquire(context.Background()))
+				n := running.Add(1)
+				for {
+					p := peak.Load()
+					if n <= p || peak.CompareAndSwap(p, n) {
+						break
+					}
+				}
+				assert.NoError(t, bcrypt.CompareHashAndPassword(hashed, []byte("password")))
+				running.Add(-1)
+				hasher.release()
+			}()
+		}
+
+		wg.Wait()
+
+		assert.LessOrEqual(t, peak.Load(), int32(2))
+		assert.True(t, compare(t, hasher, "password", string(hashed)))
+		assert.False(t, compare(t, hasher, "wrong", string(hashed)))
+	})
+}
+
+func TestPasswordHasher_Acquire(t *testing.T) {
+	t.Run("should give up waiting for a slot when the context is done", func(t *testing.T) {
+		hasher := NewPasswordHasher(testBcryptParams, 1).(*passwordHasher)
+		require.NoError(t, hasher.acquire(context.Background()))
+		defer hasher.release()
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond*20)
+		defer cancel()
+
+		_, err := hasher.Hash(ctx, "password")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		_, err = hasher.Compare(ctx, "password", "hash")
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+func compare(t *testing.T, hasher PasswordHasher, pwd, hash string) bool {
+	t.Helper()
+
+	ok, err := hasher.Compare(context.Background(), pwd, hash)
+	require.NoError(t, err)
+	return ok
+}
+
+func TestPasswordHasher_NeedsRehash(t *testing.T) {
+	bcryptHash, err := NewPasswordHasher(testBcryptParams, 0).Hash(context.Background(), "password")
+	require.NoError(t, err)
+	argon2Hash, err := NewPasswordHasher(testArgon2Params, 0).Hash(context.Background(), "password")
+	require.NoError(t, err)
+
+	t.Run("should rehash when the algorithm changed", func(t *testing.T) {
+		assert.True(t, NewPasswordHasher(testArgon2Params, 0).NeedsRehash(bcryptHash))
+		assert.True(t, NewPasswordHasher(testBcryptParams, 0).NeedsRehash(argon2Hash))
+	})
+
+	t.Run("should rehash when the bcrypt cost changed", func(t *testing.T) {
+		params := testBcryptParams
+		params.BcryptCost++
+
+		assert.True(t, NewPasswordHasher(params, 0).NeedsRehash(bcryptHash))
+	})
+
+	t.Run("should rehash when the argon2id parameters changed", func(t *testing.T) {
+		for _, change := range []func(*HashParams){
+			func(p *HashParams) { p.Argon2Time++ },
+			func(p *HashParams) { p.Argon2Memory *= 2 },
+			func(p *HashParams) { p.Argon2Threads++ },
+		} {
+			params := testArgon2Params
+			change(&params)
+
+			assert.True(t, NewPasswordHasher(params, 0).NeedsRehash(argon2Hash))
+		}
+	})
+}
+
+// BenchmarkPasswordHasher measures candidate parameters. Pick the strongest
+// ones that keep a single hash well below the login latency budget on the
+// production hardware:
+//
+//	go test ./internal/domain/auth -run '^$' -bench PasswordHasher -benchmem
+func BenchmarkPasswordHasher(b *testing.B) {
+	var candidates []HashParams
+	for _, cost := range []int{10, 11, 12, 13, 14} {
+		candidates = append(candidates, HashParams{Algorithm: Bcrypt, BcryptCost: cost})
+	}
+	for _, mem := range []uint32{19 * 1024, 46 * 1024, 64 * 1024} {
+		for _, time := range []uint32{1, 2, 3} {
+			candidates = append(candidates, HashParams{
+				Algorithm:     Argon2id,
+				Argon2Time:    time,
+				Argon2Memory:  mem,
+				Argon2Threads: 1,
+			})
+		}
+	}
+
+	for _, params := range candidates {
+		name := fmt.Sprintf("bcrypt/cost=%d", params.BcryptCost)
+		if params.Algorithm == Argon2id {
+			name = fmt.Sprintf("argon2id/m=%dMiB,t=%d,p=%d", params.Argon2Memory/1024, params.Argon2Time, params.Argon2Threads)
+		}
+
+		b.Run(name, func(b *testing.B) {
+			hasher := NewPasswordHasher(params, 0)
+			for range b.N {
+				if _, err := hasher.Hash(context.Background(), "correct horse battery staple"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
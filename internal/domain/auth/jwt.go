@@ -1,36 +1,54 @@
 package auth
 
 import (
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// IssuedAtMsClaim holds the issue time in milliseconds. iat only has second
+// precision, which cannot tell a login apart from a revocation in the same
+// second.
+const IssuedAtMsClaim = "iat_ms"
+
 type JWTService interface {
 	GenerateToken(userId int, exp time.Duration) (string, error)
 	ValidateToken(token string) (*jwt.Token, error)
+	JWKS() JWKSet
 }
 
 type jwtSvc struct {
-	secret string
-	aud    string
-	iss    string
+	keys KeyProvider
+	aud  string
+	iss  string
 }
 
 func (a *jwtSvc) GenerateToken(userId int, exp time.Duration) (string, error) {
+	jti, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
 	claims := jwt.MapClaims{
-		"sub": userId,
-		"exp": time.Now().Add(exp).Unix(),
-		"iat": time.Now().Unix(),
-		"nbf": time.Now().Unix(),
-		"iss": a.iss,
-		"aud": a.aud,
+		"jti":           jti,
+		"sub":           userId,
+		"exp":           now.Add(exp).Unix(),
+		"iat":           now.Unix(),
+		"nbf":           now.Unix(),
+		IssuedAtMsClaim: now.UnixMilli(),
+		"iss":           a.iss,
+		"aud":           a.aud,
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	key := a.keys.SigningKey()
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.KID
 
-	tokenString, err := token.SignedString([]byte(a.secret))
+	tokenString, err := token.SignedString(key.Private)
 	if err != nil {
 		return "", err
 	}
@@ -40,19 +58,33 @@ func (a *jwtSvc) GenerateToken(userId int, exp time.Duration) (string, error) {
 
 func (a *jwtSvc) ValidateToken(token string) (*jwt.Token, error) {
 	return jwt.Parse(token, func(t *jwt.Token) (any, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+		kid, ok := t.Header["kid"].(string)
+		if !ok {
+			return nil, errors.New("token is missing kid")
+		}
+
+		key, ok := a.keys.VerificationKey(kid)
+		if !ok {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+
+		if t.Method.Alg() != key.Method.Alg() {
 			return nil, fmt.Errorf("unexpected signing method %v", t.Header["alg"])
 		}
 
-		return []byte(a.secret), nil
+		return key.Public, nil
 	},
 		jwt.WithExpirationRequired(),
 		jwt.WithAudience(a.aud),
-		jwt.WithIssuer(a.aud),
-		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Name}),
+		jwt.WithIssuer(a.iss),
+		jwt.WithValidMethods([]string{AlgHS256, AlgRS256, AlgEdDSA}),
 	)
 }
 
-func NewJWTService(secret, aud, iss string) JWTService {
-	return &jwtSvc{secret, iss, aud}
+func (a *jwtSvc) JWKS() JWKSet {
+	return a.keys.JWKS()
+}
+
+func NewJWTService(keys KeyProvider, aud, iss string) JWTService {
+	return &jwtSvc{keys, aud, iss}
 }
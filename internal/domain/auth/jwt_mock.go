@@ -21,3 +21,8 @@ func (m *MockJWTService) ValidateToken(token string) (*jwt.Token, error) {
 	args := m.Called(token)
 	return args.Get(0).(*jwt.Token), args.Error(1)
 }
+
+func (m *MockJWTService) JWKS() JWKSet {
+	args := m.Called()
+	return args.Get(0).(JWKSet)
+}
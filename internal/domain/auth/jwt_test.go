@@ -0,0 +1,79 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWTService(t *testing.T) {
+	sources := map[string]KeySource{
+		AlgHS256: NewSecretKeySource("secret"),
+		AlgRS256: NewGeneratedKeySource(AlgRS256),
+		AlgEdDSA: NewGeneratedKeySource(AlgEdDSA),
+	}
+
+	for alg, source := range sources {
+		t.Run("should round trip a "+alg+" token", func(t *testing.T) {
+			manager, err := NewKeyManager(source, time.Hour)
+			require.NoError(t, err)
+
+			service := NewJWTService(manager, "picpay-aud", "picpay-iss")
+
+			token, err := service.GenerateToken(1, time.Minute)
+			require.NoError(t, err)
+
+			parsed, err := service.ValidateToken(token)
+			require.NoError(t, err)
+
+			claims := parsed.Claims.(jwt.MapClaims)
+			assert.Equal(t, alg, parsed.Method.Alg())
+			assert.Equal(t, manager.SigningKey().KID, parsed.Header["kid"])
+			assert.Equal(t, "picpay-iss", claims["iss"])
+			assert.NotEmpty(t, claims["jti"])
+			assert.InDelta(t, claims["iat"].(float64)*1000, claims[IssuedAtMsClaim], 999)
+		})
+	}
+
+	t.Run("should reject tokens signed with an unknown key", func(t *testing.T) {
+		signer, err := NewKeyManager(NewGeneratedKeySource(AlgEdDSA), time.Hour)
+		require.NoError(t, err)
+		verifier, err := NewKeyManager(NewGeneratedKeySource(AlgEdDSA), time.Hour)
+		require.NoError(t, err)
+
+		token, err := NewJWTService(signer, "aud", "iss").GenerateToken(1, time.Minute)
+		require.NoError(t, err)
+
+		_, err = NewJWTService(verifier, "aud", "iss").ValidateToken(token)
+		assert.Error(t, err)
+	})
+
+	t.Run("should keep verifying tokens after a rotation", func(t *testing.T) {
+		manager, err := NewKeyManager(NewGeneratedKeySource(AlgEdDSA), time.Hour)
+		require.NoError(t, err)
+
+		service := NewJWTService(manager, "aud", "iss")
+
+		token, err := service.GenerateToken(1, time.Minute)
+		require.NoError(t, err)
+
+		require.NoError(t, manager.Rotate())
+
+		_, err = service.ValidateToken(token)
+		assert.NoError(t, err)
+	})
+
+	t.Run("should reject tokens from another issuer", func(t *testing.T) {
+		manager, err := NewKeyManager(NewSecretKeySource("secret"), time.Hour)
+		require.NoError(t, err)
+
+		token, err := NewJWTService(manager, "aud", "other").GenerateToken(1, time.Minute)
+		require.NoError(t, err)
+
+		_, err = NewJWTService(manager, "aud", "iss").ValidateToken(token)
+		assert.Error(t, err)
+	})
+}
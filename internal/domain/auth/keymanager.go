@@ -0,0 +1,172 @@
+package auth
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"log/slog"
+	"math/big"
+	"sync"
+	"time"
+)
+
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+type KeyProvider interface {
+	SigningKey() *SigningKey
+	VerificationKey(kid string) (*SigningKey, bool)
+	JWKS() JWKSet
+}
+
+// KeyManager tracks the active signing key and every key that can still
+// verify tokens. A key that disappears from the source stays valid for the
+// overlap window so tokens signed right before a rotation keep working.
+type KeyManager struct {
+	source  KeySource
+	overlap time.Duration
+
+	mu       sync.RWMutex
+	active   *SigningKey
+	keys     map[string]*SigningKey
+	retireAt map[string]time.Time
+}
+
+func (m *KeyManager) Rotate() error {
+	loaded, err := m.source.Load()
+	if err != nil {
+		return err
+	}
+	if len(loaded) == 0 {
+		return errors.New("no signing keys available")
+	}
+
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	present := make(map[string]bool, len(loaded))
+	for _, key := range loaded {
+		present[key.KID] = true
+		m.keys[key.KID] = key
+		delete(m.retireAt, key.KID)
+	}
+
+	for kid := range m.keys {
+		if present[kid] {
+			continue
+		}
+		if _, ok := m.retireAt[kid]; !ok {
+			m.retireAt[kid] = now.Add(m.overlap)
+		}
+	}
+
+	for kid, at := range m.retireAt {
+		if now.After(at) {
+			delete(m.keys, kid)
+			delete(m.retireAt, kid)
+		}
+	}
+
+	m.active = loaded[len(loaded)-1]
+
+	return nil
+}
+
+// Run rotates keys every interval until ctx is done.
+func (m *KeyManager) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Rotate(); err != nil {
+				slog.Error("JWT key rotation failed", "err", err.Error())
+			}
+		}
+	}
+}
+
+func (m *KeyManager) SigningKey() *SigningKey {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.active
+}
+
+func (m *KeyManager) VerificationKey(kid string) (*SigningKey, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	key, ok := m.keys[kid]
+	if !ok {
+		return nil, false
+	}
+
+	if at, retiring := m.retireAt[kid]; retiring && time.Now().After(at) {
+		return nil, false
+	}
+
+	return key, true
+}
+
+// JWKS returns the public part of every asymmetric key still accepted.
+// Shared secrets are never published.
+func (m *KeyManager) JWKS() JWKSet {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	set := JWKSet{Keys: []JWK{}}
+	for kid, key := range m.keys {
+		jwk := JWK{Kid: kid, Use: "sig", Alg: key.Method.Alg()}
+
+		switch public := key.Public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		default:
+			continue
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+
+	return set
+}
+
+func NewKeyManager(source KeySource, overlap time.Duration) (*KeyManager, error) {
+	m := &KeyManager{
+		source:   source,
+		overlap:  overlap,
+		keys:     map[string]*SigningKey{},
+		retireAt: map[string]time.Time{},
+	}
+
+	if err := m.Rotate(); err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
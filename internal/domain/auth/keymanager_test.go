@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writeEd25519PEM(t *testing.T, dir, name string) {
+	t.Helper()
+
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	require.NoError(t, err)
+
+	data := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0o600))
+}
+
+func TestKeyManager_Rotate(t *testing.T) {
+	t.Run("should sign with the last key of a directory", func(t *testing.T) {
+		dir := t.TempDir()
+		writeEd25519PEM(t, dir, "2026-01.pem")
+		writeEd25519PEM(t, dir, "2026-02.pem")
+
+		manager, err := NewKeyManager(NewDirKeySource(dir), time.Hour)
+		require.NoError(t, err)
+
+		assert.Equal(t, "2026-02", manager.SigningKey().KID)
+		_, ok := manager.VerificationKey("2026-01")
+		assert.True(t, ok)
+	})
+
+	t.Run("should keep removed keys only during the overlap window", func(t *testing.T) {
+		dir := t.TempDir()
+		writeEd25519PEM(t, dir, "a.pem")
+
+		manager, err := NewKeyManager(NewDirKeySource(dir), time.Millisecond*20)
+		require.NoError(t, err)
+
+		writeEd25519PEM(t, dir, "b.pem")
+		require.NoError(t, os.Remove(filepath.Join(dir, "a.pem")))
+		require.NoError(t, manager.Rotate())
+
+		assert.Equal(t, "b", manager.SigningKey().KID)
+		_, ok := manager.VerificationKey("a")
+		assert.True(t, ok)
+
+		time.Sleep(time.Millisecond * 30)
+
+		_, ok = manager.VerificationKey("a")
+		assert.False(t, ok)
+
+		require.NoError(t, manager.Rotate())
+		assert.Len(t, manager.JWKS().Keys, 1)
+	})
+
+	t.Run("should fail without keys", func(t *testing.T) {
+		_, err := NewKeyManager(NewDirKeySource(t.TempDir()), time.Hour)
+		assert.Error(t, err)
+	})
+
+	t.Run("should generate a new key on every rotation", func(t *testing.T) {
+		manager, err := NewKeyManager(NewGeneratedKeySource(AlgEdDSA), time.Hour)
+		require.NoError(t, err)
+
+		first := manager.SigningKey().KID
+		require.NoError(t, manager.Rotate())
+
+		assert.NotEqual(t, first, manager.SigningKey().KID)
+		assert.Len(t, manager.JWKS().Keys, 2)
+	})
+}
+
+func TestKeyManager_JWKS(t *testing.T) {
+	t.Run("should publish asymmetric keys only", func(t *testing.T) {
+		manager, err := NewKeyManager(NewSecretKeySource("secret"), time.Hour)
+		require.NoError(t, err)
+		assert.Empty(t, manager.JWKS().Keys)
+	})
+
+	t.Run("should describe RSA keys", func(t *testing.T) {
+		manager, err := NewKeyManager(NewGeneratedKeySource(AlgRS256), time.Hour)
+		require.NoError(t, err)
+
+		keys := manager.JWKS().Keys
+		require.Len(t, keys, 1)
+		assert.Equal(t, "RSA", keys[0].Kty)
+		assert.Equal(t, "RS256", keys[0].Alg)
+		assert.Equal(t, "AQAB", keys[0].E)
+		assert.Equal(t, manager.SigningKey().KID, keys[0].Kid)
+	})
+}
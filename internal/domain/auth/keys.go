@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// SigningKey pairs the material used to sign tokens with the material used to
+// verify them. For HMAC both are the shared secret.
+type SigningKey struct {
+	KID     string
+	Method  jwt.SigningMethod
+	Private any
+	Public  any
+}
+
+// KeySource yields the keys that should currently be published. The last key
+// returned becomes the active signing key.
+type KeySource interface {
+	Load() ([]*SigningKey, error)
+}
+
+type secretKeySource struct {
+	key *SigningKey
+}
+
+func (s *secretKeySource) Load() ([]*SigningKey, error) {
+	return []*SigningKey{s.key}, nil
+}
+
+// NewSecretKeySource keeps the legacy HS256 shared secret behaviour.
+func NewSecretKeySource(secret string) KeySource {
+	return &secretKeySource{&SigningKey{
+		KID:     "default",
+		Method:  jwt.SigningMethodHS256,
+		Private: []byte(secret),
+		Public:  []byte(secret),
+	}}
+}
+
+type fileKeySource struct {
+	files []string
+}
+
+func (s *fileKeySource) Load() ([]*SigningKey, error) {
+	keys := make([]*SigningKey, 0, len(s.files))
+	for _, file := range s.files {
+		key, err := loadPEMKey(file)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// NewFileKeySource loads private keys from PEM files. The kid of each key is
+// its file name without extension, and the last file is used for signing.
+func NewFileKeySource(files []string) KeySource {
+	return &fileKeySource{files}
+}
+
+type dirKeySource struct {
+	dir string
+}
+
+func (s *dirKeySource) Load() ([]*SigningKey, error) {
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	return (&fileKeySource{files}).Load()
+}
+
+// NewDirKeySource loads every *.pem file in dir on each rotation, so keys are
+// rotated by dropping a new file whose name sorts last (e.g. 2026-10.pem) and
+// removing old files once their overlap window has passed.
+func NewDirKeySource(dir string) KeySource {
+	return &dirKeySource{dir}
+}
+
+type generatedKeySource struct {
+	alg string
+}
+
+func (s *generatedKeySource) Load() ([]*SigningKey, error) {
+	key, err := generateKey(s.alg)
+	if err != nil {
+		return nil, err
+	}
+	return []*SigningKey{key}, nil
+}
+
+// NewGeneratedKeySource creates a fresh in-memory key on every rotation. Keys
+// are not shared between instances, so it is only meant for local use.
+func NewGeneratedKeySource(alg string) KeySource {
+	return &generatedKeySource{alg}
+}
+
+func generateKey(alg string) (*SigningKey, error) {
+	kid := make([]byte, 8)
+	if _, err := rand.Read(kid); err != nil {
+		return nil, err
+	}
+
+	switch alg {
+	case AlgRS256:
+		private, err := rsa.GenerateKey(rand.Reader, 2048)
+		if err != nil {
+			return nil, err
+		}
+		return &SigningKey{hex.EncodeToString(kid), jwt.SigningMethodRS256, private, &private.PublicKey}, nil
+	case AlgEdDSA:
+		public, private, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		return &SigningKey{hex.EncodeToString(kid), jwt.SigningMethodEdDSA, private, public}, nil
+	default:
+		return nil, fmt.Errorf("cannot generate keys for algorithm %q", alg)
+	}
+}
+
+func loadPEMKey(file string) (*SigningKey, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s: no PEM block found", file)
+	}
+
+	var parsed any
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	default:
+		err = fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+
+	kid := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+
+	switch private := parsed.(type) {
+	case *rsa.PrivateKey:
+		return &SigningKey{kid, jwt.SigningMethodRS256, private, &private.PublicKey}, nil
+	case ed25519.PrivateKey:
+		return &SigningKey{kid, jwt.SigningMethodEdDSA, private, private.Public()}, nil
+	default:
+		return nil, errors.New(file + ": only RSA and Ed25519 keys are supported")
+	}
+}
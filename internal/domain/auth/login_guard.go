@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrAccountLocked      = errors.New("account locked")
+	ErrIPLocked           = errors.New("ip locked")
+	ErrInvalidUnlockToken = errors.New("invalid unlock token")
+)
+
+// RetryAfterError is returned while a key is inside its progressive delay.
+type RetryAfterError struct {
+	Wait time.Duration
+}
+
+func (e *RetryAfterError) Error() string {
+	return fmt.Sprintf("too many failed attempts, retry in %s", e.Wait.Round(time.Second))
+}
+
+// ThrottlePolicy describes how failed logins for one kind of key are handled.
+// After FreeAttempts failures every new attempt must wait BaseDelay, doubled
+// for each further failure up to MaxDelay. LockAfter failures lock the key for
+// LockFor; zero disables locking. Failures older than Window are forgotten.
+type ThrottlePolicy struct {
+	FreeAttempts int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	LockAfter    int
+	LockFor      time.Duration
+	Window       time.Duration
+}
+
+func (p ThrottlePolicy) delay(failures int) time.Duration {
+	over := failures - p.FreeAttempts
+	if over <= 0 {
+		return 0
+	}
+
+	d := p.BaseDelay
+	for i := 1; i < over && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+
+	return min(d, p.MaxDelay)
+}
+
+// LoginGuard throttles failed logins per email and per IP. Every attempt is
+// reserved before the password is checked, so parallel requests cannot all
+// slip through the same free slot. IPs are shared by many users behind NATs,
+// so they lock at a much higher threshold and have no unlock link.
+type LoginGuard interface {
+	Reserve(ctx context.Context, email, ip string) error
+	Release(ctx context.Context, ip string) error
+	RecordFailure(ctx context.Context, email, ip string) (unlockToken string, err error)
+	RecordSuccess(ctx context.Context, email string) error
+	Unlock(ctx context.Context, token string) (email string, err error)
+}
+
+type loginGuard struct {
+	throttleRepo LoginThrottleRepository
+	emailPolicy  ThrottlePolicy
+	ipPolicy     ThrottlePolicy
+}
+
+type throttleKey struct {
+	key    string
+	policy ThrottlePolicy
+	email  bool
+}
+
+// Reserve counts the attempt as a failure up front, for the IP first and then
+// the email, and refuses it when a key is locked or inside its delay.
+func (g *loginGuard) Reserve(ctx context.Context, email, ip string) error {
+	now := time.Now()
+
+	for _, k := range g.keys(email, ip) {
+		reserved, err := g.throttleRepo.Reserve(ctx, k.key, now, k.policy)
+		if err != nil {
+			return err
+		}
+
+		if !reserved {
+			return g.refusal(ctx, k, now)
+		}
+	}
+
+	return nil
+}
+
+// refusal explains why a reservation was refused. It only reads the row, the
+// decision itself was already taken atomically by the reservation.
+func (g *loginGuard) refusal(ctx context.Context, k throttleKey, now time.Time) error {
+	locked := ErrAccountLocked
+	if !k.email {
+		locked = ErrIPLocked
+	}
+
+	throttle, err := g.throttleRepo.Find(ctx, k.key)
+	if err != nil {
+		return err
+	}
+
+	if throttle == nil {
+		return &RetryAfterError{Wait: time.Second}
+	}
+
+	if throttle.LockedUntil != nil && now.Before(*throttle.LockedUntil) {
+		return locked
+	}
+
+	if k.policy.LockAfter > 0 && throttle.Failures >= k.policy.LockAfter {
+		return locked
+	}
+
+	wait := throttle.LastFailureAt.Add(k.policy.delay(throttle.Failures)).Sub(now)
+	return &RetryAfterError{Wait: max(wait, time.Second)}
+}
+
+// Release gives back the attempt reserved for the IP once the password
+// matched, so busy NATs are not slowed down by successful logins.
+func (g *loginGuard) Release(ctx context.Context, ip string) error {
+	if ip == "" {
+		return nil
+	}
+
+	return g.throttleRepo.Release(ctx, ipKey(ip))
+}
+
+// RecordFailure locks the keys whose reserved attempts reached the lock
+// threshold. When the email gets locked, the token to unlock it is returned
+// so it can be sent to the account owner.
+func (g *loginGuard) RecordFailure(ctx context.Context, email, ip string) (string, error) {
+	now := time.Now()
+	var unlockToken string
+
+	for _, k := range g.keys(email, ip) {
+		if k.policy.LockAfter <= 0 {
+			continue
+		}
+
+		throttle, err := g.throttleRepo.Find(ctx, k.key)
+		if err != nil {
+			return "", err
+		}
+
+		if throttle == nil || throttle.Failures < k.policy.LockAfter {
+			continue
+		}
+
+		var token, unlockHash string
+		if k.email {
+			token, err = randomToken(32)
+			if err != nil {
+				return "", err
+			}
+			unlockHash = hashToken(token)
+		}
+
+		locked, err := g.throttleRepo.Lock(ctx, k.key, now, now.Add(k.policy.LockFor), unlockHash)
+		if err != nil {
+			return "", err
+		}
+
+		// a parallel failure may have locked the key already and sent its own token
+		if locked && k.email {
+			unlockToken = token
+		}
+	}
+
+	return unlockToken, nil
+}
+
+// RecordSuccess clears the email counter only. Clearing the IP counter would
+// let an attacker reset it by logging into an account of their own.
+func (g *loginGuard) RecordSuccess(ctx context.Context, email string) error {
+	return g.throttleRepo.Reset(ctx, emailKey(email))
+}
+
+func (g *loginGuard) Unlock(ctx context.Context, token string) (string, error) {
+	key, err := g.throttleRepo.Unlock(ctx, hashToken(token), time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	if key == nil {
+		return "", ErrInvalidUnlockToken
+	}
+
+	return strings.TrimPrefix(*key, "email:"), nil
+}
+
+func (g *loginGuard) keys(email, ip string) []throttleKey {
+	var keys []throttleKey
+	if ip != "" {
+		keys = append(keys, throttleKey{key: ipKey(ip), policy: g.ipPolicy})
+	}
+	return append(keys, throttleKey{key: emailKey(email), policy: g.emailPolicy, email: true})
+}
+
+func emailKey(email string) string {
+	return "email:" + strings.ToLower(email)
+}
+
+func ipKey(ip string) string {
+	return "ip:" + ip
+}
+
+func NewLoginGuard(throttleRepo LoginThrottleRepository, emailPolicy, ipPolicy ThrottlePolicy) LoginGuard {
+	return &loginGuard{
+		throttleRepo: throttleRepo,
+		emailPolicy:  emailPolicy,
+		ipPolicy:     ipPolicy,
+	}
+}
@@ -0,0 +1,36 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockLoginGuard struct {
+	mock.Mock
+}
+
+func (m *MockLoginGuard) Reserve(ctx context.Context, email, ip string) error {
+	args := m.Called(ctx, email, ip)
+	return args.Error(0)
+}
+
+func (m *MockLoginGuard) Release(ctx context.Context, ip string) error {
+	args := m.Called(ctx, ip)
+	return args.Error(0)
+}
+
+func (m *MockLoginGuard) RecordFailure(ctx context.Context, email, ip string) (string, error) {
+	args := m.Called(ctx, email, ip)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockLoginGuard) RecordSuccess(ctx context.Context, email string) error {
+	args := m.Called(ctx, email)
+	return args.Error(0)
+}
+
+func (m *MockLoginGuard) Unlock(ctx context.Context, token string) (string, error) {
+	args := m.Called(ctx, token)
+	return args.String(0), args.Error(1)
+}
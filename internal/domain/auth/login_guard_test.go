@@ -0,0 +1,205 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var (
+	testEmailPolicy = ThrottlePolicy{
+		FreeAttempts: 3,
+		BaseDelay:    time.Second,
+		MaxDelay:     time.Second * 30,
+		LockAfter:    10,
+		LockFor:      time.Minute * 15,
+		Window:       time.Hour,
+	}
+	testIPPolicy = ThrottlePolicy{
+		FreeAttempts: 20,
+		BaseDelay:    time.Second,
+		MaxDelay:     time.Second * 30,
+		LockAfter:    100,
+		LockFor:      time.Minute * 15,
+		Window:       time.Hour,
+	}
+)
+
+func TestThrottlePolicy_Delay(t *testing.T) {
+	assert.Equal(t, time.Duration(0), testEmailPolicy.delay(3))
+	assert.Equal(t, time.Second, testEmailPolicy.delay(4))
+	assert.Equal(t, time.Second*2, testEmailPolicy.delay(5))
+	assert.Equal(t, time.Second*16, testEmailPolicy.delay(8))
+	assert.Equal(t, time.Second*30, testEmailPolicy.delay(50))
+}
+
+func TestLoginGuard_Reserve(t *testing.T) {
+	t.Run("should reserve the ip before the email", func(t *testing.T) {
+		mockRepo := new(MockLoginThrottleRepository)
+		ipCall := mockRepo.On("Reserve", mock.Anything, "ip:10.0.0.1", mock.Anything, testIPPolicy).Return(true, nil).Once()
+		mockRepo.On("Reserve", mock.Anything, "email:john@email.com", mock.Anything, testEmailPolicy).
+			Return(true, nil).Once().NotBefore(ipCall)
+
+		guard := NewLoginGuard(mockRepo, testEmailPolicy, testIPPolicy)
+
+		err := guard.Reserve(context.Background(), "John@email.com", "10.0.0.1")
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should reject locked emails", func(t *testing.T) {
+		until := time.Now().Add(time.Minute)
+
+		mockRepo := new(MockLoginThrottleRepository)
+		mockRepo.On("Reserve", mock.Anything, "ip:10.0.0.1", mock.Anything, testIPPolicy).Return(true, nil)
+		mockRepo.On("Reserve", mock.Anything, "email:john@email.com", mock.Anything, testEmailPolicy).Return(false, nil)
+		mockRepo.On("Find", mock.Anything, "email:john@email.com").
+			Return(&LoginThrottle{Failures: 10, LastFailureAt: time.Now(), LockedUntil: &until}, nil)
+
+		guard := NewLoginGuard(mockRepo, testEmailPolicy, testIPPolicy)
+
+		err := guard.Reserve(context.Background(), "John@email.com", "10.0.0.1")
+
+		assert.ErrorIs(t, err, ErrAccountLocked)
+	})
+
+	t.Run("should reject emails at the threshold before the lock is written", func(t *testing.T) {
+		mockRepo := new(MockLoginThrottleRepository)
+		mockRepo.On("Reserve", mock.Anything, "email:john@email.com", mock.Anything, testEmailPolicy).Return(false, nil)
+		mockRepo.On("Find", mock.Anything, "email:john@email.com").
+			Return(&LoginThrottle{Failures: 10, LastFailureAt: time.Now().Add(-time.Minute)}, nil)
+
+		guard := NewLoginGuard(mockRepo, testEmailPolicy, testIPPolicy)
+
+		err := guard.Reserve(context.Background(), "john@email.com", "")
+
+		assert.ErrorIs(t, err, ErrAccountLocked)
+	})
+
+	t.Run("should reject locked ips without reserving the email", func(t *testing.T) {
+		until := time.Now().Add(time.Minute)
+
+		mockRepo := new(MockLoginThrottleRepository)
+		mockRepo.On("Reserve", mock.Anything, "ip:10.0.0.1", mock.Anything, testIPPolicy).Return(false, nil)
+		mockRepo.On("Find", mock.Anything, "ip:10.0.0.1").
+			Return(&LoginThrottle{Failures: 100, LastFailureAt: time.Now(), LockedUntil: &until}, nil)
+
+		guard := NewLoginGuard(mockRepo, testEmailPolicy, testIPPolicy)
+
+		err := guard.Reserve(context.Background(), "john@email.com", "10.0.0.1")
+
+		assert.ErrorIs(t, err, ErrIPLocked)
+		mockRepo.AssertNotCalled(t, "Reserve", mock.Anything, "email:john@email.com", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should ask to wait during the progressive delay", func(t *testing.T) {
+		mockRepo := new(MockLoginThrottleRepository)
+		mockRepo.On("Reserve", mock.Anything, "ip:10.0.0.1", mock.Anything, testIPPolicy).Return(false, nil)
+		mockRepo.On("Find", mock.Anything, "ip:10.0.0.1").
+			Return(&LoginThrottle{Failures: 25, LastFailureAt: time.Now()}, nil)
+
+		guard := NewLoginGuard(mockRepo, testEmailPolicy, testIPPolicy)
+
+		err := guard.Reserve(context.Background(), "john@email.com", "10.0.0.1")
+
+		var retryErr *RetryAfterError
+		assert.ErrorAs(t, err, &retryErr)
+		assert.InDelta(t, (time.Second * 16).Seconds(), retryErr.Wait.Seconds(), 1)
+	})
+}
+
+func TestLoginGuard_RecordFailure(t *testing.T) {
+	t.Run("should lock the email on reaching the threshold", func(t *testing.T) {
+		mockRepo := new(MockLoginThrottleRepository)
+		mockRepo.On("Find", mock.Anything, "email:john@email.com").Return(&LoginThrottle{Failures: 10}, nil)
+		mockRepo.On("Find", mock.Anything, "ip:10.0.0.1").Return(&LoginThrottle{Failures: 50}, nil)
+
+		var lockedHash string
+		mockRepo.On("Lock", mock.Anything, "email:john@email.com", mock.Anything, mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				lockedHash = args.Get(4).(string)
+			}).Return(true, nil).Once()
+
+		guard := NewLoginGuard(mockRepo, testEmailPolicy, testIPPolicy)
+
+		token, err := guard.RecordFailure(context.Background(), "john@email.com", "10.0.0.1")
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, token)
+		assert.Equal(t, hashToken(token), lockedHash)
+		mockRepo.AssertExpectations(t)
+		mockRepo.AssertNotCalled(t, "Lock", mock.Anything, "ip:10.0.0.1", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should lock the ip without an unlock link", func(t *testing.T) {
+		mockRepo := new(MockLoginThrottleRepository)
+		mockRepo.On("Find", mock.Anything, "ip:10.0.0.1").Return(&LoginThrottle{Failures: 100}, nil)
+		mockRepo.On("Find", mock.Anything, "email:john@email.com").Return(&LoginThrottle{Failures: 1}, nil)
+		mockRepo.On("Lock", mock.Anything, "ip:10.0.0.1", mock.Anything, mock.Anything, "").Return(true, nil).Once()
+
+		guard := NewLoginGuard(mockRepo, testEmailPolicy, testIPPolicy)
+
+		token, err := guard.RecordFailure(context.Background(), "john@email.com", "10.0.0.1")
+
+		assert.NoError(t, err)
+		assert.Empty(t, token)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should not return a token when a parallel failure locked first", func(t *testing.T) {
+		mockRepo := new(MockLoginThrottleRepository)
+		mockRepo.On("Find", mock.Anything, "email:john@email.com").Return(&LoginThrottle{Failures: 11}, nil)
+		mockRepo.On("Lock", mock.Anything, "email:john@email.com", mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+
+		guard := NewLoginGuard(mockRepo, testEmailPolicy, testIPPolicy)
+
+		token, err := guard.RecordFailure(context.Background(), "john@email.com", "")
+
+		assert.NoError(t, err)
+		assert.Empty(t, token)
+	})
+
+	t.Run("should not lock below the threshold", func(t *testing.T) {
+		mockRepo := new(MockLoginThrottleRepository)
+		mockRepo.On("Find", mock.Anything, "email:john@email.com").Return(&LoginThrottle{Failures: 4}, nil)
+
+		guard := NewLoginGuard(mockRepo, testEmailPolicy, testIPPolicy)
+
+		token, err := guard.RecordFailure(context.Background(), "john@email.com", "")
+
+		assert.NoError(t, err)
+		assert.Empty(t, token)
+		mockRepo.AssertNotCalled(t, "Lock", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestLoginGuard_Unlock(t *testing.T) {
+	t.Run("should reject unknown or expired tokens", func(t *testing.T) {
+		mockRepo := new(MockLoginThrottleRepository)
+		mockRepo.On("Unlock", mock.Anything, hashToken("bad"), mock.Anything).Return(nil, nil)
+
+		guard := NewLoginGuard(mockRepo, testEmailPolicy, testIPPolicy)
+
+		_, err := guard.Unlock(context.Background(), "bad")
+
+		assert.ErrorIs(t, err, ErrInvalidUnlockToken)
+	})
+
+	t.Run("should return the unlocked email", func(t *testing.T) {
+		key := "email:john@email.com"
+
+		mockRepo := new(MockLoginThrottleRepository)
+		mockRepo.On("Unlock", mock.Anything, hashToken("good"), mock.Anything).Return(&key, nil)
+
+		guard := NewLoginGuard(mockRepo, testEmailPolicy, testIPPolicy)
+
+		email, err := guard.Unlock(context.Background(), "good")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "john@email.com", email)
+	})
+}
@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// LoginThrottle tracks failed logins for a key, such as an email or an IP.
+type LoginThrottle struct {
+	Key             string
+	Failures        int
+	LastFailureAt   time.Time
+	LockedUntil     *time.Time
+	UnlockTokenHash *string
+}
+
+type LoginThrottleRepository interface {
+	Find(ctx context.Context, key string) (*LoginThrottle, error)
+	Reserve(ctx context.Context, key string, now time.Time, policy ThrottlePolicy) (bool, error)
+	Release(ctx context.Context, key string) error
+	Lock(ctx context.Context, key string, now, until time.Time, unlockHash string) (bool, error)
+	Reset(ctx context.Context, key string) error
+	Unlock(ctx context.Context, unlockHash string, now time.Time) (*string, error)
+}
+
+type loginThrottleRepo struct {
+	database     *sql.DB
+	queryTimeout time.Duration
+}
+
+func (r *loginThrottleRepo) Find(ctx context.Context, key string) (*LoginThrottle, error) {
+	query := `
+		SELECT key, failures, last_failure_at, locked_until, unlock_token_hash
+		FROM login_throttles
+		WHERE key = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var t LoginThrottle
+	err := r.database.QueryRowContext(ctx, query, key).Scan(
+		&t.Key,
+		&t.Failures,
+		&t.LastFailureAt,
+		&t.LockedUntil,
+		&t.UnlockTokenHash,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// Reserve counts an attempt in a single statement, so the row lock taken by
+// the upsert serializes parallel attempts on the same key. The attempt is
+// refused, and nothing is counted, while the key is locked, has reached the
+// lock threshold or is inside its progressive delay. Failures older than the
+// window, or from before an expired lock, are forgotten and the count starts
+// over.
+func (r *loginThrottleRepo) Reserve(ctx context.Context, key string, now time.Time, policy ThrottlePolicy) (bool, error) {
+	query := `
+		INSERT INTO login_throttles (key, failures, last_failure_at)
+		VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE
+		SET failures = CASE
+				WHEN login_throttles.last_failure_at < $3 OR login_throttles.locked_until <= $2 THEN 1
+				ELSE login_throttles.failures + 1
+			END,
+			last_failure_at = $2,
+			locked_until = NULL,
+			unlock_token_hash = NULL
+		WHERE (login_throttles.locked_until IS NULL OR login_throttles.locked_until <= $2)
+			AND (
+				login_throttles.last_failure_at < $3
+				OR login_throttles.locked_until <= $2
+				OR (
+					($4 = 0 OR login_throttles.failures < $4)
+					AND login_throttles.last_failure_at + CASE
+						WHEN login_throttles.failures <= $5 THEN INTERVAL '0'
+						ELSE make_interval(secs => LEAST($7::float8, $6::float8 * power(2, LEAST(login_throttles.failures - $5 - 1, 30))))
+					END <= $2
+				)
+			)
+		RETURNING key
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var reserved string
+	err := r.database.QueryRowContext(
+		ctx,
+		query,
+		key,
+		now,
+		now.Add(-policy.Window),
+		policy.LockAfter,
+		policy.FreeAttempts,
+		policy.BaseDelay.Seconds(),
+		policy.MaxDelay.Seconds(),
+	).Scan(&reserved)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Release gives back one reserved attempt.
+func (r *loginThrottleRepo) Release(ctx context.Context, key string) error {
+	query := `
+		UPDATE login_throttles
+		SET failures = GREATEST(failures - 1, 0)
+		WHERE key = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := r.database.ExecContext(ctx, query, key)
+	return err
+}
+
+// Lock locks the key unless it is already locked, and reports whether it did.
+// An empty unlockHash leaves the lock without an unlock link.
+func (r *loginThrottleRepo) Lock(ctx context.Context, key string, now, until time.Time, unlockHash string) (bool, error) {
+	query := `
+		UPDATE login_throttles
+		SET locked_until = $3, unlock_token_hash = NULLIF($4, '')
+		WHERE key = $1 AND (locked_until IS NULL OR locked_until <= $2)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	result, err := r.database.ExecContext(ctx, query, key, now, until, unlockHash)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows > 0, nil
+}
+
+func (r *loginThrottleRepo) Reset(ctx context.Context, key string) error {
+	query := `DELETE FROM login_throttles WHERE key = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := r.database.ExecContext(ctx, query, key)
+	return err
+}
+
+// Unlock clears a lock using the token sent by email and returns the key it
+// belonged to, or nil if the token is unknown or the lock already expired.
+func (r *loginThrottleRepo) Unlock(ctx context.Context, unlockHash string, now time.Time) (*string, error) {
+	query := `
+		DELETE FROM login_throttles
+		WHERE unlock_token_hash = $1 AND locked_until > $2
+		RETURNING key
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var key string
+	err := r.database.QueryRowContext(ctx, query, unlockHash, now).Scan(&key)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func NewLoginThrottleRepository(database *sql.DB, qt time.Duration) LoginThrottleRepository {
+	return &loginThrottleRepo{
+		database:     database,
+		queryTimeout: qt,
+	}
+}
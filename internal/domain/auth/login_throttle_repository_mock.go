@@ -0,0 +1,48 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockLoginThrottleRepository struct {
+	mock.Mock
+}
+
+func (m *MockLoginThrottleRepository) Find(ctx context.Context, key string) (*LoginThrottle, error) {
+	args := m.Called(ctx, key)
+	if t, ok := args.Get(0).(*LoginThrottle); ok {
+		return t, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockLoginThrottleRepository) Reserve(ctx context.Context, key string, now time.Time, policy ThrottlePolicy) (bool, error) {
+	args := m.Called(ctx, key, now, policy)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockLoginThrottleRepository) Release(ctx context.Context, key string) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (m *MockLoginThrottleRepository) Lock(ctx context.Context, key string, now, until time.Time, unlockHash string) (bool, error) {
+	args := m.Called(ctx, key, now, until, unlockHash)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockLoginThrottleRepository) Reset(ctx context.Context, key string) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (m *MockLoginThrottleRepository) Unlock(ctx context.Context, unlockHash string, now time.Time) (*string, error) {
+	args := m.Called(ctx, unlockHash, now)
+	if k, ok := args.Get(0).(*string); ok {
+		return k, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PasswordRules configures the password policy. MaxLength should not exceed
+// 72 when passwords are hashed with bcrypt, which ignores anything longer.
+type PasswordRules struct {
+	MinLength          int
+	MaxLength          int
+	RequireUpper       bool
+	RequireLower       bool
+	RequireDigit       bool
+	RequireSymbol      bool
+	ForbidPersonalInfo bool
+}
+
+// Violation is a password rule that was not met.
+type Violation struct {
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+type PasswordPolicy interface {
+	// Check returns every rule password breaks. personal holds values the
+	// password must not contain, such as the user's email and name.
+	Check(password string, personal ...string) []Violation
+}
+
+type passwordPolicy struct {
+	rules  PasswordRules
+	breach BreachChecker
+}
+
+func (p *passwordPolicy) Check(password string, personal ...string) []Violation {
+	violations := []Violation{}
+	add := func(rule, msg string) {
+		violations = append(violations, Violation{Rule: rule, Message: msg})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.rules.MinLength {
+		add("min_length", fmt.Sprintf("password must be at least %d characters long", p.rules.MinLength))
+	}
+	if p.rules.MaxLength > 0 && length > p.rules.MaxLength {
+		add("max_length", fmt.Sprintf("password must be at most %d characters long", p.rules.MaxLength))
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+
+	if p.rules.RequireUpper && !upper {
+		add("uppercase", "password must contain an uppercase letter")
+	}
+	if p.rules.RequireLower && !lower {
+		add("lowercase", "password must contain a lowercase letter")
+	}
+	if p.rules.RequireDigit && !digit {
+		add("digit", "password must contain a digit")
+	}
+	if p.rules.RequireSymbol && !symbol {
+		add("symbol", "password must contain a symbol")
+	}
+
+	if p.rules.ForbidPersonalInfo && containsPersonalInfo(password, personal) {
+		add("personal_info", "password must not contain your email or name")
+	}
+
+	if p.breach != nil {
+		breached, err := p.breach.IsBreached(password)
+		if err != nil {
+			// the list is a safety net; being unable to read it must not
+			// block signups
+			slog.Error("breached password check failed", "err", err.Error())
+		}
+		if breached {
+			add("breached", "password appears in a known data breach")
+		}
+	}
+
+	return violations
+}
+
+// containsPersonalInfo matches the whole email, its local part and each word
+// of the name, ignoring case. Fragments shorter than 3 characters are skipped
+// so that a name like "Li" does not forbid every password containing "li".
+func containsPersonalInfo(password string, personal []string) bool {
+	pwd := strings.ToLower(password)
+
+	for _, value := range personal {
+		value = strings.ToLower(strings.TrimSpace(value))
+
+		fragments := strings.Fields(value)
+		if local, _, ok := strings.Cut(value, "@"); ok {
+			fragments = append(fragments, local)
+		}
+
+		for _, fragment := range fragments {
+			if utf8.RuneCountInString(fragment) >= 3 && strings.Contains(pwd, fragment) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// NewPasswordPolicy returns a policy enforcing rules. breach may be nil to
+// skip the breached password check.
+func NewPasswordPolicy(rules PasswordRules, breach BreachChecker) PasswordPolicy {
+	return &passwordPolicy{rules, breach}
+}
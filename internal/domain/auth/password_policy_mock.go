@@ -0,0 +1,17 @@
+package auth
+
+import (
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPasswordPolicy struct {
+	mock.Mock
+}
+
+func (m *MockPasswordPolicy) Check(password string, personal ...string) []Violation {
+	args := m.Called(password, personal)
+	if v, ok := args.Get(0).([]Violation); ok {
+		return v
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type stubBreachChecker struct {
+	breached bool
+	err      error
+}
+
+func (s stubBreachChecker) IsBreached(string) (bool, error) {
+	return s.breached, s.err
+}
+
+func rulesOf(violations []Violation) []string {
+	rules := []string{}
+	for _, v := range violations {
+		rules = append(rules, v.Rule)
+	}
+	return rules
+}
+
+func TestPasswordPolicy_Check(t *testing.T) {
+	rules := PasswordRules{
+		MinLength:          8,
+		MaxLength:          20,
+		RequireUpper:       true,
+		RequireLower:       true,
+		RequireDigit:       true,
+		RequireSymbol:      true,
+		ForbidPersonalInfo: true,
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		personal []string
+		want     []string
+	}{
+		{"valid", "Tr0ub4dor&3", []string{"john@email.com", "John Doe"}, []string{}},
+		{"too short", "Ab1!", nil, []string{"min_length"}},
+		{"too long", "Abcdefghij1!abcdefghij", nil, []string{"max_length"}},
+		{"missing classes", "abcdefgh", nil, []string{"uppercase", "digit", "symbol"}},
+		{"contains the email local part", "XjOhNx12!", []string{"john@email.com"}, []string{"personal_info"}},
+		{"contains a name", "Doe-Rules12", []string{"john@email.com", "John Doe"}, []string{"personal_info"}},
+		{"ignores short name fragments", "Lily-Pad12", []string{"li@email.com", "Li Wu"}, []string{}},
+	}
+
+	policy := NewPasswordPolicy(rules, nil)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, rulesOf(policy.Check(tt.password, tt.personal...)))
+		})
+	}
+}
+
+func TestPasswordPolicy_Breached(t *testing.T) {
+	rules := PasswordRules{MinLength: 1}
+
+	t.Run("should reject breached passwords", func(t *testing.T) {
+		policy := NewPasswordPolicy(rules, stubBreachChecker{breached: true})
+
+		assert.Equal(t, []string{"breached"}, rulesOf(policy.Check("password")))
+	})
+
+	t.Run("should let passwords through when the list cannot be read", func(t *testing.T) {
+		policy := NewPasswordPolicy(rules, stubBreachChecker{err: errors.New("disk error")})
+
+		assert.Empty(t, policy.Check("password"))
+	})
+}
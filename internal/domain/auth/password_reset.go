@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrInvalidResetToken = errors.New("invalid password reset token")
+
+type PasswordResetService interface {
+	Issue(ctx context.Context, userID int) (string, error)
+	Peek(ctx context.Context, token string) (userID int, err error)
+	Consume(ctx context.Context, token string) (userID int, err error)
+	CanRequest(ctx context.Context, email, ip string) (bool, error)
+}
+
+type passwordResetSvc struct {
+	resetRepo     PasswordResetRepository
+	throttleRepo  RequestThrottleRepository
+	ttl           time.Duration
+	emailThrottle ResendThrottle
+	ipThrottle    ResendThrottle
+}
+
+// Issue creates a new reset token for the user. Older tokens still pending are
+// invalidated, so only the most recent email can be used.
+func (s *passwordResetSvc) Issue(ctx context.Context, userID int) (string, error) {
+	if err := s.resetRepo.InvalidateByUser(ctx, userID); err != nil {
+		return "", err
+	}
+
+	token, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	err = s.resetRepo.Save(ctx, PasswordResetToken{
+		UserID:    userID,
+		TokenHash: hashToken(token),
+		ExpiresAt: now.Add(s.ttl),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// Peek returns the owner of a valid token without using it up, so the new
+// password can be checked before the token is spent.
+func (s *passwordResetSvc) Peek(ctx context.Context, token string) (int, error) {
+	stored, err := s.resetRepo.FindValid(ctx, hashToken(token), time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	if stored == nil {
+		return 0, ErrInvalidResetToken
+	}
+
+	return stored.UserID, nil
+}
+
+func (s *passwordResetSvc) Consume(ctx context.Context, token string) (int, error) {
+	stored, err := s.resetRepo.Consume(ctx, hashToken(token), time.Now())
+	if err != nil {
+		return 0, err
+	}
+
+	if stored == nil {
+		return 0, ErrInvalidResetToken
+	}
+
+	return stored.UserID, nil
+}
+
+// CanRequest counts a reset request against the IP and then the email, and
+// reports whether both are under their limits. Emails are counted whether or
+// not they belong to an account, so being throttled reveals nothing.
+func (s *passwordResetSvc) CanRequest(ctx context.Context, email, ip string) (bool, error) {
+	now := time.Now()
+
+	if ip != "" {
+		allowed, err := s.throttleRepo.Hit(ctx, "reset:ip:"+ip, now, s.ipThrottle)
+		if err != nil || !allowed {
+			return false, err
+		}
+	}
+
+	return s.throttleRepo.Hit(ctx, "reset:email:"+strings.ToLower(email), now, s.emailThrottle)
+}
+
+func NewPasswordResetService(
+	resetRepo PasswordResetRepository,
+	throttleRepo RequestThrottleRepository,
+	ttl time.Duration,
+	emailThrottle, ipThrottle ResendThrottle) PasswordResetService {
+
+	return &passwordResetSvc{resetRepo, throttleRepo, ttl, emailThrottle, ipThrottle}
+}
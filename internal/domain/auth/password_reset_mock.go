@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPasswordResetService struct {
+	mock.Mock
+}
+
+func (m *MockPasswordResetService) Issue(ctx context.Context, userID int) (string, error) {
+	args := m.Called(ctx, userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockPasswordResetService) Peek(ctx context.Context, token string) (int, error) {
+	args := m.Called(ctx, token)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockPasswordResetService) Consume(ctx context.Context, token string) (int, error) {
+	args := m.Called(ctx, token)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockPasswordResetService) CanRequest(ctx context.Context, email, ip string) (bool, error) {
+	args := m.Called(ctx, email, ip)
+	return args.Bool(0), args.Error(1)
+}
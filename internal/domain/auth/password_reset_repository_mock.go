@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPasswordResetRepository struct {
+	mock.Mock
+}
+
+func (m *MockPasswordResetRepository) Save(ctx context.Context, t PasswordResetToken) error {
+	args := m.Called(ctx, t)
+	return args.Error(0)
+}
+
+func (m *MockPasswordResetRepository) FindValid(ctx context.Context, hash string, now time.Time) (*PasswordResetToken, error) {
+	args := m.Called(ctx, hash, now)
+	if t, ok := args.Get(0).(*PasswordResetToken); ok {
+		return t, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockPasswordResetRepository) Consume(ctx context.Context, hash string, now time.Time) (*PasswordResetToken, error) {
+	args := m.Called(ctx, hash, now)
+	if t, ok := args.Get(0).(*PasswordResetToken); ok {
+		return t, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockPasswordResetRepository) InvalidateByUser(ctx context.Context, userID int) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
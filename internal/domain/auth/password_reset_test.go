@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPasswordResetService_Issue(t *testing.T) {
+	t.Run("should invalidate pending tokens and store only the hash", func(t *testing.T) {
+		mockRepo := new(MockPasswordResetRepository)
+		mockRepo.On("InvalidateByUser", mock.Anything, 1).Return(nil).Once()
+
+		var stored PasswordResetToken
+		mockRepo.On("Save", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				stored = args.Get(1).(PasswordResetToken)
+			}).Return(nil)
+
+		service := NewPasswordResetService(mockRepo, nil, time.Minute*15, ResendThrottle{}, ResendThrottle{})
+
+		token, err := service.Issue(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, hashToken(token), stored.TokenHash)
+		assert.Equal(t, 1, stored.UserID)
+		assert.WithinDuration(t, time.Now().Add(time.Minute*15), stored.ExpiresAt, time.Second)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestPasswordResetService_Consume(t *testing.T) {
+	t.Run("should reject tokens that are unknown, used or expired", func(t *testing.T) {
+		mockRepo := new(MockPasswordResetRepository)
+		mockRepo.On("Consume", mock.Anything, hashToken("used"), mock.Anything).Return(nil, nil)
+
+		service := NewPasswordResetService(mockRepo, nil, time.Minute*15, ResendThrottle{}, ResendThrottle{})
+
+		_, err := service.Consume(context.Background(), "used")
+
+		assert.ErrorIs(t, err, ErrInvalidResetToken)
+	})
+
+	t.Run("should return the owner of a valid token", func(t *testing.T) {
+		mockRepo := new(MockPasswordResetRepository)
+		mockRepo.On("Consume", mock.Anything, hashToken("valid"), mock.Anything).
+			Return(&PasswordResetToken{ID: 1, UserID: 7}, nil)
+
+		service := NewPasswordResetService(mockRepo, nil, time.Minute*15, ResendThrottle{}, ResendThrottle{})
+
+		userID, err := service.Consume(context.Background(), "valid")
+
+		assert.NoError(t, err)
+		assert.Equal(t, 7, userID)
+	})
+}
+
+func TestPasswordResetService_Peek(t *testing.T) {
+	t.Run("should return the owner without consuming the token", func(t *testing.T) {
+		mockRepo := new(MockPasswordResetRepository)
+		mockRepo.On("FindValid", mock.Anything, hashToken("good"), mock.Anything).
+			Return(&PasswordResetToken{ID: 1, UserID: 7}, nil)
+
+		service := NewPasswordResetService(mockRepo, nil, time.Minute*15, ResendThrottle{}, ResendThrottle{})
+
+		userID, err := service.Peek(context.Background(), "good")
+
+		assert.NoError(t, err)
+		assert.Equal(t, 7, userID)
+		mockRepo.AssertNotCalled(t, "Consume", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should reject tokens that are unknown, used or expired", func(t *testing.T) {
+		mockRepo := new(MockPasswordResetRepository)
+		mockRepo.On("FindValid", mock.Anything, hashToken("used"), mock.Anything).Return(nil, nil)
+
+		service := NewPasswordResetService(mockRepo, nil, time.Minute*15, ResendThrottle{}, ResendThrottle{})
+
+		_, err := service.Peek(context.Background(), "used")
+
+		assert.ErrorIs(t, err, ErrInvalidResetToken)
+	})
+}
+
+func TestPasswordResetService_CanRequest(t *testing.T) {
+	emailThrottle := ResendThrottle{Cooldown: time.Minute, Window: time.Hour, Max: 5}
+	ipThrottle := ResendThrottle{Window: time.Hour, Max: 20}
+
+	t.Run("should count the request against the ip and the email", func(t *testing.T) {
+		mockThrottle := new(MockRequestThrottleRepository)
+		mockThrottle.On("Hit", mock.Anything, "reset:ip:10.0.0.1", mock.Anything, ipThrottle).Return(true, nil).Once()
+		mockThrottle.On("Hit", mock.Anything, "reset:email:john@email.com", mock.Anything, emailThrottle).Return(true, nil).Once()
+
+		service := NewPasswordResetService(nil, mockThrottle, time.Minute*15, emailThrottle, ipThrottle)
+
+		allowed, err := service.CanRequest(context.Background(), "John@email.com", "10.0.0.1")
+
+		assert.NoError(t, err)
+		assert.True(t, allowed)
+		mockThrottle.AssertExpectations(t)
+	})
+
+	t.Run("should refuse without counting the email when the ip is over its limit", func(t *testing.T) {
+		mockThrottle := new(MockRequestThrottleRepository)
+		mockThrottle.On("Hit", mock.Anything, "reset:ip:10.0.0.1", mock.Anything, ipThrottle).Return(false, nil)
+
+		service := NewPasswordResetService(nil, mockThrottle, time.Minute*15, emailThrottle, ipThrottle)
+
+		allowed, err := service.CanRequest(context.Background(), "john@email.com", "10.0.0.1")
+
+		assert.NoError(t, err)
+		assert.False(t, allowed)
+		mockThrottle.AssertNotCalled(t, "Hit", mock.Anything, "reset:email:john@email.com", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should refuse when the email is over its limit", func(t *testing.T) {
+		mockThrottle := new(MockRequestThrottleRepository)
+		mockThrottle.On("Hit", mock.Anything, "reset:email:john@email.com", mock.Anything, emailThrottle).Return(false, nil)
+
+		service := NewPasswordResetService(nil, mockThrottle, time.Minute*15, emailThrottle, ipThrottle)
+
+		allowed, err := service.CanRequest(context.Background(), "john@email.com", "")
+
+		assert.NoError(t, err)
+		assert.False(t, allowed)
+	})
+}
@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reused")
+)
+
+type RefreshTokenService interface {
+	Issue(ctx context.Context, userID int) (string, error)
+	Rotate(ctx context.Context, token string) (userID int, next string, err error)
+	Revoke(ctx context.Context, userID int, token string) error
+	RevokeAll(ctx context.Context, userID int) error
+}
+
+type refreshTokenSvc struct {
+	tokenRepo RefreshTokenRepository
+	ttl       time.Duration
+}
+
+func (s *refreshTokenSvc) Issue(ctx context.Context, userID int) (string, error) {
+	familyID, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	return s.issueInFamily(ctx, userID, familyID)
+}
+
+// Rotate exchanges a refresh token for a new one of the same family. Presenting
+// a token that was already rotated revokes the whole family, since either the
+// client or an attacker is holding a stolen copy.
+func (s *refreshTokenSvc) Rotate(ctx context.Context, token string) (int, string, error) {
+	stored, err := s.tokenRepo.FindByHash(ctx, hashToken(token))
+	if err != nil {
+		return 0, "", err
+	}
+
+	if stored == nil || stored.RevokedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return 0, "", ErrInvalidRefreshToken
+	}
+
+	if stored.RotatedAt != nil {
+		return stored.UserID, "", s.revokeReused(ctx, stored.FamilyID)
+	}
+
+	rotated, err := s.tokenRepo.MarkRotated(ctx, stored.ID)
+	if err != nil {
+		return 0, "", err
+	}
+
+	if !rotated {
+		return stored.UserID, "", s.revokeReused(ctx, stored.FamilyID)
+	}
+
+	next, err := s.issueInFamily(ctx, stored.UserID, stored.FamilyID)
+	if err != nil {
+		return 0, "", err
+	}
+
+	return stored.UserID, next, nil
+}
+
+// Revoke ends the session the token belongs to. Tokens of other users are
+// ignored so a caller cannot log someone else out.
+func (s *refreshTokenSvc) Revoke(ctx context.Context, userID int, token string) error {
+	stored, err := s.tokenRepo.FindByHash(ctx, hashToken(token))
+	if err != nil {
+		return err
+	}
+
+	if stored == nil || stored.UserID != userID {
+		return nil
+	}
+
+	return s.tokenRepo.RevokeFamily(ctx, stored.FamilyID)
+}
+
+func (s *refreshTokenSvc) RevokeAll(ctx context.Context, userID int) error {
+	return s.tokenRepo.RevokeByUser(ctx, userID)
+}
+
+func (s *refreshTokenSvc) revokeReused(ctx context.Context, familyID string) error {
+	if err := s.tokenRepo.RevokeFamily(ctx, familyID); err != nil {
+		return err
+	}
+	return ErrRefreshTokenReused
+}
+
+func (s *refreshTokenSvc) issueInFamily(ctx context.Context, userID int, familyID string) (string, error) {
+	token, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	err = s.tokenRepo.Save(ctx, RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(token),
+		ExpiresAt: now.Add(s.ttl),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+func randomToken(size int) (string, error) {
+	b := make([]byte, size)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func NewRefreshTokenService(tokenRepo RefreshTokenRepository, ttl time.Duration) RefreshTokenService {
+	return &refreshTokenSvc{tokenRepo, ttl}
+}
@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRefreshTokenService struct {
+	mock.Mock
+}
+
+func (m *MockRefreshTokenService) Issue(ctx context.Context, userID int) (string, error) {
+	args := m.Called(ctx, userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockRefreshTokenService) Rotate(ctx context.Context, token string) (int, string, error) {
+	args := m.Called(ctx, token)
+	return args.Int(0), args.String(1), args.Error(2)
+}
+
+func (m *MockRefreshTokenService) Revoke(ctx context.Context, userID int, token string) error {
+	args := m.Called(ctx, userID, token)
+	return args.Error(0)
+}
+
+func (m *MockRefreshTokenService) RevokeAll(ctx context.Context, userID int) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
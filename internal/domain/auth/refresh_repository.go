@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/db"
+)
+
+type RefreshToken struct {
+	ID        int
+	UserID    int
+	FamilyID  string
+	TokenHash string
+	ExpiresAt time.Time
+	RotatedAt *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+type RefreshTokenRepository interface {
+	Save(ctx context.Context, t RefreshToken) error
+	FindByHash(ctx context.Context, hash string) (*RefreshToken, error)
+	MarkRotated(ctx context.Context, id int) (bool, error)
+	RevokeFamily(ctx context.Context, familyID string) error
+	// RevokeByUser joins the transaction on ctx, if any.
+	RevokeByUser(ctx context.Context, userID int) error
+}
+
+type refreshTokenRepo struct {
+	database     *sql.DB
+	queryTimeout time.Duration
+}
+
+func (r *refreshTokenRepo) Save(ctx context.Context, t RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := r.database.ExecContext(ctx, query, t.UserID, t.FamilyID, t.TokenHash, t.ExpiresAt, t.CreatedAt)
+	return err
+}
+
+func (r *refreshTokenRepo) FindByHash(ctx context.Context, hash string) (*RefreshToken, error) {
+	query := `
+		SELECT id, user_id, family_id, token_hash, expires_at, rotated_at, revoked_at, created_at
+		FROM refresh_tokens
+		WHERE token_hash = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var t RefreshToken
+	err := r.database.QueryRowContext(ctx, query, hash).Scan(
+		&t.ID,
+		&t.UserID,
+		&t.FamilyID,
+		&t.TokenHash,
+		&t.ExpiresAt,
+		&t.RotatedAt,
+		&t.RevokedAt,
+		&t.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// MarkRotated flags the token as used. It returns false when the token was
+// already rotated, so concurrent refreshes with the same token cannot both win.
+func (r *refreshTokenRepo) MarkRotated(ctx context.Context, id int) (bool, error) {
+	query := `
+		UPDATE refresh_tokens
+		SET rotated_at = NOW()
+		WHERE id = $1 AND rotated_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	res, err := r.database.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n == 1, nil
+}
+
+func (r *refreshTokenRepo) RevokeFamily(ctx context.Context, familyID string) error {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE family_id = $1 AND revoked_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := r.database.ExecContext(ctx, query, familyID)
+	return err
+}
+
+func (r *refreshTokenRepo) RevokeByUser(ctx context.Context, userID int) error {
+	query := `
+		UPDATE refresh_tokens
+		SET revoked_at = NOW()
+		WHERE user_id = $1 AND revoked_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := db.Conn(ctx, r.database).ExecContext(ctx, query, userID)
+	return err
+}
+
+func NewRefreshTokenRepository(database *sql.DB, qt time.Duration) RefreshTokenRepository {
+	return &refreshTokenRepo{
+		database:     database,
+		queryTimeout: qt,
+	}
+}
@@ -0,0 +1,144 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRefreshTokenService_Issue(t *testing.T) {
+	t.Run("should store only the hash of a new token", func(t *testing.T) {
+		mockRepo := new(MockRefreshTokenRepository)
+
+		var stored RefreshToken
+		mockRepo.On("Save", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				stored = args.Get(1).(RefreshToken)
+			}).Return(nil)
+
+		service := NewRefreshTokenService(mockRepo, time.Hour)
+
+		token, err := service.Issue(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, token)
+		assert.Equal(t, hashToken(token), stored.TokenHash)
+		assert.NotContains(t, stored.TokenHash, token)
+		assert.NotEmpty(t, stored.FamilyID)
+		assert.WithinDuration(t, time.Now().Add(time.Hour), stored.ExpiresAt, time.Second)
+	})
+}
+
+func TestRefreshTokenService_Rotate(t *testing.T) {
+	t.Run("should reject unknown tokens", func(t *testing.T) {
+		mockRepo := new(MockRefreshTokenRepository)
+		mockRepo.On("FindByHash", mock.Anything, hashToken("unknown")).Return(nil, nil)
+
+		service := NewRefreshTokenService(mockRepo, time.Hour)
+
+		_, _, err := service.Rotate(context.Background(), "unknown")
+
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+	})
+
+	t.Run("should reject expired tokens", func(t *testing.T) {
+		mockRepo := new(MockRefreshTokenRepository)
+		mockRepo.On("FindByHash", mock.Anything, hashToken("expired")).
+			Return(&RefreshToken{ID: 1, UserID: 1, ExpiresAt: time.Now().Add(-time.Minute)}, nil)
+
+		service := NewRefreshTokenService(mockRepo, time.Hour)
+
+		_, _, err := service.Rotate(context.Background(), "expired")
+
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken)
+		mockRepo.AssertNotCalled(t, "MarkRotated", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should revoke the family when a rotated token is reused", func(t *testing.T) {
+		rotatedAt := time.Now()
+		mockRepo := new(MockRefreshTokenRepository)
+		mockRepo.On("FindByHash", mock.Anything, hashToken("old")).
+			Return(&RefreshToken{ID: 1, UserID: 7, FamilyID: "fam", ExpiresAt: time.Now().Add(time.Hour), RotatedAt: &rotatedAt}, nil)
+		mockRepo.On("RevokeFamily", mock.Anything, "fam").Return(nil).Once()
+
+		service := NewRefreshTokenService(mockRepo, time.Hour)
+
+		userID, next, err := service.Rotate(context.Background(), "old")
+
+		assert.ErrorIs(t, err, ErrRefreshTokenReused)
+		assert.Equal(t, 7, userID)
+		assert.Empty(t, next)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should revoke the family when losing a concurrent rotation", func(t *testing.T) {
+		mockRepo := new(MockRefreshTokenRepository)
+		mockRepo.On("FindByHash", mock.Anything, hashToken("racy")).
+			Return(&RefreshToken{ID: 1, UserID: 7, FamilyID: "fam", ExpiresAt: time.Now().Add(time.Hour)}, nil)
+		mockRepo.On("MarkRotated", mock.Anything, 1).Return(false, nil)
+		mockRepo.On("RevokeFamily", mock.Anything, "fam").Return(nil).Once()
+
+		service := NewRefreshTokenService(mockRepo, time.Hour)
+
+		_, _, err := service.Rotate(context.Background(), "racy")
+
+		assert.ErrorIs(t, err, ErrRefreshTokenReused)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should issue a new token in the same family", func(t *testing.T) {
+		mockRepo := new(MockRefreshTokenRepository)
+		mockRepo.On("FindByHash", mock.Anything, hashToken("current")).
+			Return(&RefreshToken{ID: 1, UserID: 7, FamilyID: "fam", ExpiresAt: time.Now().Add(time.Hour)}, nil)
+		mockRepo.On("MarkRotated", mock.Anything, 1).Return(true, nil)
+
+		var stored RefreshToken
+		mockRepo.On("Save", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				stored = args.Get(1).(RefreshToken)
+			}).Return(nil)
+
+		service := NewRefreshTokenService(mockRepo, time.Hour)
+
+		userID, next, err := service.Rotate(context.Background(), "current")
+
+		assert.NoError(t, err)
+		assert.Equal(t, 7, userID)
+		assert.NotEqual(t, "current", next)
+		assert.Equal(t, "fam", stored.FamilyID)
+		assert.Equal(t, hashToken(next), stored.TokenHash)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestRefreshTokenService_Revoke(t *testing.T) {
+	t.Run("should revoke the family of the token", func(t *testing.T) {
+		mockRepo := new(MockRefreshTokenRepository)
+		mockRepo.On("FindByHash", mock.Anything, hashToken("current")).
+			Return(&RefreshToken{ID: 1, UserID: 7, FamilyID: "fam"}, nil)
+		mockRepo.On("RevokeFamily", mock.Anything, "fam").Return(nil).Once()
+
+		service := NewRefreshTokenService(mockRepo, time.Hour)
+
+		err := service.Revoke(context.Background(), 7, "current")
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should ignore tokens of other users", func(t *testing.T) {
+		mockRepo := new(MockRefreshTokenRepository)
+		mockRepo.On("FindByHash", mock.Anything, hashToken("other")).
+			Return(&RefreshToken{ID: 1, UserID: 8, FamilyID: "fam"}, nil)
+
+		service := NewRefreshTokenService(mockRepo, time.Hour)
+
+		err := service.Revoke(context.Background(), 7, "other")
+
+		assert.NoError(t, err)
+		mockRepo.AssertNotCalled(t, "RevokeFamily", mock.Anything, mock.Anything)
+	})
+}
@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type RequestThrottleRepository interface {
+	Hit(ctx context.Context, key string, now time.Time, throttle ResendThrottle) (bool, error)
+}
+
+type requestThrottleRepo struct {
+	database     *sql.DB
+	queryTimeout time.Duration
+}
+
+// Hit counts a request for key and reports whether it was allowed. The check
+// and the count are one statement, so parallel requests cannot all pass on
+// the same remaining slot. Refused requests are not counted.
+func (r *requestThrottleRepo) Hit(ctx context.Context, key string, now time.Time, throttle ResendThrottle) (bool, error) {
+	query := `
+		INSERT INTO request_throttles (key, hits, window_started_at, last_hit_at)
+		VALUES ($1, 1, $2, $2)
+		ON CONFLICT (key) DO UPDATE
+		SET hits = CASE
+				WHEN request_throttles.window_started_at <= $3 THEN 1
+				ELSE request_throttles.hits + 1
+			END,
+			window_started_at = CASE
+				WHEN request_throttles.window_started_at <= $3 THEN $2
+				ELSE request_throttles.window_started_at
+			END,
+			last_hit_at = $2
+		WHERE request_throttles.last_hit_at <= $4
+			AND (request_throttles.window_started_at <= $3 OR request_throttles.hits < $5)
+		RETURNING key
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var hit string
+	err := r.database.QueryRowContext(
+		ctx,
+		query,
+		key,
+		now,
+		now.Add(-throttle.Window),
+		now.Add(-throttle.Cooldown),
+		throttle.Max,
+	).Scan(&hit)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return true, nil
+}
+
+func NewRequestThrottleRepository(database *sql.DB, qt time.Duration) RequestThrottleRepository {
+	return &requestThrottleRepo{
+		database:     database,
+		queryTimeout: qt,
+	}
+}
@@ -0,0 +1,17 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRequestThrottleRepository struct {
+	mock.Mock
+}
+
+func (m *MockRequestThrottleRepository) Hit(ctx context.Context, key string, now time.Time, throttle ResendThrottle) (bool, error) {
+	args := m.Called(ctx, key, now, throttle)
+	return args.Bool(0), args.Error(1)
+}
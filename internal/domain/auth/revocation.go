@@ -0,0 +1,154 @@
+package auth
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/db"
+)
+
+// RevocationCacheTTL bounds how stale a negative lookup can be.
+const RevocationCacheTTL = time.Second * 30
+
+// RevocationStore decides whether an otherwise valid access token must be
+// rejected, either because its jti was revoked or because it was issued before
+// the user's "tokens valid after" cutoff.
+type RevocationStore interface {
+	RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+	RevokeAllForUser(ctx context.Context, userID int) error
+	ValidAfter(ctx context.Context, userID int) (time.Time, error)
+}
+
+type cachedEntry[T any] struct {
+	value     T
+	expiresAt time.Time
+}
+
+// revocationStore keeps recent lookups in memory so the auth middleware does
+// not hit the database on every request. Revoked tokens are cached until they
+// expire, since a revocation is final; everything else is cached for cacheTTL,
+// which bounds how long another instance's revocation takes to be seen here.
+type revocationStore struct {
+	repo     RevocationRepository
+	cacheTTL time.Duration
+
+	mu         sync.Mutex
+	tokens     map[string]cachedEntry[bool]
+	validAfter map[int]cachedEntry[time.Time]
+	lastPurge  time.Time
+}
+
+func (s *revocationStore) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	if err := s.repo.SaveRevokedToken(ctx, jti, expiresAt); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.tokens[jti] = cachedEntry[bool]{true, expiresAt}
+	s.mu.Unlock()
+
+	return nil
+}
+
+func (s *revocationStore) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	entry, ok := s.tokens[jti]
+	s.mu.Unlock()
+
+	if ok && now.Before(entry.expiresAt) {
+		return entry.value, nil
+	}
+
+	expiresAt, err := s.repo.FindRevokedToken(ctx, jti)
+	if err != nil {
+		return false, err
+	}
+
+	revoked := expiresAt != nil
+
+	s.mu.Lock()
+	if revoked {
+		s.tokens[jti] = cachedEntry[bool]{true, *expiresAt}
+	} else {
+		s.tokens[jti] = cachedEntry[bool]{false, now.Add(s.cacheTTL)}
+	}
+	s.purgeLocked(now)
+	s.mu.Unlock()
+
+	return revoked, nil
+}
+
+func (s *revocationStore) RevokeAllForUser(ctx context.Context, userID int) error {
+	now := time.Now()
+
+	if err := s.repo.SaveValidAfter(ctx, userID, now); err != nil {
+		return err
+	}
+
+	db.AfterCommit(ctx, func() {
+		s.mu.Lock()
+		s.validAfter[userID] = cachedEntry[time.Time]{now, now.Add(s.cacheTTL)}
+		s.mu.Unlock()
+	})
+
+	return nil
+}
+
+func (s *revocationStore) ValidAfter(ctx context.Context, userID int) (time.Time, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	entry, ok := s.validAfter[userID]
+	s.mu.Unlock()
+
+	if ok && now.Before(entry.expiresAt) {
+		return entry.value, nil
+	}
+
+	validAfter, err := s.repo.FindValidAfter(ctx, userID)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	var value time.Time
+	if validAfter != nil {
+		value = *validAfter
+	}
+
+	s.mu.Lock()
+	s.validAfter[userID] = cachedEntry[time.Time]{value, now.Add(s.cacheTTL)}
+	s.mu.Unlock()
+
+	return value, nil
+}
+
+func (s *revocationStore) purgeLocked(now time.Time) {
+	if now.Sub(s.lastPurge) < time.Minute {
+		return
+	}
+	s.lastPurge = now
+
+	for jti, entry := range s.tokens {
+		if now.After(entry.expiresAt) {
+			delete(s.tokens, jti)
+		}
+	}
+	for userID, entry := range s.validAfter {
+		if now.After(entry.expiresAt) {
+			delete(s.validAfter, userID)
+		}
+	}
+}
+
+func NewRevocationStore(repo RevocationRepository, cacheTTL time.Duration) RevocationStore {
+	return &revocationStore{
+		repo:       repo,
+		cacheTTL:   cacheTTL,
+		tokens:     map[string]cachedEntry[bool]{},
+		validAfter: map[int]cachedEntry[time.Time]{},
+	}
+}
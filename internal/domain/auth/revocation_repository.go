@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/db"
+)
+
+type RevocationRepository interface {
+	SaveRevokedToken(ctx context.Context, jti string, expiresAt time.Time) error
+	FindRevokedToken(ctx context.Context, jti string) (*time.Time, error)
+	// SaveValidAfter joins the transaction on ctx, if any.
+	SaveValidAfter(ctx context.Context, userID int, validAfter time.Time) error
+	FindValidAfter(ctx context.Context, userID int) (*time.Time, error)
+}
+
+type revocationRepo struct {
+	database     *sql.DB
+	queryTimeout time.Duration
+}
+
+func (r *revocationRepo) SaveRevokedToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	query := `
+		INSERT INTO revoked_tokens (jti, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := r.database.ExecContext(ctx, query, jti, expiresAt)
+	return err
+}
+
+// FindRevokedToken returns the expiry of the revoked token, or nil when the
+// jti was never revoked.
+func (r *revocationRepo) FindRevokedToken(ctx context.Context, jti string) (*time.Time, error) {
+	query := `
+		SELECT expires_at FROM revoked_tokens WHERE jti = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var expiresAt time.Time
+	err := r.database.QueryRowContext(ctx, query, jti).Scan(&expiresAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &expiresAt, nil
+}
+
+func (r *revocationRepo) SaveValidAfter(ctx context.Context, userID int, validAfter time.Time) error {
+	query := `
+		INSERT INTO user_token_cutoffs (user_id, valid_after)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET valid_after = EXCLUDED.valid_after
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := db.Conn(ctx, r.database).ExecContext(ctx, query, userID, validAfter)
+	return err
+}
+
+func (r *revocationRepo) FindValidAfter(ctx context.Context, userID int) (*time.Time, error) {
+	query := `
+		SELECT valid_after FROM user_token_cutoffs WHERE user_id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var validAfter time.Time
+	err := r.database.QueryRowContext(ctx, query, userID).Scan(&validAfter)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &validAfter, nil
+}
+
+func NewRevocationRepository(database *sql.DB, qt time.Duration) RevocationRepository {
+	return &revocationRepo{
+		database:     database,
+		queryTimeout: qt,
+	}
+}
@@ -0,0 +1,38 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRevocationRepository struct {
+	mock.Mock
+}
+
+func (m *MockRevocationRepository) SaveRevokedToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	args := m.Called(ctx, jti, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockRevocationRepository) FindRevokedToken(ctx context.Context, jti string) (*time.Time, error) {
+	args := m.Called(ctx, jti)
+	if t, ok := args.Get(0).(*time.Time); ok {
+		return t, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockRevocationRepository) SaveValidAfter(ctx context.Context, userID int, validAfter time.Time) error {
+	args := m.Called(ctx, userID, validAfter)
+	return args.Error(0)
+}
+
+func (m *MockRevocationRepository) FindValidAfter(ctx context.Context, userID int) (*time.Time, error) {
+	args := m.Called(ctx, userID)
+	if t, ok := args.Get(0).(*time.Time); ok {
+		return t, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
@@ -0,0 +1,99 @@
+package auth
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestRevocationStore_IsRevoked(t *testing.T) {
+	t.Run("should cache revoked tokens without hitting the database again", func(t *testing.T) {
+		exp := time.Now().Add(time.Minute)
+		mockRepo := new(MockRevocationRepository)
+		mockRepo.On("FindRevokedToken", mock.Anything, "jti").Return(&exp, nil).Once()
+
+		store := NewRevocationStore(mockRepo, time.Minute)
+
+		for range 3 {
+			revoked, err := store.IsRevoked(context.Background(), "jti")
+			assert.NoError(t, err)
+			assert.True(t, revoked)
+		}
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should cache misses only for the cache ttl", func(t *testing.T) {
+		mockRepo := new(MockRevocationRepository)
+		mockRepo.On("FindRevokedToken", mock.Anything, "jti").Return(nil, nil).Twice()
+
+		store := NewRevocationStore(mockRepo, time.Millisecond*20)
+
+		revoked, err := store.IsRevoked(context.Background(), "jti")
+		assert.NoError(t, err)
+		assert.False(t, revoked)
+
+		revoked, _ = store.IsRevoked(context.Background(), "jti")
+		assert.False(t, revoked)
+
+		time.Sleep(time.Millisecond * 30)
+
+		revoked, _ = store.IsRevoked(context.Background(), "jti")
+		assert.False(t, revoked)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should see a local revocation immediately", func(t *testing.T) {
+		exp := time.Now().Add(time.Minute)
+		mockRepo := new(MockRevocationRepository)
+		mockRepo.On("FindRevokedToken", mock.Anything, "jti").Return(nil, nil).Once()
+		mockRepo.On("SaveRevokedToken", mock.Anything, "jti", exp).Return(nil).Once()
+
+		store := NewRevocationStore(mockRepo, time.Minute)
+
+		revoked, _ := store.IsRevoked(context.Background(), "jti")
+		assert.False(t, revoked)
+
+		err := store.RevokeToken(context.Background(), "jti", exp)
+		assert.NoError(t, err)
+
+		revoked, _ = store.IsRevoked(context.Background(), "jti")
+		assert.True(t, revoked)
+
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestRevocationStore_ValidAfter(t *testing.T) {
+	t.Run("should return zero time when the user never logged out everywhere", func(t *testing.T) {
+		mockRepo := new(MockRevocationRepository)
+		mockRepo.On("FindValidAfter", mock.Anything, 1).Return(nil, nil).Once()
+
+		store := NewRevocationStore(mockRepo, time.Minute)
+
+		validAfter, err := store.ValidAfter(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.True(t, validAfter.IsZero())
+	})
+
+	t.Run("should return the cutoff set by RevokeAllForUser", func(t *testing.T) {
+		mockRepo := new(MockRevocationRepository)
+		mockRepo.On("SaveValidAfter", mock.Anything, 1, mock.Anything).Return(nil).Once()
+
+		store := NewRevocationStore(mockRepo, time.Minute)
+
+		err := store.RevokeAllForUser(context.Background(), 1)
+		assert.NoError(t, err)
+
+		validAfter, err := store.ValidAfter(context.Background(), 1)
+		assert.NoError(t, err)
+		assert.WithinDuration(t, time.Now(), validAfter, time.Second)
+
+		mockRepo.AssertNotCalled(t, "FindValidAfter", mock.Anything, mock.Anything)
+	})
+}
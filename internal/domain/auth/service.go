@@ -3,27 +3,81 @@ package auth
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/apikey"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/audit"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/kyc"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/mfa"
 	"github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
 	"github.com/DevVictor19/pic-pay-challenge/internal/domain/wallet"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/webhook"
 	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/db"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/mailer"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/tracing"
 )
 
+// resetMailTimeout bounds the background work of a password reset request.
+const resetMailTimeout = time.Second * 30
+
+// Runner runs work that outlives the request which started it. The server
+// waits for it on shutdown, before closing the database.
+type Runner interface {
+	Go(f func())
+}
+
 type AuthService interface {
 	Signup(ctx context.Context, dto SignupDTO) error
-	Login(ctx context.Context, dto LoginDTO) (string, error)
+	Login(ctx context.Context, dto LoginDTO) (*LoginResultDTO, error)
+	VerifyMFA(ctx context.Context, dto MFAVerifyDTO) (*TokenPairDTO, error)
+	Refresh(ctx context.Context, dto RefreshDTO) (*TokenPairDTO, error)
+	Logout(ctx context.Context, userID int, jti string, expiresAt time.Time, dto LogoutDTO) error
+	LogoutAll(ctx context.Context, userID int) error
+	ForgotPassword(ctx context.Context, dto ForgotPasswordDTO) error
+	ResetPassword(ctx context.Context, dto ResetPasswordDTO) error
+	VerifyEmail(ctx context.Context, token string) error
+	Unlock(ctx context.Context, token string) error
+	ResendVerification(ctx context.Context, usr *user.User) error
+	UpdateProfile(ctx context.Context, usr *user.User, dto user.UpdateProfileDTO) (*user.User, error)
+	ChangePassword(ctx context.Context, usr *user.User, dto ChangePasswordDTO) error
+	CloseAccount(ctx context.Context, usr *user.User, dto CloseAccountDTO) error
 }
 
 type authSvc struct {
-	userService   user.UserService
-	wallService   wallet.WalletService
-	bcryptService BcryptService
-	jwtService    JWTService
+	userService    user.UserService
+	wallService    wallet.WalletService
+	kycService     kyc.KYCService
+	apiKeyService  apikey.APIKeyService
+	webhookService webhook.WebhookService
+	transactor     db.Transactor
+	passwordHasher PasswordHasher
+	passwordPolicy PasswordPolicy
+	jwtService     JWTService
+	auditService   audit.AuditService
+	fingerprinter  audit.Fingerprinter
+	refreshService RefreshTokenService
+	revocations    RevocationStore
+	mfaService     mfa.MFAService
+	loginGuard     LoginGuard
+	resetService   PasswordResetService
+	verifyService  EmailVerificationService
+	mailer         mailer.Mailer
+	resetURL       string
+	verifyURL      string
+	unlockURL      string
+	accessTTL      time.Duration
+	background     Runner
 }
 
 func (s *authSvc) Signup(ctx context.Context, dto SignupDTO) error {
+	ctx, span := tracing.Start(ctx, "authSvc.Signup")
+	defer span.End()
+
 	if dto.CNPJ == nil && dto.CPF == nil {
 		return apperror.NewHttpError(http.StatusBadRequest, "CPF or CNPJ must be passed")
 	}
@@ -32,6 +86,10 @@ func (s *authSvc) Signup(ctx context.Context, dto SignupDTO) error {
 		return apperror.NewHttpError(http.StatusBadRequest, "chose CPF or CNPJ for create a new user")
 	}
 
+	if err := s.checkPassword(dto.Password, dto.Email, dto.Fullname); err != nil {
+		return err
+	}
+
 	withSameEmail, err := s.userService.FindByEmail(ctx, dto.Email)
 	if err != nil {
 		var httpError *apperror.HttpError
@@ -72,7 +130,7 @@ func (s *authSvc) Signup(ctx context.Context, dto SignupDTO) error {
 		}
 	}
 
-	hashed, err := s.bcryptService.Hash(dto.Password)
+	hashed, err := s.hashPassword(ctx, dto.Password)
 	if err != nil {
 		return err
 	}
@@ -81,10 +139,12 @@ func (s *authSvc) Signup(ctx context.Context, dto SignupDTO) error {
 
 	if dto.CNPJ != nil {
 		id, err := s.userService.CreateShopkeeper(ctx, user.ShopkeeperUserDTO{
-			Fullname: dto.Fullname,
-			CNPJ:     dto.CNPJ,
-			Email:    dto.Email,
-			Password: hashed,
+			Fullname:  dto.Fullname,
+			CNPJ:      dto.CNPJ,
+			LegalName: dto.LegalName,
+			TradeName: dto.TradeName,
+			Email:     dto.Email,
+			Password:  hashed,
 		})
 		if err != nil {
 			return err
@@ -110,43 +170,821 @@ func (s *authSvc) Signup(ctx context.Context, dto SignupDTO) error {
 		return err
 	}
 
+	err = s.auditService.Record(ctx, audit.RecordDTO{
+		ActorID:    &userId,
+		Action:     audit.AuthSignup,
+		TargetType: "user",
+		TargetID:   &userId,
+		After:      s.signupFingerprints(dto),
+	})
+	if err != nil {
+		return err
+	}
+
+	// the account already exists at this point, so a failed email must not
+	// fail the signup; the user can ask for another one
+	if err := s.sendVerification(ctx, userId, dto.Fullname, dto.Email); err != nil {
+		slog.Error("verification email not sent", "err", err.Error(), "user_id", userId)
+	}
+
 	return nil
 }
 
-func (s *authSvc) Login(ctx context.Context, dto LoginDTO) (string, error) {
+// Login checks the credentials and, for users with 2FA enabled, returns an
+// MFA challenge token instead of a session. The session is then created by
+// VerifyMFA.
+func (s *authSvc) Login(ctx context.Context, dto LoginDTO) (*LoginResultDTO, error) {
+	ctx, span := tracing.Start(ctx, "authSvc.Login")
+	defer span.End()
+
+	ip := audit.MetadataFromContext(ctx).IP
+
+	// reserved before hashing, so throttled attempts cost almost nothing
+	if err := s.loginGuard.Reserve(ctx, dto.Email, ip); err != nil {
+		return nil, loginBlocked(err)
+	}
+
 	user, err := s.userService.FindByEmail(ctx, dto.Email)
 	if err != nil {
 		var httpError *apperror.HttpError
 		if ok := errors.As(err, &httpError); ok {
-			return "", apperror.NewHttpError(http.StatusUnauthorized, "invalid email or password")
+			return nil, s.loginFailed(ctx, nil, dto.Email, ip)
 		}
 
-		return "", err
+		return nil, err
+	}
+
+	isValidPwd, err := s.comparePassword(ctx, dto.Password, user.Password)
+	if err != nil {
+		return nil, err
 	}
 
-	isValidPwd := s.bcryptService.Compare(dto.Password, user.Password)
 	if !isValidPwd {
-		return "", apperror.NewHttpError(http.StatusUnauthorized, "invalid email or password")
+		return nil, s.loginFailed(ctx, user, dto.Email, ip)
+	}
+
+	if err := s.loginGuard.Release(ctx, ip); err != nil {
+		return nil, err
+	}
+
+	s.rehashPassword(ctx, user, dto.Password)
+
+	mfaEnabled, err := s.mfaService.IsEnabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the email counter is only cleared once the second factor passes too
+	if mfaEnabled {
+		challenge, err := s.mfaService.StartChallenge(ctx, user.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		return &LoginResultDTO{MFARequired: true, MFAToken: challenge}, nil
+	}
+
+	if err := s.loginGuard.RecordSuccess(ctx, dto.Email); err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.startSession(ctx, user.ID, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LoginResultDTO{TokenPairDTO: tokens}, nil
+}
+
+// hashPassword and comparePassword get their own spans: with argon2id or
+// bcrypt they are most of the time spent in signup and login.
+// emailFingerprint is what audit entries keep of an email, see
+// audit.Fingerprinter.
+func (s *authSvc) emailFingerprint(email string) map[string]string {
+	return map[string]string{"email_fingerprint": s.fingerprinter.Fingerprint(email)}
+}
+
+func (s *authSvc) signupFingerprints(dto SignupDTO) map[string]string {
+	after := s.emailFingerprint(dto.Email)
+	if dto.CPF != nil {
+		after["cpf_fingerprint"] = s.fingerprinter.Fingerprint(*dto.CPF)
 	}
+	if dto.CNPJ != nil {
+		after["cnpj_fingerprint"] = s.fingerprinter.Fingerprint(*dto.CNPJ)
+	}
+	return after
+}
+
+func (s *authSvc) hashPassword(ctx context.Context, password string) (string, error) {
+	ctx, span := tracing.Start(ctx, "password.hash")
+	defer span.End()
+
+	return s.passwordHasher.Hash(ctx, password)
+}
 
-	token, err := s.jwtService.GenerateToken(user.ID, time.Minute*30)
+func (s *authSvc) comparePassword(ctx context.Context, password, hash string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "password.compare")
+	defer span.End()
+
+	return s.passwordHasher.Compare(ctx, password, hash)
+}
+
+// rehashPassword upgrades a hash made with outdated parameters while the
+// plain password is at hand. The upgrade only applies while the stored hash
+// is still the one just verified, so it cannot overwrite a password changed
+// or reset in the meantime. Failing to do so must not block the login.
+func (s *authSvc) rehashPassword(ctx context.Context, usr *user.User, password string) {
+	if !s.passwordHasher.NeedsRehash(usr.Password) {
+		return
+	}
+
+	hashed, err := s.hashPassword(ctx, password)
 	if err != nil {
-		return "", err
+		slog.Error("failed to rehash password", "err", err.Error(), "user_id", usr.ID)
+		return
 	}
 
-	return token, nil
+	updated, err := s.userService.UpdatePasswordIfUnchanged(ctx, usr.ID, usr.Password, hashed)
+	if err != nil {
+		slog.Error("failed to rehash password", "err", err.Error(), "user_id", usr.ID)
+		return
+	}
+	if !updated {
+		slog.Info("skipped rehash of a password changed concurrently", "user_id", usr.ID)
+	}
 }
 
-func NewAuthService(
-	usrSvc user.UserService,
-	wSvc wallet.WalletService,
-	bcrSvc BcryptService,
-	jwtSvc JWTService) AuthService {
+func (s *authSvc) VerifyMFA(ctx context.Context, dto MFAVerifyDTO) (*TokenPairDTO, error) {
+	ctx, span := tracing.Start(ctx, "authSvc.VerifyMFA")
+	defer span.End()
+
+	userID, err := s.mfaService.CompleteChallenge(ctx, dto.MFAToken, dto.Code)
+	if err != nil {
+		var httpError *apperror.HttpError
+		if ok := errors.As(err, &httpError); ok && userID != 0 {
+			auditErr := s.auditService.Record(ctx, audit.RecordDTO{
+				Action:     audit.AuthMFAFailure,
+				TargetType: "user",
+				TargetID:   &userID,
+			})
+			if auditErr != nil {
+				return nil, auditErr
+			}
+		}
+		return nil, err
+	}
+
+	usr, err := s.userService.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.loginGuard.RecordSuccess(ctx, usr.Email); err != nil {
+		return nil, err
+	}
+
+	return s.startSession(ctx, userID, true)
+}
+
+func (s *authSvc) startSession(ctx context.Context, userID int, withMFA bool) (*TokenPairDTO, error) {
+	refreshToken, err := s.refreshService.Issue(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tokens, err := s.tokenPair(userID, refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.auditService.Record(ctx, audit.RecordDTO{
+		ActorID:    &userID,
+		Action:     audit.AuthLoginSuccess,
+		TargetType: "user",
+		TargetID:   &userID,
+		Details:    map[string]bool{"mfa": withMFA},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (s *authSvc) Refresh(ctx context.Context, dto RefreshDTO) (*TokenPairDTO, error) {
+	ctx, span := tracing.Start(ctx, "authSvc.Refresh")
+	defer span.End()
+
+	userID, refreshToken, err := s.refreshService.Rotate(ctx, dto.RefreshToken)
+	if err != nil {
+		if errors.Is(err, ErrRefreshTokenReused) {
+			err = s.auditService.Record(ctx, audit.RecordDTO{
+				Action:     audit.AuthRefreshReuse,
+				TargetType: "user",
+				TargetID:   &userID,
+			})
+			if err != nil {
+				return nil, err
+			}
+			return nil, apperror.NewHttpError(http.StatusUnauthorized, "invalid refresh token")
+		}
+		if errors.Is(err, ErrInvalidRefreshToken) {
+			return nil, apperror.NewHttpError(http.StatusUnauthorized, "invalid refresh token")
+		}
+		return nil, err
+	}
+
+	if _, err := s.userService.FindByID(ctx, userID); err != nil {
+		var httpError *apperror.HttpError
+		if ok := errors.As(err, &httpError); ok {
+			return nil, apperror.NewHttpError(http.StatusUnauthorized, "invalid refresh token")
+		}
+		return nil, err
+	}
+
+	return s.tokenPair(userID, refreshToken)
+}
+
+// Logout revokes the access token used on the request and, when given, the
+// refresh token of the same session.
+func (s *authSvc) Logout(ctx context.Context, userID int, jti string, expiresAt time.Time, dto LogoutDTO) error {
+	ctx, span := tracing.Start(ctx, "authSvc.Logout")
+	defer span.End()
+
+	if err := s.revocations.RevokeToken(ctx, jti, expiresAt); err != nil {
+		return err
+	}
+
+	if dto.RefreshToken != nil {
+		if err := s.refreshService.Revoke(ctx, userID, *dto.RefreshToken); err != nil {
+			return err
+		}
+	}
+
+	return s.auditService.Record(ctx, audit.RecordDTO{
+		ActorID:    &userID,
+		Action:     audit.AuthLogout,
+		TargetType: "user",
+		TargetID:   &userID,
+	})
+}
+
+// LogoutAll invalidates every access and refresh token issued to the user so
+// far by moving the user's "tokens valid after" cutoff to now.
+func (s *authSvc) LogoutAll(ctx context.Context, userID int) error {
+	ctx, span := tracing.Start(ctx, "authSvc.LogoutAll")
+	defer span.End()
+
+	if err := s.revokeSessions(ctx, userID); err != nil {
+		return err
+	}
+
+	return s.auditService.Record(ctx, audit.RecordDTO{
+		ActorID:    &userID,
+		Action:     audit.AuthLogoutAll,
+		TargetType: "user",
+		TargetID:   &userID,
+	})
+}
+
+// ForgotPassword emails a reset link when the address belongs to a user. It
+// returns nil for unknown emails, and the link is issued and sent in the
+// background, so neither the response nor its timing reveals whether an
+// account exists. Requests are limited per email and per IP, known or not.
+func (s *authSvc) ForgotPassword(ctx context.Context, dto ForgotPasswordDTO) error {
+	ctx, span := tracing.Start(ctx, "authSvc.ForgotPassword")
+	defer span.End()
+
+	allowed, err := s.resetService.CanRequest(ctx, dto.Email, audit.MetadataFromContext(ctx).IP)
+	if err != nil {
+		return err
+	}
+
+	if !allowed {
+		return apperror.NewHttpError(http.StatusTooManyRequests, "password reset requested too often, try again later")
+	}
+
+	usr, err := s.userService.FindByEmail(ctx, dto.Email)
+	if err != nil {
+		var httpError *apperror.HttpError
+		if ok := errors.As(err, &httpError); !ok {
+			return err
+		}
+	}
+
+	var targetID *int
+	if usr != nil {
+		targetID = &usr.ID
+	}
+
+	err = s.auditService.Record(ctx, audit.RecordDTO{
+		Action:     audit.AuthPasswordResetRequest,
+		TargetType: "user",
+		TargetID:   targetID,
+		Details:    s.emailFingerprint(dto.Email),
+	})
+	if err != nil {
+		return err
+	}
+
+	if usr != nil {
+		// detached from the request, which ends before the email is sent
+		mailCtx := context.WithoutCancel(ctx)
+		s.background.Go(func() { s.sendReset(mailCtx, usr) })
+	}
+
+	return nil
+}
+
+func (s *authSvc) sendReset(ctx context.Context, usr *user.User) {
+	ctx, cancel := context.WithTimeout(ctx, resetMailTimeout)
+	defer cancel()
+
+	token, err := s.resetService.Issue(ctx, usr.ID)
+	if err != nil {
+		slog.Error("password reset token not issued", "err", err.Error(), "user_id", usr.ID)
+		return
+	}
+
+	err = s.mailer.Send(ctx, mailer.Message{
+		To:      usr.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nUse the link below to choose a new password. It can be used once.\n\n%s?token=%s\n\nIf you did not ask for this, ignore this email.\n",
+			usr.Fullname, s.resetURL, token,
+		),
+	})
+	if err != nil {
+		slog.Error("password reset email not sent", "err", err.Error(), "user_id", usr.ID)
+	}
+}
+
+// ResetPassword sets a new password using a reset token and ends every
+// session of the user, since they may have been opened by whoever knew the
+// old password.
+func (s *authSvc) ResetPassword(ctx context.Context, dto ResetPasswordDTO) error {
+	ctx, span := tracing.Start(ctx, "authSvc.ResetPassword")
+	defer span.End()
+
+	// the token is only spent once the new password is accepted, so a
+	// rejected password can be fixed without asking for another email
+	userID, err := s.resetService.Peek(ctx, dto.Token)
+	if err != nil {
+		return resetTokenError(err)
+	}
+
+	usr, err := s.userService.FindByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.checkPassword(dto.Password, usr.Email, usr.Fullname); err != nil {
+		return err
+	}
+
+	consumedBy, err := s.resetService.Consume(ctx, dto.Token)
+	if err != nil {
+		return resetTokenError(err)
+	}
+	if consumedBy != userID {
+		return resetTokenError(ErrInvalidResetToken)
+	}
+
+	hashed, err := s.hashPassword(ctx, dto.Password)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userService.UpdatePassword(ctx, userID, hashed); err != nil {
+		return err
+	}
+
+	if err := s.revokeSessions(ctx, userID); err != nil {
+		return err
+	}
+
+	return s.auditService.Record(ctx, audit.RecordDTO{
+		ActorID:    &userID,
+		Action:     audit.AuthPasswordReset,
+		TargetType: "user",
+		TargetID:   &userID,
+	})
+}
+
+func resetTokenError(err error) error {
+	if errors.Is(err, ErrInvalidResetToken) {
+		return apperror.NewHttpError(http.StatusBadRequest, "invalid or expired reset token")
+	}
+	return err
+}
+
+// checkPassword applies the password policy, reporting every broken rule at
+// once so the client can show them together.
+func (s *authSvc) checkPassword(password string, personal ...string) error {
+	violations := s.passwordPolicy.Check(password, personal...)
+	if len(violations) > 0 {
+		return apperror.NewHttpErrorWithDetails(
+			http.StatusUnprocessableEntity,
+			"password does not meet the policy",
+			violations,
+		)
+	}
+	return nil
+}
+
+func (s *authSvc) VerifyEmail(ctx context.Context, token string) error {
+	ctx, span := tracing.Start(ctx, "authSvc.VerifyEmail")
+	defer span.End()
+
+	userID, err := s.verifyService.Consume(ctx, token)
+	if err != nil {
+		if errors.Is(err, ErrInvalidVerificationToken) {
+			return apperror.NewHttpError(http.StatusBadRequest, "invalid or expired verification token")
+		}
+		return err
+	}
+
+	if err := s.userService.MarkEmailVerified(ctx, userID); err != nil {
+		return err
+	}
+
+	return s.auditService.Record(ctx, audit.RecordDTO{
+		ActorID:    &userID,
+		Action:     audit.AuthEmailVerified,
+		TargetType: "user",
+		TargetID:   &userID,
+	})
+}
+
+func (s *authSvc) ResendVerification(ctx context.Context, usr *user.User) error {
+	ctx, span := tracing.Start(ctx, "authSvc.ResendVerification")
+	defer span.End()
+
+	if usr.IsEmailVerified() {
+		return apperror.NewHttpError(http.StatusConflict, "email already verified")
+	}
+
+	allowed, err := s.verifyService.CanResend(ctx, usr.ID)
+	if err != nil {
+		return err
+	}
+
+	if !allowed {
+		return apperror.NewHttpError(http.StatusTooManyRequests, "verification email requested too often, try again later")
+	}
+
+	return s.sendVerification(ctx, usr.ID, usr.Fullname, usr.Email)
+}
+
+func (s *authSvc) UpdateProfile(ctx context.Context, usr *user.User, dto user.UpdateProfileDTO) (*user.User, error) {
+	ctx, span := tracing.Start(ctx, "authSvc.UpdateProfile")
+	defer span.End()
+
+	updated, err := s.userService.UpdateProfile(ctx, usr.ID, dto)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.auditService.Record(ctx, audit.RecordDTO{
+		ActorID:    &usr.ID,
+		Action:     audit.UserProfileUpdate,
+		TargetType: "user",
+		TargetID:   &usr.ID,
+		Details:    map[string]any{"fullname_changed": updated.Fullname != usr.Fullname},
+		Before:     s.emailFingerprint(usr.Email),
+		After:      s.emailFingerprint(updated.Email),
+	}); err != nil {
+		return nil, err
+	}
+
+	if updated.Email != usr.Email {
+		if err := s.sendVerification(ctx, updated.ID, updated.Fullname, updated.Email); err != nil {
+			// the change is saved; the user can ask for a new email
+			slog.Error("failed to send verification email", "user_id", updated.ID, "err", err.Error())
+		}
+	}
+
+	return updated, nil
+}
+
+// ChangePassword replaces the password of a signed-in user. Every session,
+// including the one making the request, is revoked afterwards.
+func (s *authSvc) ChangePassword(ctx context.Context, usr *user.User, dto ChangePasswordDTO) error {
+	ctx, span := tracing.Start(ctx, "authSvc.ChangePassword")
+	defer span.End()
+
+	isValidPwd, err := s.comparePassword(ctx, dto.CurrentPassword, usr.Password)
+	if err != nil {
+		return err
+	}
+
+	if !isValidPwd {
+		return apperror.NewHttpError(http.StatusForbidden, "current password is incorrect")
+	}
+
+	if dto.NewPassword == dto.CurrentPassword {
+		return apperror.NewHttpError(http.StatusUnprocessableEntity, "new password must differ from the current one")
+	}
+
+	if err := s.checkPassword(dto.NewPassword, usr.Email, usr.Fullname); err != nil {
+		return err
+	}
+
+	hashed, err := s.hashPassword(ctx, dto.NewPassword)
+	if err != nil {
+		return err
+	}
+
+	if err := s.userService.UpdatePassword(ctx, usr.ID, hashed); err != nil {
+		return err
+	}
+
+	if err := s.revokeSessions(ctx, usr.ID); err != nil {
+		return err
+	}
+
+	return s.auditService.Record(ctx, audit.RecordDTO{
+		ActorID:    &usr.ID,
+		Action:     audit.AuthPasswordChange,
+		TargetType: "user",
+		TargetID:   &usr.ID,
+	})
+}
+
+// CloseAccount anonymizes the user and signs them out everywhere. Each domain
+// closes its own part, all in one transaction: the wallet, KYC documents, API
+// keys, webhooks and MFA factor go together with the user, or not at all.
+// Only accounts with an empty wallet can be closed.
+func (s *authSvc) CloseAccount(ctx context.Context, usr *user.User, dto CloseAccountDTO) error {
+	ctx, span := tracing.Start(ctx, "authSvc.CloseAccount")
+	defer span.End()
+
+	if usr.Role == user.Admin {
+		return apperror.NewHttpError(http.StatusForbidden, "admin accounts cannot be closed")
+	}
+
+	isValidPwd, err := s.comparePassword(ctx, dto.Password, usr.Password)
+	if err != nil {
+		return err
+	}
+
+	if !isValidPwd {
+		return apperror.NewHttpError(http.StatusForbidden, "password is incorrect")
+	}
+
+	return s.transactor.WithTx(ctx, func(ctx context.Context) error {
+		steps := []func(ctx context.Context, userID int) error{
+			s.wallService.Close,
+			s.kycService.DeleteAll,
+			s.apiKeyService.RevokeAll,
+			s.webhookService.DisableAll,
+			s.mfaService.Remove,
+			s.userService.Close,
+			s.revokeSessions,
+		}
+		for _, step := range steps {
+			if err := step(ctx, usr.ID); err != nil {
+				return err
+			}
+		}
+
+		return s.auditService.Record(ctx, audit.RecordDTO{
+			ActorID:    &usr.ID,
+			Action:     audit.UserAccountClose,
+			TargetType: "user",
+			TargetID:   &usr.ID,
+		})
+	})
+}
+
+func (s *authSvc) sendVerification(ctx context.Context, userID int, fullname, email string) error {
+	token, err := s.verifyService.Issue(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, mailer.Message{
+		To:      email,
+		Subject: "Confirm your email",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nConfirm your email to start sending transfers:\n\n%s?token=%s\n",
+			fullname, s.verifyURL, token,
+		),
+	})
+}
+
+func (s *authSvc) revokeSessions(ctx context.Context, userID int) error {
+	if err := s.revocations.RevokeAllForUser(ctx, userID); err != nil {
+		return err
+	}
+
+	return s.refreshService.RevokeAll(ctx, userID)
+}
+
+func (s *authSvc) tokenPair(userID int, refreshToken string) (*TokenPairDTO, error) {
+	accessToken, err := s.jwtService.GenerateToken(userID, s.accessTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPairDTO{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int64(s.accessTTL.Seconds()),
+	}, nil
+}
+
+// loginFailed locks the account when the reserved attempts reach the
+// threshold and returns the generic error that must be sent to the client,
+// unless the throttle or audit log itself cannot be written.
+func (s *authSvc) loginFailed(ctx context.Context, usr *user.User, email, ip string) error {
+	var userID *int
+	if usr != nil {
+		userID = &usr.ID
+	}
+
+	unlockToken, err := s.loginGuard.RecordFailure(ctx, email, ip)
+	if err != nil {
+		return err
+	}
+
+	err = s.auditService.Record(ctx, audit.RecordDTO{
+		Action:     audit.AuthLoginFailure,
+		TargetType: "user",
+		TargetID:   userID,
+		Details:    s.emailFingerprint(email),
+	})
+	if err != nil {
+		return err
+	}
+
+	if unlockToken != "" {
+		err = s.auditService.Record(ctx, audit.RecordDTO{
+			Action:     audit.AuthAccountLocked,
+			TargetType: "user",
+			TargetID:   userID,
+			Details:    s.emailFingerprint(email),
+		})
+		if err != nil {
+			return err
+		}
+
+		if usr != nil {
+			s.sendUnlock(ctx, usr, unlockToken)
+		}
+	}
+
+	return apperror.NewHttpError(http.StatusUnauthorized, "invalid email or password")
+}
+
+func (s *authSvc) sendUnlock(ctx context.Context, usr *user.User, token string) {
+	err := s.mailer.Send(ctx, mailer.Message{
+		To:      usr.Email,
+		Subject: "Your account was locked",
+		Body: fmt.Sprintf(
+			"Hi %s,\n\nWe locked your account after too many failed login attempts. If it was you, unlock it here:\n\n%s?token=%s\n\nIf it was not you, unlock it and consider changing your password.\n",
+			usr.Fullname, s.unlockURL, token,
+		),
+	})
+	if err != nil {
+		slog.Error("unlock email not sent", "err", err.Error(), "user_id", usr.ID)
+	}
+}
+
+func loginBlocked(err error) error {
+	var retryErr *RetryAfterError
+	if errors.As(err, &retryErr) {
+		return apperror.NewHttpError(http.StatusTooManyRequests, retryErr.Error())
+	}
+
+	if errors.Is(err, ErrAccountLocked) {
+		return apperror.NewHttpError(http.StatusLocked, "account temporarily locked, check your email to unlock it")
+	}
+
+	if errors.Is(err, ErrIPLocked) {
+		return apperror.NewHttpError(http.StatusTooManyRequests, "too many failed logins from this address, try again later")
+	}
+
+	return err
+}
+
+// Unlock lifts a lockout using the link emailed when the account was locked.
+func (s *authSvc) Unlock(ctx context.Context, token string) error {
+	ctx, span := tracing.Start(ctx, "authSvc.Unlock")
+	defer span.End()
+
+	email, err := s.loginGuard.Unlock(ctx, token)
+	if err != nil {
+		if errors.Is(err, ErrInvalidUnlockToken) {
+			return apperror.NewHttpError(http.StatusBadRequest, "invalid or expired unlock token")
+		}
+		return err
+	}
+
+	return s.auditService.Record(ctx, audit.RecordDTO{
+		Action:     audit.AuthAccountUnlocked,
+		TargetType: "user",
+		Details:    s.emailFingerprint(email),
+	})
+}
+
+// Deps are the services AuthService is built on. All of them are required.
+type Deps struct {
+	UserService    user.UserService
+	WalletService  wallet.WalletService
+	KYCService     kyc.KYCService
+	APIKeyService  apikey.APIKeyService
+	WebhookService webhook.WebhookService
+	Transactor     db.Transactor
+	PasswordHasher PasswordHasher
+	PasswordPolicy PasswordPolicy
+	JWTService     JWTService
+	AuditService   audit.AuditService
+	Fingerprinter  audit.Fingerprinter
+	RefreshService RefreshTokenService
+	Revocations    RevocationStore
+	MFAService     mfa.MFAService
+	LoginGuard     LoginGuard
+	ResetService   PasswordResetService
+	VerifyService  EmailVerificationService
+	Mailer         mailer.Mailer
+	Background     Runner
+}
+
+// missing names the dependencies left nil.
+func (d Deps) missing() []string {
+	deps := []struct {
+		name string
+		dep  any
+	}{
+		{"UserService", d.UserService},
+		{"WalletService", d.WalletService},
+		{"KYCService", d.KYCService},
+		{"APIKeyService", d.APIKeyService},
+		{"WebhookService", d.WebhookService},
+		{"Transactor", d.Transactor},
+		{"PasswordHasher", d.PasswordHasher},
+		{"PasswordPolicy", d.PasswordPolicy},
+		{"JWTService", d.JWTService},
+		{"AuditService", d.AuditService},
+		{"Fingerprinter", d.Fingerprinter},
+		{"RefreshService", d.RefreshService},
+		{"Revocations", d.Revocations},
+		{"MFAService", d.MFAService},
+		{"LoginGuard", d.LoginGuard},
+		{"ResetService", d.ResetService},
+		{"VerifyService", d.VerifyService},
+		{"Mailer", d.Mailer},
+		{"Background", d.Background},
+	}
+
+	var missing []string
+	for _, d := range deps {
+		if d.dep == nil {
+			missing = append(missing, d.name)
+		}
+	}
+	return missing
+}
+
+// Config holds the links sent by email and the access token lifetime.
+type Config struct {
+	PasswordResetURL string
+	VerifyEmailURL   string
+	UnlockURL        string
+	AccessTTL        time.Duration
+}
+
+// NewAuthService panics when a dependency is missing, so a wiring mistake
+// fails at startup instead of on the first request that needs it.
+func NewAuthService(deps Deps, cfg Config) AuthService {
+	if missing := deps.missing(); len(missing) > 0 {
+		panic(fmt.Sprintf("auth: missing dependencies: %s", strings.Join(missing, ", ")))
+	}
 
 	return &authSvc{
-		userService:   usrSvc,
-		wallService:   wSvc,
-		bcryptService: bcrSvc,
-		jwtService:    jwtSvc,
+		userService:    deps.UserService,
+		wallService:    deps.WalletService,
+		kycService:     deps.KYCService,
+		apiKeyService:  deps.APIKeyService,
+		webhookService: deps.WebhookService,
+		transactor:     deps.Transactor,
+		passwordHasher: deps.PasswordHasher,
+		passwordPolicy: deps.PasswordPolicy,
+		jwtService:     deps.JWTService,
+		auditService:   deps.AuditService,
+		fingerprinter:  deps.Fingerprinter,
+		refreshService: deps.RefreshService,
+		revocations:    deps.Revocations,
+		mfaService:     deps.MFAService,
+		loginGuard:     deps.LoginGuard,
+		resetService:   deps.ResetService,
+		verifyService:  deps.VerifyService,
+		mailer:         deps.Mailer,
+		resetURL:       cfg.PasswordResetURL,
+		verifyURL:      cfg.VerifyEmailURL,
+		unlockURL:      cfg.UnlockURL,
+		accessTTL:      cfg.AccessTTL,
+		background:     deps.Background,
 	}
 }
@@ -4,15 +4,160 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/apikey"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/audit"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/kyc"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/mfa"
 	"github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
 	"github.com/DevVictor19/pic-pay-challenge/internal/domain/wallet"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/webhook"
 	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/db"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/mailer"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
+const (
+	accessTTL = time.Minute * 30
+	resetURL  = "http://localhost:3000/reset-password"
+	verifyURL = "http://localhost:8080/v1/auth/verify-email"
+	unlockURL = "http://localhost:8080/v1/auth/unlock"
+)
+
+var testFingerprinter = audit.NewFingerprinter("audit-test")
+
+// runnerFunc adapts a function to Runner.
+type runnerFunc func(f func())
+
+func (r runnerFunc) Go(f func()) { r(f) }
+
+func newAuditServiceMock() *audit.MockAuditService {
+	auditServiceMock := new(audit.MockAuditService)
+	auditServiceMock.On("Record", mock.Anything, mock.Anything).Return(nil).Maybe()
+	return auditServiceMock
+}
+
+func newLoginGuardMock() *MockLoginGuard {
+	loginGuardMock := new(MockLoginGuard)
+	loginGuardMock.On("Reserve", mock.Anything, mock.Anything, mock.Anything).Return(nil).Maybe()
+	loginGuardMock.On("RecordFailure", mock.Anything, mock.Anything, mock.Anything).Return("", nil).Maybe()
+	loginGuardMock.On("Release", mock.Anything, mock.Anything).Return(nil).Maybe()
+	loginGuardMock.On("RecordSuccess", mock.Anything, mock.Anything).Return(nil).Maybe()
+	return loginGuardMock
+}
+
+func newPasswordPolicyMock() *MockPasswordPolicy {
+	passwordPolicyMock := new(MockPasswordPolicy)
+	passwordPolicyMock.On("Check", mock.Anything, mock.Anything).Return([]Violation{}).Maybe()
+	return passwordPolicyMock
+}
+
+func newMFADisabledMock() *mfa.MockMFAService {
+	mfaServiceMock := new(mfa.MockMFAService)
+	mfaServiceMock.On("IsEnabled", mock.Anything, mock.Anything).Return(false, nil).Maybe()
+	return mfaServiceMock
+}
+
+// newAuthService fills the dependencies a test leaves out with mocks that
+// expect no calls, so reaching one fails the test instead of panicking.
+func newAuthService(deps Deps) AuthService {
+	if deps.UserService == nil {
+		deps.UserService = new(user.MockUserService)
+	}
+	if deps.WalletService == nil {
+		deps.WalletService = new(wallet.MockWalletService)
+	}
+	if deps.KYCService == nil {
+		deps.KYCService = new(kyc.MockKYCService)
+	}
+	if deps.APIKeyService == nil {
+		deps.APIKeyService = new(apikey.MockAPIKeyService)
+	}
+	if deps.WebhookService == nil {
+		deps.WebhookService = new(webhook.MockWebhookService)
+	}
+	if deps.Transactor == nil {
+		deps.Transactor = &db.MockTransactor{}
+	}
+	if deps.PasswordHasher == nil {
+		deps.PasswordHasher = new(MockPasswordHasher)
+	}
+	if deps.PasswordPolicy == nil {
+		deps.PasswordPolicy = newPasswordPolicyMock()
+	}
+	if deps.JWTService == nil {
+		deps.JWTService = new(MockJWTService)
+	}
+	if deps.AuditService == nil {
+		deps.AuditService = newAuditServiceMock()
+	}
+	if deps.Fingerprinter == nil {
+		deps.Fingerprinter = testFingerprinter
+	}
+	if deps.RefreshService == nil {
+		deps.RefreshService = new(MockRefreshTokenService)
+	}
+	if deps.Revocations == nil {
+		deps.Revocations = new(MockRevocationStore)
+	}
+	if deps.MFAService == nil {
+		deps.MFAService = newMFADisabledMock()
+	}
+	if deps.LoginGuard == nil {
+		deps.LoginGuard = newLoginGuardMock()
+	}
+	if deps.ResetService == nil {
+		deps.ResetService = new(MockPasswordResetService)
+	}
+	if deps.VerifyService == nil {
+		deps.VerifyService = new(MockEmailVerificationService)
+	}
+	if deps.Mailer == nil {
+		deps.Mailer = new(mailer.MockMailer)
+	}
+	if deps.Background == nil {
+		deps.Background = runnerFunc(func(f func()) { f() })
+	}
+
+	return NewAuthService(deps, Config{
+		PasswordResetURL: resetURL,
+		VerifyEmailURL:   verifyURL,
+		UnlockURL:        unlockURL,
+		AccessTTL:        accessTTL,
+	})
+}
+
+func TestNewAuthService(t *testing.T) {
+	t.Run("should panic naming the missing dependencies", func(t *testing.T) {
+		assert.PanicsWithValue(t, "auth: missing dependencies: JWTService, Mailer, Background", func() {
+			NewAuthService(Deps{
+				UserService:    new(user.MockUserService),
+				WalletService:  new(wallet.MockWalletService),
+				KYCService:     new(kyc.MockKYCService),
+				APIKeyService:  new(apikey.MockAPIKeyService),
+				WebhookService: new(webhook.MockWebhookService),
+				Transactor:     &db.MockTransactor{},
+				PasswordHasher: new(MockPasswordHasher),
+				PasswordPolicy: newPasswordPolicyMock(),
+				AuditService:   newAuditServiceMock(),
+				Fingerprinter:  testFingerprinter,
+				RefreshService: new(MockRefreshTokenService),
+				Revocations:    new(MockRevocationStore),
+				MFAService:     newMFADisabledMock(),
+				LoginGuard:     newLoginGuardMock(),
+				ResetService:   new(MockPasswordResetService),
+				VerifyService:  new(MockEmailVerificationService),
+			}, Config{})
+		})
+	})
+}
+
 func TestAuthService_Signup(t *testing.T) {
 	t.Run("should return bad request if cpnj and cpf is nil", func(t *testing.T) {
 		userServiceMock := new(user.MockUserService)
@@ -25,8 +170,8 @@ func TestAuthService_Signup(t *testing.T) {
 		wallServiceMock := new(wallet.MockWalletService)
 		wallServiceMock.AssertNotCalled(t, "Create")
 
-		bcryptServiceMock := new(MockBcryptService)
-		bcryptServiceMock.AssertNotCalled(t, "Hash")
+		hasherMock := new(MockPasswordHasher)
+		hasherMock.AssertNotCalled(t, "Hash")
 
 		jwtServiceMock := new(MockJWTService)
 
@@ -36,12 +181,13 @@ func TestAuthService_Signup(t *testing.T) {
 			Password: "password123",
 		}
 
-		service := NewAuthService(
-			userServiceMock,
-			wallServiceMock,
-			bcryptServiceMock,
-			jwtServiceMock,
-		)
+		service := newAuthService(Deps{
+			UserService:    userServiceMock,
+			WalletService:  wallServiceMock,
+			PasswordHasher: hasherMock,
+			JWTService:     jwtServiceMock,
+			MFAService:     new(mfa.MockMFAService),
+		})
 
 		err := service.Signup(context.Background(), dto)
 
@@ -52,10 +198,44 @@ func TestAuthService_Signup(t *testing.T) {
 
 		userServiceMock.AssertExpectations(t)
 		wallServiceMock.AssertExpectations(t)
-		bcryptServiceMock.AssertExpectations(t)
+		hasherMock.AssertExpectations(t)
 		jwtServiceMock.AssertExpectations(t)
 	})
 
+	t.Run("should return every broken password rule", func(t *testing.T) {
+		userServiceMock := new(user.MockUserService)
+
+		cpf := "12345678901"
+		dto := SignupDTO{
+			Fullname: "John Doe",
+			CPF:      &cpf,
+			Email:    "email@email.com",
+			Password: "short",
+		}
+
+		violations := []Violation{
+			{Rule: "min_length", Message: "password must be at least 8 characters long"},
+			{Rule: "digit", Message: "password must contain a digit"},
+		}
+		passwordPolicyMock := new(MockPasswordPolicy)
+		passwordPolicyMock.On("Check", "short", []string{dto.Email, dto.Fullname}).Return(violations).Once()
+
+		service := newAuthService(Deps{
+			UserService:    userServiceMock,
+			PasswordHasher: new(MockPasswordHasher),
+			PasswordPolicy: passwordPolicyMock,
+		})
+
+		err := service.Signup(context.Background(), dto)
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusUnprocessableEntity, httpError.Code)
+		assert.Equal(t, violations, httpError.Details)
+		userServiceMock.AssertNotCalled(t, "FindByEmail", mock.Anything, mock.Anything)
+		passwordPolicyMock.AssertExpectations(t)
+	})
+
 	t.Run("should return bad request if cpnj and cpf is not nil", func(t *testing.T) {
 		userServiceMock := new(user.MockUserService)
 		userServiceMock.AssertNotCalled(t, "FindByEmail")
@@ -67,8 +247,8 @@ func TestAuthService_Signup(t *testing.T) {
 		wallServiceMock := new(wallet.MockWalletService)
 		wallServiceMock.AssertNotCalled(t, "Create")
 
-		bcryptServiceMock := new(MockBcryptService)
-		bcryptServiceMock.AssertNotCalled(t, "Hash")
+		hasherMock := new(MockPasswordHasher)
+		hasherMock.AssertNotCalled(t, "Hash")
 
 		jwtServiceMock := new(MockJWTService)
 
@@ -82,12 +262,13 @@ func TestAuthService_Signup(t *testing.T) {
 			Password: "password123",
 		}
 
-		service := NewAuthService(
-			userServiceMock,
-			wallServiceMock,
-			bcryptServiceMock,
-			jwtServiceMock,
-		)
+		service := newAuthService(Deps{
+			UserService:    userServiceMock,
+			WalletService:  wallServiceMock,
+			PasswordHasher: hasherMock,
+			JWTService:     jwtServiceMock,
+			MFAService:     new(mfa.MockMFAService),
+		})
 
 		err := service.Signup(context.Background(), dto)
 
@@ -98,7 +279,7 @@ func TestAuthService_Signup(t *testing.T) {
 
 		userServiceMock.AssertExpectations(t)
 		wallServiceMock.AssertExpectations(t)
-		bcryptServiceMock.AssertExpectations(t)
+		hasherMock.AssertExpectations(t)
 		jwtServiceMock.AssertExpectations(t)
 	})
 
@@ -114,8 +295,8 @@ func TestAuthService_Signup(t *testing.T) {
 		wallServiceMock := new(wallet.MockWalletService)
 		wallServiceMock.AssertNotCalled(t, "Create")
 
-		bcryptServiceMock := new(MockBcryptService)
-		bcryptServiceMock.AssertNotCalled(t, "Hash")
+		hasherMock := new(MockPasswordHasher)
+		hasherMock.AssertNotCalled(t, "Hash")
 
 		jwtServiceMock := new(MockJWTService)
 
@@ -127,12 +308,13 @@ func TestAuthService_Signup(t *testing.T) {
 			Password: "password123",
 		}
 
-		service := NewAuthService(
-			userServiceMock,
-			wallServiceMock,
-			bcryptServiceMock,
-			jwtServiceMock,
-		)
+		service := newAuthService(Deps{
+			UserService:    userServiceMock,
+			WalletService:  wallServiceMock,
+			PasswordHasher: hasherMock,
+			JWTService:     jwtServiceMock,
+			MFAService:     new(mfa.MockMFAService),
+		})
 
 		err := service.Signup(context.Background(), dto)
 
@@ -141,7 +323,7 @@ func TestAuthService_Signup(t *testing.T) {
 
 		userServiceMock.AssertExpectations(t)
 		wallServiceMock.AssertExpectations(t)
-		bcryptServiceMock.AssertExpectations(t)
+		hasherMock.AssertExpectations(t)
 		jwtServiceMock.AssertExpectations(t)
 	})
 
@@ -157,8 +339,8 @@ func TestAuthService_Signup(t *testing.T) {
 		wallServiceMock := new(wallet.MockWalletService)
 		wallServiceMock.AssertNotCalled(t, "Create")
 
-		bcryptServiceMock := new(MockBcryptService)
-		bcryptServiceMock.AssertNotCalled(t, "Hash")
+		hasherMock := new(MockPasswordHasher)
+		hasherMock.AssertNotCalled(t, "Hash")
 
 		jwtServiceMock := new(MockJWTService)
 
@@ -170,12 +352,13 @@ func TestAuthService_Signup(t *testing.T) {
 			Password: "password123",
 		}
 
-		service := NewAuthService(
-			userServiceMock,
-			wallServiceMock,
-			bcryptServiceMock,
-			jwtServiceMock,
-		)
+		service := newAuthService(Deps{
+			UserService:    userServiceMock,
+			WalletService:  wallServiceMock,
+			PasswordHasher: hasherMock,
+			JWTService:     jwtServiceMock,
+			MFAService:     new(mfa.MockMFAService),
+		})
 
 		err := service.Signup(context.Background(), dto)
 
@@ -186,7 +369,7 @@ func TestAuthService_Signup(t *testing.T) {
 
 		userServiceMock.AssertExpectations(t)
 		wallServiceMock.AssertExpectations(t)
-		bcryptServiceMock.AssertExpectations(t)
+		hasherMock.AssertExpectations(t)
 		jwtServiceMock.AssertExpectations(t)
 	})
 
@@ -203,8 +386,8 @@ func TestAuthService_Signup(t *testing.T) {
 		wallServiceMock := new(wallet.MockWalletService)
 		wallServiceMock.AssertNotCalled(t, "Create")
 
-		bcryptServiceMock := new(MockBcryptService)
-		bcryptServiceMock.AssertNotCalled(t, "Hash")
+		hasherMock := new(MockPasswordHasher)
+		hasherMock.AssertNotCalled(t, "Hash")
 
 		jwtServiceMock := new(MockJWTService)
 
@@ -216,12 +399,13 @@ func TestAuthService_Signup(t *testing.T) {
 			Password: "password123",
 		}
 
-		service := NewAuthService(
-			userServiceMock,
-			wallServiceMock,
-			bcryptServiceMock,
-			jwtServiceMock,
-		)
+		service := newAuthService(Deps{
+			UserService:    userServiceMock,
+			WalletService:  wallServiceMock,
+			PasswordHasher: hasherMock,
+			JWTService:     jwtServiceMock,
+			MFAService:     new(mfa.MockMFAService),
+		})
 
 		err := service.Signup(context.Background(), dto)
 
@@ -230,7 +414,7 @@ func TestAuthService_Signup(t *testing.T) {
 
 		userServiceMock.AssertExpectations(t)
 		wallServiceMock.AssertExpectations(t)
-		bcryptServiceMock.AssertExpectations(t)
+		hasherMock.AssertExpectations(t)
 		jwtServiceMock.AssertExpectations(t)
 	})
 
@@ -247,8 +431,8 @@ func TestAuthService_Signup(t *testing.T) {
 		wallServiceMock := new(wallet.MockWalletService)
 		wallServiceMock.AssertNotCalled(t, "Create")
 
-		bcryptServiceMock := new(MockBcryptService)
-		bcryptServiceMock.AssertNotCalled(t, "Hash")
+		hasherMock := new(MockPasswordHasher)
+		hasherMock.AssertNotCalled(t, "Hash")
 
 		jwtServiceMock := new(MockJWTService)
 
@@ -260,12 +444,13 @@ func TestAuthService_Signup(t *testing.T) {
 			Password: "password123",
 		}
 
-		service := NewAuthService(
-			userServiceMock,
-			wallServiceMock,
-			bcryptServiceMock,
-			jwtServiceMock,
-		)
+		service := newAuthService(Deps{
+			UserService:    userServiceMock,
+			WalletService:  wallServiceMock,
+			PasswordHasher: hasherMock,
+			JWTService:     jwtServiceMock,
+			MFAService:     new(mfa.MockMFAService),
+		})
 
 		err := service.Signup(context.Background(), dto)
 
@@ -276,7 +461,7 @@ func TestAuthService_Signup(t *testing.T) {
 
 		userServiceMock.AssertExpectations(t)
 		wallServiceMock.AssertExpectations(t)
-		bcryptServiceMock.AssertExpectations(t)
+		hasherMock.AssertExpectations(t)
 		jwtServiceMock.AssertExpectations(t)
 	})
 
@@ -293,8 +478,8 @@ func TestAuthService_Signup(t *testing.T) {
 		wallServiceMock := new(wallet.MockWalletService)
 		wallServiceMock.AssertNotCalled(t, "Create")
 
-		bcryptServiceMock := new(MockBcryptService)
-		bcryptServiceMock.AssertNotCalled(t, "Hash")
+		hasherMock := new(MockPasswordHasher)
+		hasherMock.AssertNotCalled(t, "Hash")
 
 		jwtServiceMock := new(MockJWTService)
 
@@ -306,12 +491,13 @@ func TestAuthService_Signup(t *testing.T) {
 			Password: "password123",
 		}
 
-		service := NewAuthService(
-			userServiceMock,
-			wallServiceMock,
-			bcryptServiceMock,
-			jwtServiceMock,
-		)
+		service := newAuthService(Deps{
+			UserService:    userServiceMock,
+			WalletService:  wallServiceMock,
+			PasswordHasher: hasherMock,
+			JWTService:     jwtServiceMock,
+			MFAService:     new(mfa.MockMFAService),
+		})
 
 		err := service.Signup(context.Background(), dto)
 
@@ -320,7 +506,7 @@ func TestAuthService_Signup(t *testing.T) {
 
 		userServiceMock.AssertExpectations(t)
 		wallServiceMock.AssertExpectations(t)
-		bcryptServiceMock.AssertExpectations(t)
+		hasherMock.AssertExpectations(t)
 		jwtServiceMock.AssertExpectations(t)
 	})
 
@@ -337,8 +523,8 @@ func TestAuthService_Signup(t *testing.T) {
 		wallServiceMock := new(wallet.MockWalletService)
 		wallServiceMock.AssertNotCalled(t, "Create")
 
-		bcryptServiceMock := new(MockBcryptService)
-		bcryptServiceMock.AssertNotCalled(t, "Hash")
+		hasherMock := new(MockPasswordHasher)
+		hasherMock.AssertNotCalled(t, "Hash")
 
 		jwtServiceMock := new(MockJWTService)
 
@@ -350,12 +536,13 @@ func TestAuthService_Signup(t *testing.T) {
 			Password: "password123",
 		}
 
-		service := NewAuthService(
-			userServiceMock,
-			wallServiceMock,
-			bcryptServiceMock,
-			jwtServiceMock,
-		)
+		service := newAuthService(Deps{
+			UserService:    userServiceMock,
+			WalletService:  wallServiceMock,
+			PasswordHasher: hasherMock,
+			JWTService:     jwtServiceMock,
+			MFAService:     new(mfa.MockMFAService),
+		})
 
 		err := service.Signup(context.Background(), dto)
 
@@ -366,7 +553,7 @@ func TestAuthService_Signup(t *testing.T) {
 
 		userServiceMock.AssertExpectations(t)
 		wallServiceMock.AssertExpectations(t)
-		bcryptServiceMock.AssertExpectations(t)
+		hasherMock.AssertExpectations(t)
 		jwtServiceMock.AssertExpectations(t)
 	})
 
@@ -382,12 +569,12 @@ func TestAuthService_Signup(t *testing.T) {
 		userId := 2
 
 		userServiceMock := new(user.MockUserService)
-		userServiceMock.On("FindByEmail", ctx, signupDto.Email).Return(nil, nil)
+		userServiceMock.On("FindByEmail", mock.Anything, signupDto.Email).Return(nil, nil)
 		userServiceMock.AssertNotCalled(t, "FindByCPF")
-		userServiceMock.On("FindByCNPJ", ctx, *signupDto.CNPJ).Return(nil, nil)
+		userServiceMock.On("FindByCNPJ", mock.Anything, *signupDto.CNPJ).Return(nil, nil)
 
-		bcryptServiceMock := new(MockBcryptService)
-		bcryptServiceMock.On("Hash", signupDto.Password).Return("hashed456", nil)
+		hasherMock := new(MockPasswordHasher)
+		hasherMock.On("Hash", mock.Anything, signupDto.Password).Return("hashed456", nil)
 
 		shopkeeperDto := user.ShopkeeperUserDTO{
 			Fullname: signupDto.Fullname,
@@ -396,20 +583,31 @@ func TestAuthService_Signup(t *testing.T) {
 			Password: "hashed456",
 		}
 
-		userServiceMock.On("CreateShopkeeper", ctx, shopkeeperDto).Return(userId, nil)
+		userServiceMock.On("CreateShopkeeper", mock.Anything, shopkeeperDto).Return(userId, nil)
 		userServiceMock.AssertNotCalled(t, "CreateCommon")
 
 		wallServiceMock := new(wallet.MockWalletService)
-		wallServiceMock.On("Create", ctx, userId, int64(0)).Return(nil)
+		wallServiceMock.On("Create", mock.Anything, userId, int64(0)).Return(nil)
 
 		jwtServiceMock := new(MockJWTService)
 
-		service := NewAuthService(
-			userServiceMock,
-			wallServiceMock,
-			bcryptServiceMock,
-			jwtServiceMock,
-		)
+		verifyServiceMock := new(MockEmailVerificationService)
+		verifyServiceMock.On("Issue", mock.Anything, userId).Return("verify-token", nil).Once()
+
+		mailerMock := new(mailer.MockMailer)
+		mailerMock.On("Send", mock.Anything, mock.MatchedBy(func(m mailer.Message) bool {
+			return m.To == signupDto.Email && strings.Contains(m.Body, verifyURL+"?token=verify-token")
+		})).Return(nil).Once()
+
+		service := newAuthService(Deps{
+			UserService:    userServiceMock,
+			WalletService:  wallServiceMock,
+			PasswordHasher: hasherMock,
+			JWTService:     jwtServiceMock,
+			MFAService:     new(mfa.MockMFAService),
+			VerifyService:  verifyServiceMock,
+			Mailer:         mailerMock,
+		})
 
 		err := service.Signup(ctx, signupDto)
 
@@ -417,8 +615,10 @@ func TestAuthService_Signup(t *testing.T) {
 
 		userServiceMock.AssertExpectations(t)
 		wallServiceMock.AssertExpectations(t)
-		bcryptServiceMock.AssertExpectations(t)
+		hasherMock.AssertExpectations(t)
 		jwtServiceMock.AssertExpectations(t)
+		verifyServiceMock.AssertExpectations(t)
+		mailerMock.AssertExpectations(t)
 	})
 
 	t.Run("should create a Common user if cpf is present", func(t *testing.T) {
@@ -433,15 +633,15 @@ func TestAuthService_Signup(t *testing.T) {
 		userId := 1
 
 		userServiceMock := new(user.MockUserService)
-		userServiceMock.On("FindByEmail", ctx, signupDto.Email).Return(nil, nil)
+		userServiceMock.On("FindByEmail", mock.Anything, signupDto.Email).Return(nil, nil)
 		userServiceMock.AssertNotCalled(t, "FindByCNPJ")
-		userServiceMock.On("FindByCPF", ctx, *signupDto.CPF).Return(nil, nil)
+		userServiceMock.On("FindByCPF", mock.Anything, *signupDto.CPF).Return(nil, nil)
 
 		wallServiceMock := new(wallet.MockWalletService)
-		wallServiceMock.On("Create", ctx, userId, int64(0)).Return(nil)
+		wallServiceMock.On("Create", mock.Anything, userId, int64(0)).Return(nil)
 
-		bcryptServiceMock := new(MockBcryptService)
-		bcryptServiceMock.On("Hash", signupDto.Password).Return("hashed", nil)
+		hasherMock := new(MockPasswordHasher)
+		hasherMock.On("Hash", mock.Anything, signupDto.Password).Return("hashed", nil)
 
 		commonDto := user.CommonUserDTO{
 			Fullname: signupDto.Fullname,
@@ -450,17 +650,39 @@ func TestAuthService_Signup(t *testing.T) {
 			Password: "hashed",
 		}
 
-		userServiceMock.On("CreateCommon", ctx, commonDto).Return(userId, nil)
+		userServiceMock.On("CreateCommon", mock.Anything, commonDto).Return(userId, nil)
 		userServiceMock.AssertNotCalled(t, "CreateShopkeeper")
 
 		jwtServiceMock := new(MockJWTService)
 
-		service := NewAuthService(
-			userServiceMock,
-			wallServiceMock,
-			bcryptServiceMock,
-			jwtServiceMock,
-		)
+		verifyServiceMock := new(MockEmailVerificationService)
+		verifyServiceMock.On("Issue", mock.Anything, userId).Return("verify-token", nil).Once()
+
+		// the audit log keeps fingerprints, never the email or the document
+		auditServiceMock := new(audit.MockAuditService)
+		auditServiceMock.On("Record", mock.Anything, mock.MatchedBy(func(r audit.RecordDTO) bool {
+			return r.Action == audit.AuthSignup && assert.ObjectsAreEqual(map[string]string{
+				"email_fingerprint": testFingerprinter.Fingerprint(signupDto.Email),
+				"cpf_fingerprint":   testFingerprinter.Fingerprint(cpf),
+			}, r.After)
+		})).Return(nil).Once()
+
+		// delivery failures must not fail the signup
+		mailerMock := new(mailer.MockMailer)
+		mailerMock.On("Send", mock.Anything, mock.MatchedBy(func(m mailer.Message) bool {
+			return m.To == signupDto.Email && strings.Contains(m.Body, verifyURL+"?token=verify-token")
+		})).Return(errors.New("smtp down")).Once()
+
+		service := newAuthService(Deps{
+			UserService:    userServiceMock,
+			WalletService:  wallServiceMock,
+			PasswordHasher: hasherMock,
+			JWTService:     jwtServiceMock,
+			AuditService:   auditServiceMock,
+			MFAService:     new(mfa.MockMFAService),
+			VerifyService:  verifyServiceMock,
+			Mailer:         mailerMock,
+		})
 
 		err := service.Signup(ctx, signupDto)
 
@@ -468,16 +690,20 @@ func TestAuthService_Signup(t *testing.T) {
 
 		userServiceMock.AssertExpectations(t)
 		wallServiceMock.AssertExpectations(t)
-		bcryptServiceMock.AssertExpectations(t)
+		hasherMock.AssertExpectations(t)
 		jwtServiceMock.AssertExpectations(t)
+		verifyServiceMock.AssertExpectations(t)
+		mailerMock.AssertExpectations(t)
+		auditServiceMock.AssertExpectations(t)
 	})
 }
 
 func TestAuthService_Login(t *testing.T) {
 	t.Run("should return unauthorized if user with email is not found", func(t *testing.T) {
 		userServiceMock := new(user.MockUserService)
-		bcryptServiceMock := new(MockBcryptService)
+		hasherMock := new(MockPasswordHasher)
 		jwtServiceMock := new(MockJWTService)
+		refreshServiceMock := new(MockRefreshTokenService)
 
 		dto := LoginDTO{
 			Email:    "notfound@example.com",
@@ -487,20 +713,26 @@ func TestAuthService_Login(t *testing.T) {
 		userServiceMock.On("FindByEmail", mock.Anything, dto.Email).
 			Return(nil, apperror.NewHttpError(http.StatusNotFound, "user not found"))
 
-		service := NewAuthService(userServiceMock, nil, bcryptServiceMock, jwtServiceMock)
+		service := newAuthService(Deps{
+			UserService:    userServiceMock,
+			PasswordHasher: hasherMock,
+			JWTService:     jwtServiceMock,
+			RefreshService: refreshServiceMock,
+		})
 
-		token, err := service.Login(context.Background(), dto)
+		tokens, err := service.Login(context.Background(), dto)
 
 		assert.Error(t, err)
-		assert.Empty(t, token)
+		assert.Nil(t, tokens)
 
 		userServiceMock.AssertExpectations(t)
 	})
 
 	t.Run("should return unauthorized if password compare returns false", func(t *testing.T) {
 		userServiceMock := new(user.MockUserService)
-		bcryptServiceMock := new(MockBcryptService)
+		hasherMock := new(MockPasswordHasher)
 		jwtServiceMock := new(MockJWTService)
+		refreshServiceMock := new(MockRefreshTokenService)
 
 		dto := LoginDTO{
 			Email:    "user@example.com",
@@ -514,24 +746,30 @@ func TestAuthService_Login(t *testing.T) {
 
 		userServiceMock.On("FindByEmail", mock.Anything, dto.Email).
 			Return(user, nil)
-		bcryptServiceMock.On("Compare", dto.Password, user.Password).
-			Return(false)
+		hasherMock.On("Compare", mock.Anything, dto.Password, user.Password).
+			Return(false, nil)
 
-		service := NewAuthService(userServiceMock, nil, bcryptServiceMock, jwtServiceMock)
+		service := newAuthService(Deps{
+			UserService:    userServiceMock,
+			PasswordHasher: hasherMock,
+			JWTService:     jwtServiceMock,
+			RefreshService: refreshServiceMock,
+		})
 
-		token, err := service.Login(context.Background(), dto)
+		tokens, err := service.Login(context.Background(), dto)
 
 		assert.Error(t, err)
-		assert.Empty(t, token)
+		assert.Nil(t, tokens)
 
 		userServiceMock.AssertExpectations(t)
-		bcryptServiceMock.AssertExpectations(t)
+		hasherMock.AssertExpectations(t)
 	})
 
 	t.Run("should return a token if pass all validations", func(t *testing.T) {
 		userServiceMock := new(user.MockUserService)
-		bcryptServiceMock := new(MockBcryptService)
+		hasherMock := new(MockPasswordHasher)
 		jwtServiceMock := new(MockJWTService)
+		refreshServiceMock := new(MockRefreshTokenService)
 
 		dto := LoginDTO{
 			Email:    "user@example.com",
@@ -545,20 +783,1071 @@ func TestAuthService_Login(t *testing.T) {
 
 		userServiceMock.On("FindByEmail", mock.Anything, dto.Email).
 			Return(user, nil)
-		bcryptServiceMock.On("Compare", dto.Password, user.Password).
-			Return(true)
-		jwtServiceMock.On("GenerateToken", user.ID, mock.Anything).
+		hasherMock.On("Compare", mock.Anything, dto.Password, user.Password).
+			Return(true, nil)
+		hasherMock.On("NeedsRehash", user.Password).Return(false)
+		jwtServiceMock.On("GenerateToken", user.ID, accessTTL).
 			Return("generated-token", nil)
+		refreshServiceMock.On("Issue", mock.Anything, user.ID).
+			Return("refresh-token", nil)
+
+		service := newAuthService(Deps{
+			UserService:    userServiceMock,
+			PasswordHasher: hasherMock,
+			JWTService:     jwtServiceMock,
+			RefreshService: refreshServiceMock,
+		})
+
+		tokens, err := service.Login(context.Background(), dto)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "generated-token", tokens.AccessToken)
+		assert.Equal(t, "refresh-token", tokens.RefreshToken)
+		assert.Equal(t, int64(1800), tokens.ExpiresIn)
+
+		userServiceMock.AssertExpectations(t)
+		hasherMock.AssertExpectations(t)
+		jwtServiceMock.AssertExpectations(t)
+		refreshServiceMock.AssertExpectations(t)
+	})
+}
+
+func TestAuthService_LoginAudit(t *testing.T) {
+	t.Run("should record a failed login", func(t *testing.T) {
+		userServiceMock := new(user.MockUserService)
+		hasherMock := new(MockPasswordHasher)
+		auditServiceMock := new(audit.MockAuditService)
+
+		dto := LoginDTO{Email: "user@example.com", Password: "wrongpassword"}
+		usr := &user.User{ID: 1, Password: "hashedpassword"}
+
+		userServiceMock.On("FindByEmail", mock.Anything, dto.Email).Return(usr, nil)
+		hasherMock.On("Compare", mock.Anything, dto.Password, usr.Password).Return(false, nil)
+		auditServiceMock.On("Record", mock.Anything, mock.MatchedBy(func(r audit.RecordDTO) bool {
+			return r.Action == audit.AuthLoginFailure && r.ActorID == nil && *r.TargetID == usr.ID &&
+				assert.ObjectsAreEqual(map[string]string{"email_fingerprint": testFingerprinter.Fingerprint(dto.Email)}, r.Details)
+		})).Return(nil).Once()
+
+		service := newAuthService(Deps{
+			UserService:    userServiceMock,
+			PasswordHasher: hasherMock,
+			AuditService:   auditServiceMock,
+		})
+
+		_, err := service.Login(context.Background(), dto)
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusUnauthorized, httpError.Code)
+		auditServiceMock.AssertExpectations(t)
+	})
+
+	t.Run("should not issue a token if the success cannot be recorded", func(t *testing.T) {
+		userServiceMock := new(user.MockUserService)
+		hasherMock := new(MockPasswordHasher)
+		jwtServiceMock := new(MockJWTService)
+		auditServiceMock := new(audit.MockAuditService)
+		refreshServiceMock := new(MockRefreshTokenService)
+
+		dto := LoginDTO{Email: "user@example.com", Password: "correctpassword"}
+		usr := &user.User{ID: 1, Password: "hashedpassword"}
+
+		userServiceMock.On("FindByEmail", mock.Anything, dto.Email).Return(usr, nil)
+		hasherMock.On("Compare", mock.Anything, dto.Password, usr.Password).Return(true, nil)
+		hasherMock.On("NeedsRehash", usr.Password).Return(false)
+		jwtServiceMock.On("GenerateToken", usr.ID, mock.Anything).Return("generated-token", nil)
+		refreshServiceMock.On("Issue", mock.Anything, usr.ID).Return("refresh-token", nil)
+		auditServiceMock.On("Record", mock.Anything, mock.MatchedBy(func(r audit.RecordDTO) bool {
+			return r.Action == audit.AuthLoginSuccess && *r.ActorID == usr.ID
+		})).Return(errors.New("db fail")).Once()
+
+		service := newAuthService(Deps{
+			UserService:    userServiceMock,
+			PasswordHasher: hasherMock,
+			JWTService:     jwtServiceMock,
+			AuditService:   auditServiceMock,
+			RefreshService: refreshServiceMock,
+		})
+
+		tokens, err := service.Login(context.Background(), dto)
+
+		assert.Error(t, err)
+		assert.Nil(t, tokens)
+		auditServiceMock.AssertExpectations(t)
+	})
+}
+
+func TestAuthService_LoginRehash(t *testing.T) {
+	newLogin := func(userServiceMock *user.MockUserService, hasherMock *MockPasswordHasher) AuthService {
+		jwtServiceMock := new(MockJWTService)
+		jwtServiceMock.On("GenerateToken", mock.Anything, accessTTL).Return("generated-token", nil)
+		refreshServiceMock := new(MockRefreshTokenService)
+		refreshServiceMock.On("Issue", mock.Anything, mock.Anything).Return("refresh-token", nil)
+
+		return newAuthService(Deps{
+			UserService:    userServiceMock,
+			PasswordHasher: hasherMock,
+			JWTService:     jwtServiceMock,
+			RefreshService: refreshServiceMock,
+		})
+	}
+
+	dto := LoginDTO{Email: "user@example.com", Password: "correctpassword"}
+	usr := &user.User{ID: 1, Password: "$2a$14$legacy"}
+
+	t.Run("should rehash passwords stored with outdated parameters", func(t *testing.T) {
+		userServiceMock := new(user.MockUserService)
+		hasherMock := new(MockPasswordHasher)
+
+		userServiceMock.On("FindByEmail", mock.Anything, dto.Email).Return(usr, nil)
+		hasherMock.On("Compare", mock.Anything, dto.Password, usr.Password).Return(true, nil)
+		hasherMock.On("NeedsRehash", usr.Password).Return(true)
+		hasherMock.On("Hash", mock.Anything, dto.Password).Return("$argon2id$new", nil)
+		userServiceMock.On("UpdatePasswordIfUnchanged", mock.Anything, usr.ID, usr.Password, "$argon2id$new").Return(true, nil).Once()
+
+		_, err := newLogin(userServiceMock, hasherMock).Login(context.Background(), dto)
+
+		assert.NoError(t, err)
+		userServiceMock.AssertExpectations(t)
+	})
+
+	t.Run("should still log in if the rehash fails", func(t *testing.T) {
+		userServiceMock := new(user.MockUserService)
+		hasherMock := new(MockPasswordHasher)
+
+		userServiceMock.On("FindByEmail", mock.Anything, dto.Email).Return(usr, nil)
+		hasherMock.On("Compare", mock.Anything, dto.Password, usr.Password).Return(true, nil)
+		hasherMock.On("NeedsRehash", usr.Password).Return(true)
+		hasherMock.On("Hash", mock.Anything, dto.Password).Return("$argon2id$new", nil)
+		userServiceMock.On("UpdatePasswordIfUnchanged", mock.Anything, usr.ID, usr.Password, mock.Anything).Return(false, errors.New("db fail"))
+
+		tokens, err := newLogin(userServiceMock, hasherMock).Login(context.Background(), dto)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "generated-token", tokens.AccessToken)
+	})
+
+	t.Run("should not rehash up to date passwords", func(t *testing.T) {
+		userServiceMock := new(user.MockUserService)
+		hasherMock := new(MockPasswordHasher)
 
-		service := NewAuthService(userServiceMock, nil, bcryptServiceMock, jwtServiceMock)
+		userServiceMock.On("FindByEmail", mock.Anything, dto.Email).Return(usr, nil)
+		hasherMock.On("Compare", mock.Anything, dto.Password, usr.Password).Return(true, nil)
+		hasherMock.On("NeedsRehash", usr.Password).Return(false)
 
-		token, err := service.Login(context.Background(), dto)
+		_, err := newLogin(userServiceMock, hasherMock).Login(context.Background(), dto)
 
 		assert.NoError(t, err)
-		assert.Equal(t, "generated-token", token)
+		hasherMock.AssertNotCalled(t, "Hash", mock.Anything, mock.Anything)
+		userServiceMock.AssertNotCalled(t, "UpdatePasswordIfUnchanged", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should keep a password changed during the login", func(t *testing.T) {
+		userServiceMock := new(user.MockUserService)
+		hasherMock := new(MockPasswordHasher)
+
+		userServiceMock.On("FindByEmail", mock.Anything, dto.Email).Return(usr, nil)
+		hasherMock.On("Compare", mock.Anything, dto.Password, usr.Password).Return(true, nil)
+		hasherMock.On("NeedsRehash", usr.Password).Return(true)
+		hasherMock.On("Hash", mock.Anything, dto.Password).Return("$argon2id$new", nil)
+		userServiceMock.On("UpdatePasswordIfUnchanged", mock.Anything, usr.ID, usr.Password, "$argon2id$new").Return(false, nil).Once()
+
+		tokens, err := newLogin(userServiceMock, hasherMock).Login(context.Background(), dto)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "generated-token", tokens.AccessToken)
+		userServiceMock.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestAuthService_Refresh(t *testing.T) {
+	t.Run("should return unauthorized for an invalid token", func(t *testing.T) {
+		refreshServiceMock := new(MockRefreshTokenService)
+		refreshServiceMock.On("Rotate", mock.Anything, "bad").Return(0, "", ErrInvalidRefreshToken)
+
+		service := newAuthService(Deps{RefreshService: refreshServiceMock})
+
+		tokens, err := service.Refresh(context.Background(), RefreshDTO{RefreshToken: "bad"})
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusUnauthorized, httpError.Code)
+		assert.Nil(t, tokens)
+	})
+
+	t.Run("should record reuse and return unauthorized", func(t *testing.T) {
+		refreshServiceMock := new(MockRefreshTokenService)
+		refreshServiceMock.On("Rotate", mock.Anything, "reused").Return(1, "", ErrRefreshTokenReused)
+
+		auditServiceMock := new(audit.MockAuditService)
+		auditServiceMock.On("Record", mock.Anything, mock.MatchedBy(func(r audit.RecordDTO) bool {
+			return r.Action == audit.AuthRefreshReuse && *r.TargetID == 1
+		})).Return(nil).Once()
+
+		service := newAuthService(Deps{AuditService: auditServiceMock, RefreshService: refreshServiceMock})
+
+		tokens, err := service.Refresh(context.Background(), RefreshDTO{RefreshToken: "reused"})
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusUnauthorized, httpError.Code)
+		assert.Nil(t, tokens)
+		auditServiceMock.AssertExpectations(t)
+	})
+
+	t.Run("should return a new token pair", func(t *testing.T) {
+		refreshServiceMock := new(MockRefreshTokenService)
+		refreshServiceMock.On("Rotate", mock.Anything, "current").Return(1, "next", nil)
+
+		userServiceMock := new(user.MockUserService)
+		userServiceMock.On("FindByID", mock.Anything, 1).Return(&user.User{ID: 1}, nil)
 
+		jwtServiceMock := new(MockJWTService)
+		jwtServiceMock.On("GenerateToken", 1, accessTTL).Return("access", nil)
+
+		service := newAuthService(Deps{
+			UserService:    userServiceMock,
+			JWTService:     jwtServiceMock,
+			RefreshService: refreshServiceMock,
+		})
+
+		tokens, err := service.Refresh(context.Background(), RefreshDTO{RefreshToken: "current"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "access", tokens.AccessToken)
+		assert.Equal(t, "next", tokens.RefreshToken)
+		refreshServiceMock.AssertExpectations(t)
 		userServiceMock.AssertExpectations(t)
-		bcryptServiceMock.AssertExpectations(t)
 		jwtServiceMock.AssertExpectations(t)
 	})
 }
+
+func TestAuthService_Logout(t *testing.T) {
+	exp := time.Now().Add(time.Minute)
+
+	t.Run("should revoke the access token only", func(t *testing.T) {
+		revocationStoreMock := new(MockRevocationStore)
+		revocationStoreMock.On("RevokeToken", mock.Anything, "jti-1", exp).Return(nil).Once()
+
+		refreshServiceMock := new(MockRefreshTokenService)
+		auditServiceMock := new(audit.MockAuditService)
+		auditServiceMock.On("Record", mock.Anything, mock.MatchedBy(func(r audit.RecordDTO) bool {
+			return r.Action == audit.AuthLogout && *r.ActorID == 1
+		})).Return(nil).Once()
+
+		service := newAuthService(Deps{
+			AuditService:   auditServiceMock,
+			RefreshService: refreshServiceMock,
+			Revocations:    revocationStoreMock,
+		})
+
+		err := service.Logout(context.Background(), 1, "jti-1", exp, LogoutDTO{})
+
+		assert.NoError(t, err)
+		revocationStoreMock.AssertExpectations(t)
+		auditServiceMock.AssertExpectations(t)
+		refreshServiceMock.AssertNotCalled(t, "Revoke", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should also revoke the given refresh token", func(t *testing.T) {
+		refreshToken := "refresh"
+
+		revocationStoreMock := new(MockRevocationStore)
+		revocationStoreMock.On("RevokeToken", mock.Anything, "jti-1", exp).Return(nil).Once()
+
+		refreshServiceMock := new(MockRefreshTokenService)
+		refreshServiceMock.On("Revoke", mock.Anything, 1, refreshToken).Return(nil).Once()
+
+		service := newAuthService(Deps{RefreshService: refreshServiceMock, Revocations: revocationStoreMock})
+
+		err := service.Logout(context.Background(), 1, "jti-1", exp, LogoutDTO{RefreshToken: &refreshToken})
+
+		assert.NoError(t, err)
+		revocationStoreMock.AssertExpectations(t)
+		refreshServiceMock.AssertExpectations(t)
+	})
+
+	t.Run("should return error if revocation fails", func(t *testing.T) {
+		revocationStoreMock := new(MockRevocationStore)
+		revocationStoreMock.On("RevokeToken", mock.Anything, "jti-1", exp).Return(errors.New("db fail"))
+
+		auditServiceMock := new(audit.MockAuditService)
+
+		service := newAuthService(Deps{AuditService: auditServiceMock, Revocations: revocationStoreMock})
+
+		err := service.Logout(context.Background(), 1, "jti-1", exp, LogoutDTO{})
+
+		assert.Error(t, err)
+		auditServiceMock.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+	})
+}
+
+func TestAuthService_LogoutAll(t *testing.T) {
+	t.Run("should move the cutoff and revoke refresh tokens", func(t *testing.T) {
+		revocationStoreMock := new(MockRevocationStore)
+		revocationStoreMock.On("RevokeAllForUser", mock.Anything, 1).Return(nil).Once()
+
+		refreshServiceMock := new(MockRefreshTokenService)
+		refreshServiceMock.On("RevokeAll", mock.Anything, 1).Return(nil).Once()
+
+		auditServiceMock := new(audit.MockAuditService)
+		auditServiceMock.On("Record", mock.Anything, mock.MatchedBy(func(r audit.RecordDTO) bool {
+			return r.Action == audit.AuthLogoutAll
+		})).Return(nil).Once()
+
+		service := newAuthService(Deps{
+			AuditService:   auditServiceMock,
+			RefreshService: refreshServiceMock,
+			Revocations:    revocationStoreMock,
+		})
+
+		err := service.LogoutAll(context.Background(), 1)
+
+		assert.NoError(t, err)
+		revocationStoreMock.AssertExpectations(t)
+		refreshServiceMock.AssertExpectations(t)
+		auditServiceMock.AssertExpectations(t)
+	})
+}
+
+func TestAuthService_ForgotPassword(t *testing.T) {
+	newResetServiceMock := func(email string) *MockPasswordResetService {
+		m := new(MockPasswordResetService)
+		m.On("CanRequest", mock.Anything, email, mock.Anything).Return(true, nil)
+		return m
+	}
+
+	// runs the background work inline, so the test can assert on it
+	newService := func(usrSvc user.UserService, resetSvc PasswordResetService, mail mailer.Mailer) AuthService {
+		return newAuthService(Deps{
+			UserService:  usrSvc,
+			ResetService: resetSvc,
+			Mailer:       mail,
+			Background:   runnerFunc(func(f func()) { f() }),
+		})
+	}
+
+	t.Run("should do nothing for unknown emails", func(t *testing.T) {
+		userServiceMock := new(user.MockUserService)
+		userServiceMock.On("FindByEmail", mock.Anything, "ghost@email.com").
+			Return(nil, apperror.NewHttpError(http.StatusNotFound, "user not found"))
+
+		resetServiceMock := newResetServiceMock("ghost@email.com")
+		mailerMock := new(mailer.MockMailer)
+
+		err := newService(userServiceMock, resetServiceMock, mailerMock).ForgotPassword(context.Background(), ForgotPasswordDTO{Email: "ghost@email.com"})
+
+		assert.NoError(t, err)
+		resetServiceMock.AssertNotCalled(t, "Issue", mock.Anything, mock.Anything)
+		mailerMock.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should email a reset link to known users", func(t *testing.T) {
+		userServiceMock := new(user.MockUserService)
+		userServiceMock.On("FindByEmail", mock.Anything, "email@email.com").
+			Return(&user.User{ID: 1, Fullname: "John Doe", Email: "email@email.com"}, nil)
+
+		resetServiceMock := newResetServiceMock("email@email.com")
+		resetServiceMock.On("Issue", mock.Anything, 1).Return("reset-token", nil).Once()
+
+		mailerMock := new(mailer.MockMailer)
+		mailerMock.On("Send", mock.Anything, mock.MatchedBy(func(m mailer.Message) bool {
+			return m.To == "email@email.com" && strings.Contains(m.Body, resetURL+"?token=reset-token")
+		})).Return(nil).Once()
+
+		err := newService(userServiceMock, resetServiceMock, mailerMock).ForgotPassword(context.Background(), ForgotPasswordDTO{Email: "email@email.com"})
+
+		assert.NoError(t, err)
+		resetServiceMock.AssertExpectations(t)
+		mailerMock.AssertExpectations(t)
+	})
+
+	t.Run("should send the link outside the request", func(t *testing.T) {
+		userServiceMock := new(user.MockUserService)
+		userServiceMock.On("FindByEmail", mock.Anything, "email@email.com").
+			Return(&user.User{ID: 1, Email: "email@email.com"}, nil)
+
+		resetServiceMock := newResetServiceMock("email@email.com")
+		mailerMock := new(mailer.MockMailer)
+
+		var deferred func()
+		service := newAuthService(Deps{
+			UserService:  userServiceMock,
+			ResetService: resetServiceMock,
+			Mailer:       mailerMock,
+			Background:   runnerFunc(func(f func()) { deferred = f }),
+		})
+
+		err := service.ForgotPassword(context.Background(), ForgotPasswordDTO{Email: "email@email.com"})
+
+		assert.NoError(t, err)
+		require.NotNil(t, deferred)
+		resetServiceMock.AssertNotCalled(t, "Issue", mock.Anything, mock.Anything)
+		mailerMock.AssertNotCalled(t, "Send", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should hide delivery failures", func(t *testing.T) {
+		userServiceMock := new(user.MockUserService)
+		userServiceMock.On("FindByEmail", mock.Anything, "email@email.com").
+			Return(&user.User{ID: 1, Email: "email@email.com"}, nil)
+
+		resetServiceMock := newResetServiceMock("email@email.com")
+		resetServiceMock.On("Issue", mock.Anything, 1).Return("reset-token", nil)
+
+		mailerMock := new(mailer.MockMailer)
+		mailerMock.On("Send", mock.Anything, mock.Anything).Return(errors.New("smtp down"))
+
+		err := newService(userServiceMock, resetServiceMock, mailerMock).ForgotPassword(context.Background(), ForgotPasswordDTO{Email: "email@email.com"})
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("should return too many requests before looking up the email", func(t *testing.T) {
+		userServiceMock := new(user.MockUserService)
+
+		resetServiceMock := new(MockPasswordResetService)
+		resetServiceMock.On("CanRequest", mock.Anything, "email@email.com", mock.Anything).Return(false, nil)
+
+		err := newService(userServiceMock, resetServiceMock, nil).ForgotPassword(context.Background(), ForgotPasswordDTO{Email: "email@email.com"})
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusTooManyRequests, httpError.Code)
+		userServiceMock.AssertNotCalled(t, "FindByEmail", mock.Anything, mock.Anything)
+	})
+}
+
+func TestAuthService_ResetPassword(t *testing.T) {
+	t.Run("should return bad request for invalid tokens", func(t *testing.T) {
+		resetServiceMock := new(MockPasswordResetService)
+		resetServiceMock.On("Peek", mock.Anything, "bad").Return(0, ErrInvalidResetToken)
+
+		hasherMock := new(MockPasswordHasher)
+
+		service := newAuthService(Deps{PasswordHasher: hasherMock, ResetService: resetServiceMock})
+
+		err := service.ResetPassword(context.Background(), ResetPasswordDTO{Token: "bad", Password: "new-password"})
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusBadRequest, httpError.Code)
+		hasherMock.AssertNotCalled(t, "Hash", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should update the password and revoke every session", func(t *testing.T) {
+		resetServiceMock := new(MockPasswordResetService)
+		resetServiceMock.On("Peek", mock.Anything, "good").Return(1, nil).Once()
+		resetServiceMock.On("Consume", mock.Anything, "good").Return(1, nil).Once()
+
+		hasherMock := new(MockPasswordHasher)
+		hasherMock.On("Hash", mock.Anything, "new-password").Return("hashed", nil)
+
+		userServiceMock := new(user.MockUserService)
+		userServiceMock.On("FindByID", mock.Anything, 1).Return(&user.User{ID: 1, Email: "john@email.com", Fullname: "John Doe"}, nil)
+		userServiceMock.On("UpdatePassword", mock.Anything, 1, "hashed").Return(nil).Once()
+
+		revocationStoreMock := new(MockRevocationStore)
+		revocationStoreMock.On("RevokeAllForUser", mock.Anything, 1).Return(nil).Once()
+
+		refreshServiceMock := new(MockRefreshTokenService)
+		refreshServiceMock.On("RevokeAll", mock.Anything, 1).Return(nil).Once()
+
+		auditServiceMock := new(audit.MockAuditService)
+		auditServiceMock.On("Record", mock.Anything, mock.MatchedBy(func(r audit.RecordDTO) bool {
+			return r.Action == audit.AuthPasswordReset && *r.TargetID == 1
+		})).Return(nil).Once()
+
+		service := newAuthService(Deps{
+			UserService:    userServiceMock,
+			PasswordHasher: hasherMock,
+			AuditService:   auditServiceMock,
+			RefreshService: refreshServiceMock,
+			Revocations:    revocationStoreMock,
+			ResetService:   resetServiceMock,
+		})
+
+		err := service.ResetPassword(context.Background(), ResetPasswordDTO{Token: "good", Password: "new-password"})
+
+		assert.NoError(t, err)
+		userServiceMock.AssertExpectations(t)
+		revocationStoreMock.AssertExpectations(t)
+		refreshServiceMock.AssertExpectations(t)
+		auditServiceMock.AssertExpectations(t)
+	})
+
+	t.Run("should reject a weak password without spending the token", func(t *testing.T) {
+		resetServiceMock := new(MockPasswordResetService)
+		resetServiceMock.On("Peek", mock.Anything, "good").Return(1, nil).Once()
+
+		userServiceMock := new(user.MockUserService)
+		userServiceMock.On("FindByID", mock.Anything, 1).Return(&user.User{ID: 1, Email: "john@email.com", Fullname: "John Doe"}, nil)
+
+		violations := []Violation{{Rule: "personal_info", Message: "password must not contain your email or name"}}
+		passwordPolicyMock := new(MockPasswordPolicy)
+		passwordPolicyMock.On("Check", "John1234", []string{"john@email.com", "John Doe"}).Return(violations).Once()
+
+		hasherMock := new(MockPasswordHasher)
+
+		service := newAuthService(Deps{
+			UserService:    userServiceMock,
+			PasswordHasher: hasherMock,
+			PasswordPolicy: passwordPolicyMock,
+			ResetService:   resetServiceMock,
+		})
+
+		err := service.ResetPassword(context.Background(), ResetPasswordDTO{Token: "good", Password: "John1234"})
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusUnprocessableEntity, httpError.Code)
+		assert.Equal(t, violations, httpError.Details)
+		resetServiceMock.AssertNotCalled(t, "Consume", mock.Anything, mock.Anything)
+		hasherMock.AssertNotCalled(t, "Hash", mock.Anything, mock.Anything)
+		passwordPolicyMock.AssertExpectations(t)
+	})
+}
+
+func TestAuthService_VerifyEmail(t *testing.T) {
+	t.Run("should return bad request for invalid tokens", func(t *testing.T) {
+		verifyServiceMock := new(MockEmailVerificationService)
+		verifyServiceMock.On("Consume", mock.Anything, "bad").Return(0, ErrInvalidVerificationToken)
+
+		userServiceMock := new(user.MockUserService)
+
+		service := newAuthService(Deps{UserService: userServiceMock, VerifyService: verifyServiceMock})
+
+		err := service.VerifyEmail(context.Background(), "bad")
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusBadRequest, httpError.Code)
+		userServiceMock.AssertNotCalled(t, "MarkEmailVerified", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should mark the email as verified", func(t *testing.T) {
+		verifyServiceMock := new(MockEmailVerificationService)
+		verifyServiceMock.On("Consume", mock.Anything, "good").Return(1, nil)
+
+		userServiceMock := new(user.MockUserService)
+		userServiceMock.On("MarkEmailVerified", mock.Anything, 1).Return(nil).Once()
+
+		service := newAuthService(Deps{UserService: userServiceMock, VerifyService: verifyServiceMock})
+
+		err := service.VerifyEmail(context.Background(), "good")
+
+		assert.NoError(t, err)
+		userServiceMock.AssertExpectations(t)
+	})
+}
+
+func TestAuthService_ResendVerification(t *testing.T) {
+	t.Run("should return conflict if already verified", func(t *testing.T) {
+		verifiedAt := time.Now()
+		verifyServiceMock := new(MockEmailVerificationService)
+
+		service := newAuthService(Deps{VerifyService: verifyServiceMock})
+
+		err := service.ResendVerification(context.Background(), &user.User{ID: 1, EmailVerifiedAt: &verifiedAt})
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusConflict, httpError.Code)
+		verifyServiceMock.AssertNotCalled(t, "Issue", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should return too many requests when throttled", func(t *testing.T) {
+		verifyServiceMock := new(MockEmailVerificationService)
+		verifyServiceMock.On("CanResend", mock.Anything, 1).Return(false, nil)
+
+		service := newAuthService(Deps{VerifyService: verifyServiceMock})
+
+		err := service.ResendVerification(context.Background(), &user.User{ID: 1})
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusTooManyRequests, httpError.Code)
+		verifyServiceMock.AssertNotCalled(t, "Issue", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should send a new verification email", func(t *testing.T) {
+		verifyServiceMock := new(MockEmailVerificationService)
+		verifyServiceMock.On("CanResend", mock.Anything, 1).Return(true, nil)
+		verifyServiceMock.On("Issue", mock.Anything, 1).Return("verify-token", nil).Once()
+
+		mailerMock := new(mailer.MockMailer)
+		mailerMock.On("Send", mock.Anything, mock.Anything).Return(nil).Once()
+
+		service := newAuthService(Deps{VerifyService: verifyServiceMock, Mailer: mailerMock})
+
+		err := service.ResendVerification(context.Background(), &user.User{ID: 1, Email: "email@email.com"})
+
+		assert.NoError(t, err)
+		verifyServiceMock.AssertExpectations(t)
+		mailerMock.AssertExpectations(t)
+	})
+}
+
+func TestAuthService_UpdateProfile(t *testing.T) {
+	current := &user.User{ID: 1, Fullname: "John Doe", Email: "john@email.com"}
+
+	t.Run("should send a verification email when the email changes", func(t *testing.T) {
+		email := "new@email.com"
+		dto := user.UpdateProfileDTO{Email: &email}
+
+		userServiceMock := new(user.MockUserService)
+		userServiceMock.On("UpdateProfile", mock.Anything, 1, dto).
+			Return(&user.User{ID: 1, Fullname: "John Doe", Email: email}, nil)
+
+		verifyServiceMock := new(MockEmailVerificationService)
+		verifyServiceMock.On("Issue", mock.Anything, 1).Return("verify-token", nil).Once()
+
+		mailerMock := new(mailer.MockMailer)
+		mailerMock.On("Send", mock.Anything, mock.MatchedBy(func(m mailer.Message) bool {
+			return m.To == email
+		})).Return(nil).Once()
+
+		auditServiceMock := new(audit.MockAuditService)
+		auditServiceMock.On("Record", mock.Anything, mock.MatchedBy(func(r audit.RecordDTO) bool {
+			return r.Action == audit.UserProfileUpdate && *r.TargetID == 1
+		})).Return(nil).Once()
+
+		service := newAuthService(Deps{
+			UserService:   userServiceMock,
+			AuditService:  auditServiceMock,
+			VerifyService: verifyServiceMock,
+			Mailer:        mailerMock,
+		})
+
+		usr, err := service.UpdateProfile(context.Background(), current, dto)
+
+		assert.NoError(t, err)
+		assert.Equal(t, email, usr.Email)
+		verifyServiceMock.AssertExpectations(t)
+		mailerMock.AssertExpectations(t)
+		auditServiceMock.AssertExpectations(t)
+	})
+
+	t.Run("should not send a verification email when only the name changes", func(t *testing.T) {
+		fullname := "John Smith"
+		dto := user.UpdateProfileDTO{Fullname: &fullname}
+
+		userServiceMock := new(user.MockUserService)
+		userServiceMock.On("UpdateProfile", mock.Anything, 1, dto).
+			Return(&user.User{ID: 1, Fullname: fullname, Email: current.Email}, nil)
+
+		verifyServiceMock := new(MockEmailVerificationService)
+
+		service := newAuthService(Deps{UserService: userServiceMock, VerifyService: verifyServiceMock})
+
+		usr, err := service.UpdateProfile(context.Background(), current, dto)
+
+		assert.NoError(t, err)
+		assert.Equal(t, fullname, usr.Fullname)
+		verifyServiceMock.AssertNotCalled(t, "Issue", mock.Anything, mock.Anything)
+	})
+}
+
+func TestAuthService_ChangePassword(t *testing.T) {
+	current := &user.User{ID: 1, Fullname: "John Doe", Email: "john@email.com", Password: "hashed-password"}
+
+	t.Run("should return forbidden if the current password is wrong", func(t *testing.T) {
+		hasherMock := new(MockPasswordHasher)
+		hasherMock.On("Compare", mock.Anything, "wrong", "hashed-password").Return(false, nil)
+
+		userServiceMock := new(user.MockUserService)
+
+		service := newAuthService(Deps{UserService: userServiceMock, PasswordHasher: hasherMock})
+
+		err := service.ChangePassword(context.Background(), current, ChangePasswordDTO{CurrentPassword: "wrong", NewPassword: "N3w-password"})
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusForbidden, httpError.Code)
+		userServiceMock.AssertNotCalled(t, "UpdatePassword", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should reject a password that breaks the policy", func(t *testing.T) {
+		hasherMock := new(MockPasswordHasher)
+		hasherMock.On("Compare", mock.Anything, "Old-password1", "hashed-password").Return(true, nil)
+
+		passwordPolicyMock := new(MockPasswordPolicy)
+		passwordPolicyMock.On("Check", "short", []string{current.Email, current.Fullname}).
+			Return([]Violation{{Rule: "min_length", Message: "password must be at least 8 characters long"}})
+
+		service := newAuthService(Deps{PasswordHasher: hasherMock, PasswordPolicy: passwordPolicyMock})
+
+		err := service.ChangePassword(context.Background(), current, ChangePasswordDTO{CurrentPassword: "Old-password1", NewPassword: "short"})
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusUnprocessableEntity, httpError.Code)
+		hasherMock.AssertNotCalled(t, "Hash", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should update the password and revoke every session", func(t *testing.T) {
+		hasherMock := new(MockPasswordHasher)
+		hasherMock.On("Compare", mock.Anything, "Old-password1", "hashed-password").Return(true, nil)
+		hasherMock.On("Hash", mock.Anything, "N3w-password").Return("new-hash", nil)
+
+		userServiceMock := new(user.MockUserService)
+		userServiceMock.On("UpdatePassword", mock.Anything, 1, "new-hash").Return(nil).Once()
+
+		revocationStoreMock := new(MockRevocationStore)
+		revocationStoreMock.On("RevokeAllForUser", mock.Anything, 1).Return(nil).Once()
+
+		refreshServiceMock := new(MockRefreshTokenService)
+		refreshServiceMock.On("RevokeAll", mock.Anything, 1).Return(nil).Once()
+
+		auditServiceMock := new(audit.MockAuditService)
+		auditServiceMock.On("Record", mock.Anything, mock.MatchedBy(func(r audit.RecordDTO) bool {
+			return r.Action == audit.AuthPasswordChange && *r.TargetID == 1
+		})).Return(nil).Once()
+
+		service := newAuthService(Deps{
+			UserService:    userServiceMock,
+			PasswordHasher: hasherMock,
+			AuditService:   auditServiceMock,
+			RefreshService: refreshServiceMock,
+			Revocations:    revocationStoreMock,
+		})
+
+		err := service.ChangePassword(context.Background(), current, ChangePasswordDTO{CurrentPassword: "Old-password1", NewPassword: "N3w-password"})
+
+		assert.NoError(t, err)
+		userServiceMock.AssertExpectations(t)
+		revocationStoreMock.AssertExpectations(t)
+		refreshServiceMock.AssertExpectations(t)
+		auditServiceMock.AssertExpectations(t)
+	})
+}
+
+func TestAuthService_CloseAccount(t *testing.T) {
+	current := &user.User{ID: 1, Role: user.Common, Password: "hashed-password"}
+
+	t.Run("should return forbidden if the password is wrong", func(t *testing.T) {
+		hasherMock := new(MockPasswordHasher)
+		hasherMock.On("Compare", mock.Anything, "wrong", "hashed-password").Return(false, nil)
+
+		userServiceMock := new(user.MockUserService)
+
+		service := newAuthService(Deps{UserService: userServiceMock, PasswordHasher: hasherMock})
+
+		err := service.CloseAccount(context.Background(), current, CloseAccountDTO{Password: "wrong"})
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusForbidden, httpError.Code)
+		userServiceMock.AssertNotCalled(t, "Close", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should close nothing if the wallet cannot be closed", func(t *testing.T) {
+		hasherMock := new(MockPasswordHasher)
+		hasherMock.On("Compare", mock.Anything, "password", "hashed-password").Return(true, nil)
+
+		walletServiceMock := new(wallet.MockWalletService)
+		walletServiceMock.On("Close", mock.Anything, 1).
+			Return(apperror.NewHttpError(http.StatusConflict, "the wallet balance must be zero to close the account"))
+
+		userServiceMock := new(user.MockUserService)
+		kycServiceMock := new(kyc.MockKYCService)
+		revocationStoreMock := new(MockRevocationStore)
+
+		service := newAuthService(Deps{
+			UserService:    userServiceMock,
+			WalletService:  walletServiceMock,
+			KYCService:     kycServiceMock,
+			PasswordHasher: hasherMock,
+			Revocations:    revocationStoreMock,
+		})
+
+		err := service.CloseAccount(context.Background(), current, CloseAccountDTO{Password: "password"})
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusConflict, httpError.Code)
+		kycServiceMock.AssertNotCalled(t, "DeleteAll", mock.Anything, mock.Anything)
+		userServiceMock.AssertNotCalled(t, "Close", mock.Anything, mock.Anything)
+		revocationStoreMock.AssertNotCalled(t, "RevokeAllForUser", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should close every part of the account in one transaction", func(t *testing.T) {
+		hasherMock := new(MockPasswordHasher)
+		hasherMock.On("Compare", mock.Anything, "password", "hashed-password").Return(true, nil)
+
+		walletServiceMock := new(wallet.MockWalletService)
+		walletServiceMock.On("Close", mock.Anything, 1).Return(nil).Once()
+
+		kycServiceMock := new(kyc.MockKYCService)
+		kycServiceMock.On("DeleteAll", mock.Anything, 1).Return(nil).Once()
+
+		apiKeyServiceMock := new(apikey.MockAPIKeyService)
+		apiKeyServiceMock.On("RevokeAll", mock.Anything, 1).Return(nil).Once()
+
+		webhookServiceMock := new(webhook.MockWebhookService)
+		webhookServiceMock.On("DisableAll", mock.Anything, 1).Return(nil).Once()
+
+		mfaServiceMock := new(mfa.MockMFAService)
+		mfaServiceMock.On("Remove", mock.Anything, 1).Return(nil).Once()
+
+		userServiceMock := new(user.MockUserService)
+		userServiceMock.On("Close", mock.Anything, 1).Return(nil).Once()
+
+		revocationStoreMock := new(MockRevocationStore)
+		revocationStoreMock.On("RevokeAllForUser", mock.Anything, 1).Return(nil).Once()
+
+		refreshServiceMock := new(MockRefreshTokenService)
+		refreshServiceMock.On("RevokeAll", mock.Anything, 1).Return(nil).Once()
+
+		auditServiceMock := new(audit.MockAuditService)
+		auditServiceMock.On("Record", mock.Anything, mock.MatchedBy(func(r audit.RecordDTO) bool {
+			return r.Action == audit.UserAccountClose && *r.TargetID == 1
+		})).Return(nil).Once()
+
+		transactor := &db.MockTransactor{}
+
+		service := newAuthService(Deps{
+			UserService:    userServiceMock,
+			WalletService:  walletServiceMock,
+			KYCService:     kycServiceMock,
+			APIKeyService:  apiKeyServiceMock,
+			WebhookService: webhookServiceMock,
+			MFAService:     mfaServiceMock,
+			Transactor:     transactor,
+			PasswordHasher: hasherMock,
+			AuditService:   auditServiceMock,
+			RefreshService: refreshServiceMock,
+			Revocations:    revocationStoreMock,
+		})
+
+		err := service.CloseAccount(context.Background(), current, CloseAccountDTO{Password: "password"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, transactor.Calls)
+		walletServiceMock.AssertExpectations(t)
+		kycServiceMock.AssertExpectations(t)
+		apiKeyServiceMock.AssertExpectations(t)
+		webhookServiceMock.AssertExpectations(t)
+		mfaServiceMock.AssertExpectations(t)
+		userServiceMock.AssertExpectations(t)
+		revocationStoreMock.AssertExpectations(t)
+		refreshServiceMock.AssertExpectations(t)
+		auditServiceMock.AssertExpectations(t)
+	})
+}
+
+func TestAuthService_LoginWithMFA(t *testing.T) {
+	dto := LoginDTO{Email: "user@example.com", Password: "correctpassword"}
+	usr := &user.User{ID: 1, Email: dto.Email, Password: "hashedpassword"}
+
+	t.Run("should return a challenge instead of tokens", func(t *testing.T) {
+		userServiceMock := new(user.MockUserService)
+		userServiceMock.On("FindByEmail", mock.Anything, dto.Email).Return(usr, nil)
+
+		hasherMock := new(MockPasswordHasher)
+		hasherMock.On("Compare", mock.Anything, dto.Password, usr.Password).Return(true, nil)
+		hasherMock.On("NeedsRehash", usr.Password).Return(false)
+
+		mfaServiceMock := new(mfa.MockMFAService)
+		mfaServiceMock.On("IsEnabled", mock.Anything, 1).Return(true, nil)
+		mfaServiceMock.On("StartChallenge", mock.Anything, 1).Return("challenge", nil)
+
+		refreshServiceMock := new(MockRefreshTokenService)
+		loginGuardMock := newLoginGuardMock()
+
+		service := newAuthService(Deps{
+			UserService:    userServiceMock,
+			PasswordHasher: hasherMock,
+			RefreshService: refreshServiceMock,
+			MFAService:     mfaServiceMock,
+			LoginGuard:     loginGuardMock,
+		})
+
+		result, err := service.Login(context.Background(), dto)
+
+		assert.NoError(t, err)
+		assert.True(t, result.MFARequired)
+		assert.Equal(t, "challenge", result.MFAToken)
+		assert.Nil(t, result.TokenPairDTO)
+		refreshServiceMock.AssertNotCalled(t, "Issue", mock.Anything, mock.Anything)
+		loginGuardMock.AssertNotCalled(t, "RecordSuccess", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should issue tokens once the challenge is completed", func(t *testing.T) {
+		mfaServiceMock := new(mfa.MockMFAService)
+		mfaServiceMock.On("CompleteChallenge", mock.Anything, "challenge", "123456").Return(1, nil)
+
+		jwtServiceMock := new(MockJWTService)
+		jwtServiceMock.On("GenerateToken", 1, accessTTL).Return("generated-token", nil)
+
+		refreshServiceMock := new(MockRefreshTokenService)
+		refreshServiceMock.On("Issue", mock.Anything, 1).Return("refresh-token", nil)
+
+		userServiceMock := new(user.MockUserService)
+		userServiceMock.On("FindByID", mock.Anything, 1).Return(usr, nil)
+
+		loginGuardMock := new(MockLoginGuard)
+		loginGuardMock.On("RecordSuccess", mock.Anything, usr.Email).Return(nil).Once()
+
+		service := newAuthService(Deps{
+			UserService:    userServiceMock,
+			JWTService:     jwtServiceMock,
+			RefreshService: refreshServiceMock,
+			MFAService:     mfaServiceMock,
+			LoginGuard:     loginGuardMock,
+		})
+
+		tokens, err := service.VerifyMFA(context.Background(), MFAVerifyDTO{MFAToken: "challenge", Code: "123456"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "generated-token", tokens.AccessToken)
+		assert.Equal(t, "refresh-token", tokens.RefreshToken)
+		loginGuardMock.AssertExpectations(t)
+	})
+
+	t.Run("should record a failed second factor", func(t *testing.T) {
+		mfaServiceMock := new(mfa.MockMFAService)
+		mfaServiceMock.On("CompleteChallenge", mock.Anything, "challenge", "000000").
+			Return(1, apperror.NewHttpError(http.StatusUnauthorized, "invalid mfa code"))
+
+		auditServiceMock := new(audit.MockAuditService)
+		auditServiceMock.On("Record", mock.Anything, mock.MatchedBy(func(r audit.RecordDTO) bool {
+			return r.Action == audit.AuthMFAFailure && *r.TargetID == 1
+		})).Return(nil).Once()
+
+		service := newAuthService(Deps{AuditService: auditServiceMock, MFAService: mfaServiceMock})
+
+		_, err := service.VerifyMFA(context.Background(), MFAVerifyDTO{MFAToken: "challenge", Code: "000000"})
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusUnauthorized, httpError.Code)
+		auditServiceMock.AssertExpectations(t)
+	})
+}
+
+func TestAuthService_LoginThrottling(t *testing.T) {
+	dto := LoginDTO{Email: "user@example.com", Password: "wrongpassword"}
+
+	t.Run("should not check the password of a locked account", func(t *testing.T) {
+		loginGuardMock := new(MockLoginGuard)
+		loginGuardMock.On("Reserve", mock.Anything, dto.Email, mock.Anything).Return(ErrAccountLocked)
+
+		userServiceMock := new(user.MockUserService)
+		hasherMock := new(MockPasswordHasher)
+
+		service := newAuthService(Deps{
+			UserService:    userServiceMock,
+			PasswordHasher: hasherMock,
+			LoginGuard:     loginGuardMock,
+		})
+
+		_, err := service.Login(context.Background(), dto)
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusLocked, httpError.Code)
+		userServiceMock.AssertNotCalled(t, "FindByEmail", mock.Anything, mock.Anything)
+		hasherMock.AssertNotCalled(t, "Compare", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should return too many requests during the delay", func(t *testing.T) {
+		loginGuardMock := new(MockLoginGuard)
+		loginGuardMock.On("Reserve", mock.Anything, dto.Email, mock.Anything).Return(&RetryAfterError{Wait: time.Second * 4})
+
+		service := newAuthService(Deps{LoginGuard: loginGuardMock})
+
+		_, err := service.Login(context.Background(), dto)
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusTooManyRequests, httpError.Code)
+	})
+
+	t.Run("should return too many requests for a locked ip", func(t *testing.T) {
+		loginGuardMock := new(MockLoginGuard)
+		loginGuardMock.On("Reserve", mock.Anything, dto.Email, mock.Anything).Return(ErrIPLocked)
+
+		service := newAuthService(Deps{LoginGuard: loginGuardMock})
+
+		_, err := service.Login(context.Background(), dto)
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusTooManyRequests, httpError.Code)
+	})
+
+	t.Run("should audit the lockout and email the unlock link", func(t *testing.T) {
+		usr := &user.User{ID: 1, Email: dto.Email, Password: "hashed"}
+
+		userServiceMock := new(user.MockUserService)
+		userServiceMock.On("FindByEmail", mock.Anything, dto.Email).Return(usr, nil)
+
+		hasherMock := new(MockPasswordHasher)
+		hasherMock.On("Compare", mock.Anything, dto.Password, usr.Password).Return(false, nil)
+
+		loginGuardMock := new(MockLoginGuard)
+		loginGuardMock.On("Reserve", mock.Anything, dto.Email, mock.Anything).Return(nil)
+		loginGuardMock.On("RecordFailure", mock.Anything, dto.Email, mock.Anything).Return("unlock-token", nil)
+
+		auditServiceMock := new(audit.MockAuditService)
+		auditServiceMock.On("Record", mock.Anything, mock.MatchedBy(func(r audit.RecordDTO) bool {
+			return r.Action == audit.AuthLoginFailure
+		})).Return(nil).Once()
+		auditServiceMock.On("Record", mock.Anything, mock.MatchedBy(func(r audit.RecordDTO) bool {
+			return r.Action == audit.AuthAccountLocked && *r.TargetID == 1
+		})).Return(nil).Once()
+
+		mailerMock := new(mailer.MockMailer)
+		mailerMock.On("Send", mock.Anything, mock.MatchedBy(func(m mailer.Message) bool {
+			return m.To == dto.Email && strings.Contains(m.Body, unlockURL+"?token=unlock-token")
+		})).Return(nil).Once()
+
+		service := newAuthService(Deps{
+			UserService:    userServiceMock,
+			PasswordHasher: hasherMock,
+			AuditService:   auditServiceMock,
+			LoginGuard:     loginGuardMock,
+			Mailer:         mailerMock,
+		})
+
+		_, err := service.Login(context.Background(), dto)
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusUnauthorized, httpError.Code)
+		auditServiceMock.AssertExpectations(t)
+		mailerMock.AssertExpectations(t)
+	})
+}
+
+func TestAuthService_Unlock(t *testing.T) {
+	t.Run("should return bad request for invalid tokens", func(t *testing.T) {
+		loginGuardMock := new(MockLoginGuard)
+		loginGuardMock.On("Unlock", mock.Anything, "bad").Return("", ErrInvalidUnlockToken)
+
+		service := newAuthService(Deps{LoginGuard: loginGuardMock})
+
+		err := service.Unlock(context.Background(), "bad")
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusBadRequest, httpError.Code)
+	})
+
+	t.Run("should audit the unlock", func(t *testing.T) {
+		loginGuardMock := new(MockLoginGuard)
+		loginGuardMock.On("Unlock", mock.Anything, "good").Return("user@example.com", nil)
+
+		auditServiceMock := new(audit.MockAuditService)
+		auditServiceMock.On("Record", mock.Anything, mock.MatchedBy(func(r audit.RecordDTO) bool {
+			return r.Action == audit.AuthAccountUnlocked
+		})).Return(nil).Once()
+
+		service := newAuthService(Deps{AuditService: auditServiceMock, LoginGuard: loginGuardMock})
+
+		err := service.Unlock(context.Background(), "good")
+
+		assert.NoError(t, err)
+		auditServiceMock.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,13 @@
+package kyc
+
+import "github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
+
+type StatusDTO struct {
+	Level     user.KYCLevel  `json:"level"`
+	Documents []Document     `json:"documents"`
+	Missing   []DocumentType `json:"missing"`
+}
+
+type RejectDTO struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
@@ -0,0 +1,88 @@
+package kyc
+
+import (
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
+)
+
+type DocumentType string
+
+const (
+	IDFront             DocumentType = "id_front"
+	IDBack              DocumentType = "id_back"
+	Selfie              DocumentType = "selfie"
+	ProofOfAddress      DocumentType = "proof_of_address"
+	CompanyRegistration DocumentType = "company_registration"
+)
+
+type Status string
+
+const (
+	Pending  Status = "pending"
+	Approved Status = "approved"
+	Rejected Status = "rejected"
+)
+
+type Document struct {
+	ID              int          `json:"id"`
+	UserID          int          `json:"user_id"`
+	Type            DocumentType `json:"type"`
+	StorageKey      string       `json:"-"`
+	ContentType     string       `json:"content_type"`
+	Size            int64        `json:"size"`
+	Status          Status       `json:"status"`
+	RejectionReason *string      `json:"rejection_reason,omitempty"`
+	ReviewedBy      *int         `json:"reviewed_by,omitempty"`
+	ReviewedAt      *time.Time   `json:"reviewed_at,omitempty"`
+	CreatedAt       time.Time    `json:"created_at"`
+}
+
+// requirements lists, per role, the documents that must be approved to reach
+// each level. A shopkeeper proves the company exists and who represents it.
+var requirements = map[user.UserRole]map[user.KYCLevel][]DocumentType{
+	user.Common: {
+		user.KYCBasic: {IDFront, IDBack, Selfie},
+		user.KYCFull:  {IDFront, IDBack, Selfie, ProofOfAddress},
+	},
+	user.Shopkeeper: {
+		user.KYCBasic: {CompanyRegistration, IDFront, IDBack},
+		user.KYCFull:  {CompanyRegistration, IDFront, IDBack, Selfie, ProofOfAddress},
+	},
+}
+
+// LevelFor returns the highest level the approved documents reach.
+func LevelFor(role user.UserRole, approved []DocumentType) user.KYCLevel {
+	if requirements[role] == nil {
+		return user.KYCNone
+	}
+
+	have := make(map[DocumentType]bool, len(approved))
+	for _, t := range approved {
+		have[t] = true
+	}
+
+	level := user.KYCNone
+	for _, candidate := range []user.KYCLevel{user.KYCBasic, user.KYCFull} {
+		for _, required := range requirements[role][candidate] {
+			if !have[required] {
+				return level
+			}
+		}
+		level = candidate
+	}
+
+	return level
+}
+
+// accepts tells whether a user with role may upload documents of type t.
+func accepts(role user.UserRole, t DocumentType) bool {
+	for _, types := range requirements[role] {
+		for _, required := range types {
+			if required == t {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,33 @@
+package kyc
+
+import (
+	"testing"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevelFor(t *testing.T) {
+	tests := []struct {
+		name     string
+		role     user.UserRole
+		approved []DocumentType
+		want     user.KYCLevel
+	}{
+		{"no documents", user.Common, nil, user.KYCNone},
+		{"partial basic", user.Common, []DocumentType{IDFront, IDBack}, user.KYCNone},
+		{"basic", user.Common, []DocumentType{Selfie, IDBack, IDFront}, user.KYCBasic},
+		{"full", user.Common, []DocumentType{IDFront, IDBack, Selfie, ProofOfAddress}, user.KYCFull},
+		{"proof of address alone", user.Common, []DocumentType{ProofOfAddress}, user.KYCNone},
+		{"shopkeeper without company registration", user.Shopkeeper, []DocumentType{IDFront, IDBack, Selfie}, user.KYCNone},
+		{"shopkeeper basic", user.Shopkeeper, []DocumentType{CompanyRegistration, IDFront, IDBack}, user.KYCBasic},
+		{"shopkeeper full", user.Shopkeeper, []DocumentType{CompanyRegistration, IDFront, IDBack, Selfie, ProofOfAddress}, user.KYCFull},
+		{"admin", user.Admin, nil, user.KYCNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, LevelFor(tt.role, tt.approved))
+		})
+	}
+}
@@ -0,0 +1,158 @@
+package kyc
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+// multipartOverhead leaves room for the form boundaries and the "type"
+// field on top of the file itself.
+const multipartOverhead = 64 << 10
+
+type KYCHandler struct {
+	kycService    KYCService
+	maxUploadSize int64
+}
+
+func (h *KYCHandler) Upload(w http.ResponseWriter, r *http.Request) error {
+	usr := r.Context().Value(utils.UserKey).(*user.User)
+
+	r.Body = http.MaxBytesReader(w, r.Body, h.maxUploadSize+multipartOverhead)
+
+	if err := r.ParseMultipartForm(h.maxUploadSize); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return apperror.NewHttpError(http.StatusRequestEntityTooLarge, "file is too large")
+		}
+		return apperror.NewHttpError(http.StatusBadRequest, "error parsing multipart form")
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		return apperror.NewHttpError(http.StatusBadRequest, "missing file")
+	}
+	defer file.Close()
+
+	doc, err := h.kycService.Upload(r.Context(), usr, DocumentType(r.FormValue("type")), file)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJSON(w, http.StatusCreated, doc)
+}
+
+func (h *KYCHandler) Status(w http.ResponseWriter, r *http.Request) error {
+	usr := r.Context().Value(utils.UserKey).(*user.User)
+
+	status, err := h.kycService.Status(r.Context(), usr)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, status)
+}
+
+func (h *KYCHandler) Queue(w http.ResponseWriter, r *http.Request) error {
+	documents, err := h.kycService.ListQueue(r.Context(), queryInt(r, "limit"), queryInt(r, "offset"))
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, documents)
+}
+
+func (h *KYCHandler) File(w http.ResponseWriter, r *http.Request) error {
+	actor := r.Context().Value(utils.UserKey).(*user.User)
+
+	id, err := pathID(r, "documentID")
+	if err != nil {
+		return err
+	}
+
+	doc, file, err := h.kycService.OpenFile(r.Context(), actor.ID, id)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Type", doc.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(doc.Size, 10))
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(http.StatusOK)
+
+	_, err = io.Copy(w, file)
+	return err
+}
+
+func (h *KYCHandler) Approve(w http.ResponseWriter, r *http.Request) error {
+	actor := r.Context().Value(utils.UserKey).(*user.User)
+
+	id, err := pathID(r, "documentID")
+	if err != nil {
+		return err
+	}
+
+	doc, err := h.kycService.Approve(r.Context(), actor.ID, id)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, doc)
+}
+
+func (h *KYCHandler) Reject(w http.ResponseWriter, r *http.Request) error {
+	actor := r.Context().Value(utils.UserKey).(*user.User)
+
+	id, err := pathID(r, "documentID")
+	if err != nil {
+		return err
+	}
+
+	var body RejectDTO
+	if err := utils.ReadJSON(w, r, &body); err != nil {
+		return apperror.NewHttpError(http.StatusBadRequest, "error parsing body request")
+	}
+
+	if err := utils.Validate.Struct(body); err != nil {
+		return apperror.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	doc, err := h.kycService.Reject(r.Context(), actor.ID, id, body.Reason)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, doc)
+}
+
+func pathID(r *http.Request, param string) (int, error) {
+	id, err := strconv.Atoi(chi.URLParam(r, param))
+	if err != nil || id <= 0 {
+		return 0, apperror.NewHttpError(http.StatusBadRequest, "invalid "+param)
+	}
+	return id, nil
+}
+
+func queryInt(r *http.Request, key string) int {
+	val, err := strconv.Atoi(r.URL.Query().Get(key))
+	if err != nil {
+		return 0
+	}
+	return val
+}
+
+func NewKYCHandler(kycService KYCService, maxUploadSize int64) *KYCHandler {
+	return &KYCHandler{
+		kycService,
+		maxUploadSize,
+	}
+}
@@ -0,0 +1,269 @@
+package kyc
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/db"
+	"github.com/lib/pq"
+)
+
+// ErrPendingDocument is returned by Save when the user already has a document
+// of that type waiting for review.
+var ErrPendingDocument = errors.New("a document of this type is already pending")
+
+type KYCRepository interface {
+	Save(ctx context.Context, d Document) (int, error)
+	FindByID(ctx context.Context, id int) (*Document, error)
+	ListByUser(ctx context.Context, userID int) ([]Document, error)
+	ListByStatus(ctx context.Context, status Status, limit, offset int) ([]Document, error)
+	HasPending(ctx context.Context, userID int, docType DocumentType) (bool, error)
+	ApprovedTypes(ctx context.Context, userID int) ([]DocumentType, error)
+	// Review settles a pending document, reporting false if it was already
+	// reviewed.
+	Review(ctx context.Context, id int, status Status, reason *string, reviewerID int, reviewedAt time.Time) (bool, error)
+	// DeleteByUser joins the transaction on ctx, if any, and returns the
+	// storage keys of the deleted documents.
+	DeleteByUser(ctx context.Context, userID int) ([]string, error)
+}
+
+type kycRepo struct {
+	database     *sql.DB
+	queryTimeout time.Duration
+}
+
+const documentColumns = `id, user_id, type, storage_key, content_type, size, status,
+	rejection_reason, reviewed_by, reviewed_at, created_at`
+
+func (r *kycRepo) Save(ctx context.Context, d Document) (int, error) {
+	query := `
+		INSERT INTO kyc_documents (user_id, type, storage_key, content_type, size, status, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var id int
+	err := r.database.QueryRowContext(
+		ctx,
+		query,
+		d.UserID, d.Type, d.StorageKey, d.ContentType, d.Size, d.Status, d.CreatedAt,
+	).Scan(&id)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "kyc_documents_pending_type_idx" {
+			return 0, ErrPendingDocument
+		}
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *kycRepo) FindByID(ctx context.Context, id int) (*Document, error) {
+	query := `SELECT ` + documentColumns + ` FROM kyc_documents WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	d, err := scanDocument(r.database.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func (r *kycRepo) ListByUser(ctx context.Context, userID int) ([]Document, error) {
+	query := `SELECT ` + documentColumns + ` FROM kyc_documents WHERE user_id = $1 ORDER BY id DESC`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return r.list(ctx, query, userID)
+}
+
+// ListByStatus returns the oldest documents first, so the review queue is
+// worked through in arrival order.
+func (r *kycRepo) ListByStatus(ctx context.Context, status Status, limit, offset int) ([]Document, error) {
+	query := `
+		SELECT ` + documentColumns + `
+		FROM kyc_documents
+		WHERE status = $1
+		ORDER BY created_at, id
+		LIMIT $2 OFFSET $3
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return r.list(ctx, query, status, limit, offset)
+}
+
+func (r *kycRepo) list(ctx context.Context, query string, args ...any) ([]Document, error) {
+	rows, err := r.database.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	documents := []Document{}
+	for rows.Next() {
+		d, err := scanDocument(rows)
+		if err != nil {
+			return nil, err
+		}
+		documents = append(documents, *d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return documents, nil
+}
+
+func (r *kycRepo) HasPending(ctx context.Context, userID int, docType DocumentType) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM kyc_documents
+			WHERE user_id = $1 AND type = $2 AND status = 'pending'
+		)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var exists bool
+	if err := r.database.QueryRowContext(ctx, query, userID, docType).Scan(&exists); err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
+
+func (r *kycRepo) ApprovedTypes(ctx context.Context, userID int) ([]DocumentType, error) {
+	query := `
+		SELECT DISTINCT type
+		FROM kyc_documents
+		WHERE user_id = $1 AND status = 'approved'
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	rows, err := r.database.QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	types := []DocumentType{}
+	for rows.Next() {
+		var t DocumentType
+		if err := rows.Scan(&t); err != nil {
+			return nil, err
+		}
+		types = append(types, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return types, nil
+}
+
+func (r *kycRepo) Review(ctx context.Context, id int, status Status, reason *string, reviewerID int, reviewedAt time.Time) (bool, error) {
+	query := `
+		UPDATE kyc_documents
+		SET status = $2, rejection_reason = $3, reviewed_by = $4, reviewed_at = $5
+		WHERE id = $1 AND status = 'pending'
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	res, err := r.database.ExecContext(ctx, query, id, status, reason, reviewerID, reviewedAt)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+func (r *kycRepo) DeleteByUser(ctx context.Context, userID int) ([]string, error) {
+	query := `
+		DELETE FROM kyc_documents
+		WHERE user_id = $1
+		RETURNING storage_key
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	rows, err := db.Conn(ctx, r.database).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanDocument(row rowScanner) (*Document, error) {
+	var d Document
+	err := row.Scan(
+		&d.ID,
+		&d.UserID,
+		&d.Type,
+		&d.StorageKey,
+		&d.ContentType,
+		&d.Size,
+		&d.Status,
+		&d.RejectionReason,
+		&d.ReviewedBy,
+		&d.ReviewedAt,
+		&d.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+func NewKYCRepository(database *sql.DB, qt time.Duration) KYCRepository {
+	return &kycRepo{
+		database:     database,
+		queryTimeout: qt,
+	}
+}
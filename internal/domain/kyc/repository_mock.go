@@ -0,0 +1,67 @@
+package kyc
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockKYCRepository struct {
+	mock.Mock
+}
+
+func (m *MockKYCRepository) Save(ctx context.Context, d Document) (int, error) {
+	args := m.Called(ctx, d)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockKYCRepository) FindByID(ctx context.Context, id int) (*Document, error) {
+	args := m.Called(ctx, id)
+	if d, ok := args.Get(0).(*Document); ok {
+		return d, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockKYCRepository) ListByUser(ctx context.Context, userID int) ([]Document, error) {
+	args := m.Called(ctx, userID)
+	if d, ok := args.Get(0).([]Document); ok {
+		return d, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockKYCRepository) ListByStatus(ctx context.Context, status Status, limit, offset int) ([]Document, error) {
+	args := m.Called(ctx, status, limit, offset)
+	if d, ok := args.Get(0).([]Document); ok {
+		return d, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockKYCRepository) HasPending(ctx context.Context, userID int, docType DocumentType) (bool, error) {
+	args := m.Called(ctx, userID, docType)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockKYCRepository) ApprovedTypes(ctx context.Context, userID int) ([]DocumentType, error) {
+	args := m.Called(ctx, userID)
+	if t, ok := args.Get(0).([]DocumentType); ok {
+		return t, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockKYCRepository) Review(ctx context.Context, id int, status Status, reason *string, reviewerID int, reviewedAt time.Time) (bool, error) {
+	args := m.Called(ctx, id, status, reason, reviewerID, reviewedAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockKYCRepository) DeleteByUser(ctx context.Context, userID int) ([]string, error) {
+	args := m.Called(ctx, userID)
+	if k, ok := args.Get(0).([]string); ok {
+		return k, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
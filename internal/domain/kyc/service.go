@@ -0,0 +1,335 @@
+package kyc
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/audit"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/db"
+)
+
+const maxQueueLimit = 100
+
+var errPendingConflict = apperror.NewHttpError(http.StatusConflict, "a document of this type is already waiting for review")
+
+// allowedContentTypes are sniffed from the file itself, never taken from
+// the client.
+var allowedContentTypes = map[string]bool{
+	"image/jpeg":      true,
+	"image/png":       true,
+	"application/pdf": true,
+}
+
+type KYCService interface {
+	Upload(ctx context.Context, usr *user.User, docType DocumentType, file io.Reader) (*Document, error)
+	Status(ctx context.Context, usr *user.User) (*StatusDTO, error)
+	ListDocuments(ctx context.Context, userID int) ([]Document, error)
+	ListQueue(ctx context.Context, limit, offset int) ([]Document, error)
+	OpenFile(ctx context.Context, actorID, id int) (*Document, io.ReadCloser, error)
+	Approve(ctx context.Context, actorID, id int) (*Document, error)
+	Reject(ctx context.Context, actorID, id int, reason string) (*Document, error)
+	// DeleteAll removes every document of a closing account.
+	DeleteAll(ctx context.Context, userID int) error
+}
+
+type kycSvc struct {
+	kycRepo       KYCRepository
+	storage       Storage
+	userService   user.UserService
+	auditService  audit.AuditService
+	maxUploadSize int64
+}
+
+func (s *kycSvc) Upload(ctx context.Context, usr *user.User, docType DocumentType, file io.Reader) (*Document, error) {
+	if usr.Role == user.Admin {
+		return nil, apperror.NewHttpError(http.StatusForbidden, "admins do not go through KYC")
+	}
+
+	if !accepts(usr.Role, docType) {
+		return nil, apperror.NewHttpError(http.StatusUnprocessableEntity, fmt.Sprintf("document type %q is not accepted for this account", docType))
+	}
+
+	pending, err := s.kycRepo.HasPending(ctx, usr.ID, docType)
+	if err != nil {
+		return nil, err
+	}
+	if pending {
+		return nil, errPendingConflict
+	}
+
+	data, err := io.ReadAll(io.LimitReader(file, s.maxUploadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.maxUploadSize {
+		return nil, apperror.NewHttpError(http.StatusRequestEntityTooLarge, fmt.Sprintf("file must be at most %d bytes", s.maxUploadSize))
+	}
+	if len(data) == 0 {
+		return nil, apperror.NewHttpError(http.StatusBadRequest, "file is empty")
+	}
+
+	contentType := http.DetectContentType(data)
+	if !allowedContentTypes[contentType] {
+		return nil, apperror.NewHttpError(http.StatusUnsupportedMediaType, "file must be a JPEG, PNG or PDF")
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("%d/%s", usr.ID, hex.EncodeToString(b))
+
+	if err := s.storage.Put(ctx, key, bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+
+	doc := Document{
+		UserID:      usr.ID,
+		Type:        docType,
+		StorageKey:  key,
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		Status:      Pending,
+		CreatedAt:   time.Now(),
+	}
+
+	id, err := s.kycRepo.Save(ctx, doc)
+	if err != nil {
+		if err := s.storage.Delete(ctx, key); err != nil {
+			slog.Error("failed to remove orphan kyc file", "key", key, "err", err.Error())
+		}
+		if errors.Is(err, ErrPendingDocument) {
+			return nil, errPendingConflict
+		}
+		return nil, err
+	}
+	doc.ID = id
+
+	err = s.auditService.Record(ctx, audit.RecordDTO{
+		ActorID:    &usr.ID,
+		Action:     audit.KYCDocumentUpload,
+		TargetType: "kyc_document",
+		TargetID:   &doc.ID,
+		Details:    map[string]any{"type": docType, "size": doc.Size},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &doc, nil
+}
+
+func (s *kycSvc) Status(ctx context.Context, usr *user.User) (*StatusDTO, error) {
+	documents, err := s.kycRepo.ListByUser(ctx, usr.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	approved := map[DocumentType]bool{}
+	for _, d := range documents {
+		if d.Status == Approved {
+			approved[d.Type] = true
+		}
+	}
+
+	missing := []DocumentType{}
+	for _, t := range requirements[usr.Role][user.KYCFull] {
+		if !approved[t] {
+			missing = append(missing, t)
+		}
+	}
+
+	return &StatusDTO{
+		Level:     usr.KYCLevel,
+		Documents: documents,
+		Missing:   missing,
+	}, nil
+}
+
+func (s *kycSvc) ListDocuments(ctx context.Context, userID int) ([]Document, error) {
+	return s.kycRepo.ListByUser(ctx, userID)
+}
+
+func (s *kycSvc) ListQueue(ctx context.Context, limit, offset int) ([]Document, error) {
+	if limit <= 0 || limit > maxQueueLimit {
+		limit = maxQueueLimit
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	return s.kycRepo.ListByStatus(ctx, Pending, limit, offset)
+}
+
+// OpenFile hands a reviewer the uploaded file. Each access is audited since
+// the file is an identity document.
+func (s *kycSvc) OpenFile(ctx context.Context, actorID, id int) (*Document, io.ReadCloser, error) {
+	doc, err := s.find(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = s.auditService.Record(ctx, audit.RecordDTO{
+		ActorID:    &actorID,
+		Action:     audit.KYCDocumentView,
+		TargetType: "kyc_document",
+		TargetID:   &doc.ID,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := s.storage.Open(ctx, doc.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return doc, file, nil
+}
+
+// DeleteAll deletes the rows first and the files only once the transaction
+// on ctx commits, so a rolled back closure keeps its documents. A file that
+// cannot be removed is only logged, since the account is closed by then.
+func (s *kycSvc) DeleteAll(ctx context.Context, userID int) error {
+	keys, err := s.kycRepo.DeleteByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	db.AfterCommit(ctx, func() {
+		for _, key := range keys {
+			if err := s.storage.Delete(ctx, key); err != nil {
+				slog.Error("failed to delete kyc document of closed account", "err", err.Error(), "user_id", userID, "key", key)
+			}
+		}
+	})
+
+	return nil
+}
+
+// Approve accepts a document and raises the owner's KYC level when the
+// approved documents now meet the next level's requirements.
+func (s *kycSvc) Approve(ctx context.Context, actorID, id int) (*Document, error) {
+	doc, err := s.review(ctx, actorID, id, Approved, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	owner, err := s.userService.FindByID(ctx, doc.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	approved, err := s.kycRepo.ApprovedTypes(ctx, doc.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	level := LevelFor(owner.Role, approved)
+	if level != owner.KYCLevel {
+		if err := s.userService.SetKYCLevel(ctx, owner.ID, level); err != nil {
+			return nil, err
+		}
+	}
+
+	err = s.auditService.Record(ctx, audit.RecordDTO{
+		ActorID:    &actorID,
+		Action:     audit.KYCDocumentApprove,
+		TargetType: "kyc_document",
+		TargetID:   &doc.ID,
+		Before:     map[string]any{"kyc_level": owner.KYCLevel},
+		After:      map[string]any{"kyc_level": level},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+func (s *kycSvc) Reject(ctx context.Context, actorID, id int, reason string) (*Document, error) {
+	doc, err := s.review(ctx, actorID, id, Rejected, &reason)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.auditService.Record(ctx, audit.RecordDTO{
+		ActorID:    &actorID,
+		Action:     audit.KYCDocumentReject,
+		TargetType: "kyc_document",
+		TargetID:   &doc.ID,
+		Details:    map[string]string{"reason": reason},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+func (s *kycSvc) review(ctx context.Context, actorID, id int, status Status, reason *string) (*Document, error) {
+	doc, err := s.find(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if doc.UserID == actorID {
+		return nil, apperror.NewHttpError(http.StatusForbidden, "reviewers cannot review their own documents")
+	}
+
+	now := time.Now()
+	ok, err := s.kycRepo.Review(ctx, id, status, reason, actorID, now)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, apperror.NewHttpError(http.StatusConflict, "document was already reviewed")
+	}
+
+	doc.Status = status
+	doc.RejectionReason = reason
+	doc.ReviewedBy = &actorID
+	doc.ReviewedAt = &now
+
+	return doc, nil
+}
+
+func (s *kycSvc) find(ctx context.Context, id int) (*Document, error) {
+	doc, err := s.kycRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if doc == nil {
+		return nil, apperror.NewHttpError(http.StatusNotFound, "document not found")
+	}
+
+	return doc, nil
+}
+
+func NewKYCService(
+	kycRepo KYCRepository,
+	storage Storage,
+	usrSvc user.UserService,
+	audSvc audit.AuditService,
+	maxUploadSize int64) KYCService {
+
+	return &kycSvc{
+		kycRepo:       kycRepo,
+		storage:       storage,
+		userService:   usrSvc,
+		auditService:  audSvc,
+		maxUploadSize: maxUploadSize,
+	}
+}
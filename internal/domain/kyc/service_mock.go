@@ -0,0 +1,73 @@
+package kyc
+
+import (
+	"context"
+	"io"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockKYCService struct {
+	mock.Mock
+}
+
+func (m *MockKYCService) Upload(ctx context.Context, usr *user.User, docType DocumentType, file io.Reader) (*Document, error) {
+	args := m.Called(ctx, usr, docType, file)
+	if d, ok := args.Get(0).(*Document); ok {
+		return d, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockKYCService) Status(ctx context.Context, usr *user.User) (*StatusDTO, error) {
+	args := m.Called(ctx, usr)
+	if s, ok := args.Get(0).(*StatusDTO); ok {
+		return s, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockKYCService) ListDocuments(ctx context.Context, userID int) ([]Document, error) {
+	args := m.Called(ctx, userID)
+	if d, ok := args.Get(0).([]Document); ok {
+		return d, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockKYCService) ListQueue(ctx context.Context, limit, offset int) ([]Document, error) {
+	args := m.Called(ctx, limit, offset)
+	if d, ok := args.Get(0).([]Document); ok {
+		return d, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockKYCService) OpenFile(ctx context.Context, actorID, id int) (*Document, io.ReadCloser, error) {
+	args := m.Called(ctx, actorID, id)
+	d, _ := args.Get(0).(*Document)
+	f, _ := args.Get(1).(io.ReadCloser)
+	return d, f, args.Error(2)
+}
+
+func (m *MockKYCService) Approve(ctx context.Context, actorID, id int) (*Document, error) {
+	args := m.Called(ctx, actorID, id)
+	if d, ok := args.Get(0).(*Document); ok {
+		return d, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockKYCService) Reject(ctx context.Context, actorID, id int, reason string) (*Document, error) {
+	args := m.Called(ctx, actorID, id, reason)
+	if d, ok := args.Get(0).(*Document); ok {
+		return d, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockKYCService) DeleteAll(ctx context.Context, userID int) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
@@ -0,0 +1,282 @@
+package kyc
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/audit"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var pngHeader = []byte("\x89PNG\r\n\x1a\n0000")
+
+func assertHttpError(t *testing.T, err error, code int) {
+	t.Helper()
+
+	var httpError *apperror.HttpError
+	require.ErrorAs(t, err, &httpError)
+	assert.Equal(t, code, httpError.Code)
+}
+
+func TestKYCService_Upload(t *testing.T) {
+	usr := &user.User{ID: 1, Role: user.Common, KYCLevel: user.KYCNone}
+
+	t.Run("should store the file and record the upload", func(t *testing.T) {
+		mockRepo := new(MockKYCRepository)
+		mockAudit := new(audit.MockAuditService)
+		storage := NewDiskStorage(t.TempDir())
+
+		var saved Document
+		mockRepo.On("HasPending", mock.Anything, 1, IDFront).Return(false, nil)
+		mockRepo.On("Save", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) { saved = args.Get(1).(Document) }).
+			Return(9, nil)
+		mockAudit.On("Record", mock.Anything, mock.MatchedBy(func(dto audit.RecordDTO) bool {
+			return dto.Action == audit.KYCDocumentUpload && *dto.TargetID == 9
+		})).Return(nil)
+
+		doc, err := NewKYCService(mockRepo, storage, nil, mockAudit, 1024).
+			Upload(context.Background(), usr, IDFront, bytes.NewReader(pngHeader))
+
+		require.NoError(t, err)
+		assert.Equal(t, 9, doc.ID)
+		assert.Equal(t, "image/png", saved.ContentType)
+		assert.Equal(t, Pending, saved.Status)
+		assert.True(t, strings.HasPrefix(saved.StorageKey, "1/"))
+
+		f, err := storage.Open(context.Background(), saved.StorageKey)
+		require.NoError(t, err)
+		f.Close()
+		mockAudit.AssertExpectations(t)
+	})
+
+	t.Run("should reject admins", func(t *testing.T) {
+		_, err := NewKYCService(new(MockKYCRepository), nil, nil, nil, 1024).
+			Upload(context.Background(), &user.User{ID: 1, Role: user.Admin}, IDFront, bytes.NewReader(pngHeader))
+
+		assertHttpError(t, err, http.StatusForbidden)
+	})
+
+	t.Run("should reject document types the role does not use", func(t *testing.T) {
+		_, err := NewKYCService(new(MockKYCRepository), nil, nil, nil, 1024).
+			Upload(context.Background(), usr, CompanyRegistration, bytes.NewReader(pngHeader))
+
+		assertHttpError(t, err, http.StatusUnprocessableEntity)
+	})
+
+	t.Run("should reject a second pending document of the same type", func(t *testing.T) {
+		mockRepo := new(MockKYCRepository)
+		mockRepo.On("HasPending", mock.Anything, 1, IDFront).Return(true, nil)
+
+		_, err := NewKYCService(mockRepo, nil, nil, nil, 1024).
+			Upload(context.Background(), usr, IDFront, bytes.NewReader(pngHeader))
+
+		assertHttpError(t, err, http.StatusConflict)
+	})
+
+	t.Run("should return conflict when a concurrent upload wins the race", func(t *testing.T) {
+		mockRepo := new(MockKYCRepository)
+		storage := NewDiskStorage(t.TempDir())
+
+		var key string
+		mockRepo.On("HasPending", mock.Anything, 1, IDFront).Return(false, nil)
+		mockRepo.On("Save", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) { key = args.Get(1).(Document).StorageKey }).
+			Return(0, ErrPendingDocument)
+
+		_, err := NewKYCService(mockRepo, storage, nil, nil, 1024).
+			Upload(context.Background(), usr, IDFront, bytes.NewReader(pngHeader))
+
+		assertHttpError(t, err, http.StatusConflict)
+		_, err = storage.Open(context.Background(), key)
+		assert.Error(t, err)
+	})
+
+	t.Run("should reject files over the size limit", func(t *testing.T) {
+		mockRepo := new(MockKYCRepository)
+		mockRepo.On("HasPending", mock.Anything, 1, IDFront).Return(false, nil)
+
+		_, err := NewKYCService(mockRepo, nil, nil, nil, 8).
+			Upload(context.Background(), usr, IDFront, bytes.NewReader(pngHeader))
+
+		assertHttpError(t, err, http.StatusRequestEntityTooLarge)
+	})
+
+	t.Run("should reject content that is not an image or pdf", func(t *testing.T) {
+		mockRepo := new(MockKYCRepository)
+		mockRepo.On("HasPending", mock.Anything, 1, IDFront).Return(false, nil)
+
+		_, err := NewKYCService(mockRepo, nil, nil, nil, 1024).
+			Upload(context.Background(), usr, IDFront, strings.NewReader("<html><script></script></html>"))
+
+		assertHttpError(t, err, http.StatusUnsupportedMediaType)
+	})
+
+	t.Run("should remove the file when saving fails", func(t *testing.T) {
+		mockRepo := new(MockKYCRepository)
+		dir := t.TempDir()
+		storage := NewDiskStorage(dir)
+
+		var key string
+		mockRepo.On("HasPending", mock.Anything, 1, IDFront).Return(false, nil)
+		mockRepo.On("Save", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) { key = args.Get(1).(Document).StorageKey }).
+			Return(0, errors.New("db down"))
+
+		_, err := NewKYCService(mockRepo, storage, nil, nil, 1024).
+			Upload(context.Background(), usr, IDFront, bytes.NewReader(pngHeader))
+
+		require.Error(t, err)
+		_, err = storage.Open(context.Background(), key)
+		assert.Error(t, err)
+	})
+}
+
+func TestKYCService_Approve(t *testing.T) {
+	doc := &Document{ID: 9, UserID: 1, Type: Selfie, Status: Pending}
+	owner := &user.User{ID: 1, Role: user.Common, KYCLevel: user.KYCNone}
+
+	t.Run("should raise the level once the requirements are met", func(t *testing.T) {
+		mockRepo := new(MockKYCRepository)
+		mockUser := new(user.MockUserService)
+		mockAudit := new(audit.MockAuditService)
+
+		mockRepo.On("FindByID", mock.Anything, 9).Return(doc, nil)
+		mockRepo.On("Review", mock.Anything, 9, Approved, (*string)(nil), 2, mock.Anything).Return(true, nil)
+		mockRepo.On("ApprovedTypes", mock.Anything, 1).Return([]DocumentType{IDFront, IDBack, Selfie}, nil)
+		mockUser.On("FindByID", mock.Anything, 1).Return(owner, nil)
+		mockUser.On("SetKYCLevel", mock.Anything, 1, user.KYCBasic).Return(nil)
+		mockAudit.On("Record", mock.Anything, mock.MatchedBy(func(dto audit.RecordDTO) bool {
+			return dto.Action == audit.KYCDocumentApprove && *dto.ActorID == 2
+		})).Return(nil)
+
+		approved, err := NewKYCService(mockRepo, nil, mockUser, mockAudit, 1024).Approve(context.Background(), 2, 9)
+
+		require.NoError(t, err)
+		assert.Equal(t, Approved, approved.Status)
+		mockUser.AssertExpectations(t)
+	})
+
+	t.Run("should keep the level while documents are missing", func(t *testing.T) {
+		mockRepo := new(MockKYCRepository)
+		mockUser := new(user.MockUserService)
+		mockAudit := new(audit.MockAuditService)
+
+		mockRepo.On("FindByID", mock.Anything, 9).Return(doc, nil)
+		mockRepo.On("Review", mock.Anything, 9, Approved, (*string)(nil), 2, mock.Anything).Return(true, nil)
+		mockRepo.On("ApprovedTypes", mock.Anything, 1).Return([]DocumentType{Selfie}, nil)
+		mockUser.On("FindByID", mock.Anything, 1).Return(owner, nil)
+		mockAudit.On("Record", mock.Anything, mock.Anything).Return(nil)
+
+		_, err := NewKYCService(mockRepo, nil, mockUser, mockAudit, 1024).Approve(context.Background(), 2, 9)
+
+		require.NoError(t, err)
+		mockUser.AssertNotCalled(t, "SetKYCLevel", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should return conflict for documents already reviewed", func(t *testing.T) {
+		mockRepo := new(MockKYCRepository)
+		mockRepo.On("FindByID", mock.Anything, 9).Return(doc, nil)
+		mockRepo.On("Review", mock.Anything, 9, Approved, (*string)(nil), 2, mock.Anything).Return(false, nil)
+
+		_, err := NewKYCService(mockRepo, nil, nil, nil, 1024).Approve(context.Background(), 2, 9)
+
+		assertHttpError(t, err, http.StatusConflict)
+	})
+
+	t.Run("should not let reviewers approve their own documents", func(t *testing.T) {
+		mockRepo := new(MockKYCRepository)
+		mockRepo.On("FindByID", mock.Anything, 9).Return(doc, nil)
+
+		_, err := NewKYCService(mockRepo, nil, nil, nil, 1024).Approve(context.Background(), 1, 9)
+
+		assertHttpError(t, err, http.StatusForbidden)
+	})
+
+	t.Run("should return not found for unknown documents", func(t *testing.T) {
+		mockRepo := new(MockKYCRepository)
+		mockRepo.On("FindByID", mock.Anything, 9).Return(nil, nil)
+
+		_, err := NewKYCService(mockRepo, nil, nil, nil, 1024).Approve(context.Background(), 2, 9)
+
+		assertHttpError(t, err, http.StatusNotFound)
+	})
+}
+
+func TestKYCService_Reject(t *testing.T) {
+	t.Run("should store the reason and record the rejection", func(t *testing.T) {
+		mockRepo := new(MockKYCRepository)
+		mockAudit := new(audit.MockAuditService)
+
+		mockRepo.On("FindByID", mock.Anything, 9).Return(&Document{ID: 9, UserID: 1, Status: Pending}, nil)
+		mockRepo.On("Review", mock.Anything, 9, Rejected, mock.MatchedBy(func(reason *string) bool {
+			return reason != nil && *reason == "blurry"
+		}), 2, mock.Anything).Return(true, nil)
+		mockAudit.On("Record", mock.Anything, mock.MatchedBy(func(dto audit.RecordDTO) bool {
+			return dto.Action == audit.KYCDocumentReject
+		})).Return(nil)
+
+		rejected, err := NewKYCService(mockRepo, nil, nil, mockAudit, 1024).Reject(context.Background(), 2, 9, "blurry")
+
+		require.NoError(t, err)
+		assert.Equal(t, Rejected, rejected.Status)
+		assert.Equal(t, "blurry", *rejected.RejectionReason)
+		mockAudit.AssertExpectations(t)
+	})
+}
+
+func TestKYCService_Status(t *testing.T) {
+	t.Run("should list the documents still missing for the full level", func(t *testing.T) {
+		mockRepo := new(MockKYCRepository)
+		mockRepo.On("ListByUser", mock.Anything, 1).Return([]Document{
+			{Type: IDFront, Status: Approved},
+			{Type: IDBack, Status: Rejected},
+		}, nil)
+
+		status, err := NewKYCService(mockRepo, nil, nil, nil, 1024).
+			Status(context.Background(), &user.User{ID: 1, Role: user.Common, KYCLevel: user.KYCNone})
+
+		require.NoError(t, err)
+		assert.Equal(t, []DocumentType{IDBack, Selfie, ProofOfAddress}, status.Missing)
+		assert.Len(t, status.Documents, 2)
+	})
+}
+
+func TestKYCService_DeleteAll(t *testing.T) {
+	t.Run("should delete the rows and then the files", func(t *testing.T) {
+		mockRepo := new(MockKYCRepository)
+		storage := NewDiskStorage(t.TempDir())
+		require.NoError(t, storage.Put(context.Background(), "1/a.png", bytes.NewReader(pngHeader)))
+
+		mockRepo.On("DeleteByUser", mock.Anything, 1).Return([]string{"1/a.png", "1/missing.pdf"}, nil)
+
+		err := NewKYCService(mockRepo, storage, nil, nil, 1024).DeleteAll(context.Background(), 1)
+
+		require.NoError(t, err)
+		_, err = storage.Open(context.Background(), "1/a.png")
+		assert.Error(t, err)
+	})
+
+	t.Run("should keep the files when the rows cannot be deleted", func(t *testing.T) {
+		mockRepo := new(MockKYCRepository)
+		storage := NewDiskStorage(t.TempDir())
+		require.NoError(t, storage.Put(context.Background(), "1/a.png", bytes.NewReader(pngHeader)))
+
+		mockRepo.On("DeleteByUser", mock.Anything, 1).Return(nil, errors.New("db down"))
+
+		err := NewKYCService(mockRepo, storage, nil, nil, 1024).DeleteAll(context.Background(), 1)
+
+		require.Error(t, err)
+		f, err := storage.Open(context.Background(), "1/a.png")
+		require.NoError(t, err)
+		f.Close()
+	})
+}
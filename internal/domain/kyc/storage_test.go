@@ -0,0 +1,40 @@
+package kyc
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiskStorage(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should store, read and delete files", func(t *testing.T) {
+		storage := NewDiskStorage(t.TempDir())
+
+		require.NoError(t, storage.Put(ctx, "1/abc", strings.NewReader("content")))
+
+		f, err := storage.Open(ctx, "1/abc")
+		require.NoError(t, err)
+		data, err := io.ReadAll(f)
+		f.Close()
+		require.NoError(t, err)
+		assert.Equal(t, "content", string(data))
+
+		require.NoError(t, storage.Delete(ctx, "1/abc"))
+		_, err = storage.Open(ctx, "1/abc")
+		assert.Error(t, err)
+	})
+
+	t.Run("should reject keys escaping the storage directory", func(t *testing.T) {
+		storage := NewDiskStorage(t.TempDir())
+
+		assert.Error(t, storage.Put(ctx, "../outside", strings.NewReader("x")))
+		_, err := storage.Open(ctx, "../../etc/passwd")
+		assert.Error(t, err)
+	})
+}
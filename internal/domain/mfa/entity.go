@@ -0,0 +1,32 @@
+package mfa
+
+import "time"
+
+// Factor is the TOTP enrollment of a user. It stays pending until the user
+// proves the authenticator works by confirming a code. FailedAttempts counts
+// wrong codes across every challenge and step-up check until a correct one.
+type Factor struct {
+	UserID         int
+	Secret         string
+	LastUsedStep   int64
+	FailedAttempts int
+	LockedUntil    *time.Time
+	EnabledAt      *time.Time
+	CreatedAt      time.Time
+}
+
+func (f *Factor) IsEnabled() bool {
+	return f.EnabledAt != nil
+}
+
+// Challenge is the second step of a login for users with 2FA enabled. It is
+// single-use and dies after a few wrong codes.
+type Challenge struct {
+	ID        int
+	UserID    int
+	TokenHash string
+	Attempts  int
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
@@ -0,0 +1,50 @@
+package mfa
+
+import (
+	"net/http"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/utils"
+)
+
+type MFAHandler struct {
+	mfaService MFAService
+}
+
+func (h *MFAHandler) Enroll(w http.ResponseWriter, r *http.Request) error {
+	usr := r.Context().Value(utils.UserKey).(*user.User)
+
+	enrollment, err := h.mfaService.Enroll(r.Context(), usr.ID, usr.Email)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, enrollment)
+}
+
+func (h *MFAHandler) Confirm(w http.ResponseWriter, r *http.Request) error {
+	usr := r.Context().Value(utils.UserKey).(*user.User)
+
+	var body ConfirmDTO
+	if err := utils.ReadJSON(w, r, &body); err != nil {
+		return apperror.NewHttpError(http.StatusBadRequest, "error parsing body request")
+	}
+
+	if err := utils.Validate.Struct(body); err != nil {
+		return apperror.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	codes, err := h.mfaService.Confirm(r.Context(), usr.ID, body.Code)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, codes)
+}
+
+func NewMFAHandler(mfaService MFAService) *MFAHandler {
+	return &MFAHandler{
+		mfaService,
+	}
+}
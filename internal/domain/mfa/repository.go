@@ -0,0 +1,315 @@
+package mfa
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/db"
+)
+
+type MFARepository interface {
+	FindByUser(ctx context.Context, userID int) (*Factor, error)
+	SavePending(ctx context.Context, f Factor) error
+	Enable(ctx context.Context, userID int, step int64, recoveryHashes []string) error
+	UseStep(ctx context.Context, userID int, step int64) (bool, error)
+	UseRecoveryCode(ctx context.Context, userID int, hash string) (bool, error)
+	SaveChallenge(ctx context.Context, c Challenge) error
+	FindChallenge(ctx context.Context, hash string) (*Challenge, error)
+	RecordChallengeFailure(ctx context.Context, id int) error
+	ConsumeChallenge(ctx context.Context, id int) (bool, error)
+	ReserveAttempt(ctx context.Context, userID, lockAfter int, now, lockUntil time.Time) (bool, error)
+	ResetFailures(ctx context.Context, userID int) error
+	// DeleteByUser removes the factor, its recovery codes and challenges. It
+	// joins the transaction on ctx, if any.
+	DeleteByUser(ctx context.Context, userID int) error
+}
+
+type mfaRepo struct {
+	database     *sql.DB
+	queryTimeout time.Duration
+}
+
+func (r *mfaRepo) FindByUser(ctx context.Context, userID int) (*Factor, error) {
+	query := `
+		SELECT user_id, secret, last_used_step, failed_attempts, locked_until, enabled_at, created_at
+		FROM user_mfa
+		WHERE user_id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var f Factor
+	err := r.database.QueryRowContext(ctx, query, userID).Scan(
+		&f.UserID,
+		&f.Secret,
+		&f.LastUsedStep,
+		&f.FailedAttempts,
+		&f.LockedUntil,
+		&f.EnabledAt,
+		&f.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &f, nil
+}
+
+// SavePending stores a new secret for a user that has not enabled 2FA yet,
+// replacing any previous unconfirmed enrollment.
+func (r *mfaRepo) SavePending(ctx context.Context, f Factor) error {
+	query := `
+		INSERT INTO user_mfa (user_id, secret, last_used_step, created_at)
+		VALUES ($1, $2, 0, $3)
+		ON CONFLICT (user_id) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = 0, created_at = EXCLUDED.created_at
+		WHERE user_mfa.enabled_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := r.database.ExecContext(ctx, query, f.UserID, f.Secret, f.CreatedAt)
+	return err
+}
+
+// Enable turns 2FA on and replaces the user's recovery codes in a single
+// database transaction.
+func (r *mfaRepo) Enable(ctx context.Context, userID int, step int64, recoveryHashes []string) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.database.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE user_mfa
+		SET enabled_at = NOW(), last_used_step = $2
+		WHERE user_id = $1
+	`, userID, step)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM mfa_recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+
+	for _, hash := range recoveryHashes {
+		_, err = tx.ExecContext(ctx, `
+			INSERT INTO mfa_recovery_codes (user_id, code_hash)
+			VALUES ($1, $2)
+		`, userID, hash)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// UseStep records step as the last accepted TOTP step. It returns false when
+// a code of the same or a later step was already accepted, blocking replays.
+func (r *mfaRepo) UseStep(ctx context.Context, userID int, step int64) (bool, error) {
+	query := `
+		UPDATE user_mfa
+		SET last_used_step = $2
+		WHERE user_id = $1 AND last_used_step < $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	res, err := r.database.ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n == 1, nil
+}
+
+func (r *mfaRepo) UseRecoveryCode(ctx context.Context, userID int, hash string) (bool, error) {
+	query := `
+		UPDATE mfa_recovery_codes
+		SET used_at = NOW()
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	res, err := r.database.ExecContext(ctx, query, userID, hash)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n == 1, nil
+}
+
+func (r *mfaRepo) SaveChallenge(ctx context.Context, c Challenge) error {
+	query := `
+		INSERT INTO mfa_challenges (user_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := r.database.ExecContext(ctx, query, c.UserID, c.TokenHash, c.ExpiresAt, c.CreatedAt)
+	return err
+}
+
+func (r *mfaRepo) FindChallenge(ctx context.Context, hash string) (*Challenge, error) {
+	query := `
+		SELECT id, user_id, token_hash, attempts, expires_at, used_at, created_at
+		FROM mfa_challenges
+		WHERE token_hash = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var c Challenge
+	err := r.database.QueryRowContext(ctx, query, hash).Scan(
+		&c.ID,
+		&c.UserID,
+		&c.TokenHash,
+		&c.Attempts,
+		&c.ExpiresAt,
+		&c.UsedAt,
+		&c.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &c, nil
+}
+
+func (r *mfaRepo) RecordChallengeFailure(ctx context.Context, id int) error {
+	query := `
+		UPDATE mfa_challenges
+		SET attempts = attempts + 1
+		WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := r.database.ExecContext(ctx, query, id)
+	return err
+}
+
+func (r *mfaRepo) ConsumeChallenge(ctx context.Context, id int) (bool, error) {
+	query := `
+		UPDATE mfa_challenges
+		SET used_at = NOW()
+		WHERE id = $1 AND used_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	res, err := r.database.ExecContext(ctx, query, id)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n == 1, nil
+}
+
+// ReserveAttempt counts an attempt as failed before its code is checked, so
+// parallel guesses are serialized by the row lock and cannot overrun the
+// limit. The attempt that reaches lockAfter locks the factor until lockUntil.
+// Once a lock expires the counter is still over the limit, so every further
+// wrong code locks it again. It returns false while the factor is locked.
+func (r *mfaRepo) ReserveAttempt(ctx context.Context, userID, lockAfter int, now, lockUntil time.Time) (bool, error) {
+	query := `
+		UPDATE user_mfa
+		SET failed_attempts = failed_attempts + 1,
+			locked_until = CASE WHEN failed_attempts + 1 >= $2 THEN $4 ELSE NULL END
+		WHERE user_id = $1 AND (locked_until IS NULL OR locked_until <= $3)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	res, err := r.database.ExecContext(ctx, query, userID, lockAfter, now, lockUntil)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n == 1, nil
+}
+
+func (r *mfaRepo) ResetFailures(ctx context.Context, userID int) error {
+	query := `
+		UPDATE user_mfa
+		SET failed_attempts = 0, locked_until = NULL
+		WHERE user_id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := r.database.ExecContext(ctx, query, userID)
+	return err
+}
+
+func (r *mfaRepo) DeleteByUser(ctx context.Context, userID int) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return db.RunInTx(ctx, r.database, func(tx *sql.Tx) error {
+		for _, query := range []string{
+			`DELETE FROM mfa_challenges WHERE user_id = $1`,
+			`DELETE FROM mfa_recovery_codes WHERE user_id = $1`,
+			`DELETE FROM user_mfa WHERE user_id = $1`,
+		} {
+			if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func NewMFARepository(database *sql.DB, qt time.Duration) MFARepository {
+	return &mfaRepo{
+		database:     database,
+		queryTimeout: qt,
+	}
+}
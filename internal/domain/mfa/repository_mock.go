@@ -0,0 +1,78 @@
+package mfa
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockMFARepository struct {
+	mock.Mock
+}
+
+func (m *MockMFARepository) FindByUser(ctx context.Context, userID int) (*Factor, error) {
+	args := m.Called(ctx, userID)
+	if f, ok := args.Get(0).(*Factor); ok {
+		return f, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockMFARepository) SavePending(ctx context.Context, f Factor) error {
+	args := m.Called(ctx, f)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) Enable(ctx context.Context, userID int, step int64, recoveryHashes []string) error {
+	args := m.Called(ctx, userID, step, recoveryHashes)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) UseStep(ctx context.Context, userID int, step int64) (bool, error) {
+	args := m.Called(ctx, userID, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMFARepository) UseRecoveryCode(ctx context.Context, userID int, hash string) (bool, error) {
+	args := m.Called(ctx, userID, hash)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMFARepository) SaveChallenge(ctx context.Context, c Challenge) error {
+	args := m.Called(ctx, c)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) FindChallenge(ctx context.Context, hash string) (*Challenge, error) {
+	args := m.Called(ctx, hash)
+	if c, ok := args.Get(0).(*Challenge); ok {
+		return c, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockMFARepository) RecordChallengeFailure(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) ConsumeChallenge(ctx context.Context, id int) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMFARepository) ReserveAttempt(ctx context.Context, userID, lockAfter int, now, lockUntil time.Time) (bool, error) {
+	args := m.Called(ctx, userID, lockAfter, now, lockUntil)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMFARepository) ResetFailures(ctx context.Context, userID int) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockMFARepository) DeleteByUser(ctx context.Context, userID int) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
@@ -0,0 +1,324 @@
+package mfa
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	recoveryCodeCount    = 10
+	maxChallengeAttempts = 5
+)
+
+type MFAService interface {
+	Enroll(ctx context.Context, userID int, account string) (*EnrollmentDTO, error)
+	Confirm(ctx context.Context, userID int, code string) (*RecoveryCodesDTO, error)
+	IsEnabled(ctx context.Context, userID int) (bool, error)
+	StartChallenge(ctx context.Context, userID int) (string, error)
+	CompleteChallenge(ctx context.Context, token, code string) (userID int, err error)
+	VerifyTOTP(ctx context.Context, userID int, code string) error
+	// Remove deletes the factor of a closing account, along with its
+	// recovery codes and pending challenges.
+	Remove(ctx context.Context, userID int) error
+}
+
+type mfaSvc struct {
+	mfaRepo       MFARepository
+	encryptionKey string
+	issuer        string
+	challengeTTL  time.Duration
+	lockAfter     int
+	lockFor       time.Duration
+}
+
+// Enroll generates a new secret and returns it in the formats authenticator
+// apps accept. 2FA only becomes active after Confirm.
+func (s *mfaSvc) Enroll(ctx context.Context, userID int, account string) (*EnrollmentDTO, error) {
+	factor, err := s.mfaRepo.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if factor != nil && factor.IsEnabled() {
+		return nil, apperror.NewHttpError(http.StatusConflict, "two-factor authentication already enabled")
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := encryptSecret(s.encryptionKey, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.mfaRepo.SavePending(ctx, Factor{
+		UserID:    userID,
+		Secret:    encrypted,
+		CreatedAt: time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	uri := keyURI(s.issuer, account, secret)
+
+	png, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EnrollmentDTO{
+		Secret: secret,
+		URI:    uri,
+		QRCode: "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	}, nil
+}
+
+// Confirm enables 2FA once the user sends a valid code, and returns the
+// recovery codes. They are only stored hashed, so this is the only time they
+// can be shown.
+func (s *mfaSvc) Confirm(ctx context.Context, userID int, code string) (*RecoveryCodesDTO, error) {
+	factor, err := s.mfaRepo.FindByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if factor == nil {
+		return nil, apperror.NewHttpError(http.StatusNotFound, "no pending two-factor enrollment")
+	}
+
+	if factor.IsEnabled() {
+		return nil, apperror.NewHttpError(http.StatusConflict, "two-factor authentication already enabled")
+	}
+
+	secret, err := decryptSecret(s.encryptionKey, factor.Secret)
+	if err != nil {
+		return nil, err
+	}
+
+	step, ok := matchStep(secret, code, time.Now())
+	if !ok {
+		return nil, apperror.NewHttpError(http.StatusBadRequest, "invalid totp code")
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		codes[i], err = newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		hashes[i] = hashCode(codes[i])
+	}
+
+	if err := s.mfaRepo.Enable(ctx, userID, step, hashes); err != nil {
+		return nil, err
+	}
+
+	return &RecoveryCodesDTO{RecoveryCodes: codes}, nil
+}
+
+func (s *mfaSvc) IsEnabled(ctx context.Context, userID int) (bool, error) {
+	factor, err := s.mfaRepo.FindByUser(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	return factor != nil && factor.IsEnabled(), nil
+}
+
+func (s *mfaSvc) StartChallenge(ctx context.Context, userID int) (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+
+	now := time.Now()
+	err := s.mfaRepo.SaveChallenge(ctx, Challenge{
+		UserID:    userID,
+		TokenHash: hashToken(token),
+		ExpiresAt: now.Add(s.challengeTTL),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// CompleteChallenge accepts either a TOTP code or one of the recovery codes.
+// On a wrong code the user ID is still returned, so the failure can be
+// attributed.
+func (s *mfaSvc) CompleteChallenge(ctx context.Context, token, code string) (int, error) {
+	challenge, err := s.mfaRepo.FindChallenge(ctx, hashToken(token))
+	if err != nil {
+		return 0, err
+	}
+
+	if challenge == nil ||
+		challenge.UsedAt != nil ||
+		challenge.Attempts >= maxChallengeAttempts ||
+		time.Now().After(challenge.ExpiresAt) {
+		return 0, apperror.NewHttpError(http.StatusUnauthorized, "invalid or expired mfa token")
+	}
+
+	factor, err := s.mfaRepo.FindByUser(ctx, challenge.UserID)
+	if err != nil {
+		return 0, err
+	}
+
+	if factor == nil || !factor.IsEnabled() {
+		return 0, apperror.NewHttpError(http.StatusUnauthorized, "invalid or expired mfa token")
+	}
+
+	if err := s.reserveAttempt(ctx, factor.UserID); err != nil {
+		return challenge.UserID, err
+	}
+
+	var ok bool
+	if len(code) == totpDigits {
+		ok, err = s.useTOTP(ctx, factor, code)
+	} else {
+		ok, err = s.mfaRepo.UseRecoveryCode(ctx, factor.UserID, hashCode(code))
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	if !ok {
+		if err := s.mfaRepo.RecordChallengeFailure(ctx, challenge.ID); err != nil {
+			return 0, err
+		}
+		return challenge.UserID, apperror.NewHttpError(http.StatusUnauthorized, "invalid mfa code")
+	}
+
+	consumed, err := s.mfaRepo.ConsumeChallenge(ctx, challenge.ID)
+	if err != nil {
+		return 0, err
+	}
+
+	if !consumed {
+		return 0, apperror.NewHttpError(http.StatusUnauthorized, "invalid or expired mfa token")
+	}
+
+	if err := s.mfaRepo.ResetFailures(ctx, factor.UserID); err != nil {
+		return 0, err
+	}
+
+	return challenge.UserID, nil
+}
+
+func (s *mfaSvc) Remove(ctx context.Context, userID int) error {
+	return s.mfaRepo.DeleteByUser(ctx, userID)
+}
+
+// VerifyTOTP checks a fresh code for sensitive operations inside an existing
+// session. Recovery codes are not accepted here.
+func (s *mfaSvc) VerifyTOTP(ctx context.Context, userID int, code string) error {
+	factor, err := s.mfaRepo.FindByUser(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if factor == nil || !factor.IsEnabled() {
+		return apperror.NewHttpError(http.StatusForbidden, "two-factor authentication is not enabled")
+	}
+
+	if err := s.reserveAttempt(ctx, userID); err != nil {
+		return err
+	}
+
+	ok, err := s.useTOTP(ctx, factor, code)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return apperror.NewHttpError(http.StatusUnauthorized, "invalid totp code")
+	}
+
+	return s.mfaRepo.ResetFailures(ctx, userID)
+}
+
+// reserveAttempt takes one attempt from the user's budget of wrong codes.
+// The budget is shared by login challenges and step-up checks, so asking for
+// a new challenge does not buy more guesses.
+func (s *mfaSvc) reserveAttempt(ctx context.Context, userID int) error {
+	now := time.Now()
+
+	ok, err := s.mfaRepo.ReserveAttempt(ctx, userID, s.lockAfter, now, now.Add(s.lockFor))
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return apperror.NewHttpError(http.StatusTooManyRequests, "too many invalid codes, two-factor authentication is temporarily locked")
+	}
+
+	return nil
+}
+
+func (s *mfaSvc) useTOTP(ctx context.Context, factor *Factor, code string) (bool, error) {
+	secret, err := decryptSecret(s.encryptionKey, factor.Secret)
+	if err != nil {
+		return false, err
+	}
+
+	step, ok := matchStep(secret, code, time.Now())
+	if !ok {
+		return false, nil
+	}
+
+	return s.mfaRepo.UseStep(ctx, factor.UserID, step)
+}
+
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	code := strings.ToLower(b32.EncodeToString(b))
+	return code[:4] + "-" + code[4:8] + "-" + code[8:12] + "-" + code[12:], nil
+}
+
+// hashCode normalizes recovery codes before hashing, so users can type them
+// with or without dashes and in any case.
+func hashCode(code string) string {
+	return hashToken(strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code)))
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func NewMFAService(
+	mfaRepo MFARepository,
+	encryptionKey, issuer string,
+	challengeTTL time.Duration,
+	lockAfter int,
+	lockFor time.Duration) MFAService {
+
+	return &mfaSvc{
+		mfaRepo:       mfaRepo,
+		encryptionKey: encryptionKey,
+		issuer:        issuer,
+		challengeTTL:  challengeTTL,
+		lockAfter:     lockAfter,
+		lockFor:       lockFor,
+	}
+}
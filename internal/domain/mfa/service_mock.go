@@ -0,0 +1,52 @@
+package mfa
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockMFAService struct {
+	mock.Mock
+}
+
+func (m *MockMFAService) Enroll(ctx context.Context, userID int, account string) (*EnrollmentDTO, error) {
+	args := m.Called(ctx, userID, account)
+	if e, ok := args.Get(0).(*EnrollmentDTO); ok {
+		return e, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockMFAService) Confirm(ctx context.Context, userID int, code string) (*RecoveryCodesDTO, error) {
+	args := m.Called(ctx, userID, code)
+	if c, ok := args.Get(0).(*RecoveryCodesDTO); ok {
+		return c, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockMFAService) IsEnabled(ctx context.Context, userID int) (bool, error) {
+	args := m.Called(ctx, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockMFAService) StartChallenge(ctx context.Context, userID int) (string, error) {
+	args := m.Called(ctx, userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockMFAService) CompleteChallenge(ctx context.Context, token, code string) (int, error) {
+	args := m.Called(ctx, token, code)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockMFAService) VerifyTOTP(ctx context.Context, userID int, code string) error {
+	args := m.Called(ctx, userID, code)
+	return args.Error(0)
+}
+
+func (m *MockMFAService) Remove(ctx context.Context, userID int) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
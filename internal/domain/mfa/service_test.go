@@ -0,0 +1,239 @@
+package mfa
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const encryptionKey = "test-key"
+
+func newService(repo MFARepository) MFAService {
+	return NewMFAService(repo, encryptionKey, "PicPay", time.Minute*5, 5, time.Minute*15)
+}
+
+func enabledFactor(t *testing.T) *Factor {
+	t.Helper()
+
+	encrypted, err := encryptSecret(encryptionKey, rfcSecret)
+	require.NoError(t, err)
+
+	enabledAt := time.Now()
+	return &Factor{UserID: 1, Secret: encrypted, EnabledAt: &enabledAt}
+}
+
+func currentCode(t *testing.T) string {
+	t.Helper()
+
+	code, err := totpCode(rfcSecret, timeStep(time.Now()))
+	require.NoError(t, err)
+	return code
+}
+
+func assertHttpCode(t *testing.T, err error, code int) {
+	t.Helper()
+
+	var httpError *apperror.HttpError
+	require.ErrorAs(t, err, &httpError)
+	assert.Equal(t, code, httpError.Code)
+}
+
+func TestMFAService_Enroll(t *testing.T) {
+	t.Run("should return conflict if already enabled", func(t *testing.T) {
+		mockRepo := new(MockMFARepository)
+		mockRepo.On("FindByUser", mock.Anything, 1).Return(enabledFactor(t), nil)
+
+		_, err := newService(mockRepo).Enroll(context.Background(), 1, "john@email.com")
+
+		assertHttpCode(t, err, http.StatusConflict)
+		mockRepo.AssertNotCalled(t, "SavePending", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should store the secret encrypted", func(t *testing.T) {
+		mockRepo := new(MockMFARepository)
+		mockRepo.On("FindByUser", mock.Anything, 1).Return(nil, nil)
+
+		var stored Factor
+		mockRepo.On("SavePending", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				stored = args.Get(1).(Factor)
+			}).Return(nil)
+
+		enrollment, err := newService(mockRepo).Enroll(context.Background(), 1, "john@email.com")
+
+		require.NoError(t, err)
+		assert.NotEqual(t, enrollment.Secret, stored.Secret)
+		assert.Contains(t, enrollment.URI, "secret="+enrollment.Secret)
+		assert.True(t, strings.HasPrefix(enrollment.QRCode, "data:image/png;base64,"))
+
+		decrypted, err := decryptSecret(encryptionKey, stored.Secret)
+		require.NoError(t, err)
+		assert.Equal(t, enrollment.Secret, decrypted)
+	})
+}
+
+func TestMFAService_Confirm(t *testing.T) {
+	t.Run("should reject a wrong code", func(t *testing.T) {
+		factor := enabledFactor(t)
+		factor.EnabledAt = nil
+
+		mockRepo := new(MockMFARepository)
+		mockRepo.On("FindByUser", mock.Anything, 1).Return(factor, nil)
+
+		_, err := newService(mockRepo).Confirm(context.Background(), 1, "000000")
+
+		assertHttpCode(t, err, http.StatusBadRequest)
+		mockRepo.AssertNotCalled(t, "Enable", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should enable 2FA and return recovery codes stored hashed", func(t *testing.T) {
+		factor := enabledFactor(t)
+		factor.EnabledAt = nil
+
+		mockRepo := new(MockMFARepository)
+		mockRepo.On("FindByUser", mock.Anything, 1).Return(factor, nil)
+
+		var hashes []string
+		mockRepo.On("Enable", mock.Anything, 1, mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				hashes = args.Get(3).([]string)
+			}).Return(nil)
+
+		codes, err := newService(mockRepo).Confirm(context.Background(), 1, currentCode(t))
+
+		require.NoError(t, err)
+		require.Len(t, codes.RecoveryCodes, recoveryCodeCount)
+		assert.Equal(t, hashCode(codes.RecoveryCodes[0]), hashes[0])
+		assert.Equal(t, hashCode(strings.ToUpper(codes.RecoveryCodes[0])), hashes[0])
+	})
+}
+
+func TestMFAService_CompleteChallenge(t *testing.T) {
+	challenge := func() *Challenge {
+		return &Challenge{ID: 9, UserID: 1, ExpiresAt: time.Now().Add(time.Minute)}
+	}
+
+	t.Run("should reject challenges with too many failures", func(t *testing.T) {
+		c := challenge()
+		c.Attempts = maxChallengeAttempts
+
+		mockRepo := new(MockMFARepository)
+		mockRepo.On("FindChallenge", mock.Anything, hashToken("token")).Return(c, nil)
+
+		_, err := newService(mockRepo).CompleteChallenge(context.Background(), "token", currentCode(t))
+
+		assertHttpCode(t, err, http.StatusUnauthorized)
+		mockRepo.AssertNotCalled(t, "UseStep", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should count a wrong code as a failed attempt", func(t *testing.T) {
+		mockRepo := new(MockMFARepository)
+		mockRepo.On("FindChallenge", mock.Anything, hashToken("token")).Return(challenge(), nil)
+		mockRepo.On("FindByUser", mock.Anything, 1).Return(enabledFactor(t), nil)
+		mockRepo.On("ReserveAttempt", mock.Anything, 1, 5, mock.Anything, mock.Anything).Return(true, nil).Once()
+		mockRepo.On("RecordChallengeFailure", mock.Anything, 9).Return(nil).Once()
+
+		_, err := newService(mockRepo).CompleteChallenge(context.Background(), "token", "000000")
+
+		assertHttpCode(t, err, http.StatusUnauthorized)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should reject a replayed code", func(t *testing.T) {
+		mockRepo := new(MockMFARepository)
+		mockRepo.On("FindChallenge", mock.Anything, hashToken("token")).Return(challenge(), nil)
+		mockRepo.On("FindByUser", mock.Anything, 1).Return(enabledFactor(t), nil)
+		mockRepo.On("ReserveAttempt", mock.Anything, 1, 5, mock.Anything, mock.Anything).Return(true, nil)
+		mockRepo.On("UseStep", mock.Anything, 1, mock.Anything).Return(false, nil)
+		mockRepo.On("RecordChallengeFailure", mock.Anything, 9).Return(nil)
+
+		_, err := newService(mockRepo).CompleteChallenge(context.Background(), "token", currentCode(t))
+
+		assertHttpCode(t, err, http.StatusUnauthorized)
+		mockRepo.AssertNotCalled(t, "ConsumeChallenge", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should accept a recovery code", func(t *testing.T) {
+		mockRepo := new(MockMFARepository)
+		mockRepo.On("FindChallenge", mock.Anything, hashToken("token")).Return(challenge(), nil)
+		mockRepo.On("FindByUser", mock.Anything, 1).Return(enabledFactor(t), nil)
+		mockRepo.On("ReserveAttempt", mock.Anything, 1, 5, mock.Anything, mock.Anything).Return(true, nil)
+		mockRepo.On("UseRecoveryCode", mock.Anything, 1, hashCode("abcd-efgh-ijkl-mnop")).Return(true, nil)
+		mockRepo.On("ConsumeChallenge", mock.Anything, 9).Return(true, nil)
+		mockRepo.On("ResetFailures", mock.Anything, 1).Return(nil).Once()
+
+		userID, err := newService(mockRepo).CompleteChallenge(context.Background(), "token", "ABCD-EFGH-IJKL-MNOP")
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, userID)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should refuse any code while the factor is locked", func(t *testing.T) {
+		mockRepo := new(MockMFARepository)
+		mockRepo.On("FindChallenge", mock.Anything, hashToken("token")).Return(challenge(), nil)
+		mockRepo.On("FindByUser", mock.Anything, 1).Return(enabledFactor(t), nil)
+		mockRepo.On("ReserveAttempt", mock.Anything, 1, 5, mock.Anything, mock.Anything).Return(false, nil)
+
+		userID, err := newService(mockRepo).CompleteChallenge(context.Background(), "token", currentCode(t))
+
+		assertHttpCode(t, err, http.StatusTooManyRequests)
+		assert.Equal(t, 1, userID)
+		mockRepo.AssertNotCalled(t, "UseStep", mock.Anything, mock.Anything, mock.Anything)
+		mockRepo.AssertNotCalled(t, "ConsumeChallenge", mock.Anything, mock.Anything)
+	})
+}
+
+func TestMFAService_VerifyTOTP(t *testing.T) {
+	t.Run("should return forbidden if 2FA is not enabled", func(t *testing.T) {
+		mockRepo := new(MockMFARepository)
+		mockRepo.On("FindByUser", mock.Anything, 1).Return(nil, nil)
+
+		err := newService(mockRepo).VerifyTOTP(context.Background(), 1, "123456")
+
+		assertHttpCode(t, err, http.StatusForbidden)
+	})
+
+	t.Run("should accept a fresh code", func(t *testing.T) {
+		mockRepo := new(MockMFARepository)
+		mockRepo.On("FindByUser", mock.Anything, 1).Return(enabledFactor(t), nil)
+		mockRepo.On("ReserveAttempt", mock.Anything, 1, 5, mock.Anything, mock.Anything).Return(true, nil)
+		mockRepo.On("UseStep", mock.Anything, 1, mock.Anything).Return(true, nil)
+		mockRepo.On("ResetFailures", mock.Anything, 1).Return(nil).Once()
+
+		err := newService(mockRepo).VerifyTOTP(context.Background(), 1, currentCode(t))
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should count a wrong code against the shared budget", func(t *testing.T) {
+		mockRepo := new(MockMFARepository)
+		mockRepo.On("FindByUser", mock.Anything, 1).Return(enabledFactor(t), nil)
+		mockRepo.On("ReserveAttempt", mock.Anything, 1, 5, mock.Anything, mock.Anything).Return(true, nil).Once()
+
+		err := newService(mockRepo).VerifyTOTP(context.Background(), 1, "000000")
+
+		assertHttpCode(t, err, http.StatusUnauthorized)
+		mockRepo.AssertExpectations(t)
+		mockRepo.AssertNotCalled(t, "ResetFailures", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should refuse step-up while the factor is locked", func(t *testing.T) {
+		mockRepo := new(MockMFARepository)
+		mockRepo.On("FindByUser", mock.Anything, 1).Return(enabledFactor(t), nil)
+		mockRepo.On("ReserveAttempt", mock.Anything, 1, 5, mock.Anything, mock.Anything).Return(false, nil)
+
+		err := newService(mockRepo).VerifyTOTP(context.Background(), 1, currentCode(t))
+
+		assertHttpCode(t, err, http.StatusTooManyRequests)
+		mockRepo.AssertNotCalled(t, "UseStep", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
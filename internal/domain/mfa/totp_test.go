@@ -0,0 +1,69 @@
+package mfa
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RFC 6238 appendix B vectors, truncated to 6 digits.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	vectors := map[int64]string{
+		59:         "287082",
+		1111111109: "081804",
+		1234567890: "005924",
+		2000000000: "279037",
+	}
+
+	for unix, expected := range vectors {
+		code, err := totpCode(rfcSecret, timeStep(time.Unix(unix, 0)))
+		require.NoError(t, err)
+		assert.Equal(t, expected, code, "t=%d", unix)
+	}
+}
+
+func TestMatchStep(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+
+	t.Run("should accept one period of drift", func(t *testing.T) {
+		step, ok := matchStep(rfcSecret, "005924", now.Add(time.Second*totpPeriod))
+		assert.True(t, ok)
+		assert.Equal(t, timeStep(now), step)
+	})
+
+	t.Run("should reject codes outside the window", func(t *testing.T) {
+		_, ok := matchStep(rfcSecret, "005924", now.Add(time.Second*totpPeriod*2))
+		assert.False(t, ok)
+	})
+
+	t.Run("should reject malformed codes", func(t *testing.T) {
+		_, ok := matchStep(rfcSecret, "5924", now)
+		assert.False(t, ok)
+	})
+}
+
+func TestKeyURI(t *testing.T) {
+	uri := keyURI("PicPay", "john@email.com", rfcSecret)
+
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/PicPay:john@email.com?"))
+	assert.Contains(t, uri, "secret="+rfcSecret)
+	assert.Contains(t, uri, "issuer=PicPay")
+}
+
+func TestSecretEncryption(t *testing.T) {
+	encrypted, err := encryptSecret("key", rfcSecret)
+	require.NoError(t, err)
+	assert.NotContains(t, encrypted, rfcSecret)
+
+	decrypted, err := decryptSecret("key", encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, rfcSecret, decrypted)
+
+	_, err = decryptSecret("other-key", encrypted)
+	assert.Error(t, err)
+}
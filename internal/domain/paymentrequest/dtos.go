@@ -0,0 +1,8 @@
+package paymentrequest
+
+type CreateDTO struct {
+	Amount      int64  `json:"amount" validate:"required,gt=0"`
+	Description string `json:"description" validate:"max=255"`
+	// ExpiresIn is in seconds; zero means the default of 24 hours.
+	ExpiresIn int `json:"expires_in" validate:"omitempty,min=60,max=2592000"`
+}
@@ -0,0 +1,14 @@
+package paymentrequest
+
+import "time"
+
+// PaymentRequest is an amount a shopkeeper asks to be paid, usually created
+// by its backend with an API key when a customer checks out.
+type PaymentRequest struct {
+	ID          int       `json:"id"`
+	PayeeID     int       `json:"payee_id"`
+	Amount      int64     `json:"amount"`
+	Description string    `json:"description"`
+	ExpiresAt   time.Time `json:"expires_at"`
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,71 @@
+package paymentrequest
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+type PaymentRequestHandler struct {
+	paymentRequestService PaymentRequestService
+}
+
+func (h *PaymentRequestHandler) Create(w http.ResponseWriter, r *http.Request) error {
+	usr := r.Context().Value(utils.UserKey).(*user.User)
+
+	var body CreateDTO
+	if err := utils.ReadJSON(w, r, &body); err != nil {
+		return apperror.NewHttpError(http.StatusBadRequest, "error parsing body request")
+	}
+
+	if err := utils.Validate.Struct(body); err != nil {
+		return apperror.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	p, err := h.paymentRequestService.Create(r.Context(), usr.ID, body)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJSON(w, http.StatusCreated, p)
+}
+
+func (h *PaymentRequestHandler) Get(w http.ResponseWriter, r *http.Request) error {
+	usr := r.Context().Value(utils.UserKey).(*user.User)
+
+	id, err := strconv.Atoi(chi.URLParam(r, "requestID"))
+	if err != nil || id <= 0 {
+		return apperror.NewHttpError(http.StatusBadRequest, "invalid requestID")
+	}
+
+	p, err := h.paymentRequestService.FindByID(r.Context(), usr.ID, id)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, p)
+}
+
+func (h *PaymentRequestHandler) List(w http.ResponseWriter, r *http.Request) error {
+	usr := r.Context().Value(utils.UserKey).(*user.User)
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+
+	requests, err := h.paymentRequestService.List(r.Context(), usr.ID, limit, offset)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, requests)
+}
+
+func NewPaymentRequestHandler(paymentRequestService PaymentRequestService) *PaymentRequestHandler {
+	return &PaymentRequestHandler{
+		paymentRequestService,
+	}
+}
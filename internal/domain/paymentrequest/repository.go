@@ -0,0 +1,112 @@
+package paymentrequest
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+type PaymentRequestRepository interface {
+	Save(ctx context.Context, p PaymentRequest) (int, error)
+	FindByID(ctx context.Context, id int) (*PaymentRequest, error)
+	ListByPayee(ctx context.Context, payeeID, limit, offset int) ([]PaymentRequest, error)
+}
+
+type paymentRequestRepo struct {
+	database     *sql.DB
+	queryTimeout time.Duration
+}
+
+func (r *paymentRequestRepo) Save(ctx context.Context, p PaymentRequest) (int, error) {
+	query := `
+		INSERT INTO payment_requests (payee_id, amount, description, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var id int
+	err := r.database.QueryRowContext(ctx, query, p.PayeeID, p.Amount, p.Description, p.ExpiresAt, p.CreatedAt).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *paymentRequestRepo) FindByID(ctx context.Context, id int) (*PaymentRequest, error) {
+	query := `
+		SELECT id, payee_id, amount, COALESCE(description, ''), expires_at, created_at
+		FROM payment_requests
+		WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var p PaymentRequest
+	err := r.database.QueryRowContext(ctx, query, id).Scan(
+		&p.ID,
+		&p.PayeeID,
+		&p.Amount,
+		&p.Description,
+		&p.ExpiresAt,
+		&p.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func (r *paymentRequestRepo) ListByPayee(ctx context.Context, payeeID, limit, offset int) ([]PaymentRequest, error) {
+	query := `
+		SELECT id, payee_id, amount, COALESCE(description, ''), expires_at, created_at
+		FROM payment_requests
+		WHERE payee_id = $1
+		ORDER BY id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	rows, err := r.database.QueryContext(ctx, query, payeeID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	requests := []PaymentRequest{}
+	for rows.Next() {
+		var p PaymentRequest
+		err := rows.Scan(
+			&p.ID,
+			&p.PayeeID,
+			&p.Amount,
+			&p.Description,
+			&p.ExpiresAt,
+			&p.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		requests = append(requests, p)
+	}
+
+	return requests, rows.Err()
+}
+
+func NewPaymentRequestRepository(database *sql.DB, qt time.Duration) PaymentRequestRepository {
+	return &paymentRequestRepo{
+		database:     database,
+		queryTimeout: qt,
+	}
+}
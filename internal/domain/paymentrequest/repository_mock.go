@@ -0,0 +1,32 @@
+package paymentrequest
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPaymentRequestRepository struct {
+	mock.Mock
+}
+
+func (m *MockPaymentRequestRepository) Save(ctx context.Context, p PaymentRequest) (int, error) {
+	args := m.Called(ctx, p)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockPaymentRequestRepository) FindByID(ctx context.Context, id int) (*PaymentRequest, error) {
+	args := m.Called(ctx, id)
+	if p, ok := args.Get(0).(*PaymentRequest); ok {
+		return p, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockPaymentRequestRepository) ListByPayee(ctx context.Context, payeeID, limit, offset int) ([]PaymentRequest, error) {
+	args := m.Called(ctx, payeeID, limit, offset)
+	if p, ok := args.Get(0).([]PaymentRequest); ok {
+		return p, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
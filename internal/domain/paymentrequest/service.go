@@ -0,0 +1,82 @@
+package paymentrequest
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
+)
+
+const (
+	defaultTTL   = 24 * time.Hour
+	maxListLimit = 100
+)
+
+type PaymentRequestService interface {
+	Create(ctx context.Context, payeeID int, dto CreateDTO) (*PaymentRequest, error)
+	FindByID(ctx context.Context, payeeID, id int) (*PaymentRequest, error)
+	List(ctx context.Context, payeeID, limit, offset int) ([]PaymentRequest, error)
+}
+
+type paymentRequestSvc struct {
+	paymentRequestRepo PaymentRequestRepository
+}
+
+func (s *paymentRequestSvc) Create(ctx context.Context, payeeID int, dto CreateDTO) (*PaymentRequest, error) {
+	ttl := defaultTTL
+	if dto.ExpiresIn > 0 {
+		ttl = time.Duration(dto.ExpiresIn) * time.Second
+	}
+
+	// postgres keeps microsecond precision
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	p := PaymentRequest{
+		PayeeID:     payeeID,
+		Amount:      dto.Amount,
+		Description: dto.Description,
+		ExpiresAt:   now.Add(ttl),
+		CreatedAt:   now,
+	}
+
+	id, err := s.paymentRequestRepo.Save(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	p.ID = id
+
+	return &p, nil
+}
+
+// FindByID only finds requests of payeeID, so ids of other shopkeepers are
+// indistinguishable from missing ones.
+func (s *paymentRequestSvc) FindByID(ctx context.Context, payeeID, id int) (*PaymentRequest, error) {
+	p, err := s.paymentRequestRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if p == nil || p.PayeeID != payeeID {
+		return nil, apperror.NewHttpError(http.StatusNotFound, "payment request not found")
+	}
+
+	return p, nil
+}
+
+func (s *paymentRequestSvc) List(ctx context.Context, payeeID, limit, offset int) ([]PaymentRequest, error) {
+	if limit <= 0 || limit > maxListLimit {
+		limit = maxListLimit
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	return s.paymentRequestRepo.ListByPayee(ctx, payeeID, limit, offset)
+}
+
+func NewPaymentRequestService(paymentRequestRepo PaymentRequestRepository) PaymentRequestService {
+	return &paymentRequestSvc{
+		paymentRequestRepo: paymentRequestRepo,
+	}
+}
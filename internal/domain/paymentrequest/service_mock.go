@@ -0,0 +1,35 @@
+package paymentrequest
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockPaymentRequestService struct {
+	mock.Mock
+}
+
+func (m *MockPaymentRequestService) Create(ctx context.Context, payeeID int, dto CreateDTO) (*PaymentRequest, error) {
+	args := m.Called(ctx, payeeID, dto)
+	if p, ok := args.Get(0).(*PaymentRequest); ok {
+		return p, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockPaymentRequestService) FindByID(ctx context.Context, payeeID, id int) (*PaymentRequest, error) {
+	args := m.Called(ctx, payeeID, id)
+	if p, ok := args.Get(0).(*PaymentRequest); ok {
+		return p, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockPaymentRequestService) List(ctx context.Context, payeeID, limit, offset int) ([]PaymentRequest, error) {
+	args := m.Called(ctx, payeeID, limit, offset)
+	if p, ok := args.Get(0).([]PaymentRequest); ok {
+		return p, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
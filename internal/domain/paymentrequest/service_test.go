@@ -0,0 +1,64 @@
+package paymentrequest
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPaymentRequestService_Create(t *testing.T) {
+	t.Run("should expire in 24 hours by default", func(t *testing.T) {
+		mockRepo := new(MockPaymentRequestRepository)
+		mockRepo.On("Save", mock.Anything, mock.MatchedBy(func(p PaymentRequest) bool {
+			return p.PayeeID == 3 && p.Amount == 1500 && p.ExpiresAt.Sub(p.CreatedAt) == defaultTTL
+		})).Return(8, nil)
+
+		p, err := NewPaymentRequestService(mockRepo).Create(context.Background(), 3, CreateDTO{Amount: 1500})
+
+		require.NoError(t, err)
+		assert.Equal(t, 8, p.ID)
+	})
+
+	t.Run("should use the requested expiry", func(t *testing.T) {
+		mockRepo := new(MockPaymentRequestRepository)
+		mockRepo.On("Save", mock.Anything, mock.MatchedBy(func(p PaymentRequest) bool {
+			return p.ExpiresAt.Sub(p.CreatedAt) == 10*time.Minute
+		})).Return(8, nil)
+
+		_, err := NewPaymentRequestService(mockRepo).Create(context.Background(), 3, CreateDTO{Amount: 1500, ExpiresIn: 600})
+
+		require.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestPaymentRequestService_FindByID(t *testing.T) {
+	t.Run("should hide requests of other shopkeepers", func(t *testing.T) {
+		mockRepo := new(MockPaymentRequestRepository)
+		mockRepo.On("FindByID", mock.Anything, 8).Return(&PaymentRequest{ID: 8, PayeeID: 4}, nil)
+
+		_, err := NewPaymentRequestService(mockRepo).FindByID(context.Background(), 3, 8)
+
+		var httpError *apperror.HttpError
+		require.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusNotFound, httpError.Code)
+	})
+}
+
+func TestPaymentRequestService_List(t *testing.T) {
+	t.Run("should cap the page size", func(t *testing.T) {
+		mockRepo := new(MockPaymentRequestRepository)
+		mockRepo.On("ListByPayee", mock.Anything, 3, maxListLimit, 0).Return([]PaymentRequest{}, nil).Once()
+
+		_, err := NewPaymentRequestService(mockRepo).List(context.Background(), 3, 1000, -1)
+
+		require.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,25 @@
+package privacy
+
+import (
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/apikey"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/kyc"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/transaction"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/wallet"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/webhook"
+)
+
+// ExportDTO is everything stored about a user, as handed over on an LGPD
+// access request.
+type ExportDTO struct {
+	ExportedAt   time.Time                 `json:"exported_at"`
+	Profile      *user.User                `json:"profile"`
+	MFAEnabled   bool                      `json:"mfa_enabled"`
+	Wallet       *wallet.Wallet            `json:"wallet"`
+	Transactions []transaction.Transaction `json:"transactions"`
+	APIKeys      []apikey.APIKey           `json:"api_keys"`
+	Webhooks     []webhook.Endpoint        `json:"webhooks"`
+	KYCDocuments []kyc.Document            `json:"kyc_documents"`
+}
@@ -0,0 +1,97 @@
+package privacy
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/utils"
+)
+
+type PrivacyHandler struct {
+	privacyService PrivacyService
+}
+
+// Export sends the user's data as a JSON download, or with ?format=zip as an
+// archive holding one JSON file per kind of data.
+func (h *PrivacyHandler) Export(w http.ResponseWriter, r *http.Request) error {
+	usr := r.Context().Value(utils.UserKey).(*user.User)
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "zip" {
+		return apperror.NewHttpError(http.StatusBadRequest, "format must be json or zip")
+	}
+
+	export, err := h.privacyService.Export(r.Context(), usr)
+	if err != nil {
+		return err
+	}
+
+	filename := fmt.Sprintf("picpay-export-%d-%s.%s", usr.ID, export.ExportedAt.Format("20060102"), format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	w.Header().Set("Cache-Control", "no-store")
+
+	if format == "json" {
+		return utils.WriteJSON(w, http.StatusOK, export)
+	}
+
+	// built in memory so a failure can still be reported as an error response
+	var buf bytes.Buffer
+	if err := writeZip(&buf, export); err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.WriteHeader(http.StatusOK)
+
+	_, err = w.Write(buf.Bytes())
+	return err
+}
+
+func writeZip(w io.Writer, export *ExportDTO) error {
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", map[string]any{
+			"exported_at": export.ExportedAt,
+			"user":        export.Profile,
+			"mfa_enabled": export.MFAEnabled,
+		}},
+		{"wallet.json", export.Wallet},
+		{"transactions.json", export.Transactions},
+		{"api_keys.json", export.APIKeys},
+		{"webhooks.json", export.Webhooks},
+		{"kyc_documents.json", export.KYCDocuments},
+	}
+
+	zw := zip.NewWriter(w)
+	for _, file := range files {
+		f, err := zw.Create(file.name)
+		if err != nil {
+			return err
+		}
+
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(file.data); err != nil {
+			return err
+		}
+	}
+
+	return zw.Close()
+}
+
+func NewPrivacyHandler(privacyService PrivacyService) *PrivacyHandler {
+	return &PrivacyHandler{
+		privacyService,
+	}
+}
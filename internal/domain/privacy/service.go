@@ -0,0 +1,116 @@
+package privacy
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/apikey"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/audit"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/kyc"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/mfa"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/transaction"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/wallet"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/webhook"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
+)
+
+// exportPageSize is how many transactions are read per query while building
+// an export.
+const exportPageSize = 100
+
+type PrivacyService interface {
+	Export(ctx context.Context, usr *user.User) (*ExportDTO, error)
+}
+
+type privacySvc struct {
+	wallService        wallet.WalletService
+	transactionService transaction.TransactionService
+	mfaService         mfa.MFAService
+	apiKeyService      apikey.APIKeyService
+	webhookService     webhook.WebhookService
+	kycService         kyc.KYCService
+	auditService       audit.AuditService
+}
+
+func (s *privacySvc) Export(ctx context.Context, usr *user.User) (*ExportDTO, error) {
+	export := &ExportDTO{
+		ExportedAt: time.Now(),
+		Profile:    usr,
+	}
+
+	mfaEnabled, err := s.mfaService.IsEnabled(ctx, usr.ID)
+	if err != nil {
+		return nil, err
+	}
+	export.MFAEnabled = mfaEnabled
+
+	// admins have no wallet
+	wall, err := s.wallService.FindByUserID(ctx, usr.ID)
+	if err != nil {
+		var httpError *apperror.HttpError
+		if !errors.As(err, &httpError) || httpError.Code != http.StatusNotFound {
+			return nil, err
+		}
+	}
+	export.Wallet = wall
+
+	export.Transactions = []transaction.Transaction{}
+	for offset := 0; ; offset += exportPageSize {
+		page, err := s.transactionService.ListByUser(ctx, usr.ID, exportPageSize, offset)
+		if err != nil {
+			return nil, err
+		}
+
+		export.Transactions = append(export.Transactions, page...)
+		if len(page) < exportPageSize {
+			break
+		}
+	}
+
+	if export.APIKeys, err = s.apiKeyService.List(ctx, usr.ID); err != nil {
+		return nil, err
+	}
+
+	if export.Webhooks, err = s.webhookService.List(ctx, usr.ID); err != nil {
+		return nil, err
+	}
+
+	if export.KYCDocuments, err = s.kycService.ListDocuments(ctx, usr.ID); err != nil {
+		return nil, err
+	}
+
+	err = s.auditService.Record(ctx, audit.RecordDTO{
+		ActorID:    &usr.ID,
+		Action:     audit.UserDataExport,
+		TargetType: "user",
+		TargetID:   &usr.ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return export, nil
+}
+
+func NewPrivacyService(
+	wSvc wallet.WalletService,
+	tSvc transaction.TransactionService,
+	mfaSvc mfa.MFAService,
+	keySvc apikey.APIKeyService,
+	hookSvc webhook.WebhookService,
+	kycSvc kyc.KYCService,
+	audSvc audit.AuditService) PrivacyService {
+
+	return &privacySvc{
+		wallService:        wSvc,
+		transactionService: tSvc,
+		mfaService:         mfaSvc,
+		apiKeyService:      keySvc,
+		webhookService:     hookSvc,
+		kycService:         kycSvc,
+		auditService:       audSvc,
+	}
+}
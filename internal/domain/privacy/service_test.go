@@ -0,0 +1,99 @@
+package privacy
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/apikey"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/audit"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/kyc"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/mfa"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/transaction"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/wallet"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/webhook"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPrivacyService_Export(t *testing.T) {
+	usr := &user.User{ID: 1, Fullname: "John Doe", Email: "john@email.com"}
+
+	t.Run("should gather every page of transactions and record the export", func(t *testing.T) {
+		fullPage := make([]transaction.Transaction, exportPageSize)
+		for i := range fullPage {
+			fullPage[i] = transaction.Transaction{ID: i + 1}
+		}
+
+		walletServiceMock := new(wallet.MockWalletService)
+		walletServiceMock.On("FindByUserID", mock.Anything, 1).Return(&wallet.Wallet{UserID: 1, Balance: 0}, nil)
+
+		transactionServiceMock := new(transaction.MockTransactionService)
+		transactionServiceMock.On("ListByUser", mock.Anything, 1, exportPageSize, 0).Return(fullPage, nil).Once()
+		transactionServiceMock.On("ListByUser", mock.Anything, 1, exportPageSize, exportPageSize).
+			Return([]transaction.Transaction{{ID: exportPageSize + 1}}, nil).Once()
+
+		mfaServiceMock := new(mfa.MockMFAService)
+		mfaServiceMock.On("IsEnabled", mock.Anything, 1).Return(true, nil)
+
+		apiKeyServiceMock := new(apikey.MockAPIKeyService)
+		apiKeyServiceMock.On("List", mock.Anything, 1).Return([]apikey.APIKey{{ID: 3}}, nil)
+
+		webhookServiceMock := new(webhook.MockWebhookService)
+		webhookServiceMock.On("List", mock.Anything, 1).Return([]webhook.Endpoint{{ID: 4}}, nil)
+		kycServiceMock := new(kyc.MockKYCService)
+		kycServiceMock.On("ListDocuments", mock.Anything, 1).Return([]kyc.Document{{ID: 5}}, nil)
+
+		auditServiceMock := new(audit.MockAuditService)
+		auditServiceMock.On("Record", mock.Anything, mock.MatchedBy(func(r audit.RecordDTO) bool {
+			return r.Action == audit.UserDataExport && *r.TargetID == 1
+		})).Return(nil).Once()
+
+		service := NewPrivacyService(walletServiceMock, transactionServiceMock, mfaServiceMock, apiKeyServiceMock, webhookServiceMock, kycServiceMock, auditServiceMock)
+
+		export, err := service.Export(context.Background(), usr)
+
+		assert.NoError(t, err)
+		assert.Equal(t, usr, export.Profile)
+		assert.True(t, export.MFAEnabled)
+		assert.Len(t, export.Transactions, exportPageSize+1)
+		assert.Len(t, export.APIKeys, 1)
+		assert.Len(t, export.Webhooks, 1)
+		assert.Len(t, export.KYCDocuments, 1)
+		transactionServiceMock.AssertExpectations(t)
+		auditServiceMock.AssertExpectations(t)
+	})
+
+	t.Run("should export users without a wallet", func(t *testing.T) {
+		walletServiceMock := new(wallet.MockWalletService)
+		walletServiceMock.On("FindByUserID", mock.Anything, 1).
+			Return(nil, apperror.NewHttpError(http.StatusNotFound, "wallet not found"))
+
+		transactionServiceMock := new(transaction.MockTransactionService)
+		transactionServiceMock.On("ListByUser", mock.Anything, 1, exportPageSize, 0).Return([]transaction.Transaction{}, nil)
+
+		mfaServiceMock := new(mfa.MockMFAService)
+		mfaServiceMock.On("IsEnabled", mock.Anything, 1).Return(false, nil)
+
+		apiKeyServiceMock := new(apikey.MockAPIKeyService)
+		apiKeyServiceMock.On("List", mock.Anything, 1).Return([]apikey.APIKey{}, nil)
+
+		webhookServiceMock := new(webhook.MockWebhookService)
+		webhookServiceMock.On("List", mock.Anything, 1).Return([]webhook.Endpoint{}, nil)
+		kycServiceMock := new(kyc.MockKYCService)
+		kycServiceMock.On("ListDocuments", mock.Anything, 1).Return([]kyc.Document{}, nil)
+
+		auditServiceMock := new(audit.MockAuditService)
+		auditServiceMock.On("Record", mock.Anything, mock.Anything).Return(nil)
+
+		service := NewPrivacyService(walletServiceMock, transactionServiceMock, mfaServiceMock, apiKeyServiceMock, webhookServiceMock, kycServiceMock, auditServiceMock)
+
+		export, err := service.Export(context.Background(), usr)
+
+		assert.NoError(t, err)
+		assert.Nil(t, export.Wallet)
+		assert.Empty(t, export.Transactions)
+	})
+}
@@ -0,0 +1,12 @@
+package transaction
+
+type TransferDTO struct {
+	PayeeID     int     `json:"payee_id" validate:"required,gt=0"`
+	Amount      int64   `json:"amount" validate:"required,gt=0"`
+	Description string  `json:"description" validate:"max=255"`
+	TOTPCode    *string `json:"totp_code,omitempty" validate:"omitempty,len=6,numeric"`
+}
+
+type RejectPendingDTO struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
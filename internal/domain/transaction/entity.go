@@ -9,6 +9,7 @@ type TransactionType string
 const (
 	PaymentReceived TransactionType = "payment_received"
 	PaymentSent     TransactionType = "payment_sent"
+	Refund          TransactionType = "refund"
 )
 
 type Transaction struct {
@@ -18,6 +19,55 @@ type Transaction struct {
 	Type        TransactionType `json:"type"`
 	Amount      int64           `json:"amount"`
 	Description string          `json:"description"`
+	RefundOf    *int            `json:"refund_of,omitempty"`
+	RefundedAt  *time.Time      `json:"refunded_at,omitempty"`
 	UpdatedAt   time.Time       `json:"updated_at"`
 	CreatedAt   time.Time       `json:"created_at"`
 }
+
+type ReviewStatus string
+
+const (
+	ReviewPending  ReviewStatus = "pending"
+	ReviewApproved ReviewStatus = "approved"
+	ReviewRejected ReviewStatus = "rejected"
+)
+
+// PendingTransfer is a transfer the risk engine held for review. No money
+// moves until an admin approves it.
+type PendingTransfer struct {
+	ID              int          `json:"id"`
+	PayerID         int          `json:"payer_id"`
+	PayeeID         int          `json:"payee_id"`
+	Amount          int64        `json:"amount"`
+	Description     string       `json:"description"`
+	Reasons         []string     `json:"reasons"`
+	Status          ReviewStatus `json:"status"`
+	TransactionID   *int         `json:"transaction_id,omitempty"`
+	RejectionReason *string      `json:"rejection_reason,omitempty"`
+	ReviewedBy      *int         `json:"reviewed_by,omitempty"`
+	ReviewedAt      *time.Time   `json:"reviewed_at,omitempty"`
+	CreatedAt       time.Time    `json:"created_at"`
+	// DailyLimit is the payer's daily limit when the transfer was held. Zero
+	// means no cap.
+	DailyLimit int64 `json:"-"`
+}
+
+// WalletState is what the audit log keeps of a wallet around a transfer.
+type WalletState struct {
+	UserID  int   `json:"user_id"`
+	Active  bool  `json:"active"`
+	Balance int64 `json:"balance"`
+}
+
+type TransferWallets struct {
+	Payer WalletState `json:"payer"`
+	Payee WalletState `json:"payee"`
+}
+
+// Moved returns the wallets after amount went from the payer to the payee.
+func (w TransferWallets) Moved(amount int64) TransferWallets {
+	w.Payer.Balance -= amount
+	w.Payee.Balance += amount
+	return w
+}
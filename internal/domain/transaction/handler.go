@@ -0,0 +1,68 @@
+package transaction
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/utils"
+)
+
+type TransactionHandler struct {
+	transactionService TransactionService
+}
+
+func (h *TransactionHandler) Transfer(w http.ResponseWriter, r *http.Request) error {
+	usr := r.Context().Value(utils.UserKey).(*user.User)
+
+	var body TransferDTO
+	if err := utils.ReadJSON(w, r, &body); err != nil {
+		return apperror.NewHttpError(http.StatusBadRequest, "error parsing body request")
+	}
+
+	if err := utils.Validate.Struct(body); err != nil {
+		return apperror.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	transaction, pending, err := h.transactionService.Transfer(r.Context(), usr, body)
+	if err != nil {
+		return err
+	}
+
+	if pending != nil {
+		return utils.WriteJSON(w, http.StatusAccepted, pending)
+	}
+
+	return utils.WriteJSON(w, http.StatusCreated, transaction)
+}
+
+func (h *TransactionHandler) List(w http.ResponseWriter, r *http.Request) error {
+	usr := r.Context().Value(utils.UserKey).(*user.User)
+
+	transactions, err := h.transactionService.ListByUser(
+		r.Context(),
+		usr.ID,
+		queryInt(r, "limit"),
+		queryInt(r, "offset"),
+	)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, transactions)
+}
+
+func queryInt(r *http.Request, key string) int {
+	val, err := strconv.Atoi(r.URL.Query().Get(key))
+	if err != nil {
+		return 0
+	}
+	return val
+}
+
+func NewTransactionHandler(transactionService TransactionService) *TransactionHandler {
+	return &TransactionHandler{
+		transactionService,
+	}
+}
@@ -0,0 +1,66 @@
+package transaction
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/metrics"
+)
+
+// Transfer outcomes. A held transfer is counted again once reviewed.
+const (
+	outcomeCompleted      = "completed"
+	outcomeHeld           = "held"
+	outcomeDenied         = "denied"
+	outcomeRejected       = "rejected"
+	outcomeFailed         = "failed"
+	outcomeApproved       = "approved"
+	outcomeReviewRejected = "review_rejected"
+)
+
+var (
+	transfersTotal = metrics.NewCounter(
+		"picpay_transfers_total",
+		"Transfers by outcome. Held transfers are counted again as approved or review_rejected.",
+		"outcome",
+	)
+	transferAmount = metrics.NewCounter(
+		"picpay_transfer_amount_cents_total",
+		"Sum of transfer amounts in cents by outcome.",
+		"outcome",
+	)
+	riskDuration = metrics.NewHistogram(
+		"picpay_risk_evaluation_duration_seconds",
+		"Time the risk evaluator took to authorize a transfer.",
+		metrics.DefaultBuckets,
+	)
+	riskErrors = metrics.NewCounter(
+		"picpay_risk_evaluation_errors_total",
+		"Risk evaluations that failed, failing the transfer with them.",
+	)
+)
+
+func recordTransfer(outcome string, amount int64) {
+	transfersTotal.Inc(outcome)
+	transferAmount.Add(float64(amount), outcome)
+}
+
+// transferOutcome tells refusals, which are the client's doing, apart from
+// failures.
+func transferOutcome(t *Transaction, pending *PendingTransfer, err error) string {
+	switch {
+	case err == nil && pending != nil:
+		return outcomeHeld
+	case err == nil && t != nil:
+		return outcomeCompleted
+	case errors.Is(err, errTransferDeclined):
+		return outcomeDenied
+	}
+
+	var httpErr *apperror.HttpError
+	if errors.As(err, &httpErr) && httpErr.Code < http.StatusInternalServerError {
+		return outcomeRejected
+	}
+	return outcomeFailed
+}
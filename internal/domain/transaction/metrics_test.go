@@ -0,0 +1,32 @@
+package transaction
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransferOutcome(t *testing.T) {
+	tests := []struct {
+		name    string
+		t       *Transaction
+		pending *PendingTransfer
+		err     error
+		want    string
+	}{
+		{"Completed", &Transaction{ID: 1}, nil, nil, outcomeCompleted},
+		{"Held For Review", nil, &PendingTransfer{ID: 1}, nil, outcomeHeld},
+		{"Denied By Risk Rules", nil, nil, errTransferDeclined, outcomeDenied},
+		{"Refused Request", nil, nil, apperror.NewHttpError(http.StatusUnprocessableEntity, "insufficient balance"), outcomeRejected},
+		{"Server Failure", nil, nil, errors.New("connection reset"), outcomeFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, transferOutcome(tt.t, tt.pending, tt.err))
+		})
+	}
+}
@@ -0,0 +1,536 @@
+package transaction
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/db"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrAlreadyRefunded     = errors.New("transaction already refunded")
+	ErrInsufficientBalance = errors.New("insufficient balance")
+	ErrWalletNotFound      = errors.New("wallet not found")
+	ErrWalletInactive      = errors.New("wallet inactive")
+	ErrDailyLimitExceeded  = errors.New("daily transfer limit exceeded")
+	ErrNotPending          = errors.New("transfer is not pending review")
+)
+
+// TransactionRepository joins the transaction on ctx, if any, in every method
+// that moves money or reads back what was just written.
+type TransactionRepository interface {
+	FindByID(ctx context.Context, id int) (*Transaction, error)
+	ListByUser(ctx context.Context, userID, limit, offset int) ([]Transaction, error)
+	Refund(ctx context.Context, t Transaction) (int, error)
+	// Transfer moves t.Amount from payer to payee. A positive dailyLimit caps
+	// what the payer may send over the last 24 hours, this transfer included.
+	// The wallets are returned as they were read under lock, before the move.
+	Transfer(ctx context.Context, t Transaction, dailyLimit int64) (int, TransferWallets, error)
+	FindWallets(ctx context.Context, payerID, payeeID int) (TransferWallets, error)
+	CountPayeesSince(ctx context.Context, payerID int, since time.Time) (int, error)
+	HasPaid(ctx context.Context, payerID, payeeID int) (bool, error)
+	Hold(ctx context.Context, p PendingTransfer) (int, error)
+	FindPending(ctx context.Context, id int) (*PendingTransfer, error)
+	ListPending(ctx context.Context, limit, offset int) ([]PendingTransfer, error)
+	// ApprovePending settles a held transfer and returns the id of the new
+	// transaction. The daily limit stored with it is applied as in Transfer,
+	// and the wallets are returned as they were read under lock.
+	ApprovePending(ctx context.Context, id, reviewerID int, reviewedAt time.Time) (int, TransferWallets, error)
+	RejectPending(ctx context.Context, id, reviewerID int, reason string, reviewedAt time.Time) (bool, error)
+}
+
+type transactionRepo struct {
+	database     *sql.DB
+	queryTimeout time.Duration
+}
+
+const pendingColumns = `id, payer_id, payee_id, amount, COALESCE(description, ''), reasons, status,
+	transaction_id, rejection_reason, reviewed_by, reviewed_at, created_at, daily_limit`
+
+func (r *transactionRepo) FindByID(ctx context.Context, id int) (*Transaction, error) {
+	query := `
+		SELECT id, payer_id, payee_id, type, amount, COALESCE(description, ''), refund_of, refunded_at, updated_at, created_at
+		FROM transactions
+		WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var t Transaction
+	err := db.Conn(ctx, r.database).QueryRowContext(ctx, query, id).Scan(
+		&t.ID,
+		&t.PayerID,
+		&t.PayeeID,
+		&t.Type,
+		&t.Amount,
+		&t.Description,
+		&t.RefundOf,
+		&t.RefundedAt,
+		&t.UpdatedAt,
+		&t.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+func (r *transactionRepo) ListByUser(ctx context.Context, userID, limit, offset int) ([]Transaction, error) {
+	query := `
+		SELECT id, payer_id, payee_id, type, amount, COALESCE(description, ''), refund_of, refunded_at, updated_at, created_at
+		FROM transactions
+		WHERE payer_id = $1 OR payee_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	rows, err := r.database.QueryContext(ctx, query, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	transactions := []Transaction{}
+	for rows.Next() {
+		var t Transaction
+		err := rows.Scan(
+			&t.ID,
+			&t.PayerID,
+			&t.PayeeID,
+			&t.Type,
+			&t.Amount,
+			&t.Description,
+			&t.RefundOf,
+			&t.RefundedAt,
+			&t.UpdatedAt,
+			&t.CreatedAt,
+		)
+		if err != nil {
+			return nil, err
+		}
+		transactions = append(transactions, t)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return transactions, nil
+}
+
+// Refund moves the amount of t back from the payee to the payer and records
+// a refund transaction pointing to t, all inside a single database transaction.
+func (r *transactionRepo) Refund(ctx context.Context, t Transaction) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var refundID int
+	err := db.RunInTx(ctx, r.database, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, `
+			UPDATE transactions
+			SET refunded_at = NOW(), updated_at = NOW()
+			WHERE id = $1 AND refunded_at IS NULL AND type <> 'refund'
+		`, t.ID)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrAlreadyRefunded
+		}
+
+		res, err = tx.ExecContext(ctx, `
+			UPDATE wallets
+			SET balance = balance - $1, updated_at = NOW()
+			WHERE user_id = $2 AND balance >= $1
+		`, t.Amount, t.PayeeID)
+		if err != nil {
+			return err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			return ErrInsufficientBalance
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE wallets
+			SET balance = balance + $1, updated_at = NOW()
+			WHERE user_id = $2
+		`, t.Amount, t.PayerID)
+		if err != nil {
+			return err
+		}
+
+		return tx.QueryRowContext(ctx, `
+			INSERT INTO transactions (payer_id, payee_id, type, amount, description, refund_of)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id
+		`, t.PayeeID, t.PayerID, Refund, t.Amount, t.Description, t.ID).Scan(&refundID)
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return refundID, nil
+}
+
+// Transfer debits the payer, credits the payee and records t in a single
+// database transaction. Both wallets are locked in user id order so that
+// concurrent transfers between the same users cannot deadlock.
+func (r *transactionRepo) Transfer(ctx context.Context, t Transaction, dailyLimit int64) (int, TransferWallets, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var id int
+	var wallets TransferWallets
+	err := db.RunInTx(ctx, r.database, func(tx *sql.Tx) error {
+		var err error
+		id, wallets, err = transfer(ctx, tx, t, dailyLimit)
+		return err
+	})
+	if err != nil {
+		return 0, TransferWallets{}, err
+	}
+
+	return id, wallets, nil
+}
+
+func (r *transactionRepo) FindWallets(ctx context.Context, payerID, payeeID int) (TransferWallets, error) {
+	query := `
+		SELECT user_id, active, balance
+		FROM wallets
+		WHERE user_id IN ($1, $2)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	rows, err := db.Conn(ctx, r.database).QueryContext(ctx, query, payerID, payeeID)
+	if err != nil {
+		return TransferWallets{}, err
+	}
+
+	return scanWallets(rows, payerID, payeeID)
+}
+
+func scanWallets(rows *sql.Rows, payerID, payeeID int) (TransferWallets, error) {
+	defer rows.Close()
+
+	wallets := make(map[int]WalletState, 2)
+	for rows.Next() {
+		var w WalletState
+		if err := rows.Scan(&w.UserID, &w.Active, &w.Balance); err != nil {
+			return TransferWallets{}, err
+		}
+		wallets[w.UserID] = w
+	}
+	if err := rows.Err(); err != nil {
+		return TransferWallets{}, err
+	}
+
+	payer, okPayer := wallets[payerID]
+	payee, okPayee := wallets[payeeID]
+	if !okPayer || !okPayee {
+		return TransferWallets{}, ErrWalletNotFound
+	}
+
+	return TransferWallets{Payer: payer, Payee: payee}, nil
+}
+
+func transfer(ctx context.Context, tx *sql.Tx, t Transaction, dailyLimit int64) (int, TransferWallets, error) {
+	rows, err := tx.QueryContext(ctx, `
+		SELECT user_id, active, balance
+		FROM wallets
+		WHERE user_id IN ($1, $2)
+		ORDER BY user_id
+		FOR UPDATE
+	`, t.PayerID, t.PayeeID)
+	if err != nil {
+		return 0, TransferWallets{}, err
+	}
+
+	wallets, err := scanWallets(rows, t.PayerID, t.PayeeID)
+	if err != nil {
+		return 0, TransferWallets{}, err
+	}
+	if !wallets.Payer.Active || !wallets.Payee.Active {
+		return 0, TransferWallets{}, ErrWalletInactive
+	}
+	if wallets.Payer.Balance < t.Amount {
+		return 0, TransferWallets{}, ErrInsufficientBalance
+	}
+
+	// the payer's wallet is locked, so concurrent transfers cannot both
+	// slip under the limit
+	if dailyLimit > 0 {
+		var sent int64
+		err = tx.QueryRowContext(ctx, `
+			SELECT COALESCE(SUM(amount), 0)
+			FROM transactions
+			WHERE payer_id = $1 AND type = 'payment_sent' AND created_at > NOW() - INTERVAL '24 hours'
+		`, t.PayerID).Scan(&sent)
+		if err != nil {
+			return 0, TransferWallets{}, err
+		}
+		if sent+t.Amount > dailyLimit {
+			return 0, TransferWallets{}, ErrDailyLimitExceeded
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE wallets
+		SET balance = balance - $1, updated_at = NOW()
+		WHERE user_id = $2
+	`, t.Amount, t.PayerID)
+	if err != nil {
+		return 0, TransferWallets{}, err
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE wallets
+		SET balance = balance + $1, updated_at = NOW()
+		WHERE user_id = $2
+	`, t.Amount, t.PayeeID)
+	if err != nil {
+		return 0, TransferWallets{}, err
+	}
+
+	var id int
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO transactions (payer_id, payee_id, type, amount, description)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id
+	`, t.PayerID, t.PayeeID, t.Type, t.Amount, t.Description).Scan(&id)
+	if err != nil {
+		return 0, TransferWallets{}, err
+	}
+
+	return id, wallets, nil
+}
+
+func (r *transactionRepo) CountPayeesSince(ctx context.Context, payerID int, since time.Time) (int, error) {
+	query := `
+		SELECT COUNT(DISTINCT payee_id)
+		FROM transactions
+		WHERE payer_id = $1 AND type = 'payment_sent' AND created_at >= $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var count int
+	err := r.database.QueryRowContext(ctx, query, payerID, since).Scan(&count)
+	return count, err
+}
+
+func (r *transactionRepo) HasPaid(ctx context.Context, payerID, payeeID int) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1
+			FROM transactions
+			WHERE payer_id = $1 AND payee_id = $2 AND type = 'payment_sent'
+		)
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var paid bool
+	err := r.database.QueryRowContext(ctx, query, payerID, payeeID).Scan(&paid)
+	return paid, err
+}
+
+func (r *transactionRepo) Hold(ctx context.Context, p PendingTransfer) (int, error) {
+	query := `
+		INSERT INTO pending_transfers (payer_id, payee_id, amount, description, reasons, status, created_at, daily_limit)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var id int
+	err := db.Conn(ctx, r.database).QueryRowContext(
+		ctx,
+		query,
+		p.PayerID, p.PayeeID, p.Amount, p.Description, pq.Array(p.Reasons), p.Status, p.CreatedAt, p.DailyLimit,
+	).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *transactionRepo) FindPending(ctx context.Context, id int) (*PendingTransfer, error) {
+	query := `
+		SELECT ` + pendingColumns + `
+		FROM pending_transfers
+		WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	p, err := scanPending(db.Conn(ctx, r.database).QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return p, nil
+}
+
+func (r *transactionRepo) ListPending(ctx context.Context, limit, offset int) ([]PendingTransfer, error) {
+	query := `
+		SELECT ` + pendingColumns + `
+		FROM pending_transfers
+		WHERE status = 'pending'
+		ORDER BY created_at, id
+		LIMIT $1 OFFSET $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	rows, err := r.database.QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	pending := []PendingTransfer{}
+	for rows.Next() {
+		p, err := scanPending(rows)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, *p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return pending, nil
+}
+
+// ApprovePending locks the held transfer so two reviewers cannot settle it
+// twice, then moves the money the same way Transfer does, daily limit
+// included, so holding a transfer is no way around the payer's cap. If the
+// transfer fails, for example because the balance is gone, it stays pending.
+func (r *transactionRepo) ApprovePending(ctx context.Context, id, reviewerID int, reviewedAt time.Time) (int, TransferWallets, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var transactionID int
+	var wallets TransferWallets
+	err := db.RunInTx(ctx, r.database, func(tx *sql.Tx) error {
+		var t Transaction
+		var status ReviewStatus
+		var dailyLimit int64
+		err := tx.QueryRowContext(ctx, `
+			SELECT payer_id, payee_id, amount, COALESCE(description, ''), status, daily_limit
+			FROM pending_transfers
+			WHERE id = $1
+			FOR UPDATE
+		`, id).Scan(&t.PayerID, &t.PayeeID, &t.Amount, &t.Description, &status, &dailyLimit)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrNotPending
+			}
+			return err
+		}
+		if status != ReviewPending {
+			return ErrNotPending
+		}
+
+		t.Type = PaymentSent
+		transactionID, wallets, err = transfer(ctx, tx, t, dailyLimit)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE pending_transfers
+			SET status = 'approved', transaction_id = $2, reviewed_by = $3, reviewed_at = $4
+			WHERE id = $1
+		`, id, transactionID, reviewerID, reviewedAt)
+		return err
+	})
+	if err != nil {
+		return 0, TransferWallets{}, err
+	}
+
+	return transactionID, wallets, nil
+}
+
+func (r *transactionRepo) RejectPending(ctx context.Context, id, reviewerID int, reason string, reviewedAt time.Time) (bool, error) {
+	query := `
+		UPDATE pending_transfers
+		SET status = 'rejected', rejection_reason = $2, reviewed_by = $3, reviewed_at = $4
+		WHERE id = $1 AND status = 'pending'
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	res, err := db.Conn(ctx, r.database).ExecContext(ctx, query, id, reason, reviewerID, reviewedAt)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanPending(row rowScanner) (*PendingTransfer, error) {
+	var p PendingTransfer
+	err := row.Scan(
+		&p.ID,
+		&p.PayerID,
+		&p.PayeeID,
+		&p.Amount,
+		&p.Description,
+		pq.Array(&p.Reasons),
+		&p.Status,
+		&p.TransactionID,
+		&p.RejectionReason,
+		&p.ReviewedBy,
+		&p.ReviewedAt,
+		&p.CreatedAt,
+		&p.DailyLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func NewTransactionRepository(database *sql.DB, qt time.Duration) TransactionRepository {
+	return &transactionRepo{
+		database:     database,
+		queryTimeout: qt,
+	}
+}
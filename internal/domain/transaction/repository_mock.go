@@ -0,0 +1,85 @@
+package transaction
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockTransactionRepository struct {
+	mock.Mock
+}
+
+func (m *MockTransactionRepository) FindByID(ctx context.Context, id int) (*Transaction, error) {
+	args := m.Called(ctx, id)
+	if t, ok := args.Get(0).(*Transaction); ok {
+		return t, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTransactionRepository) ListByUser(ctx context.Context, userID, limit, offset int) ([]Transaction, error) {
+	args := m.Called(ctx, userID, limit, offset)
+	if t, ok := args.Get(0).([]Transaction); ok {
+		return t, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTransactionRepository) Refund(ctx context.Context, t Transaction) (int, error) {
+	args := m.Called(ctx, t)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockTransactionRepository) Transfer(ctx context.Context, t Transaction, dailyLimit int64) (int, TransferWallets, error) {
+	args := m.Called(ctx, t, dailyLimit)
+	return args.Int(0), args.Get(1).(TransferWallets), args.Error(2)
+}
+
+func (m *MockTransactionRepository) FindWallets(ctx context.Context, payerID, payeeID int) (TransferWallets, error) {
+	args := m.Called(ctx, payerID, payeeID)
+	return args.Get(0).(TransferWallets), args.Error(1)
+}
+
+func (m *MockTransactionRepository) CountPayeesSince(ctx context.Context, payerID int, since time.Time) (int, error) {
+	args := m.Called(ctx, payerID, since)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockTransactionRepository) HasPaid(ctx context.Context, payerID, payeeID int) (bool, error) {
+	args := m.Called(ctx, payerID, payeeID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockTransactionRepository) Hold(ctx context.Context, p PendingTransfer) (int, error) {
+	args := m.Called(ctx, p)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockTransactionRepository) FindPending(ctx context.Context, id int) (*PendingTransfer, error) {
+	args := m.Called(ctx, id)
+	if p, ok := args.Get(0).(*PendingTransfer); ok {
+		return p, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTransactionRepository) ListPending(ctx context.Context, limit, offset int) ([]PendingTransfer, error) {
+	args := m.Called(ctx, limit, offset)
+	if p, ok := args.Get(0).([]PendingTransfer); ok {
+		return p, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockTransactionRepository) ApprovePending(ctx context.Context, id, reviewerID int, reviewedAt time.Time) (int, TransferWallets, error) {
+	args := m.Called(ctx, id, reviewerID, reviewedAt)
+	wallets, _ := args.Get(1).(TransferWallets)
+	return args.Int(0), wallets, args.Error(2)
+}
+
+func (m *MockTransactionRepository) RejectPending(ctx context.Context, id, reviewerID int, reason string, reviewedAt time.Time) (bool, error) {
+	args := m.Called(ctx, id, reviewerID, reason, reviewedAt)
+	return args.Bool(0), args.Error(1)
+}
@@ -0,0 +1,54 @@
+package transaction
+
+import (
+	"context"
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
+)
+
+// RiskOutcome is what the risk engine decides for a transfer.
+type RiskOutcome string
+
+const (
+	RiskAllow  RiskOutcome = "allow"
+	RiskReview RiskOutcome = "review"
+	RiskDeny   RiskOutcome = "deny"
+)
+
+func (o RiskOutcome) severity() int {
+	switch o {
+	case RiskDeny:
+		return 2
+	case RiskReview:
+		return 1
+	}
+	return 0
+}
+
+type RiskInput struct {
+	Payer   *user.User
+	PayeeID int
+	Amount  int64
+	IP      string
+	At      time.Time
+}
+
+// RiskDecision holds the strictest outcome of the rules that fired and the
+// names of those rules.
+type RiskDecision struct {
+	Outcome RiskOutcome
+	Reasons []string
+}
+
+// RiskEvaluator is consulted before a transfer settles. Denied transfers are
+// refused and reviewed ones are held until an admin decides.
+type RiskEvaluator interface {
+	Evaluate(ctx context.Context, in RiskInput) (RiskDecision, error)
+}
+
+// RiskHistory is the transfer history the built-in rules look at.
+type RiskHistory interface {
+	CountPayeesSince(ctx context.Context, payerID int, since time.Time) (int, error)
+	HasPaid(ctx context.Context, payerID, payeeID int) (bool, error)
+}
@@ -0,0 +1,16 @@
+package transaction
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockRiskEvaluator struct {
+	mock.Mock
+}
+
+func (m *MockRiskEvaluator) Evaluate(ctx context.Context, in RiskInput) (RiskDecision, error) {
+	args := m.Called(ctx, in)
+	return args.Get(0).(RiskDecision), args.Error(1)
+}
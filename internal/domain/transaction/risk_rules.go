@@ -0,0 +1,268 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/netip"
+	"os"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// RiskRules configures the built-in evaluator. Every rule is optional and
+// its outcome defaults to review. The file is YAML; JSON works as well since
+// it is valid YAML.
+type RiskRules struct {
+	// NewAccount flags transfers of at least MinAmount from accounts younger
+	// than MaxAge.
+	NewAccount *struct {
+		MaxAge    time.Duration `yaml:"max_age"`
+		MinAmount int64         `yaml:"min_amount"`
+		Outcome   RiskOutcome   `yaml:"outcome"`
+	} `yaml:"new_account"`
+
+	// FanOut flags transfers from payers who already paid MaxPayees distinct
+	// payees within Window.
+	FanOut *struct {
+		Window    time.Duration `yaml:"window"`
+		MaxPayees int           `yaml:"max_payees"`
+		Outcome   RiskOutcome   `yaml:"outcome"`
+	} `yaml:"fan_out"`
+
+	// NewPayee flags the first transfer to a payee when it is at least
+	// MinAmount.
+	NewPayee *struct {
+		MinAmount int64       `yaml:"min_amount"`
+		Outcome   RiskOutcome `yaml:"outcome"`
+	} `yaml:"new_payee"`
+
+	// UnusualHour flags transfers of at least MinAmount made between Start
+	// and End, full hours in Timezone. Start may be greater than End for a
+	// window crossing midnight.
+	UnusualHour *struct {
+		Timezone  string      `yaml:"timezone"`
+		Start     int         `yaml:"start"`
+		End       int         `yaml:"end"`
+		MinAmount int64       `yaml:"min_amount"`
+		Outcome   RiskOutcome `yaml:"outcome"`
+
+		location *time.Location
+	} `yaml:"unusual_hour"`
+
+	// Networks flags transfers made from client addresses inside CIDR.
+	Networks []struct {
+		CIDR    string      `yaml:"cidr"`
+		Outcome RiskOutcome `yaml:"outcome"`
+
+		prefix netip.Prefix
+	} `yaml:"networks"`
+}
+
+// LoadRiskRules reads and validates the rules at path.
+func LoadRiskRules(path string) (*RiskRules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var rules RiskRules
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("parsing risk rules: %w", err)
+	}
+
+	if err := rules.compile(); err != nil {
+		return nil, fmt.Errorf("invalid risk rules: %w", err)
+	}
+
+	return &rules, nil
+}
+
+func (r *RiskRules) compile() error {
+	if rule := r.NewAccount; rule != nil {
+		if rule.MaxAge <= 0 {
+			return errors.New("new_account.max_age must be positive")
+		}
+		if err := defaultOutcome(&rule.Outcome); err != nil {
+			return fmt.Errorf("new_account: %w", err)
+		}
+	}
+
+	if rule := r.FanOut; rule != nil {
+		if rule.Window <= 0 || rule.MaxPayees <= 0 {
+			return errors.New("fan_out.window and fan_out.max_payees must be positive")
+		}
+		if err := defaultOutcome(&rule.Outcome); err != nil {
+			return fmt.Errorf("fan_out: %w", err)
+		}
+	}
+
+	if rule := r.NewPayee; rule != nil {
+		if err := defaultOutcome(&rule.Outcome); err != nil {
+			return fmt.Errorf("new_payee: %w", err)
+		}
+	}
+
+	if rule := r.UnusualHour; rule != nil {
+		if rule.Start < 0 || rule.Start > 23 || rule.End < 0 || rule.End > 23 {
+			return errors.New("unusual_hour.start and unusual_hour.end must be between 0 and 23")
+		}
+		loc, err := time.LoadLocation(rule.Timezone)
+		if err != nil {
+			return fmt.Errorf("unusual_hour.timezone: %w", err)
+		}
+		rule.location = loc
+		if err := defaultOutcome(&rule.Outcome); err != nil {
+			return fmt.Errorf("unusual_hour: %w", err)
+		}
+	}
+
+	for i := range r.Networks {
+		rule := &r.Networks[i]
+		prefix, err := netip.ParsePrefix(rule.CIDR)
+		if err != nil {
+			return fmt.Errorf("networks[%d].cidr: %w", i, err)
+		}
+		rule.prefix = prefix.Masked()
+		if err := defaultOutcome(&rule.Outcome); err != nil {
+			return fmt.Errorf("networks[%d]: %w", i, err)
+		}
+	}
+
+	return nil
+}
+
+func defaultOutcome(o *RiskOutcome) error {
+	switch *o {
+	case "":
+		*o = RiskReview
+	case RiskReview, RiskDeny:
+	default:
+		return fmt.Errorf("outcome must be review or deny, got %q", *o)
+	}
+	return nil
+}
+
+// rulesEvaluator applies RiskRules loaded from a file. The file is checked
+// for changes on every evaluation, so ops can tune the rules without a
+// deploy; a broken edit is logged and the previous rules stay in place.
+type rulesEvaluator struct {
+	history RiskHistory
+	path    string
+
+	mu      sync.Mutex
+	rules   *RiskRules
+	modTime time.Time
+}
+
+func (e *rulesEvaluator) Evaluate(ctx context.Context, in RiskInput) (RiskDecision, error) {
+	rules := e.current()
+	decision := RiskDecision{Outcome: RiskAllow, Reasons: []string{}}
+
+	flag := func(outcome RiskOutcome, reason string) {
+		if outcome.severity() > decision.Outcome.severity() {
+			decision.Outcome = outcome
+		}
+		decision.Reasons = append(decision.Reasons, reason)
+	}
+
+	if rule := rules.NewAccount; rule != nil {
+		if in.Amount >= rule.MinAmount && in.At.Sub(in.Payer.CreatedAt) < rule.MaxAge {
+			flag(rule.Outcome, "new_account")
+		}
+	}
+
+	if rule := rules.UnusualHour; rule != nil && in.Amount >= rule.MinAmount {
+		if inHours(in.At.In(rule.location).Hour(), rule.Start, rule.End) {
+			flag(rule.Outcome, "unusual_hour")
+		}
+	}
+
+	if addr, err := netip.ParseAddr(in.IP); err == nil {
+		addr = addr.Unmap()
+		for _, rule := range rules.Networks {
+			if rule.prefix.Contains(addr) {
+				flag(rule.Outcome, "network")
+				break
+			}
+		}
+	}
+
+	if rule := rules.NewPayee; rule != nil && in.Amount >= rule.MinAmount {
+		paid, err := e.history.HasPaid(ctx, in.Payer.ID, in.PayeeID)
+		if err != nil {
+			return RiskDecision{}, err
+		}
+		if !paid {
+			flag(rule.Outcome, "new_payee")
+		}
+	}
+
+	if rule := rules.FanOut; rule != nil {
+		payees, err := e.history.CountPayeesSince(ctx, in.Payer.ID, in.At.Add(-rule.Window))
+		if err != nil {
+			return RiskDecision{}, err
+		}
+		if payees >= rule.MaxPayees {
+			flag(rule.Outcome, "fan_out")
+		}
+	}
+
+	return decision, nil
+}
+
+func (e *rulesEvaluator) current() *RiskRules {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	info, err := os.Stat(e.path)
+	if err != nil {
+		slog.Error("failed to stat risk rules, keeping previous rules", "path", e.path, "err", err.Error())
+		return e.rules
+	}
+
+	if !info.ModTime().Equal(e.modTime) {
+		rules, err := LoadRiskRules(e.path)
+		if err != nil {
+			slog.Error("failed to reload risk rules, keeping previous rules", "path", e.path, "err", err.Error())
+		} else {
+			e.rules = rules
+			slog.Info("risk rules reloaded", "path", e.path)
+		}
+		// a broken file is reported once, not on every transfer
+		e.modTime = info.ModTime()
+	}
+
+	return e.rules
+}
+
+func inHours(hour, start, end int) bool {
+	if start <= end {
+		return hour >= start && hour < end
+	}
+	return hour >= start || hour < end
+}
+
+// NewRulesEvaluator loads the rules at path. Unlike later reloads, an
+// invalid file here is an error so a bad config cannot boot silently.
+func NewRulesEvaluator(history RiskHistory, path string) (RiskEvaluator, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	rules, err := LoadRiskRules(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rulesEvaluator{
+		history: history,
+		path:    path,
+		rules:   rules,
+		modTime: info.ModTime(),
+	}, nil
+}
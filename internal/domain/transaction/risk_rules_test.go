@@ -0,0 +1,191 @@
+package transaction
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func writeRules(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadRiskRules(t *testing.T) {
+	t.Run("should load yaml and default outcomes to review", func(t *testing.T) {
+		path := writeRules(t, "rules.yaml", `
+new_account:
+  max_age: 72h
+  min_amount: 1000
+networks:
+  - cidr: 10.0.0.0/8
+    outcome: deny
+`)
+
+		rules, err := LoadRiskRules(path)
+
+		require.NoError(t, err)
+		assert.Equal(t, 72*time.Hour, rules.NewAccount.MaxAge)
+		assert.Equal(t, RiskReview, rules.NewAccount.Outcome)
+		assert.Equal(t, RiskDeny, rules.Networks[0].Outcome)
+		assert.Nil(t, rules.FanOut)
+	})
+
+	t.Run("should load json", func(t *testing.T) {
+		path := writeRules(t, "rules.json", `{"new_payee": {"min_amount": 500, "outcome": "deny"}}`)
+
+		rules, err := LoadRiskRules(path)
+
+		require.NoError(t, err)
+		assert.Equal(t, int64(500), rules.NewPayee.MinAmount)
+		assert.Equal(t, RiskDeny, rules.NewPayee.Outcome)
+	})
+
+	t.Run("should reject invalid rules", func(t *testing.T) {
+		for name, content := range map[string]string{
+			"outcome":  "new_payee: {min_amount: 1, outcome: allow}",
+			"cidr":     "networks: [{cidr: not-a-network}]",
+			"timezone": "unusual_hour: {timezone: Nowhere/City, start: 0, end: 6}",
+			"hours":    "unusual_hour: {timezone: UTC, start: 0, end: 24}",
+			"window":   "fan_out: {max_payees: 3}",
+		} {
+			t.Run(name, func(t *testing.T) {
+				_, err := LoadRiskRules(writeRules(t, "rules.yaml", content))
+				assert.Error(t, err)
+			})
+		}
+	})
+}
+
+func TestRulesEvaluator_Evaluate(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2026, 3, 10, 15, 0, 0, 0, time.UTC)
+	oldPayer := &user.User{ID: 1, CreatedAt: now.AddDate(-1, 0, 0)}
+
+	t.Run("should allow transfers no rule flags", func(t *testing.T) {
+		history := new(MockTransactionRepository)
+		history.On("HasPaid", mock.Anything, 1, 2).Return(true, nil)
+		history.On("CountPayeesSince", mock.Anything, 1, now.Add(-time.Hour)).Return(1, nil)
+
+		evaluator, err := NewRulesEvaluator(history, writeRules(t, "rules.yaml", `
+new_account: {max_age: 72h, min_amount: 1000}
+new_payee: {min_amount: 1000}
+fan_out: {window: 1h, max_payees: 5}
+`))
+		require.NoError(t, err)
+
+		decision, err := evaluator.Evaluate(ctx, RiskInput{Payer: oldPayer, PayeeID: 2, Amount: 5000, At: now})
+
+		require.NoError(t, err)
+		assert.Equal(t, RiskAllow, decision.Outcome)
+		assert.Empty(t, decision.Reasons)
+	})
+
+	t.Run("should review large transfers from new accounts", func(t *testing.T) {
+		evaluator, err := NewRulesEvaluator(nil, writeRules(t, "rules.yaml", "new_account: {max_age: 72h, min_amount: 1000}"))
+		require.NoError(t, err)
+
+		newPayer := &user.User{ID: 1, CreatedAt: now.Add(-time.Hour)}
+
+		small, err := evaluator.Evaluate(ctx, RiskInput{Payer: newPayer, PayeeID: 2, Amount: 999, At: now})
+		require.NoError(t, err)
+		large, err := evaluator.Evaluate(ctx, RiskInput{Payer: newPayer, PayeeID: 2, Amount: 1000, At: now})
+		require.NoError(t, err)
+
+		assert.Equal(t, RiskAllow, small.Outcome)
+		assert.Equal(t, RiskReview, large.Outcome)
+		assert.Equal(t, []string{"new_account"}, large.Reasons)
+	})
+
+	t.Run("should review the first large transfer to a payee", func(t *testing.T) {
+		history := new(MockTransactionRepository)
+		history.On("HasPaid", mock.Anything, 1, 2).Return(false, nil)
+
+		evaluator, err := NewRulesEvaluator(history, writeRules(t, "rules.yaml", "new_payee: {min_amount: 1000}"))
+		require.NoError(t, err)
+
+		decision, err := evaluator.Evaluate(ctx, RiskInput{Payer: oldPayer, PayeeID: 2, Amount: 1000, At: now})
+
+		require.NoError(t, err)
+		assert.Equal(t, RiskReview, decision.Outcome)
+		assert.Equal(t, []string{"new_payee"}, decision.Reasons)
+	})
+
+	t.Run("should flag rapid fan-out to many payees", func(t *testing.T) {
+		history := new(MockTransactionRepository)
+		history.On("CountPayeesSince", mock.Anything, 1, now.Add(-time.Hour)).Return(5, nil)
+
+		evaluator, err := NewRulesEvaluator(history, writeRules(t, "rules.yaml", "fan_out: {window: 1h, max_payees: 5}"))
+		require.NoError(t, err)
+
+		decision, err := evaluator.Evaluate(ctx, RiskInput{Payer: oldPayer, PayeeID: 2, Amount: 1, At: now})
+
+		require.NoError(t, err)
+		assert.Equal(t, RiskReview, decision.Outcome)
+	})
+
+	t.Run("should flag transfers in a window crossing midnight", func(t *testing.T) {
+		evaluator, err := NewRulesEvaluator(nil, writeRules(t, "rules.yaml", "unusual_hour: {timezone: America/Sao_Paulo, start: 22, end: 6}"))
+		require.NoError(t, err)
+
+		// 03:00 UTC is 00:00 in Sao Paulo
+		night, err := evaluator.Evaluate(ctx, RiskInput{Payer: oldPayer, Amount: 1, At: time.Date(2026, 3, 10, 3, 0, 0, 0, time.UTC)})
+		require.NoError(t, err)
+		day, err := evaluator.Evaluate(ctx, RiskInput{Payer: oldPayer, Amount: 1, At: now})
+		require.NoError(t, err)
+
+		assert.Equal(t, RiskReview, night.Outcome)
+		assert.Equal(t, RiskAllow, day.Outcome)
+	})
+
+	t.Run("should let the strictest outcome win", func(t *testing.T) {
+		evaluator, err := NewRulesEvaluator(nil, writeRules(t, "rules.yaml", `
+new_account: {max_age: 72h, min_amount: 0}
+networks:
+  - cidr: 203.0.113.0/24
+    outcome: deny
+`))
+		require.NoError(t, err)
+
+		decision, err := evaluator.Evaluate(ctx, RiskInput{
+			Payer:  &user.User{ID: 1, CreatedAt: now},
+			Amount: 1,
+			IP:     "::ffff:203.0.113.7",
+			At:     now,
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, RiskDeny, decision.Outcome)
+		assert.Equal(t, []string{"new_account", "network"}, decision.Reasons)
+	})
+
+	t.Run("should reload changed rules and keep the old ones on a broken edit", func(t *testing.T) {
+		path := writeRules(t, "rules.yaml", "networks: [{cidr: 10.0.0.0/8, outcome: deny}]")
+		evaluator, err := NewRulesEvaluator(nil, path)
+		require.NoError(t, err)
+
+		in := RiskInput{Payer: oldPayer, Amount: 1, IP: "10.1.2.3", At: now}
+
+		require.NoError(t, os.WriteFile(path, []byte("networks: [{cidr: 10.0.0.0/8, outcome: review}]"), 0o600))
+		require.NoError(t, os.Chtimes(path, now, now))
+		decision, err := evaluator.Evaluate(ctx, in)
+		require.NoError(t, err)
+		assert.Equal(t, RiskReview, decision.Outcome)
+
+		require.NoError(t, os.WriteFile(path, []byte("networks: [{cidr: broken}]"), 0o600))
+		require.NoError(t, os.Chtimes(path, now.Add(time.Minute), now.Add(time.Minute)))
+		decision, err = evaluator.Evaluate(ctx, in)
+		require.NoError(t, err)
+		assert.Equal(t, RiskReview, decision.Outcome)
+	})
+}
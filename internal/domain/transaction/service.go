@@ -0,0 +1,483 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/audit"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/mfa"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/webhook"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/db"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+const maxListLimit = 100
+
+var errTransferDeclined = apperror.NewHttpError(http.StatusForbidden, "transfer was declined")
+
+// TransferLimit caps what a user may send, by KYC level. Zero means no cap.
+type TransferLimit struct {
+	PerTransaction int64
+	Daily          int64
+}
+
+type TransactionService interface {
+	FindByID(ctx context.Context, id int) (*Transaction, error)
+	ListByUser(ctx context.Context, userID, limit, offset int) ([]Transaction, error)
+	Refund(ctx context.Context, id int) (*Transaction, error)
+	// Transfer settles the transfer, or holds it for review when the risk
+	// engine asks for one; exactly one of the results is non-nil.
+	Transfer(ctx context.Context, payer *user.User, dto TransferDTO) (*Transaction, *PendingTransfer, error)
+	ListPending(ctx context.Context, limit, offset int) ([]PendingTransfer, error)
+	FindPending(ctx context.Context, id int) (*PendingTransfer, error)
+	// ApprovePending settles a held transfer and also returns the wallets as
+	// they were before the money moved.
+	ApprovePending(ctx context.Context, reviewerID, id int) (*PendingTransfer, TransferWallets, error)
+	RejectPending(ctx context.Context, reviewerID, id int, reason string) (*PendingTransfer, error)
+}
+
+type transactionSvc struct {
+	transactionRepo TransactionRepository
+	transactor      db.Transactor
+	mfaService      mfa.MFAService
+	webhookService  webhook.WebhookService
+	auditService    audit.AuditService
+	riskEvaluator   RiskEvaluator
+	stepUpThreshold int64
+	limits          map[user.KYCLevel]TransferLimit
+}
+
+func (s *transactionSvc) FindByID(ctx context.Context, id int) (*Transaction, error) {
+	ctx, span := tracing.Start(ctx, "transactionSvc.FindByID")
+	defer span.End()
+
+	t, err := s.transactionRepo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if t == nil {
+		return nil, apperror.NewHttpError(http.StatusNotFound, "transaction not found")
+	}
+
+	return t, nil
+}
+
+func (s *transactionSvc) ListByUser(ctx context.Context, userID, limit, offset int) ([]Transaction, error) {
+	ctx, span := tracing.Start(ctx, "transactionSvc.ListByUser")
+	defer span.End()
+
+	if limit <= 0 || limit > maxListLimit {
+		limit = maxListLimit
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	return s.transactionRepo.ListByUser(ctx, userID, limit, offset)
+}
+
+func (s *transactionSvc) Refund(ctx context.Context, id int) (*Transaction, error) {
+	ctx, span := tracing.Start(ctx, "transactionSvc.Refund")
+	defer span.End()
+
+	original, err := s.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if original.Type == Refund {
+		return nil, apperror.NewHttpError(http.StatusUnprocessableEntity, "a refund cannot be refunded")
+	}
+
+	if original.RefundedAt != nil {
+		return nil, apperror.NewHttpError(http.StatusConflict, "transaction already refunded")
+	}
+
+	var refund *Transaction
+	err = s.transactor.WithTx(ctx, func(ctx context.Context) error {
+		refundID, err := s.transactionRepo.Refund(ctx, *original)
+		if err != nil {
+			if errors.Is(err, ErrAlreadyRefunded) {
+				return apperror.NewHttpError(http.StatusConflict, err.Error())
+			}
+			if errors.Is(err, ErrInsufficientBalance) {
+				return apperror.NewHttpError(http.StatusUnprocessableEntity, "payee has insufficient balance for the refund")
+			}
+			return err
+		}
+
+		refund, err = s.FindByID(ctx, refundID)
+		if err != nil {
+			return err
+		}
+
+		s.notify(ctx, refund.PayerID, webhook.RefundCreated, refund)
+		s.notify(ctx, refund.PayeeID, webhook.RefundCreated, refund)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return refund, nil
+}
+
+func (s *transactionSvc) Transfer(ctx context.Context, payer *user.User, dto TransferDTO) (*Transaction, *PendingTransfer, error) {
+	ctx, span := tracing.Start(ctx, "transactionSvc.Transfer")
+	defer span.End()
+
+	t, pending, err := s.transfer(ctx, payer, dto)
+	recordTransfer(transferOutcome(t, pending, err), dto.Amount)
+	return t, pending, err
+}
+
+func (s *transactionSvc) transfer(ctx context.Context, payer *user.User, dto TransferDTO) (*Transaction, *PendingTransfer, error) {
+	if payer.Role != user.Common {
+		return nil, nil, apperror.NewHttpError(http.StatusForbidden, "only common users can send transfers")
+	}
+
+	if payer.ID == dto.PayeeID {
+		return nil, nil, apperror.NewHttpError(http.StatusUnprocessableEntity, "payer and payee must be different")
+	}
+
+	limit := s.limits[payer.KYCLevel]
+	if limit.PerTransaction > 0 && dto.Amount > limit.PerTransaction {
+		return nil, nil, apperror.NewHttpError(
+			http.StatusUnprocessableEntity,
+			fmt.Sprintf("amount exceeds the limit of %d per transfer for kyc level %s", limit.PerTransaction, payer.KYCLevel),
+		)
+	}
+
+	if err := s.checkStepUp(ctx, payer.ID, dto); err != nil {
+		return nil, nil, err
+	}
+
+	decision, err := s.evaluateRisk(ctx, payer, dto)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch decision.Outcome {
+	case RiskDeny:
+		slog.Warn("transfer denied by risk rules", "payer_id", payer.ID, "payee_id", dto.PayeeID, "amount", dto.Amount, "reasons", decision.Reasons)
+		// the rules that fired are not disclosed to the payer
+		return nil, nil, errTransferDeclined
+	case RiskReview:
+		pending, err := s.hold(ctx, payer, dto, decision.Reasons, limit.Daily)
+		return nil, pending, err
+	}
+
+	// the audit entry is part of the transfer: if it cannot be written, no
+	// money moves
+	var t *Transaction
+	err = s.transactor.WithTx(ctx, func(ctx context.Context) error {
+		id, wallets, err := s.transactionRepo.Transfer(ctx, Transaction{
+			PayerID:     payer.ID,
+			PayeeID:     dto.PayeeID,
+			Type:        PaymentSent,
+			Amount:      dto.Amount,
+			Description: dto.Description,
+		}, limit.Daily)
+		if err != nil {
+			if errors.Is(err, ErrDailyLimitExceeded) {
+				return apperror.NewHttpError(
+					http.StatusUnprocessableEntity,
+					fmt.Sprintf("daily transfer limit of %d for kyc level %s exceeded", limit.Daily, payer.KYCLevel),
+				)
+			}
+			return transferError(err)
+		}
+
+		t, err = s.FindByID(ctx, id)
+		if err != nil {
+			return err
+		}
+
+		err = s.auditService.Record(ctx, audit.RecordDTO{
+			ActorID:    &payer.ID,
+			Action:     audit.TransferCreate,
+			TargetType: "transaction",
+			TargetID:   &t.ID,
+			Details:    map[string]any{"payee_id": t.PayeeID, "amount": t.Amount},
+			Before:     wallets,
+			After:      wallets.Moved(t.Amount),
+		})
+		if err != nil {
+			return err
+		}
+
+		s.notify(ctx, t.PayeeID, webhook.PaymentReceived, t)
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return t, nil, nil
+}
+
+func (s *transactionSvc) evaluateRisk(ctx context.Context, payer *user.User, dto TransferDTO) (RiskDecision, error) {
+	if s.riskEvaluator == nil {
+		return RiskDecision{Outcome: RiskAllow}, nil
+	}
+
+	ctx, span := tracing.Start(ctx, "risk.evaluate")
+	defer span.End()
+
+	start := time.Now()
+	decision, err := s.riskEvaluator.Evaluate(ctx, RiskInput{
+		Payer:   payer,
+		PayeeID: dto.PayeeID,
+		Amount:  dto.Amount,
+		IP:      audit.MetadataFromContext(ctx).IP,
+		At:      start,
+	})
+	riskDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		riskErrors.Inc()
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "risk evaluation failed")
+	} else {
+		span.SetAttributes(attribute.String("risk.outcome", string(decision.Outcome)))
+	}
+
+	return decision, err
+}
+
+func (s *transactionSvc) hold(ctx context.Context, payer *user.User, dto TransferDTO, reasons []string, dailyLimit int64) (*PendingTransfer, error) {
+	p := PendingTransfer{
+		PayerID:     payer.ID,
+		PayeeID:     dto.PayeeID,
+		Amount:      dto.Amount,
+		Description: dto.Description,
+		Reasons:     reasons,
+		Status:      ReviewPending,
+		CreatedAt:   time.Now(),
+		DailyLimit:  dailyLimit,
+	}
+
+	wallets, err := s.transactionRepo.FindWallets(ctx, payer.ID, dto.PayeeID)
+	if err != nil {
+		return nil, transferError(err)
+	}
+
+	err = s.transactor.WithTx(ctx, func(ctx context.Context) error {
+		id, err := s.transactionRepo.Hold(ctx, p)
+		if err != nil {
+			return err
+		}
+		p.ID = id
+
+		// no money moves until review, so both snapshots are the same
+		return s.auditService.Record(ctx, audit.RecordDTO{
+			ActorID:    &payer.ID,
+			Action:     audit.TransferHeld,
+			TargetType: "pending_transfer",
+			TargetID:   &p.ID,
+			Details:    map[string]any{"payee_id": p.PayeeID, "amount": p.Amount, "reasons": p.Reasons},
+			Before:     wallets,
+			After:      wallets,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &p, nil
+}
+
+func (s *transactionSvc) ListPending(ctx context.Context, limit, offset int) ([]PendingTransfer, error) {
+	ctx, span := tracing.Start(ctx, "transactionSvc.ListPending")
+	defer span.End()
+
+	if limit <= 0 || limit > maxListLimit {
+		limit = maxListLimit
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	return s.transactionRepo.ListPending(ctx, limit, offset)
+}
+
+func (s *transactionSvc) FindPending(ctx context.Context, id int) (*PendingTransfer, error) {
+	ctx, span := tracing.Start(ctx, "transactionSvc.FindPending")
+	defer span.End()
+
+	p, err := s.transactionRepo.FindPending(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if p == nil {
+		return nil, apperror.NewHttpError(http.StatusNotFound, "pending transfer not found")
+	}
+
+	return p, nil
+}
+
+func (s *transactionSvc) ApprovePending(ctx context.Context, reviewerID, id int) (*PendingTransfer, TransferWallets, error) {
+	ctx, span := tracing.Start(ctx, "transactionSvc.ApprovePending")
+	defer span.End()
+
+	p, err := s.FindPending(ctx, id)
+	if err != nil {
+		return nil, TransferWallets{}, err
+	}
+
+	var approved *PendingTransfer
+	var wallets TransferWallets
+	err = s.transactor.WithTx(ctx, func(ctx context.Context) error {
+		var transactionID int
+		transactionID, wallets, err = s.transactionRepo.ApprovePending(ctx, id, reviewerID, time.Now())
+		if err != nil {
+			if errors.Is(err, ErrNotPending) {
+				return apperror.NewHttpError(http.StatusConflict, err.Error())
+			}
+			if errors.Is(err, ErrDailyLimitExceeded) {
+				return apperror.NewHttpError(
+					http.StatusUnprocessableEntity,
+					fmt.Sprintf("approving would exceed the payer's daily transfer limit of %d", p.DailyLimit),
+				)
+			}
+			return transferError(err)
+		}
+		db.AfterCommit(ctx, func() { recordTransfer(outcomeApproved, p.Amount) })
+
+		t, err := s.FindByID(ctx, transactionID)
+		if err != nil {
+			return err
+		}
+
+		s.notify(ctx, t.PayeeID, webhook.PaymentReceived, t)
+
+		approved, err = s.FindPending(ctx, id)
+		return err
+	})
+	if err != nil {
+		return nil, TransferWallets{}, err
+	}
+
+	return approved, wallets, nil
+}
+
+func (s *transactionSvc) RejectPending(ctx context.Context, reviewerID, id int, reason string) (*PendingTransfer, error) {
+	ctx, span := tracing.Start(ctx, "transactionSvc.RejectPending")
+	defer span.End()
+
+	p, err := s.FindPending(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	ok, err := s.transactionRepo.RejectPending(ctx, id, reviewerID, reason, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, apperror.NewHttpError(http.StatusConflict, ErrNotPending.Error())
+	}
+
+	recordTransfer(outcomeReviewRejected, p.Amount)
+
+	return s.FindPending(ctx, id)
+}
+
+// transferError maps the errors a settling transfer can fail with.
+func transferError(err error) error {
+	if errors.Is(err, ErrWalletNotFound) {
+		return apperror.NewHttpError(http.StatusNotFound, err.Error())
+	}
+	if errors.Is(err, ErrWalletInactive) {
+		return apperror.NewHttpError(http.StatusUnprocessableEntity, err.Error())
+	}
+	if errors.Is(err, ErrInsufficientBalance) {
+		return apperror.NewHttpError(http.StatusUnprocessableEntity, err.Error())
+	}
+	return err
+}
+
+// notify queues a webhook event for userID once the transaction on ctx
+// commits, so no event announces money that did not move. The money already
+// moved by then, so a failure is only logged.
+func (s *transactionSvc) notify(ctx context.Context, userID int, event webhook.EventType, t *Transaction) {
+	db.AfterCommit(ctx, func() {
+		if err := s.webhookService.Dispatch(ctx, userID, event, t); err != nil {
+			slog.Error("failed to dispatch webhook", "err", err.Error(), "event", event, "transaction_id", t.ID)
+		}
+	})
+}
+
+// checkStepUp demands a fresh TOTP code for transfers at or above the
+// threshold, even inside a valid session. Users without 2FA cannot send them.
+func (s *transactionSvc) checkStepUp(ctx context.Context, userID int, dto TransferDTO) error {
+	if s.stepUpThreshold <= 0 || dto.Amount < s.stepUpThreshold {
+		return nil
+	}
+
+	enabled, err := s.mfaService.IsEnabled(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	if !enabled {
+		return apperror.NewHttpError(
+			http.StatusForbidden,
+			fmt.Sprintf("two-factor authentication is required for transfers of %d or more", s.stepUpThreshold),
+		)
+	}
+
+	if dto.TOTPCode == nil {
+		return apperror.NewHttpError(http.StatusUnauthorized, "totp_code is required for this amount")
+	}
+
+	err = s.mfaService.VerifyTOTP(ctx, userID, *dto.TOTPCode)
+	var httpError *apperror.HttpError
+	if errors.As(err, &httpError) {
+		auditErr := s.auditService.Record(ctx, audit.RecordDTO{
+			ActorID:    &userID,
+			Action:     audit.AuthMFAFailure,
+			TargetType: "user",
+			TargetID:   &userID,
+			Details:    map[string]string{"context": "transfer_step_up"},
+		})
+		if auditErr != nil {
+			return auditErr
+		}
+	}
+
+	return err
+}
+
+func NewTransactionService(
+	transactionRepo TransactionRepository,
+	transactor db.Transactor,
+	mfaSvc mfa.MFAService,
+	webhookSvc webhook.WebhookService,
+	audSvc audit.AuditService,
+	riskEvaluator RiskEvaluator,
+	stepUpThreshold int64,
+	limits map[user.KYCLevel]TransferLimit) TransactionService {
+
+	return &transactionSvc{
+		transactionRepo: transactionRepo,
+		transactor:      transactor,
+		mfaService:      mfaSvc,
+		webhookService:  webhookSvc,
+		auditService:    audSvc,
+		riskEvaluator:   riskEvaluator,
+		stepUpThreshold: stepUpThreshold,
+		limits:          limits,
+	}
+}
@@ -0,0 +1,83 @@
+package transaction
+
+import (
+	"context"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockTransactionService struct {
+	mock.Mock
+}
+
+func (m *MockTransactionService) FindByID(ctx context.Context, id int) (*Transaction, error) {
+	args := m.Called(ctx, id)
+	t, ok := args.Get(0).(*Transaction)
+	if !ok && args.Get(0) != nil {
+		panic("expected *Transaction or nil")
+	}
+	return t, args.Error(1)
+}
+
+func (m *MockTransactionService) ListByUser(ctx context.Context, userID, limit, offset int) ([]Transaction, error) {
+	args := m.Called(ctx, userID, limit, offset)
+	t, ok := args.Get(0).([]Transaction)
+	if !ok && args.Get(0) != nil {
+		panic("expected []Transaction or nil")
+	}
+	return t, args.Error(1)
+}
+
+func (m *MockTransactionService) Refund(ctx context.Context, id int) (*Transaction, error) {
+	args := m.Called(ctx, id)
+	t, ok := args.Get(0).(*Transaction)
+	if !ok && args.Get(0) != nil {
+		panic("expected *Transaction or nil")
+	}
+	return t, args.Error(1)
+}
+
+func (m *MockTransactionService) Transfer(ctx context.Context, payer *user.User, dto TransferDTO) (*Transaction, *PendingTransfer, error) {
+	args := m.Called(ctx, payer, dto)
+	t, _ := args.Get(0).(*Transaction)
+	p, _ := args.Get(1).(*PendingTransfer)
+	return t, p, args.Error(2)
+}
+
+func (m *MockTransactionService) ListPending(ctx context.Context, limit, offset int) ([]PendingTransfer, error) {
+	args := m.Called(ctx, limit, offset)
+	p, ok := args.Get(0).([]PendingTransfer)
+	if !ok && args.Get(0) != nil {
+		panic("expected []PendingTransfer or nil")
+	}
+	return p, args.Error(1)
+}
+
+func (m *MockTransactionService) FindPending(ctx context.Context, id int) (*PendingTransfer, error) {
+	args := m.Called(ctx, id)
+	p, ok := args.Get(0).(*PendingTransfer)
+	if !ok && args.Get(0) != nil {
+		panic("expected *PendingTransfer or nil")
+	}
+	return p, args.Error(1)
+}
+
+func (m *MockTransactionService) ApprovePending(ctx context.Context, reviewerID, id int) (*PendingTransfer, TransferWallets, error) {
+	args := m.Called(ctx, reviewerID, id)
+	wallets, _ := args.Get(1).(TransferWallets)
+	if p, ok := args.Get(0).(*PendingTransfer); ok {
+		return p, wallets, args.Error(2)
+	}
+	return nil, wallets, args.Error(2)
+}
+
+func (m *MockTransactionService) RejectPending(ctx context.Context, reviewerID, id int, reason string) (*PendingTransfer, error) {
+	args := m.Called(ctx, reviewerID, id, reason)
+	p, ok := args.Get(0).(*PendingTransfer)
+	if !ok && args.Get(0) != nil {
+		panic("expected *PendingTransfer or nil")
+	}
+	return p, args.Error(1)
+}
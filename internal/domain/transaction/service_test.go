@@ -0,0 +1,535 @@
+package transaction
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/audit"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/mfa"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/webhook"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/db"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestTransactionService_FindByID(t *testing.T) {
+	t.Run("should return not found if transaction is nil", func(t *testing.T) {
+		mockRepo := new(MockTransactionRepository)
+		mockRepo.On("FindByID", mock.Anything, 1).Return(nil, nil)
+
+		service := NewTransactionService(mockRepo, &db.MockTransactor{}, nil, nil, newAuditServiceMock(), nil, 0, nil)
+
+		tr, err := service.FindByID(context.Background(), 1)
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusNotFound, httpError.Code)
+		assert.Nil(t, tr)
+
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestTransactionService_ListByUser(t *testing.T) {
+	t.Run("should clamp limit and offset", func(t *testing.T) {
+		mockRepo := new(MockTransactionRepository)
+		mockRepo.On("ListByUser", mock.Anything, 1, maxListLimit, 0).
+			Return([]Transaction{{ID: 1}}, nil)
+
+		service := NewTransactionService(mockRepo, &db.MockTransactor{}, nil, nil, newAuditServiceMock(), nil, 0, nil)
+
+		transactions, err := service.ListByUser(context.Background(), 1, 0, -1)
+
+		assert.NoError(t, err)
+		assert.Len(t, transactions, 1)
+
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestTransactionService_Refund(t *testing.T) {
+	t.Run("should return conflict if transaction is already refunded", func(t *testing.T) {
+		now := time.Now()
+		mockRepo := new(MockTransactionRepository)
+		mockRepo.On("FindByID", mock.Anything, 1).
+			Return(&Transaction{ID: 1, Type: PaymentSent, RefundedAt: &now}, nil)
+
+		service := NewTransactionService(mockRepo, &db.MockTransactor{}, nil, nil, newAuditServiceMock(), nil, 0, nil)
+
+		refund, err := service.Refund(context.Background(), 1)
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusConflict, httpError.Code)
+		assert.Nil(t, refund)
+
+		mockRepo.AssertNotCalled(t, "Refund", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should not refund a refund", func(t *testing.T) {
+		mockRepo := new(MockTransactionRepository)
+		mockRepo.On("FindByID", mock.Anything, 1).
+			Return(&Transaction{ID: 1, Type: Refund}, nil)
+
+		service := NewTransactionService(mockRepo, &db.MockTransactor{}, nil, nil, newAuditServiceMock(), nil, 0, nil)
+
+		refund, err := service.Refund(context.Background(), 1)
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusUnprocessableEntity, httpError.Code)
+		assert.Nil(t, refund)
+
+		mockRepo.AssertNotCalled(t, "Refund", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should map insufficient balance to unprocessable entity", func(t *testing.T) {
+		original := &Transaction{ID: 1, PayerID: 1, PayeeID: 2, Type: PaymentSent, Amount: 100}
+		mockRepo := new(MockTransactionRepository)
+		mockRepo.On("FindByID", mock.Anything, 1).Return(original, nil)
+		mockRepo.On("Refund", mock.Anything, *original).Return(0, ErrInsufficientBalance)
+
+		service := NewTransactionService(mockRepo, &db.MockTransactor{}, nil, nil, newAuditServiceMock(), nil, 0, nil)
+
+		refund, err := service.Refund(context.Background(), 1)
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusUnprocessableEntity, httpError.Code)
+		assert.Nil(t, refund)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should return generic error if db fails", func(t *testing.T) {
+		original := &Transaction{ID: 1, PayerID: 1, PayeeID: 2, Type: PaymentSent, Amount: 100}
+		mockRepo := new(MockTransactionRepository)
+		mockRepo.On("FindByID", mock.Anything, 1).Return(original, nil)
+		mockRepo.On("Refund", mock.Anything, *original).Return(0, errors.New("db fail"))
+
+		service := NewTransactionService(mockRepo, &db.MockTransactor{}, nil, nil, newAuditServiceMock(), nil, 0, nil)
+
+		refund, err := service.Refund(context.Background(), 1)
+
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "db fail")
+		assert.Nil(t, refund)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should return the refund transaction", func(t *testing.T) {
+		original := &Transaction{ID: 1, PayerID: 1, PayeeID: 2, Type: PaymentSent, Amount: 100}
+		refundOf := 1
+		created := &Transaction{ID: 2, PayerID: 2, PayeeID: 1, Type: Refund, Amount: 100, RefundOf: &refundOf}
+
+		mockRepo := new(MockTransactionRepository)
+		mockRepo.On("FindByID", mock.Anything, 1).Return(original, nil)
+		mockRepo.On("Refund", mock.Anything, *original).Return(2, nil)
+		mockRepo.On("FindByID", mock.Anything, 2).Return(created, nil)
+
+		webhookServiceMock := new(webhook.MockWebhookService)
+		webhookServiceMock.On("Dispatch", mock.Anything, 1, webhook.RefundCreated, created).Return(nil).Once()
+		webhookServiceMock.On("Dispatch", mock.Anything, 2, webhook.RefundCreated, created).Return(nil).Once()
+
+		service := NewTransactionService(mockRepo, &db.MockTransactor{}, nil, webhookServiceMock, newAuditServiceMock(), nil, 0, nil)
+
+		refund, err := service.Refund(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, created, refund)
+
+		mockRepo.AssertExpectations(t)
+		webhookServiceMock.AssertExpectations(t)
+	})
+}
+
+func newAuditServiceMock() *audit.MockAuditService {
+	auditServiceMock := new(audit.MockAuditService)
+	auditServiceMock.On("Record", mock.Anything, mock.Anything).Return(nil).Maybe()
+	return auditServiceMock
+}
+
+func TestTransactionService_Transfer(t *testing.T) {
+	payer := &user.User{ID: 1, Role: user.Common}
+
+	t.Run("should forbid shopkeepers from sending", func(t *testing.T) {
+		mockRepo := new(MockTransactionRepository)
+
+		service := NewTransactionService(mockRepo, &db.MockTransactor{}, nil, nil, newAuditServiceMock(), nil, 0, nil)
+
+		_, _, err := service.Transfer(context.Background(), &user.User{ID: 1, Role: user.Shopkeeper}, TransferDTO{PayeeID: 2, Amount: 100})
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusForbidden, httpError.Code)
+		mockRepo.AssertNotCalled(t, "Transfer", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should map insufficient balance to unprocessable entity", func(t *testing.T) {
+		mockRepo := new(MockTransactionRepository)
+		mockRepo.On("Transfer", mock.Anything, mock.Anything, int64(0)).Return(0, TransferWallets{}, ErrInsufficientBalance)
+
+		service := NewTransactionService(mockRepo, &db.MockTransactor{}, nil, nil, newAuditServiceMock(), nil, 0, nil)
+
+		_, _, err := service.Transfer(context.Background(), payer, TransferDTO{PayeeID: 2, Amount: 100})
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusUnprocessableEntity, httpError.Code)
+	})
+
+	t.Run("should transfer below the step-up threshold without a code", func(t *testing.T) {
+		mockRepo := new(MockTransactionRepository)
+		mockRepo.On("Transfer", mock.Anything, Transaction{PayerID: 1, PayeeID: 2, Type: PaymentSent, Amount: 999}, int64(0)).
+			Return(10, TransferWallets{}, nil)
+		mockRepo.On("FindByID", mock.Anything, 10).Return(&Transaction{ID: 10, PayeeID: 2}, nil)
+
+		mfaServiceMock := new(mfa.MockMFAService)
+		webhookServiceMock := new(webhook.MockWebhookService)
+		webhookServiceMock.On("Dispatch", mock.Anything, 2, webhook.PaymentReceived, mock.Anything).Return(nil)
+
+		service := NewTransactionService(mockRepo, &db.MockTransactor{}, mfaServiceMock, webhookServiceMock, newAuditServiceMock(), nil, 1000, nil)
+
+		tr, _, err := service.Transfer(context.Background(), payer, TransferDTO{PayeeID: 2, Amount: 999})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 10, tr.ID)
+		mfaServiceMock.AssertNotCalled(t, "IsEnabled", mock.Anything, mock.Anything)
+		webhookServiceMock.AssertExpectations(t)
+	})
+
+	t.Run("should not fail the transfer if the webhook cannot be queued", func(t *testing.T) {
+		mockRepo := new(MockTransactionRepository)
+		mockRepo.On("Transfer", mock.Anything, mock.Anything, int64(0)).Return(10, TransferWallets{}, nil)
+		mockRepo.On("FindByID", mock.Anything, 10).Return(&Transaction{ID: 10, PayeeID: 2}, nil)
+
+		webhookServiceMock := new(webhook.MockWebhookService)
+		webhookServiceMock.On("Dispatch", mock.Anything, 2, webhook.PaymentReceived, mock.Anything).Return(errors.New("db down"))
+
+		service := NewTransactionService(mockRepo, &db.MockTransactor{}, nil, webhookServiceMock, newAuditServiceMock(), nil, 0, nil)
+
+		tr, _, err := service.Transfer(context.Background(), payer, TransferDTO{PayeeID: 2, Amount: 100})
+
+		assert.NoError(t, err)
+		assert.Equal(t, 10, tr.ID)
+	})
+
+	t.Run("should refuse large transfers from users without 2FA", func(t *testing.T) {
+		mockRepo := new(MockTransactionRepository)
+
+		mfaServiceMock := new(mfa.MockMFAService)
+		mfaServiceMock.On("IsEnabled", mock.Anything, 1).Return(false, nil)
+
+		service := NewTransactionService(mockRepo, &db.MockTransactor{}, mfaServiceMock, nil, newAuditServiceMock(), nil, 1000, nil)
+
+		_, _, err := service.Transfer(context.Background(), payer, TransferDTO{PayeeID: 2, Amount: 1000})
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusForbidden, httpError.Code)
+		mockRepo.AssertNotCalled(t, "Transfer", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should demand a totp code for large transfers", func(t *testing.T) {
+		mockRepo := new(MockTransactionRepository)
+
+		mfaServiceMock := new(mfa.MockMFAService)
+		mfaServiceMock.On("IsEnabled", mock.Anything, 1).Return(true, nil)
+
+		service := NewTransactionService(mockRepo, &db.MockTransactor{}, mfaServiceMock, nil, newAuditServiceMock(), nil, 1000, nil)
+
+		_, _, err := service.Transfer(context.Background(), payer, TransferDTO{PayeeID: 2, Amount: 5000})
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusUnauthorized, httpError.Code)
+		mockRepo.AssertNotCalled(t, "Transfer", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should transfer large amounts with a valid totp code", func(t *testing.T) {
+		code := "123456"
+
+		mockRepo := new(MockTransactionRepository)
+		mockRepo.On("Transfer", mock.Anything, mock.Anything, int64(0)).Return(10, TransferWallets{}, nil)
+		mockRepo.On("FindByID", mock.Anything, 10).Return(&Transaction{ID: 10}, nil)
+
+		mfaServiceMock := new(mfa.MockMFAService)
+		mfaServiceMock.On("IsEnabled", mock.Anything, 1).Return(true, nil)
+		mfaServiceMock.On("VerifyTOTP", mock.Anything, 1, code).Return(nil).Once()
+
+		webhookServiceMock := new(webhook.MockWebhookService)
+		webhookServiceMock.On("Dispatch", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		service := NewTransactionService(mockRepo, &db.MockTransactor{}, mfaServiceMock, webhookServiceMock, newAuditServiceMock(), nil, 1000, nil)
+
+		_, _, err := service.Transfer(context.Background(), payer, TransferDTO{PayeeID: 2, Amount: 5000, TOTPCode: &code})
+
+		assert.NoError(t, err)
+		mfaServiceMock.AssertExpectations(t)
+	})
+	t.Run("should audit a wrong step-up code", func(t *testing.T) {
+		code := "000000"
+
+		mockRepo := new(MockTransactionRepository)
+
+		mfaServiceMock := new(mfa.MockMFAService)
+		mfaServiceMock.On("IsEnabled", mock.Anything, 1).Return(true, nil)
+		mfaServiceMock.On("VerifyTOTP", mock.Anything, 1, code).
+			Return(apperror.NewHttpError(http.StatusUnauthorized, "invalid totp code"))
+
+		auditServiceMock := new(audit.MockAuditService)
+		auditServiceMock.On("Record", mock.Anything, mock.MatchedBy(func(dto audit.RecordDTO) bool {
+			return dto.Action == audit.AuthMFAFailure && *dto.TargetID == 1
+		})).Return(nil).Once()
+
+		service := NewTransactionService(mockRepo, &db.MockTransactor{}, mfaServiceMock, nil, auditServiceMock, nil, 1000, nil)
+
+		_, _, err := service.Transfer(context.Background(), payer, TransferDTO{PayeeID: 2, Amount: 5000, TOTPCode: &code})
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusUnauthorized, httpError.Code)
+		auditServiceMock.AssertExpectations(t)
+		mockRepo.AssertNotCalled(t, "Transfer", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	limits := map[user.KYCLevel]TransferLimit{
+		user.KYCNone:  {PerTransaction: 200, Daily: 500},
+		user.KYCBasic: {PerTransaction: 5000, Daily: 10000},
+	}
+	unverified := &user.User{ID: 1, Role: user.Common, KYCLevel: user.KYCNone}
+
+	t.Run("should refuse amounts above the per transfer limit of the kyc level", func(t *testing.T) {
+		mockRepo := new(MockTransactionRepository)
+
+		service := NewTransactionService(mockRepo, &db.MockTransactor{}, nil, nil, newAuditServiceMock(), nil, 0, limits)
+
+		_, _, err := service.Transfer(context.Background(), unverified, TransferDTO{PayeeID: 2, Amount: 300})
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusUnprocessableEntity, httpError.Code)
+		mockRepo.AssertNotCalled(t, "Transfer", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should pass the daily limit of the kyc level to the repository", func(t *testing.T) {
+		mockRepo := new(MockTransactionRepository)
+		webhookServiceMock := new(webhook.MockWebhookService)
+		mockRepo.On("Transfer", mock.Anything, mock.Anything, int64(10000)).Return(5, TransferWallets{}, nil)
+		mockRepo.On("FindByID", mock.Anything, 5).Return(&Transaction{ID: 5, PayeeID: 2}, nil)
+		webhookServiceMock.On("Dispatch", mock.Anything, 2, webhook.PaymentReceived, mock.Anything).Return(nil)
+
+		service := NewTransactionService(mockRepo, &db.MockTransactor{}, nil, webhookServiceMock, newAuditServiceMock(), nil, 0, limits)
+
+		_, _, err := service.Transfer(context.Background(), &user.User{ID: 1, Role: user.Common, KYCLevel: user.KYCBasic}, TransferDTO{PayeeID: 2, Amount: 300})
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should map an exceeded daily limit to unprocessable entity", func(t *testing.T) {
+		mockRepo := new(MockTransactionRepository)
+		mockRepo.On("Transfer", mock.Anything, mock.Anything, int64(500)).Return(0, TransferWallets{}, ErrDailyLimitExceeded)
+
+		service := NewTransactionService(mockRepo, &db.MockTransactor{}, nil, nil, newAuditServiceMock(), nil, 0, limits)
+
+		_, _, err := service.Transfer(context.Background(), unverified, TransferDTO{PayeeID: 2, Amount: 100})
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusUnprocessableEntity, httpError.Code)
+	})
+}
+
+func TestTransactionService_TransferRisk(t *testing.T) {
+	payer := &user.User{ID: 1, Role: user.Common}
+
+	t.Run("should refuse transfers the risk engine denies", func(t *testing.T) {
+		mockRepo := new(MockTransactionRepository)
+		riskMock := new(MockRiskEvaluator)
+		riskMock.On("Evaluate", mock.Anything, mock.Anything).
+			Return(RiskDecision{Outcome: RiskDeny, Reasons: []string{"network"}}, nil)
+
+		service := NewTransactionService(mockRepo, &db.MockTransactor{}, nil, nil, newAuditServiceMock(), riskMock, 0, nil)
+
+		_, _, err := service.Transfer(context.Background(), payer, TransferDTO{PayeeID: 2, Amount: 100})
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusForbidden, httpError.Code)
+		assert.NotContains(t, httpError.Message, "network")
+		mockRepo.AssertNotCalled(t, "Transfer", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should hold transfers the risk engine sends to review", func(t *testing.T) {
+		mockRepo := new(MockTransactionRepository)
+		riskMock := new(MockRiskEvaluator)
+		riskMock.On("Evaluate", mock.Anything, mock.MatchedBy(func(in RiskInput) bool {
+			return in.Payer == payer && in.PayeeID == 2 && in.Amount == 100 && in.IP == "10.0.0.1"
+		})).Return(RiskDecision{Outcome: RiskReview, Reasons: []string{"new_payee"}}, nil)
+		wallets := TransferWallets{
+			Payer: WalletState{UserID: 1, Active: true, Balance: 1000},
+			Payee: WalletState{UserID: 2, Active: true, Balance: 0},
+		}
+		mockRepo.On("FindWallets", mock.Anything, 1, 2).Return(wallets, nil)
+		mockRepo.On("Hold", mock.Anything, mock.MatchedBy(func(p PendingTransfer) bool {
+			return p.PayerID == 1 && p.Status == ReviewPending && p.Reasons[0] == "new_payee"
+		})).Return(4, nil)
+
+		auditServiceMock := new(audit.MockAuditService)
+		auditServiceMock.On("Record", mock.Anything, mock.MatchedBy(func(dto audit.RecordDTO) bool {
+			return dto.Action == audit.TransferHeld && *dto.ActorID == 1 && *dto.TargetID == 4 &&
+				dto.Before == wallets && dto.After == wallets
+		})).Return(nil).Once()
+
+		service := NewTransactionService(mockRepo, &db.MockTransactor{}, nil, nil, auditServiceMock, riskMock, 0, nil)
+
+		ctx := audit.WithMetadata(context.Background(), audit.Metadata{IP: "10.0.0.1"})
+		tr, pending, err := service.Transfer(ctx, payer, TransferDTO{PayeeID: 2, Amount: 100})
+
+		assert.NoError(t, err)
+		assert.Nil(t, tr)
+		assert.Equal(t, 4, pending.ID)
+		mockRepo.AssertNotCalled(t, "Transfer", mock.Anything, mock.Anything, mock.Anything)
+		auditServiceMock.AssertExpectations(t)
+	})
+
+	t.Run("should audit a settled transfer with wallet snapshots", func(t *testing.T) {
+		before := TransferWallets{
+			Payer: WalletState{UserID: 1, Active: true, Balance: 1000},
+			Payee: WalletState{UserID: 2, Active: true, Balance: 50},
+		}
+
+		mockRepo := new(MockTransactionRepository)
+		mockRepo.On("Transfer", mock.Anything, mock.Anything, int64(0)).Return(10, before, nil)
+		mockRepo.On("FindByID", mock.Anything, 10).Return(&Transaction{ID: 10, PayerID: 1, PayeeID: 2, Amount: 300}, nil)
+
+		webhookServiceMock := new(webhook.MockWebhookService)
+		webhookServiceMock.On("Dispatch", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		auditServiceMock := new(audit.MockAuditService)
+		auditServiceMock.On("Record", mock.Anything, mock.MatchedBy(func(dto audit.RecordDTO) bool {
+			after := dto.After.(TransferWallets)
+			return dto.Action == audit.TransferCreate && *dto.ActorID == 1 && *dto.TargetID == 10 &&
+				dto.Before == before && after.Payer.Balance == 700 && after.Payee.Balance == 350
+		})).Return(nil).Once()
+
+		service := NewTransactionService(mockRepo, &db.MockTransactor{}, nil, webhookServiceMock, auditServiceMock, nil, 0, nil)
+
+		_, _, err := service.Transfer(context.Background(), payer, TransferDTO{PayeeID: 2, Amount: 300})
+
+		assert.NoError(t, err)
+		auditServiceMock.AssertExpectations(t)
+	})
+
+	t.Run("should fail the transfer if it cannot be audited", func(t *testing.T) {
+		mockRepo := new(MockTransactionRepository)
+		mockRepo.On("Transfer", mock.Anything, mock.Anything, int64(0)).Return(10, TransferWallets{}, nil)
+		mockRepo.On("FindByID", mock.Anything, 10).Return(&Transaction{ID: 10, PayeeID: 2}, nil)
+
+		webhookServiceMock := new(webhook.MockWebhookService)
+
+		auditServiceMock := new(audit.MockAuditService)
+		auditServiceMock.On("Record", mock.Anything, mock.Anything).Return(errors.New("db down"))
+
+		transactor := &db.MockTransactor{}
+		service := NewTransactionService(mockRepo, transactor, nil, webhookServiceMock, auditServiceMock, nil, 0, nil)
+
+		tr, _, err := service.Transfer(context.Background(), payer, TransferDTO{PayeeID: 2, Amount: 100})
+
+		assert.Error(t, err)
+		assert.Nil(t, tr)
+		assert.Equal(t, 1, transactor.Calls)
+		webhookServiceMock.AssertNotCalled(t, "Dispatch", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestTransactionService_ApprovePending(t *testing.T) {
+	t.Run("should settle the transfer and notify the payee", func(t *testing.T) {
+		mockRepo := new(MockTransactionRepository)
+		webhookServiceMock := new(webhook.MockWebhookService)
+		transactionID := 10
+		wallets := TransferWallets{Payer: WalletState{Balance: 500}, Payee: WalletState{Balance: 0}}
+
+		mockRepo.On("FindPending", mock.Anything, 4).Return(&PendingTransfer{ID: 4, Status: ReviewPending}, nil).Once()
+		mockRepo.On("ApprovePending", mock.Anything, 4, 9, mock.Anything).Return(transactionID, wallets, nil)
+		mockRepo.On("FindByID", mock.Anything, 10).Return(&Transaction{ID: 10, PayeeID: 2}, nil)
+		mockRepo.On("FindPending", mock.Anything, 4).
+			Return(&PendingTransfer{ID: 4, Status: ReviewApproved, TransactionID: &transactionID}, nil).Once()
+		webhookServiceMock.On("Dispatch", mock.Anything, 2, webhook.PaymentReceived, mock.Anything).Return(nil)
+
+		service := NewTransactionService(mockRepo, &db.MockTransactor{}, nil, webhookServiceMock, newAuditServiceMock(), nil, 0, nil)
+
+		approved, before, err := service.ApprovePending(context.Background(), 9, 4)
+
+		assert.NoError(t, err)
+		assert.Equal(t, ReviewApproved, approved.Status)
+		assert.Equal(t, wallets, before)
+		webhookServiceMock.AssertExpectations(t)
+	})
+
+	t.Run("should return conflict if the transfer was already reviewed", func(t *testing.T) {
+		mockRepo := new(MockTransactionRepository)
+		mockRepo.On("FindPending", mock.Anything, 4).Return(&PendingTransfer{ID: 4, Status: ReviewRejected}, nil)
+		mockRepo.On("ApprovePending", mock.Anything, 4, 9, mock.Anything).Return(0, TransferWallets{}, ErrNotPending)
+
+		service := NewTransactionService(mockRepo, &db.MockTransactor{}, nil, nil, newAuditServiceMock(), nil, 0, nil)
+
+		_, _, err := service.ApprovePending(context.Background(), 9, 4)
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusConflict, httpError.Code)
+	})
+
+	t.Run("should keep the transfer pending when the balance is gone", func(t *testing.T) {
+		mockRepo := new(MockTransactionRepository)
+		mockRepo.On("FindPending", mock.Anything, 4).Return(&PendingTransfer{ID: 4, Status: ReviewPending}, nil)
+		mockRepo.On("ApprovePending", mock.Anything, 4, 9, mock.Anything).Return(0, TransferWallets{}, ErrInsufficientBalance)
+
+		service := NewTransactionService(mockRepo, &db.MockTransactor{}, nil, nil, newAuditServiceMock(), nil, 0, nil)
+
+		_, _, err := service.ApprovePending(context.Background(), 9, 4)
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusUnprocessableEntity, httpError.Code)
+	})
+
+	t.Run("should refuse approval past the payer's daily limit", func(t *testing.T) {
+		mockRepo := new(MockTransactionRepository)
+		mockRepo.On("FindPending", mock.Anything, 4).
+			Return(&PendingTransfer{ID: 4, Status: ReviewPending, DailyLimit: 1000}, nil)
+		mockRepo.On("ApprovePending", mock.Anything, 4, 9, mock.Anything).Return(0, TransferWallets{}, ErrDailyLimitExceeded)
+
+		service := NewTransactionService(mockRepo, &db.MockTransactor{}, nil, nil, newAuditServiceMock(), nil, 0, nil)
+
+		_, _, err := service.ApprovePending(context.Background(), 9, 4)
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusUnprocessableEntity, httpError.Code)
+		assert.Contains(t, httpError.Message, "daily transfer limit")
+	})
+}
+
+func TestTransactionService_RejectPending(t *testing.T) {
+	t.Run("should return not found for unknown transfers", func(t *testing.T) {
+		mockRepo := new(MockTransactionRepository)
+		mockRepo.On("FindPending", mock.Anything, 4).Return(nil, nil)
+
+		service := NewTransactionService(mockRepo, &db.MockTransactor{}, nil, nil, newAuditServiceMock(), nil, 0, nil)
+
+		_, err := service.RejectPending(context.Background(), 9, 4, "fraud")
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusNotFound, httpError.Code)
+	})
+}
@@ -0,0 +1,68 @@
+package user
+
+import "strings"
+
+// normalizeDocument strips the punctuation and spaces a CPF or CNPJ is
+// usually written with ("123.456.789-09", "12.345.678/0001-95") and
+// upper-cases letters, which alphanumeric CNPJs may contain.
+func normalizeDocument(str string) string {
+	var b strings.Builder
+	b.Grow(len(str))
+
+	for _, ch := range str {
+		switch ch {
+		case '.', '-', '/', ' ':
+			continue
+		}
+		if ch >= 'a' && ch <= 'z' {
+			ch -= 'a' - 'A'
+		}
+		b.WriteRune(ch)
+	}
+
+	return b.String()
+}
+
+func normalizeDocumentPtr(str *string) *string {
+	if str == nil {
+		return nil
+	}
+	normalized := normalizeDocument(*str)
+	return &normalized
+}
+
+// checkDigit computes a mod-11 check digit over values, weighting them from
+// right to left with 2, 3, ... up to maxWeight and wrapping back to 2. The
+// CPF never wraps (maxWeight 11); the CNPJ wraps after 9.
+func checkDigit(values []int, maxWeight int) int {
+	sum, weight := 0, 2
+	for i := len(values) - 1; i >= 0; i-- {
+		sum += values[i] * weight
+		if weight++; weight > maxWeight {
+			weight = 2
+		}
+	}
+
+	if rem := sum % 11; rem >= 2 {
+		return 11 - rem
+	}
+	return 0
+}
+
+func cpfCheckDigits(base []int) (int, int) {
+	first := checkDigit(base, 11)
+	second := checkDigit(append(base[:len(base):len(base)], first), 11)
+	return first, second
+}
+
+// cnpjCheckDigits also covers the alphanumeric CNPJ, whose characters are
+// valued by their ASCII code minus 48, so digits keep their face value.
+func cnpjCheckDigits(base []int) (int, int) {
+	first := checkDigit(base, 9)
+	second := checkDigit(append(base[:len(base):len(base)], first), 9)
+	return first, second
+}
+
+func allSame(str string) bool {
+	return strings.Count(str, str[:1]) == len(str)
+}
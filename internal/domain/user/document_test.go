@@ -0,0 +1,158 @@
+package user
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+	"testing/quick"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// the generators below spell the weights out instead of reusing checkDigit,
+// so the properties do not just compare the implementation with itself
+
+var (
+	cpfWeights1  = []int{10, 9, 8, 7, 6, 5, 4, 3, 2}
+	cpfWeights2  = []int{11, 10, 9, 8, 7, 6, 5, 4, 3, 2}
+	cnpjWeights1 = []int{5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2}
+	cnpjWeights2 = []int{6, 5, 4, 3, 2, 9, 8, 7, 6, 5, 4, 3, 2}
+)
+
+const cnpjAlphabet = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+func weightedDigit(chars string, weights []int) byte {
+	sum := 0
+	for i, w := range weights {
+		sum += int(chars[i]-'0') * w
+	}
+	if rem := sum % 11; rem >= 2 {
+		return byte('0' + 11 - rem)
+	}
+	return '0'
+}
+
+type validCPF string
+
+func (validCPF) Generate(r *rand.Rand, _ int) reflect.Value {
+	for {
+		base := make([]byte, 9)
+		for i := range base {
+			base[i] = byte('0' + r.Intn(10))
+		}
+		cpf := string(base)
+		cpf += string(weightedDigit(cpf, cpfWeights1))
+		cpf += string(weightedDigit(cpf, cpfWeights2))
+		if !allSame(cpf) {
+			return reflect.ValueOf(validCPF(cpf))
+		}
+	}
+}
+
+type validCNPJ string
+
+func (validCNPJ) Generate(r *rand.Rand, _ int) reflect.Value {
+	// half of the generated documents are numeric, half alphanumeric
+	alphabet := cnpjAlphabet[:10]
+	if r.Intn(2) == 0 {
+		alphabet = cnpjAlphabet
+	}
+
+	for {
+		base := make([]byte, 12)
+		for i := range base {
+			base[i] = alphabet[r.Intn(len(alphabet))]
+		}
+		cnpj := string(base)
+		cnpj += string(weightedDigit(cnpj, cnpjWeights1))
+		cnpj += string(weightedDigit(cnpj, cnpjWeights2))
+		if !allSame(cnpj) {
+			return reflect.ValueOf(validCNPJ(cnpj))
+		}
+	}
+}
+
+func formatCPF(cpf string) string {
+	return fmt.Sprintf("%s.%s.%s-%s", cpf[:3], cpf[3:6], cpf[6:9], cpf[9:])
+}
+
+func formatCNPJ(cnpj string) string {
+	return fmt.Sprintf("%s.%s.%s/%s-%s", cnpj[:2], cnpj[2:5], cnpj[5:8], cnpj[8:12], cnpj[12:])
+}
+
+func TestCPFProperties(t *testing.T) {
+	t.Run("generated CPFs are valid", func(t *testing.T) {
+		prop := func(cpf validCPF) bool {
+			return isValidCPF(string(cpf)) == nil
+		}
+		assert.NoError(t, quick.Check(prop, nil))
+	})
+
+	t.Run("formatted CPFs normalize to valid ones", func(t *testing.T) {
+		prop := func(cpf validCPF) bool {
+			normalized := normalizeDocument(formatCPF(string(cpf)))
+			return normalized == string(cpf) && isValidCPF(normalized) == nil
+		}
+		assert.NoError(t, quick.Check(prop, nil))
+	})
+
+	t.Run("any other last digit is rejected", func(t *testing.T) {
+		prop := func(cpf validCPF, delta uint8) bool {
+			b := []byte(cpf)
+			b[10] = '0' + (b[10]-'0'+1+delta%9)%10
+			return isValidCPF(string(b)) != nil
+		}
+		assert.NoError(t, quick.Check(prop, nil))
+	})
+}
+
+func TestCNPJProperties(t *testing.T) {
+	t.Run("generated CNPJs are valid", func(t *testing.T) {
+		prop := func(cnpj validCNPJ) bool {
+			return isValidCNPJ(string(cnpj)) == nil
+		}
+		assert.NoError(t, quick.Check(prop, nil))
+	})
+
+	t.Run("formatted lower-case CNPJs normalize to valid ones", func(t *testing.T) {
+		prop := func(cnpj validCNPJ) bool {
+			formatted := []byte(formatCNPJ(string(cnpj)))
+			for i, ch := range formatted {
+				if ch >= 'A' && ch <= 'Z' {
+					formatted[i] = ch + ('a' - 'A')
+				}
+			}
+			normalized := normalizeDocument(string(formatted))
+			return normalized == string(cnpj) && isValidCNPJ(normalized) == nil
+		}
+		assert.NoError(t, quick.Check(prop, nil))
+	})
+
+	t.Run("any other last digit is rejected", func(t *testing.T) {
+		prop := func(cnpj validCNPJ, delta uint8) bool {
+			b := []byte(cnpj)
+			b[13] = '0' + (b[13]-'0'+1+delta%9)%10
+			return isValidCNPJ(string(b)) != nil
+		}
+		assert.NoError(t, quick.Check(prop, nil))
+	})
+}
+
+func TestNormalizeDocument(t *testing.T) {
+	tests := []struct {
+		val  string
+		want string
+	}{
+		{"529.982.247-25", "52998224725"},
+		{" 529 982 247 25 ", "52998224725"},
+		{"06.532.946/0001-85", "06532946000185"},
+		{"12.abc.345/01de-35", "12ABC34501DE35"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.val, func(t *testing.T) {
+			assert.Equal(t, tt.want, normalizeDocument(tt.val))
+		})
+	}
+}
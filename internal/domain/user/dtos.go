@@ -2,14 +2,38 @@ package user
 
 type CommonUserDTO struct {
 	Fullname string  `json:"fullname" validate:"required,max=100"`
-	CPF      *string `json:"cpf,omitempty" validate:"omitempty,len=11"`
+	CPF      *string `json:"cpf,omitempty" validate:"omitempty,max=14"`
 	Email    string  `json:"email" validate:"required,email,max=100"`
 	Password string  `json:"password" validate:"required,gte=6,max=100"`
 }
 
 type ShopkeeperUserDTO struct {
-	Fullname string  `json:"fullname" validate:"required,max=100"`
-	CNPJ     *string `json:"cnpj,omitempty" validate:"omitempty,len=14"`
-	Email    string  `json:"email" validate:"required,email,max=100"`
-	Password string  `json:"password" validate:"required,gte=6,max=100"`
+	Fullname  string  `json:"fullname" validate:"required,max=100"`
+	CNPJ      *string `json:"cnpj,omitempty" validate:"omitempty,max=18"`
+	LegalName *string `json:"legal_name,omitempty" validate:"omitempty,max=255"`
+	TradeName *string `json:"trade_name,omitempty" validate:"omitempty,max=255"`
+	Email     string  `json:"email" validate:"required,email,max=100"`
+	Password  string  `json:"password" validate:"required,gte=6,max=100"`
+}
+
+type AdminUserDTO struct {
+	Fullname string `json:"fullname" validate:"required,max=100"`
+	Email    string `json:"email" validate:"required,email,max=100"`
+	Password string `json:"password" validate:"required,gte=6,max=100"`
+}
+
+// UpdateProfileDTO holds the fields a user may change on their own profile;
+// nil fields are left as they are.
+type UpdateProfileDTO struct {
+	Fullname  *string `json:"fullname,omitempty" validate:"omitempty,max=100"`
+	Email     *string `json:"email,omitempty" validate:"omitempty,email,max=100"`
+	LegalName *string `json:"legal_name,omitempty" validate:"omitempty,max=255"`
+	TradeName *string `json:"trade_name,omitempty" validate:"omitempty,max=255"`
+}
+
+type SearchFilter struct {
+	Query  string
+	Role   UserRole
+	Limit  int
+	Offset int
 }
@@ -13,18 +13,38 @@ type UserRole string
 const (
 	Common     UserRole = "common"
 	Shopkeeper UserRole = "shopkeeper"
+	Admin      UserRole = "admin"
+)
+
+// KYCLevel is how well the customer is known, raised as their documents are
+// approved.
+type KYCLevel string
+
+const (
+	KYCNone  KYCLevel = "none"
+	KYCBasic KYCLevel = "basic"
+	KYCFull  KYCLevel = "full"
 )
 
 type User struct {
-	ID        int       `json:"id"`
-	Fullname  string    `json:"fullname"`
-	Role      UserRole  `json:"role"`
-	CPF       *string   `json:"cpf"`
-	CNPJ      *string   `json:"cnpj"`
-	Email     string    `json:"email"`
-	Password  string    `json:"-"`
-	UpdatedAt time.Time `json:"updated_at"`
-	CreatedAt time.Time `json:"created_at"`
+	ID              int        `json:"id"`
+	Fullname        string     `json:"fullname"`
+	Role            UserRole   `json:"role"`
+	CPF             *string    `json:"cpf"`
+	CNPJ            *string    `json:"cnpj"`
+	LegalName       *string    `json:"legal_name,omitempty"`
+	TradeName       *string    `json:"trade_name,omitempty"`
+	Email           string     `json:"email"`
+	Password        string     `json:"-"`
+	KYCLevel        KYCLevel   `json:"kyc_level"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	DeletedAt       *time.Time `json:"deleted_at,omitempty"`
+}
+
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
 }
 
 func (u *User) Validate() error {
@@ -43,6 +63,11 @@ func (u *User) Validate() error {
 		if err := isValidCNPJ(*u.CNPJ); err != nil {
 			return err
 		}
+		if err := isValidCompanyNames(u.LegalName, u.TradeName); err != nil {
+			return err
+		}
+	case Admin:
+		// admins operate the system and are not tied to a CPF or CNPJ
 	default:
 		return errors.New("unsupported user role")
 	}
@@ -62,9 +87,21 @@ func isValidFullname(str string) error {
 	return nil
 }
 
+// isValidCompanyNames requires the legal name of a shopkeeper's company; the
+// trade name is optional.
+func isValidCompanyNames(legalName, tradeName *string) error {
+	if legalName == nil || len(strings.TrimSpace(*legalName)) < 3 {
+		return errors.New("legal name must be at least 3 characters")
+	}
+	if tradeName != nil && len(strings.TrimSpace(*tradeName)) < 2 {
+		return errors.New("trade name must be at least 2 characters")
+	}
+	return nil
+}
+
 func isValidRole(r UserRole) error {
-	if r != Common && r != Shopkeeper {
-		return errors.New("role must be common or shopkeeper")
+	if r != Common && r != Shopkeeper && r != Admin {
+		return errors.New("role must be common, shopkeeper or admin")
 	}
 	return nil
 }
@@ -73,22 +110,52 @@ func isValidCPF(str string) error {
 	if len(str) != 11 {
 		return errors.New("CPF must have exactly 11 digits")
 	}
-	for _, ch := range str {
+
+	values := make([]int, len(str))
+	for i, ch := range str {
 		if !unicode.IsDigit(ch) {
 			return errors.New("CPF must contain only numeric digits")
 		}
+		values[i] = int(ch - '0')
+	}
+
+	if allSame(str) {
+		return errors.New("invalid CPF")
+	}
+
+	first, second := cpfCheckDigits(values[:9])
+	if values[9] != first || values[10] != second {
+		return errors.New("invalid CPF check digits")
 	}
 	return nil
 }
 
+// isValidCNPJ accepts both the numeric CNPJ and the alphanumeric one, whose
+// first 12 characters may be upper-case letters; the check digits stay
+// numeric.
 func isValidCNPJ(str string) error {
 	if len(str) != 14 {
-		return errors.New("CNPJ must have exactly 14 digits")
+		return errors.New("CNPJ must have exactly 14 characters")
 	}
-	for _, ch := range str {
-		if !unicode.IsDigit(ch) {
-			return errors.New("CNPJ must contain only numeric digits")
+
+	values := make([]int, len(str))
+	for i, ch := range str {
+		switch {
+		case ch >= '0' && ch <= '9':
+		case ch >= 'A' && ch <= 'Z' && i < 12:
+		default:
+			return errors.New("CNPJ must contain only digits and upper-case letters")
 		}
+		values[i] = int(ch - '0')
+	}
+
+	if allSame(str) {
+		return errors.New("invalid CNPJ")
+	}
+
+	first, second := cnpjCheckDigits(values[:12])
+	if values[12] != first || values[13] != second {
+		return errors.New("invalid CNPJ check digits")
 	}
 	return nil
 }
@@ -8,7 +8,7 @@ import (
 
 func TestUserValidate(t *testing.T) {
 	t.Run("Valid Common User", func(t *testing.T) {
-		cpf := "12345678901"
+		cpf := "52998224725"
 		user := User{
 			Fullname: "John Doe",
 			Role:     Common,
@@ -21,12 +21,25 @@ func TestUserValidate(t *testing.T) {
 	})
 
 	t.Run("Valid Shopkeeper User", func(t *testing.T) {
-		cnpj := "12345678000199"
+		cnpj := "11222333000181"
+		legalName := "Doe Comercio LTDA"
 		user := User{
-			Fullname: "Jane Shop",
-			Role:     Shopkeeper,
-			CNPJ:     &cnpj,
-			Email:    "jane@shop.com",
+			Fullname:  "Jane Shop",
+			Role:      Shopkeeper,
+			CNPJ:      &cnpj,
+			LegalName: &legalName,
+			Email:     "jane@shop.com",
+			Password:  "strongpass",
+		}
+		err := user.Validate()
+		assert.NoError(t, err)
+	})
+
+	t.Run("Valid Admin User without document", func(t *testing.T) {
+		user := User{
+			Fullname: "Root Admin",
+			Role:     Admin,
+			Email:    "admin@picpay.com",
 			Password: "strongpass",
 		}
 		err := user.Validate()
@@ -34,7 +47,7 @@ func TestUserValidate(t *testing.T) {
 	})
 
 	t.Run("Invalid Fullname", func(t *testing.T) {
-		cpf := "12345678901"
+		cpf := "52998224725"
 		user := User{
 			Fullname: "Jo",
 			Role:     Common,
@@ -47,7 +60,7 @@ func TestUserValidate(t *testing.T) {
 	})
 
 	t.Run("Invalid Role", func(t *testing.T) {
-		cpf := "12345678901"
+		cpf := "52998224725"
 		user := User{
 			Fullname: "John Doe",
 			Role:     "INVALID_ROLE",
@@ -74,6 +87,21 @@ func TestUserValidate(t *testing.T) {
 
 	t.Run("Invalid CNPJ for Shopkeeper User", func(t *testing.T) {
 		cnpj := "12a45678000199"
+		legalName := "Doe Comercio LTDA"
+		user := User{
+			Fullname:  "Jane Shop",
+			Role:      Shopkeeper,
+			CNPJ:      &cnpj,
+			LegalName: &legalName,
+			Email:     "jane@shop.com",
+			Password:  "strongpass",
+		}
+		err := user.Validate()
+		assert.Error(t, err)
+	})
+
+	t.Run("Shopkeeper without legal name", func(t *testing.T) {
+		cnpj := "11222333000181"
 		user := User{
 			Fullname: "Jane Shop",
 			Role:     Shopkeeper,
@@ -85,8 +113,25 @@ func TestUserValidate(t *testing.T) {
 		assert.Error(t, err)
 	})
 
+	t.Run("Shopkeeper with blank trade name", func(t *testing.T) {
+		cnpj := "11222333000181"
+		legalName := "Doe Comercio LTDA"
+		tradeName := " "
+		user := User{
+			Fullname:  "Jane Shop",
+			Role:      Shopkeeper,
+			CNPJ:      &cnpj,
+			LegalName: &legalName,
+			TradeName: &tradeName,
+			Email:     "jane@shop.com",
+			Password:  "strongpass",
+		}
+		err := user.Validate()
+		assert.Error(t, err)
+	})
+
 	t.Run("Invalid Email", func(t *testing.T) {
-		cpf := "12345678901"
+		cpf := "52998224725"
 		user := User{
 			Fullname: "John Doe",
 			Role:     Common,
@@ -99,7 +144,7 @@ func TestUserValidate(t *testing.T) {
 	})
 
 	t.Run("Invalid Password", func(t *testing.T) {
-		cpf := "12345678901"
+		cpf := "52998224725"
 		user := User{
 			Fullname: "John Doe",
 			Role:     Common,
@@ -144,6 +189,7 @@ func TestIsValidRole(t *testing.T) {
 	}{
 		{"Common Role", Common, true},
 		{"Shopkeeper Role", Shopkeeper, true},
+		{"Admin Role", Admin, true},
 		{"Invalid Role", "ADMIN", false},
 	}
 
@@ -165,10 +211,15 @@ func TestIsValidCPF(t *testing.T) {
 		val  string
 		want bool
 	}{
-		{"Valid Input", "12345678901", true},
+		{"Valid Input", "52998224725", true},
+		{"Valid Input With Zero Check Digit", "11144477735", true},
 		{"Less Than 11 Digits", "1234567890", false},
 		{"More Than 11 Digits", "123456789012", false},
 		{"Non Numeric Characters", "12345abc901", false},
+		{"Wrong First Check Digit", "52998224735", false},
+		{"Wrong Second Check Digit", "52998224726", false},
+		{"Repeated Digits", "00000000000", false},
+		{"Repeated Digits With Valid Check Digits", "11111111111", false},
 	}
 
 	for _, tt := range tests {
@@ -190,8 +241,14 @@ func TestIsValidCNPJ(t *testing.T) {
 		want bool
 	}{
 		{"Valid Input", "06532946000185", true},
+		{"Valid Alphanumeric Input", "12ABC34501DE35", true},
 		{"Invalid Characters", "06532946000abc", false},
+		{"Lower-Case Letters", "12abc34501de35", false},
+		{"Letters In Check Digits", "12ABC34501DEAB", false},
 		{"Invalid Length", "17.901.294/0001-25", false},
+		{"Wrong Check Digits", "06532946000186", false},
+		{"Wrong Alphanumeric Check Digits", "12ABC34501DF35", false},
+		{"Repeated Digits", "00000000000000", false},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,20 @@
+package user
+
+import (
+	"net/http"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/utils"
+)
+
+type UserHandler struct{}
+
+// Me returns the authenticated user, as loaded by the auth middleware.
+func (h *UserHandler) Me(w http.ResponseWriter, r *http.Request) error {
+	usr := r.Context().Value(utils.UserKey).(*User)
+
+	return utils.WriteJSON(w, http.StatusOK, usr)
+}
+
+func NewUserHandler() *UserHandler {
+	return &UserHandler{}
+}
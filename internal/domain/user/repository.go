@@ -5,6 +5,8 @@ import (
 	"database/sql"
 	"errors"
 	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/db"
 )
 
 type UserRepository interface {
@@ -13,6 +15,15 @@ type UserRepository interface {
 	FindByCNPJ(ctx context.Context, cnpj string) (*User, error)
 	FindByEmail(ctx context.Context, email string) (*User, error)
 	FindByID(ctx context.Context, id int) (*User, error)
+	Search(ctx context.Context, filter SearchFilter) ([]User, error)
+	UpdatePassword(ctx context.Context, id int, password string, updatedAt time.Time) error
+	// UpdatePasswordIfUnchanged replaces the password only while it still
+	// is oldHash, reporting whether it did.
+	UpdatePasswordIfUnchanged(ctx context.Context, id int, oldHash, newHash string, updatedAt time.Time) (bool, error)
+	UpdateProfile(ctx context.Context, u User) error
+	UpdateKYCLevel(ctx context.Context, id int, level KYCLevel, updatedAt time.Time) error
+	MarkEmailVerified(ctx context.Context, id int, verifiedAt time.Time) error
+	Close(ctx context.Context, anonymized User) error
 }
 
 type userRepo struct {
@@ -22,8 +33,9 @@ type userRepo struct {
 
 func (r *userRepo) Save(ctx context.Context, u User) (int, error) {
 	query := `
-		INSERT INTO users (fullname, role, cpf, cnpj, email, password, updated_at, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO users (fullname, role, cpf, cnpj, email, password, email_verified_at, updated_at, created_at,
+			kyc_level, legal_name, trade_name)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING id
 	`
 
@@ -36,7 +48,8 @@ func (r *userRepo) Save(ctx context.Context, u User) (int, error) {
 	err := db.QueryRowContext(
 		ctx,
 		query,
-		u.Fullname, u.Role, u.CPF, u.CNPJ, u.Email, u.Password, u.UpdatedAt, u.CreatedAt,
+		u.Fullname, u.Role, u.CPF, u.CNPJ, u.Email, u.Password, u.EmailVerifiedAt, u.UpdatedAt, u.CreatedAt,
+		u.KYCLevel, u.LegalName, u.TradeName,
 	).Scan(&userId)
 	if err != nil {
 		return 0, err
@@ -47,9 +60,10 @@ func (r *userRepo) Save(ctx context.Context, u User) (int, error) {
 
 func (r *userRepo) FindByCPF(ctx context.Context, cpf string) (*User, error) {
 	query := `
-		SELECT id, fullname, role, cpf, cnpj, email, password, updated_at, created_at
+		SELECT id, fullname, role, cpf, cnpj, email, password, email_verified_at, updated_at, created_at, deleted_at,
+			kyc_level, legal_name, trade_name
 		FROM users
-		WHERE cpf = $1
+		WHERE cpf = $1 AND deleted_at IS NULL
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
@@ -68,8 +82,13 @@ func (r *userRepo) FindByCPF(ctx context.Context, cpf string) (*User, error) {
 		&cnpjPtr,
 		&u.Email,
 		&u.Password,
+		&u.EmailVerifiedAt,
 		&u.UpdatedAt,
 		&u.CreatedAt,
+		&u.DeletedAt,
+		&u.KYCLevel,
+		&u.LegalName,
+		&u.TradeName,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -86,9 +105,10 @@ func (r *userRepo) FindByCPF(ctx context.Context, cpf string) (*User, error) {
 
 func (r *userRepo) FindByCNPJ(ctx context.Context, cnpj string) (*User, error) {
 	query := `
-		SELECT id, fullname, role, cpf, cnpj, email, password, updated_at, created_at
+		SELECT id, fullname, role, cpf, cnpj, email, password, email_verified_at, updated_at, created_at, deleted_at,
+			kyc_level, legal_name, trade_name
 		FROM users
-		WHERE cnpj = $1
+		WHERE cnpj = $1 AND deleted_at IS NULL
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
@@ -107,8 +127,13 @@ func (r *userRepo) FindByCNPJ(ctx context.Context, cnpj string) (*User, error) {
 		&cnpjPtr,
 		&u.Email,
 		&u.Password,
+		&u.EmailVerifiedAt,
 		&u.UpdatedAt,
 		&u.CreatedAt,
+		&u.DeletedAt,
+		&u.KYCLevel,
+		&u.LegalName,
+		&u.TradeName,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -125,9 +150,10 @@ func (r *userRepo) FindByCNPJ(ctx context.Context, cnpj string) (*User, error) {
 
 func (r *userRepo) FindByEmail(ctx context.Context, email string) (*User, error) {
 	query := `
-		SELECT id, fullname, role, cpf, cnpj, email, password, updated_at, created_at
+		SELECT id, fullname, role, cpf, cnpj, email, password, email_verified_at, updated_at, created_at, deleted_at,
+			kyc_level, legal_name, trade_name
 		FROM users
-		WHERE email = $1
+		WHERE email = $1 AND deleted_at IS NULL
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
@@ -146,8 +172,13 @@ func (r *userRepo) FindByEmail(ctx context.Context, email string) (*User, error)
 		&cnpjPtr,
 		&u.Email,
 		&u.Password,
+		&u.EmailVerifiedAt,
 		&u.UpdatedAt,
 		&u.CreatedAt,
+		&u.DeletedAt,
+		&u.KYCLevel,
+		&u.LegalName,
+		&u.TradeName,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -164,9 +195,10 @@ func (r *userRepo) FindByEmail(ctx context.Context, email string) (*User, error)
 
 func (r *userRepo) FindByID(ctx context.Context, id int) (*User, error) {
 	query := `
-		SELECT id, fullname, role, cpf, cnpj, email, password, updated_at, created_at
+		SELECT id, fullname, role, cpf, cnpj, email, password, email_verified_at, updated_at, created_at, deleted_at,
+			kyc_level, legal_name, trade_name
 		FROM users
-		WHERE id = $1
+		WHERE id = $1 AND deleted_at IS NULL
 	`
 
 	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
@@ -185,8 +217,13 @@ func (r *userRepo) FindByID(ctx context.Context, id int) (*User, error) {
 		&cnpjPtr,
 		&u.Email,
 		&u.Password,
+		&u.EmailVerifiedAt,
 		&u.UpdatedAt,
 		&u.CreatedAt,
+		&u.DeletedAt,
+		&u.KYCLevel,
+		&u.LegalName,
+		&u.TradeName,
 	)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -201,6 +238,170 @@ func (r *userRepo) FindByID(ctx context.Context, id int) (*User, error) {
 	return &u, nil
 }
 
+func (r *userRepo) Search(ctx context.Context, filter SearchFilter) ([]User, error) {
+	query := `
+		SELECT id, fullname, role, cpf, cnpj, email, password, email_verified_at, updated_at, created_at, deleted_at,
+			kyc_level, legal_name, trade_name
+		FROM users
+		WHERE ($1::text = '' OR fullname ILIKE '%' || $1::text || '%' OR email ILIKE '%' || $1::text || '%'
+			OR cpf = $1::text OR cnpj = $1::text)
+		AND ($2::text = '' OR role::text = $2::text)
+		ORDER BY id
+		LIMIT $3 OFFSET $4
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	rows, err := r.database.QueryContext(ctx, query, filter.Query, string(filter.Role), filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []User{}
+	for rows.Next() {
+		var u User
+		var cpfPtr, cnpjPtr *string
+
+		err := rows.Scan(
+			&u.ID,
+			&u.Fullname,
+			&u.Role,
+			&cpfPtr,
+			&cnpjPtr,
+			&u.Email,
+			&u.Password,
+			&u.EmailVerifiedAt,
+			&u.UpdatedAt,
+			&u.CreatedAt,
+			&u.DeletedAt,
+			&u.KYCLevel,
+			&u.LegalName,
+			&u.TradeName,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		u.CPF = cpfPtr
+		u.CNPJ = cnpjPtr
+
+		users = append(users, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (r *userRepo) UpdatePassword(ctx context.Context, id int, password string, updatedAt time.Time) error {
+	query := `
+		UPDATE users
+		SET password = $2, updated_at = $3
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := r.database.ExecContext(ctx, query, id, password, updatedAt)
+	return err
+}
+
+func (r *userRepo) UpdatePasswordIfUnchanged(ctx context.Context, id int, oldHash, newHash string, updatedAt time.Time) (bool, error) {
+	query := `
+		UPDATE users
+		SET password = $3, updated_at = $4
+		WHERE id = $1 AND password = $2 AND deleted_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	res, err := r.database.ExecContext(ctx, query, id, oldHash, newHash, updatedAt)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n == 1, nil
+}
+
+func (r *userRepo) UpdateProfile(ctx context.Context, u User) error {
+	query := `
+		UPDATE users
+		SET fullname = $2, email = $3, email_verified_at = $4, legal_name = $5, trade_name = $6, updated_at = $7
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := r.database.ExecContext(
+		ctx,
+		query,
+		u.ID, u.Fullname, u.Email, u.EmailVerifiedAt, u.LegalName, u.TradeName, u.UpdatedAt,
+	)
+	return err
+}
+
+func (r *userRepo) UpdateKYCLevel(ctx context.Context, id int, level KYCLevel, updatedAt time.Time) error {
+	query := `
+		UPDATE users
+		SET kyc_level = $2, updated_at = $3
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := r.database.ExecContext(ctx, query, id, level, updatedAt)
+	return err
+}
+
+func (r *userRepo) MarkEmailVerified(ctx context.Context, id int, verifiedAt time.Time) error {
+	query := `
+		UPDATE users
+		SET email_verified_at = $2, updated_at = $2
+		WHERE id = $1 AND email_verified_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := r.database.ExecContext(ctx, query, id, verifiedAt)
+	return err
+}
+
+// Close overwrites the personal data of the user with anonymized. It joins
+// the transaction on ctx, if any.
+func (r *userRepo) Close(ctx context.Context, anonymized User) error {
+	query := `
+		UPDATE users
+		SET fullname = $2, email = $3, cpf = $4, cnpj = $5, password = $6,
+			legal_name = NULL, trade_name = NULL, kyc_level = 'none', updated_at = $7, deleted_at = $8
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := db.Conn(ctx, r.database).ExecContext(
+		ctx,
+		query,
+		anonymized.ID, anonymized.Fullname, anonymized.Email, anonymized.CPF, anonymized.CNPJ,
+		anonymized.Password, anonymized.UpdatedAt, anonymized.DeletedAt,
+	)
+	return err
+}
+
 func NewUserRepository(database *sql.DB, qt time.Duration) UserRepository {
 	return &userRepo{
 		database:     database,
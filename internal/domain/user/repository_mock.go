@@ -2,6 +2,7 @@ package user
 
 import (
 	"context"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -46,3 +47,41 @@ func (m *MockUserRepository) FindByID(ctx context.Context, id int) (*User, error
 	}
 	return nil, args.Error(1)
 }
+
+func (m *MockUserRepository) Search(ctx context.Context, filter SearchFilter) ([]User, error) {
+	args := m.Called(ctx, filter)
+	if u, ok := args.Get(0).([]User); ok {
+		return u, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUserRepository) UpdatePassword(ctx context.Context, id int, password string, updatedAt time.Time) error {
+	args := m.Called(ctx, id, password, updatedAt)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdatePasswordIfUnchanged(ctx context.Context, id int, oldHash, newHash string, updatedAt time.Time) (bool, error) {
+	args := m.Called(ctx, id, oldHash, newHash, updatedAt)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) UpdateProfile(ctx context.Context, u User) error {
+	args := m.Called(ctx, u)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UpdateKYCLevel(ctx context.Context, id int, level KYCLevel, updatedAt time.Time) error {
+	args := m.Called(ctx, id, level, updatedAt)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) MarkEmailVerified(ctx context.Context, id int, verifiedAt time.Time) error {
+	args := m.Called(ctx, id, verifiedAt)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) Close(ctx context.Context, anonymized User) error {
+	args := m.Called(ctx, anonymized)
+	return args.Error(0)
+}
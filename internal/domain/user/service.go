@@ -2,19 +2,35 @@ package user
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/tracing"
 )
 
+const maxSearchLimit = 100
+
 type UserService interface {
 	CreateCommon(ctx context.Context, dto CommonUserDTO) (int, error)
 	CreateShopkeeper(ctx context.Context, dto ShopkeeperUserDTO) (int, error)
+	CreateAdmin(ctx context.Context, dto AdminUserDTO) (int, error)
 	FindByCPF(ctx context.Context, cpf string) (*User, error)
 	FindByCNPJ(ctx context.Context, cnpj string) (*User, error)
 	FindByEmail(ctx context.Context, email string) (*User, error)
 	FindByID(ctx context.Context, id int) (*User, error)
+	Search(ctx context.Context, filter SearchFilter) ([]User, error)
+	UpdatePassword(ctx context.Context, id int, password string) error
+	// UpdatePasswordIfUnchanged replaces the password only while it still
+	// is oldHash, so an upgrade cannot undo a concurrent password change.
+	UpdatePasswordIfUnchanged(ctx context.Context, id int, oldHash, newHash string) (bool, error)
+	UpdateProfile(ctx context.Context, id int, dto UpdateProfileDTO) (*User, error)
+	SetKYCLevel(ctx context.Context, id int, level KYCLevel) error
+	MarkEmailVerified(ctx context.Context, id int) error
+	Close(ctx context.Context, id int) error
 }
 
 type userSvc struct {
@@ -22,14 +38,18 @@ type userSvc struct {
 }
 
 func (s *userSvc) CreateCommon(ctx context.Context, dto CommonUserDTO) (int, error) {
+	ctx, span := tracing.Start(ctx, "userSvc.CreateCommon")
+	defer span.End()
+
 	now := time.Now()
 
 	user := User{
 		Fullname:  dto.Fullname,
 		Role:      Common,
-		CPF:       dto.CPF,
+		CPF:       normalizeDocumentPtr(dto.CPF),
 		Email:     dto.Email,
 		Password:  dto.Password,
+		KYCLevel:  KYCNone,
 		UpdatedAt: now,
 		CreatedAt: now,
 	}
@@ -48,14 +68,20 @@ func (s *userSvc) CreateCommon(ctx context.Context, dto CommonUserDTO) (int, err
 }
 
 func (s *userSvc) CreateShopkeeper(ctx context.Context, dto ShopkeeperUserDTO) (int, error) {
+	ctx, span := tracing.Start(ctx, "userSvc.CreateShopkeeper")
+	defer span.End()
+
 	now := time.Now()
 
 	user := User{
 		Fullname:  dto.Fullname,
 		Role:      Shopkeeper,
-		CNPJ:      dto.CNPJ,
+		CNPJ:      normalizeDocumentPtr(dto.CNPJ),
+		LegalName: trimPtr(dto.LegalName),
+		TradeName: trimPtr(dto.TradeName),
 		Email:     dto.Email,
 		Password:  dto.Password,
+		KYCLevel:  KYCNone,
 		UpdatedAt: now,
 		CreatedAt: now,
 	}
@@ -73,8 +99,42 @@ func (s *userSvc) CreateShopkeeper(ctx context.Context, dto ShopkeeperUserDTO) (
 	return userId, nil
 }
 
+func (s *userSvc) CreateAdmin(ctx context.Context, dto AdminUserDTO) (int, error) {
+	ctx, span := tracing.Start(ctx, "userSvc.CreateAdmin")
+	defer span.End()
+
+	now := time.Now()
+
+	// admins are configured by the operator, so their email is trusted
+	user := User{
+		Fullname:        dto.Fullname,
+		Role:            Admin,
+		Email:           dto.Email,
+		Password:        dto.Password,
+		KYCLevel:        KYCNone,
+		EmailVerifiedAt: &now,
+		UpdatedAt:       now,
+		CreatedAt:       now,
+	}
+
+	err := user.Validate()
+	if err != nil {
+		return 0, apperror.NewHttpError(http.StatusUnprocessableEntity, err.Error())
+	}
+
+	userId, err := s.userRepo.Save(ctx, user)
+	if err != nil {
+		return 0, err
+	}
+
+	return userId, nil
+}
+
 func (s *userSvc) FindByCPF(ctx context.Context, cpf string) (*User, error) {
-	usr, err := s.userRepo.FindByCPF(ctx, cpf)
+	ctx, span := tracing.Start(ctx, "userSvc.FindByCPF")
+	defer span.End()
+
+	usr, err := s.userRepo.FindByCPF(ctx, normalizeDocument(cpf))
 	if err != nil {
 		return nil, err
 	}
@@ -87,7 +147,10 @@ func (s *userSvc) FindByCPF(ctx context.Context, cpf string) (*User, error) {
 }
 
 func (s *userSvc) FindByCNPJ(ctx context.Context, cnpj string) (*User, error) {
-	usr, err := s.userRepo.FindByCNPJ(ctx, cnpj)
+	ctx, span := tracing.Start(ctx, "userSvc.FindByCNPJ")
+	defer span.End()
+
+	usr, err := s.userRepo.FindByCNPJ(ctx, normalizeDocument(cnpj))
 	if err != nil {
 		return nil, err
 	}
@@ -100,6 +163,9 @@ func (s *userSvc) FindByCNPJ(ctx context.Context, cnpj string) (*User, error) {
 }
 
 func (s *userSvc) FindByEmail(ctx context.Context, email string) (*User, error) {
+	ctx, span := tracing.Start(ctx, "userSvc.FindByEmail")
+	defer span.End()
+
 	usr, err := s.userRepo.FindByEmail(ctx, email)
 	if err != nil {
 		return nil, err
@@ -113,6 +179,9 @@ func (s *userSvc) FindByEmail(ctx context.Context, email string) (*User, error)
 }
 
 func (s *userSvc) FindByID(ctx context.Context, id int) (*User, error) {
+	ctx, span := tracing.Start(ctx, "userSvc.FindByID")
+	defer span.End()
+
 	usr, err := s.userRepo.FindByID(ctx, id)
 	if err != nil {
 		return nil, err
@@ -125,6 +194,150 @@ func (s *userSvc) FindByID(ctx context.Context, id int) (*User, error) {
 	return usr, nil
 }
 
+func (s *userSvc) Search(ctx context.Context, filter SearchFilter) ([]User, error) {
+	ctx, span := tracing.Start(ctx, "userSvc.Search")
+	defer span.End()
+
+	if filter.Role != "" {
+		if err := isValidRole(filter.Role); err != nil {
+			return nil, apperror.NewHttpError(http.StatusBadRequest, err.Error())
+		}
+	}
+
+	if filter.Limit <= 0 || filter.Limit > maxSearchLimit {
+		filter.Limit = maxSearchLimit
+	}
+
+	if filter.Offset < 0 {
+		filter.Offset = 0
+	}
+
+	return s.userRepo.Search(ctx, filter)
+}
+
+func (s *userSvc) UpdatePassword(ctx context.Context, id int, password string) error {
+	ctx, span := tracing.Start(ctx, "userSvc.UpdatePassword")
+	defer span.End()
+
+	return s.userRepo.UpdatePassword(ctx, id, password, time.Now())
+}
+
+func (s *userSvc) UpdatePasswordIfUnchanged(ctx context.Context, id int, oldHash, newHash string) (bool, error) {
+	ctx, span := tracing.Start(ctx, "userSvc.UpdatePasswordIfUnchanged")
+	defer span.End()
+
+	return s.userRepo.UpdatePasswordIfUnchanged(ctx, id, oldHash, newHash, time.Now())
+}
+
+// UpdateProfile changes the user's name and email. A new email is no longer
+// verified, so the user has to confirm it before sending transfers again.
+func (s *userSvc) UpdateProfile(ctx context.Context, id int, dto UpdateProfileDTO) (*User, error) {
+	ctx, span := tracing.Start(ctx, "userSvc.UpdateProfile")
+	defer span.End()
+
+	usr, err := s.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	updated := *usr
+
+	if dto.Fullname != nil {
+		updated.Fullname = strings.TrimSpace(*dto.Fullname)
+	}
+
+	if dto.LegalName != nil || dto.TradeName != nil {
+		if usr.Role != Shopkeeper {
+			return nil, apperror.NewHttpError(http.StatusUnprocessableEntity, "only shopkeepers have a legal or trade name")
+		}
+		if dto.LegalName != nil {
+			updated.LegalName = trimPtr(dto.LegalName)
+		}
+		if dto.TradeName != nil {
+			updated.TradeName = trimPtr(dto.TradeName)
+		}
+	}
+
+	if dto.Email != nil && *dto.Email != usr.Email {
+		withSameEmail, err := s.userRepo.FindByEmail(ctx, *dto.Email)
+		if err != nil {
+			return nil, err
+		}
+
+		if withSameEmail != nil {
+			return nil, apperror.NewHttpError(http.StatusConflict, "email already in use")
+		}
+
+		updated.Email = *dto.Email
+		updated.EmailVerifiedAt = nil
+	}
+
+	if err := updated.Validate(); err != nil {
+		return nil, apperror.NewHttpError(http.StatusUnprocessableEntity, err.Error())
+	}
+
+	updated.UpdatedAt = time.Now()
+
+	if err := s.userRepo.UpdateProfile(ctx, updated); err != nil {
+		return nil, err
+	}
+
+	return &updated, nil
+}
+
+func (s *userSvc) SetKYCLevel(ctx context.Context, id int, level KYCLevel) error {
+	ctx, span := tracing.Start(ctx, "userSvc.SetKYCLevel")
+	defer span.End()
+
+	return s.userRepo.UpdateKYCLevel(ctx, id, level, time.Now())
+}
+
+func (s *userSvc) MarkEmailVerified(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "userSvc.MarkEmailVerified")
+	defer span.End()
+
+	return s.userRepo.MarkEmailVerified(ctx, id, time.Now())
+}
+
+// Close soft-deletes the user. The name, email and documents are replaced by
+// a random pseudonym, so ledger entries still point at an account but no
+// longer at a person.
+func (s *userSvc) Close(ctx context.Context, id int) error {
+	ctx, span := tracing.Start(ctx, "userSvc.Close")
+	defer span.End()
+
+	usr, err := s.FindByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	pseudonym := "deleted-" + hex.EncodeToString(b)
+
+	now := time.Now()
+	anonymized := User{
+		ID:        usr.ID,
+		Fullname:  pseudonym,
+		Role:      usr.Role,
+		Email:     pseudonym + "@deleted.invalid",
+		UpdatedAt: now,
+		DeletedAt: &now,
+	}
+
+	return s.userRepo.Close(ctx, anonymized)
+}
+
+func trimPtr(str *string) *string {
+	if str == nil {
+		return nil
+	}
+	trimmed := strings.TrimSpace(*str)
+	return &trimmed
+}
+
 func NewUserService(userRepo UserRepository) UserService {
 	return &userSvc{
 		userRepo,
@@ -20,6 +20,11 @@ func (m *MockUserService) CreateShopkeeper(ctx context.Context, dto ShopkeeperUs
 	return args.Int(0), args.Error(1)
 }
 
+func (m *MockUserService) CreateAdmin(ctx context.Context, dto AdminUserDTO) (int, error) {
+	args := m.Called(ctx, dto)
+	return args.Int(0), args.Error(1)
+}
+
 func (m *MockUserService) FindByCPF(ctx context.Context, cpf string) (*User, error) {
 	args := m.Called(ctx, cpf)
 	u, ok := args.Get(0).(*User)
@@ -55,3 +60,45 @@ func (m *MockUserService) FindByID(ctx context.Context, id int) (*User, error) {
 	}
 	return u, args.Error(1)
 }
+
+func (m *MockUserService) Search(ctx context.Context, filter SearchFilter) ([]User, error) {
+	args := m.Called(ctx, filter)
+	u, ok := args.Get(0).([]User)
+	if !ok && args.Get(0) != nil {
+		panic("expected []User or nil")
+	}
+	return u, args.Error(1)
+}
+
+func (m *MockUserService) UpdatePassword(ctx context.Context, id int, password string) error {
+	args := m.Called(ctx, id, password)
+	return args.Error(0)
+}
+
+func (m *MockUserService) UpdatePasswordIfUnchanged(ctx context.Context, id int, oldHash, newHash string) (bool, error) {
+	args := m.Called(ctx, id, oldHash, newHash)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserService) UpdateProfile(ctx context.Context, id int, dto UpdateProfileDTO) (*User, error) {
+	args := m.Called(ctx, id, dto)
+	if u, ok := args.Get(0).(*User); ok {
+		return u, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockUserService) SetKYCLevel(ctx context.Context, id int, level KYCLevel) error {
+	args := m.Called(ctx, id, level)
+	return args.Error(0)
+}
+
+func (m *MockUserService) MarkEmailVerified(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserService) Close(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
 	"github.com/stretchr/testify/assert"
@@ -16,7 +17,7 @@ func TestUserService_CreateCommon(t *testing.T) {
 		mockRepo := new(MockUserRepository)
 		service := NewUserService(mockRepo)
 
-		cpf := "12345678900"
+		cpf := "52998224725"
 		dto := CommonUserDTO{
 			Fullname: "",
 			CPF:      &cpf,
@@ -43,7 +44,7 @@ func TestUserService_CreateCommon(t *testing.T) {
 
 		service := NewUserService(mockRepo)
 
-		cpf := "12345678900"
+		cpf := "52998224725"
 		dto := CommonUserDTO{
 			Fullname: "John Doe",
 			CPF:      &cpf,
@@ -72,7 +73,7 @@ func TestUserService_CreateCommon(t *testing.T) {
 
 		service := NewUserService(mockRepo)
 
-		cpf := "12345678900"
+		cpf := "52998224725"
 		dto := CommonUserDTO{
 			Fullname: "John Doe",
 			CPF:      &cpf,
@@ -100,12 +101,14 @@ func TestUserService_CreateShopkeeper(t *testing.T) {
 		mockRepo := new(MockUserRepository)
 		service := NewUserService(mockRepo)
 
-		cnpj := "12345678901234"
+		cnpj := "11222333000181"
+		legalName := "Doe Comercio LTDA"
 		dto := ShopkeeperUserDTO{
-			Fullname: "",
-			CNPJ:     &cnpj,
-			Email:    "test@test.com",
-			Password: "pass123",
+			Fullname:  "",
+			CNPJ:      &cnpj,
+			LegalName: &legalName,
+			Email:     "test@test.com",
+			Password:  "pass123",
 		}
 
 		id, err := service.CreateShopkeeper(context.Background(), dto)
@@ -127,12 +130,14 @@ func TestUserService_CreateShopkeeper(t *testing.T) {
 
 		service := NewUserService(mockRepo)
 
-		cnpj := "12345678901234"
+		cnpj := "11222333000181"
+		legalName := "Doe Comercio LTDA"
 		dto := ShopkeeperUserDTO{
-			Fullname: "John Doe",
-			CNPJ:     &cnpj,
-			Email:    "test@test.com",
-			Password: "pass123",
+			Fullname:  "John Doe",
+			CNPJ:      &cnpj,
+			LegalName: &legalName,
+			Email:     "test@test.com",
+			Password:  "pass123",
 		}
 
 		id, err := service.CreateShopkeeper(context.Background(), dto)
@@ -156,12 +161,14 @@ func TestUserService_CreateShopkeeper(t *testing.T) {
 
 		service := NewUserService(mockRepo)
 
-		cnpj := "12345678901234"
+		cnpj := "11222333000181"
+		legalName := "Doe Comercio LTDA"
 		dto := ShopkeeperUserDTO{
-			Fullname: "John Doe",
-			CNPJ:     &cnpj,
-			Email:    "test@test.com",
-			Password: "pass123",
+			Fullname:  "John Doe",
+			CNPJ:      &cnpj,
+			LegalName: &legalName,
+			Email:     "test@test.com",
+			Password:  "pass123",
 		}
 
 		id, err := service.CreateShopkeeper(context.Background(), dto)
@@ -177,6 +184,86 @@ func TestUserService_CreateShopkeeper(t *testing.T) {
 
 		mockRepo.AssertExpectations(t)
 	})
+
+	t.Run("should store a formatted cnpj as plain characters", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+
+		var entity User
+
+		mockRepo.On("Save", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				entity = args.Get(1).(User)
+			}).Return(1, nil)
+
+		service := NewUserService(mockRepo)
+
+		cnpj := "12.abc.345/01de-35"
+		legalName := "Doe Comercio LTDA"
+		dto := ShopkeeperUserDTO{
+			Fullname:  "John Doe",
+			CNPJ:      &cnpj,
+			LegalName: &legalName,
+			Email:     "test@test.com",
+			Password:  "pass123",
+		}
+
+		_, err := service.CreateShopkeeper(context.Background(), dto)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "12ABC34501DE35", *entity.CNPJ)
+	})
+}
+
+func TestUserService_CreateAdmin(t *testing.T) {
+	t.Run("should return unprocessable entity if validation fails", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+
+		dto := AdminUserDTO{
+			Fullname: "Root",
+			Email:    "invalid-email",
+			Password: "pass123",
+		}
+
+		id, err := service.CreateAdmin(context.Background(), dto)
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusUnprocessableEntity, httpError.Code)
+		assert.Equal(t, 0, id)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should create an admin user without document", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+
+		var entity User
+
+		mockRepo.On("Save", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				entity = args.Get(1).(User)
+			}).Return(7, nil)
+
+		service := NewUserService(mockRepo)
+
+		dto := AdminUserDTO{
+			Fullname: "Root Admin",
+			Email:    "admin@picpay.com",
+			Password: "hashed",
+		}
+
+		id, err := service.CreateAdmin(context.Background(), dto)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 7, id)
+		assert.Equal(t, Admin, entity.Role)
+		assert.Nil(t, entity.CPF)
+		assert.Nil(t, entity.CNPJ)
+		assert.True(t, entity.IsEmailVerified())
+
+		mockRepo.AssertExpectations(t)
+	})
 }
 
 func TestUserService_FindByCPF(t *testing.T) {
@@ -386,3 +473,156 @@ func TestUserService_FindByID(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 }
+
+func TestUserService_Search(t *testing.T) {
+	t.Run("should return bad request for unknown role", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		service := NewUserService(mockRepo)
+
+		users, err := service.Search(context.Background(), SearchFilter{Role: "root"})
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusBadRequest, httpError.Code)
+		assert.Nil(t, users)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should clamp limit and offset", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		mockRepo.On("Search", mock.Anything, SearchFilter{
+			Query:  "john",
+			Limit:  maxSearchLimit,
+			Offset: 0,
+		}).Return([]User{{ID: 1}}, nil)
+
+		service := NewUserService(mockRepo)
+
+		users, err := service.Search(context.Background(), SearchFilter{
+			Query:  "john",
+			Limit:  1000,
+			Offset: -5,
+		})
+
+		assert.NoError(t, err)
+		assert.Len(t, users, 1)
+
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestUserService_UpdateProfile(t *testing.T) {
+	verifiedAt := time.Now()
+	cpf := "52998224725"
+	existing := func() *User {
+		return &User{
+			ID:              1,
+			Fullname:        "John Doe",
+			Role:            Common,
+			CPF:             &cpf,
+			Email:           "john@email.com",
+			Password:        "hashed-password",
+			EmailVerifiedAt: &verifiedAt,
+		}
+	}
+
+	t.Run("should keep the email verified when only the name changes", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		mockRepo.On("FindByID", mock.Anything, 1).Return(existing(), nil)
+		mockRepo.On("UpdateProfile", mock.Anything, mock.MatchedBy(func(u User) bool {
+			return u.Fullname == "John Smith" && u.Email == "john@email.com" && u.EmailVerifiedAt != nil
+		})).Return(nil).Once()
+
+		service := NewUserService(mockRepo)
+
+		fullname := "  John Smith "
+		usr, err := service.UpdateProfile(context.Background(), 1, UpdateProfileDTO{Fullname: &fullname})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "John Smith", usr.Fullname)
+		mockRepo.AssertExpectations(t)
+		mockRepo.AssertNotCalled(t, "FindByEmail", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should require a new email to be verified again", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		mockRepo.On("FindByID", mock.Anything, 1).Return(existing(), nil)
+		mockRepo.On("FindByEmail", mock.Anything, "new@email.com").Return(nil, nil)
+		mockRepo.On("UpdateProfile", mock.Anything, mock.Anything).Return(nil).Once()
+
+		service := NewUserService(mockRepo)
+
+		email := "new@email.com"
+		usr, err := service.UpdateProfile(context.Background(), 1, UpdateProfileDTO{Email: &email})
+
+		assert.NoError(t, err)
+		assert.Equal(t, email, usr.Email)
+		assert.Nil(t, usr.EmailVerifiedAt)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should return conflict if the email is taken", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		mockRepo.On("FindByID", mock.Anything, 1).Return(existing(), nil)
+		mockRepo.On("FindByEmail", mock.Anything, "taken@email.com").Return(&User{ID: 2}, nil)
+
+		service := NewUserService(mockRepo)
+
+		email := "taken@email.com"
+		usr, err := service.UpdateProfile(context.Background(), 1, UpdateProfileDTO{Email: &email})
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusConflict, httpError.Code)
+		assert.Nil(t, usr)
+		mockRepo.AssertNotCalled(t, "UpdateProfile", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should return unprocessable entity if validation fails", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		mockRepo.On("FindByID", mock.Anything, 1).Return(existing(), nil)
+
+		service := NewUserService(mockRepo)
+
+		fullname := "Jo"
+		usr, err := service.UpdateProfile(context.Background(), 1, UpdateProfileDTO{Fullname: &fullname})
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusUnprocessableEntity, httpError.Code)
+		assert.Nil(t, usr)
+		mockRepo.AssertNotCalled(t, "UpdateProfile", mock.Anything, mock.Anything)
+	})
+}
+
+func TestUserService_Close(t *testing.T) {
+	cpf := "52998224725"
+	existing := &User{ID: 1, Fullname: "John Doe", Role: Common, CPF: &cpf, Email: "john@email.com", Password: "hashed"}
+
+	t.Run("should replace personal data with a pseudonym", func(t *testing.T) {
+		mockRepo := new(MockUserRepository)
+		mockRepo.On("FindByID", mock.Anything, 1).Return(existing, nil)
+
+		var anonymized User
+		mockRepo.On("Close", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				anonymized = args.Get(1).(User)
+			}).Return(nil).Once()
+
+		service := NewUserService(mockRepo)
+
+		err := service.Close(context.Background(), 1)
+
+		assert.NoError(t, err)
+		assert.Equal(t, 1, anonymized.ID)
+		assert.Equal(t, Common, anonymized.Role)
+		assert.Regexp(t, `^deleted-[0-9a-f]{16}$`, anonymized.Fullname)
+		assert.Equal(t, anonymized.Fullname+"@deleted.invalid", anonymized.Email)
+		assert.Nil(t, anonymized.CPF)
+		assert.Nil(t, anonymized.CNPJ)
+		assert.Empty(t, anonymized.Password)
+		assert.NotNil(t, anonymized.DeletedAt)
+		mockRepo.AssertExpectations(t)
+	})
+}
@@ -3,11 +3,21 @@ package wallet
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/db"
 )
 
+var ErrBalanceNotZero = errors.New("wallet balance is not zero")
+
 type WalletRepository interface {
 	Save(ctx context.Context, w Wallet) error
+	FindByUserID(ctx context.Context, userID int) (*Wallet, error)
+	UpdateActive(ctx context.Context, userID int, active bool) error
+	// Close deactivates the wallet for good, failing with ErrBalanceNotZero
+	// while it still holds money. It joins the transaction on ctx, if any.
+	Close(ctx context.Context, userID int, closedAt time.Time) error
 }
 
 type walletRepo struct {
@@ -45,6 +55,80 @@ func (r *walletRepo) Save(ctx context.Context, w Wallet) error {
 	return nil
 }
 
+func (r *walletRepo) FindByUserID(ctx context.Context, userID int) (*Wallet, error) {
+	query := `
+		SELECT id, user_id, active, balance, updated_at, created_at
+		FROM wallets
+		WHERE user_id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var w Wallet
+	err := r.database.QueryRowContext(ctx, query, userID).Scan(
+		&w.ID,
+		&w.UserID,
+		&w.Active,
+		&w.Balance,
+		&w.UpdatedAt,
+		&w.CreatedAt,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &w, nil
+}
+
+func (r *walletRepo) UpdateActive(ctx context.Context, userID int, active bool) error {
+	query := `
+		UPDATE wallets
+		SET active = $1, updated_at = NOW()
+		WHERE user_id = $2
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := r.database.ExecContext(ctx, query, active, userID)
+	return err
+}
+
+func (r *walletRepo) Close(ctx context.Context, userID int, closedAt time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return db.RunInTx(ctx, r.database, func(tx *sql.Tx) error {
+		var balance int64
+		err := tx.QueryRowContext(ctx, `
+			SELECT balance
+			FROM wallets
+			WHERE user_id = $1
+			FOR UPDATE
+		`, userID).Scan(&balance)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if balance != 0 {
+			return ErrBalanceNotZero
+		}
+
+		_, err = tx.ExecContext(ctx, `
+			UPDATE wallets
+			SET active = FALSE, updated_at = $2
+			WHERE user_id = $1
+		`, userID, closedAt)
+		return err
+	})
+}
+
 func NewWalletRepository(database *sql.DB, qt time.Duration) WalletRepository {
 	return &walletRepo{
 		database:     database,
@@ -2,6 +2,7 @@ package wallet
 
 import (
 	"context"
+	"time"
 
 	"github.com/stretchr/testify/mock"
 )
@@ -14,3 +15,21 @@ func (m *MockWalletRepository) Save(ctx context.Context, wall Wallet) error {
 	args := m.Called(ctx, wall)
 	return args.Error(0)
 }
+
+func (m *MockWalletRepository) FindByUserID(ctx context.Context, userID int) (*Wallet, error) {
+	args := m.Called(ctx, userID)
+	if w, ok := args.Get(0).(*Wallet); ok {
+		return w, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockWalletRepository) UpdateActive(ctx context.Context, userID int, active bool) error {
+	args := m.Called(ctx, userID, active)
+	return args.Error(0)
+}
+
+func (m *MockWalletRepository) Close(ctx context.Context, userID int, closedAt time.Time) error {
+	args := m.Called(ctx, userID, closedAt)
+	return args.Error(0)
+}
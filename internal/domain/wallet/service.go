@@ -2,14 +2,21 @@ package wallet
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/tracing"
 )
 
 type WalletService interface {
 	Create(ctx context.Context, userID int, balance int64) error
+	FindByUserID(ctx context.Context, userID int) (*Wallet, error)
+	SetActive(ctx context.Context, userID int, active bool) error
+	// Close deactivates the wallet of a closing account. Users without a
+	// wallet are let through.
+	Close(ctx context.Context, userID int) error
 }
 
 type walletSvc struct {
@@ -17,6 +24,9 @@ type walletSvc struct {
 }
 
 func (s *walletSvc) Create(ctx context.Context, userID int, balance int64) error {
+	ctx, span := tracing.Start(ctx, "walletSvc.Create")
+	defer span.End()
+
 	wallRepo := s.wallRepo
 
 	now := time.Now()
@@ -39,6 +49,44 @@ func (s *walletSvc) Create(ctx context.Context, userID int, balance int64) error
 	return nil
 }
 
+func (s *walletSvc) FindByUserID(ctx context.Context, userID int) (*Wallet, error) {
+	ctx, span := tracing.Start(ctx, "walletSvc.FindByUserID")
+	defer span.End()
+
+	wall, err := s.wallRepo.FindByUserID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if wall == nil {
+		return nil, apperror.NewHttpError(http.StatusNotFound, "wallet not found")
+	}
+
+	return wall, nil
+}
+
+func (s *walletSvc) SetActive(ctx context.Context, userID int, active bool) error {
+	ctx, span := tracing.Start(ctx, "walletSvc.SetActive")
+	defer span.End()
+
+	if _, err := s.FindByUserID(ctx, userID); err != nil {
+		return err
+	}
+
+	return s.wallRepo.UpdateActive(ctx, userID, active)
+}
+
+func (s *walletSvc) Close(ctx context.Context, userID int) error {
+	ctx, span := tracing.Start(ctx, "walletSvc.Close")
+	defer span.End()
+
+	err := s.wallRepo.Close(ctx, userID, time.Now())
+	if errors.Is(err, ErrBalanceNotZero) {
+		return apperror.NewHttpError(http.StatusConflict, "the wallet balance must be zero to close the account")
+	}
+	return err
+}
+
 func NewWalletService(wallRepo WalletRepository) WalletService {
 	return &walletSvc{
 		wallRepo,
@@ -14,3 +14,22 @@ func (m *MockWalletService) Create(ctx context.Context, userID int, balance int6
 	args := m.Called(ctx, userID, balance)
 	return args.Error(0)
 }
+
+func (m *MockWalletService) FindByUserID(ctx context.Context, userID int) (*Wallet, error) {
+	args := m.Called(ctx, userID)
+	w, ok := args.Get(0).(*Wallet)
+	if !ok && args.Get(0) != nil {
+		panic("expected *Wallet or nil")
+	}
+	return w, args.Error(1)
+}
+
+func (m *MockWalletService) SetActive(ctx context.Context, userID int, active bool) error {
+	args := m.Called(ctx, userID, active)
+	return args.Error(0)
+}
+
+func (m *MockWalletService) Close(ctx context.Context, userID int) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
@@ -76,3 +76,86 @@ func TestWalletService_Create(t *testing.T) {
 		mockRepo.AssertExpectations(t)
 	})
 }
+
+func TestWalletService_FindByUserID(t *testing.T) {
+	t.Run("should return not found if wallet is nil", func(t *testing.T) {
+		mockRepo := new(MockWalletRepository)
+		mockRepo.On("FindByUserID", mock.Anything, 1).Return(nil, nil).Once()
+
+		service := NewWalletService(mockRepo)
+
+		wall, err := service.FindByUserID(context.Background(), 1)
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusNotFound, httpError.Code)
+		assert.Nil(t, wall)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should return the wallet", func(t *testing.T) {
+		mockRepo := new(MockWalletRepository)
+		mockWallet := &Wallet{ID: 1, UserID: 1}
+		mockRepo.On("FindByUserID", mock.Anything, 1).Return(mockWallet, nil).Once()
+
+		service := NewWalletService(mockRepo)
+
+		wall, err := service.FindByUserID(context.Background(), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, mockWallet, wall)
+
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestWalletService_SetActive(t *testing.T) {
+	t.Run("should return not found if wallet does not exist", func(t *testing.T) {
+		mockRepo := new(MockWalletRepository)
+		mockRepo.On("FindByUserID", mock.Anything, 1).Return(nil, nil).Once()
+
+		service := NewWalletService(mockRepo)
+
+		err := service.SetActive(context.Background(), 1, false)
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusNotFound, httpError.Code)
+
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should update the wallet active flag", func(t *testing.T) {
+		mockRepo := new(MockWalletRepository)
+		mockRepo.On("FindByUserID", mock.Anything, 1).Return(&Wallet{ID: 1, UserID: 1}, nil).Once()
+		mockRepo.On("UpdateActive", mock.Anything, 1, false).Return(nil).Once()
+
+		service := NewWalletService(mockRepo)
+
+		err := service.SetActive(context.Background(), 1, false)
+		assert.NoError(t, err)
+
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestWalletService_Close(t *testing.T) {
+	t.Run("should return conflict while the wallet holds money", func(t *testing.T) {
+		mockRepo := new(MockWalletRepository)
+		mockRepo.On("Close", mock.Anything, 1, mock.Anything).Return(ErrBalanceNotZero).Once()
+
+		err := NewWalletService(mockRepo).Close(context.Background(), 1)
+
+		var httpError *apperror.HttpError
+		assert.ErrorAs(t, err, &httpError)
+		assert.Equal(t, http.StatusConflict, httpError.Code)
+	})
+
+	t.Run("should close the wallet", func(t *testing.T) {
+		mockRepo := new(MockWalletRepository)
+		mockRepo.On("Close", mock.Anything, 1, mock.Anything).Return(nil).Once()
+
+		err := NewWalletService(mockRepo).Close(context.Background(), 1)
+
+		assert.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+}
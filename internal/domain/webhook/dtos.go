@@ -0,0 +1,11 @@
+package webhook
+
+type CreateEndpointDTO struct {
+	URL    string      `json:"url" validate:"required,http_url,max=2048"`
+	Events []EventType `json:"events" validate:"required,min=1,dive,oneof=payment.received refund.created"`
+}
+
+type CreatedEndpointDTO struct {
+	Endpoint
+	Secret string `json:"secret"`
+}
@@ -0,0 +1,68 @@
+package webhook
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+)
+
+type EventType string
+
+const (
+	PaymentReceived EventType = "payment.received"
+	RefundCreated   EventType = "refund.created"
+)
+
+// Endpoint is a URL registered by a user to receive events. It is disabled
+// automatically after too many consecutive failed attempts.
+type Endpoint struct {
+	ID                  int         `json:"id"`
+	UserID              int         `json:"user_id"`
+	URL                 string      `json:"url"`
+	Events              []EventType `json:"events"`
+	ConsecutiveFailures int         `json:"consecutive_failures"`
+	DisabledAt          *time.Time  `json:"disabled_at"`
+	CreatedAt           time.Time   `json:"created_at"`
+}
+
+func (e *Endpoint) IsActive() bool {
+	return e.DisabledAt == nil
+}
+
+func (e *Endpoint) Subscribes(event EventType) bool {
+	return slices.Contains(e.Events, event)
+}
+
+type DeliveryStatus string
+
+const (
+	DeliveryPending   DeliveryStatus = "pending"
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	DeliveryFailed    DeliveryStatus = "failed"
+)
+
+// Delivery is one event queued for one endpoint, together with the result of
+// its latest attempt.
+type Delivery struct {
+	ID             int             `json:"id"`
+	EndpointID     int             `json:"endpoint_id"`
+	EventID        string          `json:"event_id"`
+	EventType      EventType       `json:"event_type"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         DeliveryStatus  `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	LastStatusCode *int            `json:"last_status_code"`
+	LastError      string          `json:"last_error,omitempty"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// Event is the JSON body POSTed to endpoints.
+type Event struct {
+	ID        string    `json:"id"`
+	Type      EventType `json:"type"`
+	CreatedAt time.Time `json:"created_at"`
+	Data      any       `json:"data"`
+}
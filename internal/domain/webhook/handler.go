@@ -0,0 +1,145 @@
+package webhook
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/utils"
+	"github.com/go-chi/chi/v5"
+)
+
+type WebhookHandler struct {
+	webhookService WebhookService
+}
+
+func (h *WebhookHandler) Create(w http.ResponseWriter, r *http.Request) error {
+	usr := r.Context().Value(utils.UserKey).(*user.User)
+
+	var body CreateEndpointDTO
+	if err := utils.ReadJSON(w, r, &body); err != nil {
+		return apperror.NewHttpError(http.StatusBadRequest, "error parsing body request")
+	}
+
+	if err := utils.Validate.Struct(body); err != nil {
+		return apperror.NewHttpError(http.StatusBadRequest, err.Error())
+	}
+
+	endpoint, err := h.webhookService.Create(r.Context(), usr.ID, body)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJSON(w, http.StatusCreated, endpoint)
+}
+
+func (h *WebhookHandler) List(w http.ResponseWriter, r *http.Request) error {
+	usr := r.Context().Value(utils.UserKey).(*user.User)
+
+	endpoints, err := h.webhookService.List(r.Context(), usr.ID)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, endpoints)
+}
+
+func (h *WebhookHandler) Delete(w http.ResponseWriter, r *http.Request) error {
+	usr := r.Context().Value(utils.UserKey).(*user.User)
+
+	id, err := pathID(r, "webhookID")
+	if err != nil {
+		return err
+	}
+
+	if err := h.webhookService.Delete(r.Context(), usr.ID, id); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *WebhookHandler) Enable(w http.ResponseWriter, r *http.Request) error {
+	usr := r.Context().Value(utils.UserKey).(*user.User)
+
+	id, err := pathID(r, "webhookID")
+	if err != nil {
+		return err
+	}
+
+	if err := h.webhookService.Enable(r.Context(), usr.ID, id); err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *WebhookHandler) ListDeliveries(w http.ResponseWriter, r *http.Request) error {
+	usr := r.Context().Value(utils.UserKey).(*user.User)
+
+	id, err := pathID(r, "webhookID")
+	if err != nil {
+		return err
+	}
+
+	deliveries, err := h.webhookService.ListDeliveries(
+		r.Context(),
+		usr.ID,
+		id,
+		queryInt(r, "limit"),
+		queryInt(r, "offset"),
+	)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJSON(w, http.StatusOK, deliveries)
+}
+
+func (h *WebhookHandler) Redeliver(w http.ResponseWriter, r *http.Request) error {
+	usr := r.Context().Value(utils.UserKey).(*user.User)
+
+	id, err := pathID(r, "webhookID")
+	if err != nil {
+		return err
+	}
+
+	deliveryID, err := pathID(r, "deliveryID")
+	if err != nil {
+		return err
+	}
+
+	delivery, err := h.webhookService.Redeliver(r.Context(), usr.ID, id, deliveryID)
+	if err != nil {
+		return err
+	}
+
+	return utils.WriteJSON(w, http.StatusAccepted, delivery)
+}
+
+func pathID(r *http.Request, param string) (int, error) {
+	id, err := strconv.Atoi(chi.URLParam(r, param))
+	if err != nil || id <= 0 {
+		return 0, apperror.NewHttpError(http.StatusBadRequest, "invalid "+param)
+	}
+	return id, nil
+}
+
+func queryInt(r *http.Request, key string) int {
+	val, err := strconv.Atoi(r.URL.Query().Get(key))
+	if err != nil {
+		return 0
+	}
+	return val
+}
+
+func NewWebhookHandler(webhookService WebhookService) *WebhookHandler {
+	return &WebhookHandler{
+		webhookService,
+	}
+}
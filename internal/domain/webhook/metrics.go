@@ -0,0 +1,19 @@
+package webhook
+
+import "github.com/DevVictor19/pic-pay-challenge/internal/infra/metrics"
+
+var (
+	deliveryDuration = metrics.NewHistogram(
+		"picpay_webhook_delivery_duration_seconds",
+		"Time to send a webhook delivery, by result.",
+		metrics.DefaultBuckets,
+		"result",
+	)
+	// deliveryLag is the outbox lag: deliveries are written with the
+	// transaction and sent later by the worker
+	deliveryLag = metrics.NewHistogram(
+		"picpay_webhook_delivery_lag_seconds",
+		"How long a due webhook delivery waited before a worker picked it up.",
+		[]float64{.1, .5, 1, 5, 10, 30, 60, 300, 900, 3600},
+	)
+)
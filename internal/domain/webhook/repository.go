@@ -0,0 +1,435 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/db"
+	"github.com/lib/pq"
+)
+
+type WebhookRepository interface {
+	SaveEndpoint(ctx context.Context, e Endpoint) (int, error)
+	FindEndpoint(ctx context.Context, id int) (*Endpoint, error)
+	ListEndpoints(ctx context.Context, userID int) ([]Endpoint, error)
+	ListSubscribed(ctx context.Context, userID int, event EventType) ([]Endpoint, error)
+	DeleteEndpoint(ctx context.Context, id, userID int) (bool, error)
+	EnableEndpoint(ctx context.Context, id int) error
+	// DisableByUser disables every endpoint of the user and fails their
+	// pending deliveries. It joins the transaction on ctx, if any.
+	DisableByUser(ctx context.Context, userID int, disabledAt time.Time) error
+	RecordEndpointFailure(ctx context.Context, id, disableAfter int, now time.Time) (bool, error)
+	ResetEndpointFailures(ctx context.Context, id int) error
+	SaveDeliveries(ctx context.Context, deliveries []Delivery) error
+	FindDelivery(ctx context.Context, id int) (*Delivery, error)
+	ListDeliveries(ctx context.Context, endpointID, limit, offset int) ([]Delivery, error)
+	ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]Delivery, error)
+	// RecordAttempt saves the outcome of an attempt if the delivery is still
+	// held under the lease it was claimed with, and reports whether it was.
+	RecordAttempt(ctx context.Context, d Delivery, leaseUntil time.Time) (bool, error)
+}
+
+type webhookRepo struct {
+	database     *sql.DB
+	queryTimeout time.Duration
+}
+
+const endpointColumns = `id, user_id, url, events, consecutive_failures, disabled_at, created_at`
+
+const deliveryColumns = `id, endpoint_id, event_id, event_type, payload, status, attempts, next_attempt_at,
+	last_status_code, COALESCE(last_error, ''), last_attempt_at, delivered_at, created_at`
+
+func (r *webhookRepo) SaveEndpoint(ctx context.Context, e Endpoint) (int, error) {
+	query := `
+		INSERT INTO webhook_endpoints (user_id, url, events, created_at)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var id int
+	err := r.database.QueryRowContext(ctx, query, e.UserID, e.URL, pq.Array(eventStrings(e.Events)), e.CreatedAt).Scan(&id)
+	if err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (r *webhookRepo) FindEndpoint(ctx context.Context, id int) (*Endpoint, error) {
+	query := `SELECT ` + endpointColumns + ` FROM webhook_endpoints WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	e, err := scanEndpoint(r.database.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return e, nil
+}
+
+func (r *webhookRepo) ListEndpoints(ctx context.Context, userID int) ([]Endpoint, error) {
+	query := `
+		SELECT ` + endpointColumns + `
+		FROM webhook_endpoints
+		WHERE user_id = $1
+		ORDER BY id
+	`
+
+	return r.queryEndpoints(ctx, query, userID)
+}
+
+func (r *webhookRepo) ListSubscribed(ctx context.Context, userID int, event EventType) ([]Endpoint, error) {
+	query := `
+		SELECT ` + endpointColumns + `
+		FROM webhook_endpoints
+		WHERE user_id = $1 AND $2 = ANY(events) AND disabled_at IS NULL
+		ORDER BY id
+	`
+
+	return r.queryEndpoints(ctx, query, userID, event)
+}
+
+func (r *webhookRepo) DeleteEndpoint(ctx context.Context, id, userID int) (bool, error) {
+	query := `DELETE FROM webhook_endpoints WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	res, err := r.database.ExecContext(ctx, query, id, userID)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+func (r *webhookRepo) EnableEndpoint(ctx context.Context, id int) error {
+	query := `
+		UPDATE webhook_endpoints
+		SET disabled_at = NULL, consecutive_failures = 0
+		WHERE id = $1
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := r.database.ExecContext(ctx, query, id)
+	return err
+}
+
+func (r *webhookRepo) DisableByUser(ctx context.Context, userID int, disabledAt time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	return db.RunInTx(ctx, r.database, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE webhook_endpoints
+			SET disabled_at = $2
+			WHERE user_id = $1 AND disabled_at IS NULL
+		`, userID, disabledAt)
+		if err != nil {
+			return err
+		}
+
+		// the worker claims pending deliveries without looking at the endpoint
+		_, err = tx.ExecContext(ctx, `
+			UPDATE webhook_deliveries
+			SET status = 'failed', last_error = 'account closed'
+			WHERE status = 'pending'
+				AND endpoint_id IN (SELECT id FROM webhook_endpoints WHERE user_id = $1)
+		`, userID)
+		return err
+	})
+}
+
+// RecordEndpointFailure counts a failed attempt and disables the endpoint once
+// it reaches disableAfter consecutive failures. It reports whether this call
+// disabled it.
+func (r *webhookRepo) RecordEndpointFailure(ctx context.Context, id, disableAfter int, now time.Time) (bool, error) {
+	query := `
+		UPDATE webhook_endpoints
+		SET consecutive_failures = consecutive_failures + 1,
+			disabled_at = CASE
+				WHEN disabled_at IS NULL AND consecutive_failures + 1 >= $2 THEN $3
+				ELSE disabled_at
+			END
+		WHERE id = $1
+		RETURNING disabled_at = $3
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	var disabled sql.NullBool
+	err := r.database.QueryRowContext(ctx, query, id, disableAfter, now).Scan(&disabled)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return disabled.Bool, nil
+}
+
+func (r *webhookRepo) ResetEndpointFailures(ctx context.Context, id int) error {
+	query := `UPDATE webhook_endpoints SET consecutive_failures = 0 WHERE id = $1 AND consecutive_failures > 0`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	_, err := r.database.ExecContext(ctx, query, id)
+	return err
+}
+
+func (r *webhookRepo) SaveDeliveries(ctx context.Context, deliveries []Delivery) error {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	tx, err := r.database.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, d := range deliveries {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO webhook_deliveries (endpoint_id, event_id, event_type, payload, status, next_attempt_at, created_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, d.EndpointID, d.EventID, d.EventType, []byte(d.Payload), d.Status, d.NextAttemptAt, d.CreatedAt)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (r *webhookRepo) FindDelivery(ctx context.Context, id int) (*Delivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	d, err := scanDelivery(r.database.QueryRowContext(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func (r *webhookRepo) ListDeliveries(ctx context.Context, endpointID, limit, offset int) ([]Delivery, error) {
+	query := `
+		SELECT ` + deliveryColumns + `
+		FROM webhook_deliveries
+		WHERE endpoint_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	return r.queryDeliveries(ctx, query, endpointID, limit, offset)
+}
+
+// ClaimDue picks pending deliveries whose next attempt is due and pushes their
+// next attempt to leaseUntil, so that other workers skip them while they are
+// being sent. A delivery whose worker dies is retried once the lease expires.
+// The deliveries are returned with the time they were due, not the lease.
+func (r *webhookRepo) ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]Delivery, error) {
+	query := `
+		WITH due AS (
+			SELECT id, next_attempt_at
+			FROM webhook_deliveries
+			WHERE status = 'pending' AND next_attempt_at <= $1
+			ORDER BY next_attempt_at
+			LIMIT $3
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE webhook_deliveries d
+		SET next_attempt_at = $2
+		FROM due
+		WHERE d.id = due.id
+		RETURNING d.id, d.endpoint_id, d.event_id, d.event_type, d.payload, d.status, d.attempts,
+			due.next_attempt_at, d.last_status_code, COALESCE(d.last_error, ''), d.last_attempt_at,
+			d.delivered_at, d.created_at
+	`
+
+	return r.queryDeliveries(ctx, query, now, leaseUntil, limit)
+}
+
+// RecordAttempt only matches while next_attempt_at still holds the lease, so a
+// worker whose lease expired cannot overwrite the attempt of the worker that
+// claimed the delivery after it.
+func (r *webhookRepo) RecordAttempt(ctx context.Context, d Delivery, leaseUntil time.Time) (bool, error) {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, next_attempt_at = $4, last_status_code = $5,
+			last_error = NULLIF($6, ''), last_attempt_at = $7, delivered_at = $8
+		WHERE id = $1 AND status = 'pending' AND next_attempt_at = $9
+	`
+
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	result, err := r.database.ExecContext(
+		ctx,
+		query,
+		d.ID,
+		d.Status,
+		d.Attempts,
+		d.NextAttemptAt,
+		d.LastStatusCode,
+		d.LastError,
+		d.LastAttemptAt,
+		d.DeliveredAt,
+		leaseUntil,
+	)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
+
+func (r *webhookRepo) queryEndpoints(ctx context.Context, query string, args ...any) ([]Endpoint, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	rows, err := r.database.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	endpoints := []Endpoint{}
+	for rows.Next() {
+		e, err := scanEndpoint(rows)
+		if err != nil {
+			return nil, err
+		}
+		endpoints = append(endpoints, *e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return endpoints, nil
+}
+
+func (r *webhookRepo) queryDeliveries(ctx context.Context, query string, args ...any) ([]Delivery, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.queryTimeout)
+	defer cancel()
+
+	rows, err := r.database.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []Delivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanEndpoint(row rowScanner) (*Endpoint, error) {
+	var e Endpoint
+	var events []string
+	err := row.Scan(
+		&e.ID,
+		&e.UserID,
+		&e.URL,
+		pq.Array(&events),
+		&e.ConsecutiveFailures,
+		&e.DisabledAt,
+		&e.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ev := range events {
+		e.Events = append(e.Events, EventType(ev))
+	}
+
+	return &e, nil
+}
+
+func scanDelivery(row rowScanner) (*Delivery, error) {
+	var d Delivery
+	var payload []byte
+	err := row.Scan(
+		&d.ID,
+		&d.EndpointID,
+		&d.EventID,
+		&d.EventType,
+		&payload,
+		&d.Status,
+		&d.Attempts,
+		&d.NextAttemptAt,
+		&d.LastStatusCode,
+		&d.LastError,
+		&d.LastAttemptAt,
+		&d.DeliveredAt,
+		&d.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	d.Payload = payload
+	return &d, nil
+}
+
+func eventStrings(events []EventType) []string {
+	out := make([]string, len(events))
+	for i, e := range events {
+		out[i] = string(e)
+	}
+	return out
+}
+
+func NewWebhookRepository(database *sql.DB, qt time.Duration) WebhookRepository {
+	return &webhookRepo{
+		database:     database,
+		queryTimeout: qt,
+	}
+}
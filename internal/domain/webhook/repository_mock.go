@@ -0,0 +1,100 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockWebhookRepository struct {
+	mock.Mock
+}
+
+func (m *MockWebhookRepository) SaveEndpoint(ctx context.Context, e Endpoint) (int, error) {
+	args := m.Called(ctx, e)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockWebhookRepository) FindEndpoint(ctx context.Context, id int) (*Endpoint, error) {
+	args := m.Called(ctx, id)
+	if e, ok := args.Get(0).(*Endpoint); ok {
+		return e, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockWebhookRepository) ListEndpoints(ctx context.Context, userID int) ([]Endpoint, error) {
+	args := m.Called(ctx, userID)
+	if e, ok := args.Get(0).([]Endpoint); ok {
+		return e, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockWebhookRepository) ListSubscribed(ctx context.Context, userID int, event EventType) ([]Endpoint, error) {
+	args := m.Called(ctx, userID, event)
+	if e, ok := args.Get(0).([]Endpoint); ok {
+		return e, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockWebhookRepository) DeleteEndpoint(ctx context.Context, id, userID int) (bool, error) {
+	args := m.Called(ctx, id, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockWebhookRepository) EnableEndpoint(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) DisableByUser(ctx context.Context, userID int, disabledAt time.Time) error {
+	args := m.Called(ctx, userID, disabledAt)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) RecordEndpointFailure(ctx context.Context, id, disableAfter int, now time.Time) (bool, error) {
+	args := m.Called(ctx, id, disableAfter, now)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockWebhookRepository) ResetEndpointFailures(ctx context.Context, id int) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) SaveDeliveries(ctx context.Context, deliveries []Delivery) error {
+	args := m.Called(ctx, deliveries)
+	return args.Error(0)
+}
+
+func (m *MockWebhookRepository) FindDelivery(ctx context.Context, id int) (*Delivery, error) {
+	args := m.Called(ctx, id)
+	if d, ok := args.Get(0).(*Delivery); ok {
+		return d, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockWebhookRepository) ListDeliveries(ctx context.Context, endpointID, limit, offset int) ([]Delivery, error) {
+	args := m.Called(ctx, endpointID, limit, offset)
+	if d, ok := args.Get(0).([]Delivery); ok {
+		return d, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockWebhookRepository) ClaimDue(ctx context.Context, now, leaseUntil time.Time, limit int) ([]Delivery, error) {
+	args := m.Called(ctx, now, leaseUntil, limit)
+	if d, ok := args.Get(0).([]Delivery); ok {
+		return d, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockWebhookRepository) RecordAttempt(ctx context.Context, d Delivery, leaseUntil time.Time) (bool, error) {
+	args := m.Called(ctx, d, leaseUntil)
+	return args.Bool(0), args.Error(1)
+}
@@ -0,0 +1,226 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
+	"github.com/DevVictor19/pic-pay-challenge/pkg/signature"
+)
+
+const (
+	maxEndpointsPerUser  = 10
+	maxDeliveryListLimit = 100
+)
+
+type WebhookService interface {
+	Create(ctx context.Context, userID int, dto CreateEndpointDTO) (*CreatedEndpointDTO, error)
+	List(ctx context.Context, userID int) ([]Endpoint, error)
+	Delete(ctx context.Context, userID, id int) error
+	Enable(ctx context.Context, userID, id int) error
+	// DisableAll stops every endpoint of a closing account.
+	DisableAll(ctx context.Context, userID int) error
+	ListDeliveries(ctx context.Context, userID, endpointID, limit, offset int) ([]Delivery, error)
+	Redeliver(ctx context.Context, userID, endpointID, deliveryID int) (*Delivery, error)
+	Dispatch(ctx context.Context, userID int, event EventType, data any) error
+}
+
+type webhookSvc struct {
+	webhookRepo WebhookRepository
+	signingKey  string
+	targets     TargetPolicy
+	lookupHost  func(ctx context.Context, host string) ([]netip.Addr, error)
+}
+
+func (s *webhookSvc) Create(ctx context.Context, userID int, dto CreateEndpointDTO) (*CreatedEndpointDTO, error) {
+	endpoints, err := s.webhookRepo.ListEndpoints(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(endpoints) >= maxEndpointsPerUser {
+		return nil, apperror.NewHttpError(http.StatusUnprocessableEntity, "webhook endpoint limit reached")
+	}
+
+	if err := s.checkTarget(ctx, dto.URL); err != nil {
+		return nil, err
+	}
+
+	e := Endpoint{
+		UserID:    userID,
+		URL:       dto.URL,
+		Events:    dto.Events,
+		CreatedAt: time.Now(),
+	}
+
+	e.ID, err = s.webhookRepo.SaveEndpoint(ctx, e)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CreatedEndpointDTO{Endpoint: e, Secret: endpointSecret(s.signingKey, e.ID)}, nil
+}
+
+func (s *webhookSvc) List(ctx context.Context, userID int) ([]Endpoint, error) {
+	return s.webhookRepo.ListEndpoints(ctx, userID)
+}
+
+func (s *webhookSvc) Delete(ctx context.Context, userID, id int) error {
+	deleted, err := s.webhookRepo.DeleteEndpoint(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return apperror.NewHttpError(http.StatusNotFound, "webhook endpoint not found")
+	}
+
+	return nil
+}
+
+func (s *webhookSvc) Enable(ctx context.Context, userID, id int) error {
+	if _, err := s.findOwnEndpoint(ctx, userID, id); err != nil {
+		return err
+	}
+
+	return s.webhookRepo.EnableEndpoint(ctx, id)
+}
+
+func (s *webhookSvc) DisableAll(ctx context.Context, userID int) error {
+	return s.webhookRepo.DisableByUser(ctx, userID, time.Now())
+}
+
+func (s *webhookSvc) ListDeliveries(ctx context.Context, userID, endpointID, limit, offset int) ([]Delivery, error) {
+	if _, err := s.findOwnEndpoint(ctx, userID, endpointID); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 || limit > maxDeliveryListLimit {
+		limit = maxDeliveryListLimit
+	}
+
+	if offset < 0 {
+		offset = 0
+	}
+
+	return s.webhookRepo.ListDeliveries(ctx, endpointID, limit, offset)
+}
+
+// Redeliver queues a copy of a past delivery. The original entry is kept so
+// the delivery log stays intact.
+func (s *webhookSvc) Redeliver(ctx context.Context, userID, endpointID, deliveryID int) (*Delivery, error) {
+	e, err := s.findOwnEndpoint(ctx, userID, endpointID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !e.IsActive() {
+		return nil, apperror.NewHttpError(http.StatusConflict, "webhook endpoint is disabled")
+	}
+
+	original, err := s.webhookRepo.FindDelivery(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+
+	if original == nil || original.EndpointID != e.ID {
+		return nil, apperror.NewHttpError(http.StatusNotFound, "webhook delivery not found")
+	}
+
+	d := newDelivery(e.ID, original.EventID, original.EventType, original.Payload, time.Now())
+	if err := s.webhookRepo.SaveDeliveries(ctx, []Delivery{d}); err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+// Dispatch queues event for every active endpoint of userID subscribed to it.
+// The deliveries are sent later by the DeliveryWorker.
+func (s *webhookSvc) Dispatch(ctx context.Context, userID int, event EventType, data any) error {
+	endpoints, err := s.webhookRepo.ListSubscribed(ctx, userID, event)
+	if err != nil {
+		return err
+	}
+
+	if len(endpoints) == 0 {
+		return nil
+	}
+
+	eventID, err := newEventID()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	payload, err := json.Marshal(Event{
+		ID:        eventID,
+		Type:      event,
+		CreatedAt: now,
+		Data:      data,
+	})
+	if err != nil {
+		return err
+	}
+
+	deliveries := make([]Delivery, 0, len(endpoints))
+	for _, e := range endpoints {
+		deliveries = append(deliveries, newDelivery(e.ID, eventID, event, payload, now))
+	}
+
+	return s.webhookRepo.SaveDeliveries(ctx, deliveries)
+}
+
+func (s *webhookSvc) findOwnEndpoint(ctx context.Context, userID, id int) (*Endpoint, error) {
+	e, err := s.webhookRepo.FindEndpoint(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if e == nil || e.UserID != userID {
+		return nil, apperror.NewHttpError(http.StatusNotFound, "webhook endpoint not found")
+	}
+
+	return e, nil
+}
+
+func newDelivery(endpointID int, eventID string, event EventType, payload json.RawMessage, now time.Time) Delivery {
+	return Delivery{
+		EndpointID:    endpointID,
+		EventID:       eventID,
+		EventType:     event,
+		Payload:       payload,
+		Status:        DeliveryPending,
+		NextAttemptAt: now,
+		CreatedAt:     now,
+	}
+}
+
+func newEventID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "evt_" + hex.EncodeToString(b), nil
+}
+
+// endpointSecret derives the signing secret of an endpoint from the server
+// signing key, so it never has to be stored.
+func endpointSecret(signingKey string, endpointID int) string {
+	return "whsec_" + signature.Compute(signingKey, "webhook_endpoint:"+strconv.Itoa(endpointID))
+}
+
+func NewWebhookService(webhookRepo WebhookRepository, signingKey string, targets TargetPolicy) WebhookService {
+	return &webhookSvc{
+		webhookRepo: webhookRepo,
+		signingKey:  signingKey,
+		targets:     targets,
+		lookupHost:  lookupHost,
+	}
+}
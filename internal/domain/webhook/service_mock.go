@@ -0,0 +1,63 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/stretchr/testify/mock"
+)
+
+type MockWebhookService struct {
+	mock.Mock
+}
+
+func (m *MockWebhookService) Create(ctx context.Context, userID int, dto CreateEndpointDTO) (*CreatedEndpointDTO, error) {
+	args := m.Called(ctx, userID, dto)
+	if e, ok := args.Get(0).(*CreatedEndpointDTO); ok {
+		return e, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockWebhookService) List(ctx context.Context, userID int) ([]Endpoint, error) {
+	args := m.Called(ctx, userID)
+	if e, ok := args.Get(0).([]Endpoint); ok {
+		return e, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockWebhookService) Delete(ctx context.Context, userID, id int) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+func (m *MockWebhookService) Enable(ctx context.Context, userID, id int) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+func (m *MockWebhookService) DisableAll(ctx context.Context, userID int) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockWebhookService) ListDeliveries(ctx context.Context, userID, endpointID, limit, offset int) ([]Delivery, error) {
+	args := m.Called(ctx, userID, endpointID, limit, offset)
+	if d, ok := args.Get(0).([]Delivery); ok {
+		return d, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockWebhookService) Redeliver(ctx context.Context, userID, endpointID, deliveryID int) (*Delivery, error) {
+	args := m.Called(ctx, userID, endpointID, deliveryID)
+	if d, ok := args.Get(0).(*Delivery); ok {
+		return d, args.Error(1)
+	}
+	return nil, args.Error(1)
+}
+
+func (m *MockWebhookService) Dispatch(ctx context.Context, userID int, event EventType, data any) error {
+	args := m.Called(ctx, userID, event, data)
+	return args.Error(0)
+}
@@ -0,0 +1,206 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func assertHttpCode(t *testing.T, err error, code int) {
+	t.Helper()
+
+	var httpError *apperror.HttpError
+	require.ErrorAs(t, err, &httpError)
+	assert.Equal(t, code, httpError.Code)
+}
+
+func TestWebhookService_Create(t *testing.T) {
+	t.Run("should return the signing secret once", func(t *testing.T) {
+		mockRepo := new(MockWebhookRepository)
+		mockRepo.On("ListEndpoints", mock.Anything, 3).Return([]Endpoint{}, nil)
+		mockRepo.On("SaveEndpoint", mock.Anything, mock.Anything).Return(1, nil)
+
+		created, err := newTargetService(mockRepo, TargetPolicy{RequireHTTPS: true}, "93.184.216.34").Create(context.Background(), 3, CreateEndpointDTO{
+			URL:    "https://shop.example.com/hooks",
+			Events: []EventType{PaymentReceived},
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, created.ID)
+		assert.Equal(t, endpointSecret(signingKey, 1), created.Secret)
+	})
+
+	t.Run("should limit the endpoints per user", func(t *testing.T) {
+		mockRepo := new(MockWebhookRepository)
+		mockRepo.On("ListEndpoints", mock.Anything, 3).Return(make([]Endpoint, maxEndpointsPerUser), nil)
+
+		_, err := NewWebhookService(mockRepo, signingKey, TargetPolicy{}).Create(context.Background(), 3, CreateEndpointDTO{})
+
+		assertHttpCode(t, err, http.StatusUnprocessableEntity)
+		mockRepo.AssertNotCalled(t, "SaveEndpoint", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should refuse non-public targets", func(t *testing.T) {
+		cases := []struct {
+			name     string
+			url      string
+			resolved string
+		}{
+			{"plain http in production", "http://shop.example.com/hooks", "93.184.216.34"},
+			{"cloud metadata", "https://169.254.169.254/latest", ""},
+			{"loopback", "https://127.0.0.1:8080/hooks", ""},
+			{"mapped loopback", "https://[::ffff:127.0.0.1]/hooks", ""},
+			{"private network", "https://10.0.0.5/hooks", ""},
+			{"localhost", "https://localhost/hooks", ""},
+			{"single label host", "https://payments/hooks", ""},
+			{"host resolving to a private address", "https://internal.example.com/hooks", "192.168.1.10"},
+		}
+
+		for _, tc := range cases {
+			t.Run(tc.name, func(t *testing.T) {
+				mockRepo := new(MockWebhookRepository)
+				mockRepo.On("ListEndpoints", mock.Anything, 3).Return([]Endpoint{}, nil)
+
+				_, err := newTargetService(mockRepo, TargetPolicy{RequireHTTPS: true}, tc.resolved).Create(context.Background(), 3, CreateEndpointDTO{
+					URL:    tc.url,
+					Events: []EventType{PaymentReceived},
+				})
+
+				assertHttpCode(t, err, http.StatusUnprocessableEntity)
+				mockRepo.AssertNotCalled(t, "SaveEndpoint", mock.Anything, mock.Anything)
+			})
+		}
+	})
+
+	t.Run("should allow private targets when configured", func(t *testing.T) {
+		mockRepo := new(MockWebhookRepository)
+		mockRepo.On("ListEndpoints", mock.Anything, 3).Return([]Endpoint{}, nil)
+		mockRepo.On("SaveEndpoint", mock.Anything, mock.Anything).Return(1, nil)
+
+		_, err := newTargetService(mockRepo, TargetPolicy{AllowPrivate: true}, "").Create(context.Background(), 3, CreateEndpointDTO{
+			URL:    "http://localhost:9000/hooks",
+			Events: []EventType{PaymentReceived},
+		})
+
+		require.NoError(t, err)
+	})
+}
+
+func newTargetService(repo WebhookRepository, targets TargetPolicy, resolved string) WebhookService {
+	svc := NewWebhookService(repo, signingKey, targets).(*webhookSvc)
+	svc.lookupHost = func(ctx context.Context, host string) ([]netip.Addr, error) {
+		if resolved == "" {
+			return nil, errors.New("no such host")
+		}
+		return []netip.Addr{netip.MustParseAddr(resolved)}, nil
+	}
+	return svc
+}
+
+func TestDialControl(t *testing.T) {
+	assert.NoError(t, DialControl("tcp4", "93.184.216.34:443", nil))
+	assert.Error(t, DialControl("tcp4", "169.254.169.254:80", nil))
+	assert.Error(t, DialControl("tcp4", "100.100.100.200:80", nil))
+	assert.Error(t, DialControl("tcp6", "[::1]:443", nil))
+	assert.Error(t, DialControl("tcp6", "[fd00::1]:443", nil))
+	assert.Error(t, DialControl("tcp4", "0.0.0.0:443", nil))
+}
+
+func TestWebhookService_Dispatch(t *testing.T) {
+	t.Run("should queue the same event for every subscribed endpoint", func(t *testing.T) {
+		mockRepo := new(MockWebhookRepository)
+		mockRepo.On("ListSubscribed", mock.Anything, 3, PaymentReceived).
+			Return([]Endpoint{{ID: 1}, {ID: 2}}, nil)
+
+		var saved []Delivery
+		mockRepo.On("SaveDeliveries", mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) { saved = args.Get(1).([]Delivery) }).
+			Return(nil)
+
+		err := NewWebhookService(mockRepo, signingKey, TargetPolicy{}).Dispatch(context.Background(), 3, PaymentReceived, map[string]int{"amount": 10})
+
+		require.NoError(t, err)
+		require.Len(t, saved, 2)
+		assert.Equal(t, saved[0].EventID, saved[1].EventID)
+		assert.Equal(t, DeliveryPending, saved[0].Status)
+
+		var event map[string]any
+		require.NoError(t, json.Unmarshal(saved[0].Payload, &event))
+		assert.Equal(t, saved[0].EventID, event["id"])
+		assert.Equal(t, string(PaymentReceived), event["type"])
+		assert.Equal(t, map[string]any{"amount": float64(10)}, event["data"])
+	})
+
+	t.Run("should do nothing without subscribers", func(t *testing.T) {
+		mockRepo := new(MockWebhookRepository)
+		mockRepo.On("ListSubscribed", mock.Anything, 3, RefundCreated).Return([]Endpoint{}, nil)
+
+		err := NewWebhookService(mockRepo, signingKey, TargetPolicy{}).Dispatch(context.Background(), 3, RefundCreated, nil)
+
+		require.NoError(t, err)
+		mockRepo.AssertNotCalled(t, "SaveDeliveries", mock.Anything, mock.Anything)
+	})
+}
+
+func TestWebhookService_Redeliver(t *testing.T) {
+	t.Run("should not expose endpoints of other users", func(t *testing.T) {
+		mockRepo := new(MockWebhookRepository)
+		mockRepo.On("FindEndpoint", mock.Anything, 1).Return(&Endpoint{ID: 1, UserID: 4}, nil)
+
+		_, err := NewWebhookService(mockRepo, signingKey, TargetPolicy{}).Redeliver(context.Background(), 3, 1, 9)
+
+		assertHttpCode(t, err, http.StatusNotFound)
+	})
+
+	t.Run("should return conflict for disabled endpoints", func(t *testing.T) {
+		disabledAt := time.Now()
+		mockRepo := new(MockWebhookRepository)
+		mockRepo.On("FindEndpoint", mock.Anything, 1).Return(&Endpoint{ID: 1, UserID: 3, DisabledAt: &disabledAt}, nil)
+
+		_, err := NewWebhookService(mockRepo, signingKey, TargetPolicy{}).Redeliver(context.Background(), 3, 1, 9)
+
+		assertHttpCode(t, err, http.StatusConflict)
+	})
+
+	t.Run("should reject deliveries of another endpoint", func(t *testing.T) {
+		mockRepo := new(MockWebhookRepository)
+		mockRepo.On("FindEndpoint", mock.Anything, 1).Return(&Endpoint{ID: 1, UserID: 3}, nil)
+		mockRepo.On("FindDelivery", mock.Anything, 9).Return(&Delivery{ID: 9, EndpointID: 2}, nil)
+
+		_, err := NewWebhookService(mockRepo, signingKey, TargetPolicy{}).Redeliver(context.Background(), 3, 1, 9)
+
+		assertHttpCode(t, err, http.StatusNotFound)
+	})
+
+	t.Run("should queue a copy and keep the original", func(t *testing.T) {
+		mockRepo := new(MockWebhookRepository)
+		mockRepo.On("FindEndpoint", mock.Anything, 1).Return(&Endpoint{ID: 1, UserID: 3}, nil)
+		mockRepo.On("FindDelivery", mock.Anything, 9).Return(&Delivery{
+			ID:         9,
+			EndpointID: 1,
+			EventID:    "evt_1",
+			EventType:  PaymentReceived,
+			Payload:    []byte(`{}`),
+			Status:     DeliveryFailed,
+			Attempts:   8,
+		}, nil)
+		mockRepo.On("SaveDeliveries", mock.Anything, mock.MatchedBy(func(d []Delivery) bool {
+			return len(d) == 1 && d[0].ID == 0 && d[0].EventID == "evt_1" && d[0].Status == DeliveryPending && d[0].Attempts == 0
+		})).Return(nil)
+
+		d, err := NewWebhookService(mockRepo, signingKey, TargetPolicy{}).Redeliver(context.Background(), 3, 1, 9)
+
+		require.NoError(t, err)
+		assert.Equal(t, DeliveryPending, d.Status)
+		mockRepo.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,125 @@
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
+)
+
+// TargetPolicy restricts where endpoints may point, so the delivery worker
+// cannot be used to reach the platform's own network.
+type TargetPolicy struct {
+	RequireHTTPS bool
+	// AllowPrivate lets endpoints target loopback and private networks. It is
+	// meant for local development only.
+	AllowPrivate bool
+}
+
+// blockedPrefixes are ranges that are not public but that netip's
+// predicates do not cover.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"), // carrier-grade NAT, used by some metadata services
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("64:ff9b::/96"), // NAT64 could map to any of the above
+}
+
+// IsPublicAddr reports whether addr may receive webhook deliveries.
+func IsPublicAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	if !addr.IsValid() ||
+		addr.IsUnspecified() ||
+		addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() {
+		return false
+	}
+
+	for _, p := range blockedPrefixes {
+		if p.Contains(addr) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// DialControl refuses connections to addresses that are not public. It runs
+// on the resolved address right before connecting, so a host that resolves
+// to an internal address after it was registered is still refused.
+func DialControl(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+
+	if !IsPublicAddr(addr) {
+		return fmt.Errorf("refusing to deliver to non-public address %s", addr)
+	}
+
+	return nil
+}
+
+func (s *webhookSvc) checkTarget(ctx context.Context, rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Hostname() == "" {
+		return apperror.NewHttpError(http.StatusUnprocessableEntity, "invalid webhook url")
+	}
+
+	if s.targets.RequireHTTPS && u.Scheme != "https" {
+		return apperror.NewHttpError(http.StatusUnprocessableEntity, "webhook url must use https")
+	}
+
+	if s.targets.AllowPrivate {
+		return nil
+	}
+
+	refused := apperror.NewHttpError(http.StatusUnprocessableEntity, "webhook url must point to a public address")
+
+	host := strings.ToLower(u.Hostname())
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !IsPublicAddr(addr) {
+			return refused
+		}
+		return nil
+	}
+
+	// single-label names only resolve inside a network
+	if !strings.Contains(host, ".") || host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return refused
+	}
+
+	addrs, err := s.lookupHost(ctx, host)
+	if err != nil || len(addrs) == 0 {
+		return apperror.NewHttpError(http.StatusUnprocessableEntity, "webhook url host cannot be resolved")
+	}
+
+	for _, addr := range addrs {
+		if !IsPublicAddr(addr) {
+			return refused
+		}
+	}
+
+	return nil
+}
+
+func lookupHost(ctx context.Context, host string) ([]netip.Addr, error) {
+	return net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+}
@@ -0,0 +1,230 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/tracing"
+	"github.com/DevVictor19/pic-pay-challenge/pkg/signature"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RetryPolicy controls how failed deliveries are retried. The delay doubles
+// after every failed attempt, starting at BaseDelay and capped at MaxDelay.
+// Endpoints are disabled after DisableAfter consecutive failed attempts.
+type RetryPolicy struct {
+	MaxAttempts  int
+	BaseDelay    time.Duration
+	MaxDelay     time.Duration
+	DisableAfter int
+}
+
+func (p RetryPolicy) backoff(attempts int) time.Duration {
+	delay := p.BaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= p.MaxDelay {
+			return p.MaxDelay
+		}
+	}
+	return min(delay, p.MaxDelay)
+}
+
+// DeliveryWorker sends the deliveries queued by WebhookService.Dispatch. Many
+// workers can run at once since each delivery is claimed before being sent.
+type DeliveryWorker struct {
+	webhookRepo WebhookRepository
+	client      *http.Client
+	signingKey  string
+	policy      RetryPolicy
+}
+
+func (w *DeliveryWorker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := w.ProcessDue(ctx); err != nil {
+				slog.Error("webhook delivery failed", "err", err.Error())
+			}
+		}
+	}
+}
+
+// ProcessDue sends every delivery that is due and returns how many were
+// attempted. Deliveries are claimed one at a time, so the lease only has to
+// outlive a single request however slow the endpoints are.
+func (w *DeliveryWorker) ProcessDue(ctx context.Context) (int, error) {
+	processed := 0
+	for ctx.Err() == nil {
+		now := time.Now()
+		// the lease doubles as the claim token, so keep it at the precision
+		// the database stores
+		lease := now.Add(w.lease()).Truncate(time.Microsecond)
+		deliveries, err := w.webhookRepo.ClaimDue(ctx, now, lease, 1)
+		if err != nil {
+			return processed, err
+		}
+		if len(deliveries) == 0 {
+			return processed, nil
+		}
+
+		d := deliveries[0]
+		deliveryLag.Observe(max(now.Sub(d.NextAttemptAt).Seconds(), 0))
+		// on shutdown a delivery already claimed is finished and recorded
+		if err := w.deliver(context.WithoutCancel(ctx), d, lease); err != nil {
+			return processed, err
+		}
+		processed++
+	}
+	return processed, nil
+}
+
+func (w *DeliveryWorker) deliver(ctx context.Context, d Delivery, lease time.Time) error {
+	ctx, span := tracing.Start(ctx, "webhook.deliver", trace.WithAttributes(
+		attribute.Int("webhook.delivery_id", d.ID),
+		attribute.Int("webhook.endpoint_id", d.EndpointID),
+		attribute.String("webhook.event_type", string(d.EventType)),
+	))
+	defer span.End()
+
+	e, err := w.webhookRepo.FindEndpoint(ctx, d.EndpointID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	d.LastAttemptAt = &now
+
+	if e == nil || !e.IsActive() {
+		d.Status = DeliveryFailed
+		d.LastError = "endpoint disabled"
+		_, err := w.webhookRepo.RecordAttempt(ctx, d, lease)
+		return err
+	}
+
+	code, sendErr := w.send(ctx, e, d)
+	result := "success"
+	if sendErr != nil {
+		result = "failure"
+	}
+	deliveryDuration.Observe(time.Since(now).Seconds(), result)
+
+	d.Attempts++
+	d.LastStatusCode = nil
+	if code != 0 {
+		d.LastStatusCode = &code
+	}
+
+	if sendErr == nil {
+		d.Status = DeliverySucceeded
+		d.DeliveredAt = &now
+		d.LastError = ""
+		if recorded, err := w.recordAttempt(ctx, d, lease); err != nil || !recorded {
+			return err
+		}
+
+		if e.ConsecutiveFailures > 0 {
+			return w.webhookRepo.ResetEndpointFailures(ctx, e.ID)
+		}
+		return nil
+	}
+
+	span.SetStatus(codes.Error, sendErr.Error())
+	d.LastError = sendErr.Error()
+	if d.Attempts >= w.policy.MaxAttempts {
+		d.Status = DeliveryFailed
+	} else {
+		d.NextAttemptAt = now.Add(w.policy.backoff(d.Attempts))
+	}
+
+	if recorded, err := w.recordAttempt(ctx, d, lease); err != nil || !recorded {
+		return err
+	}
+
+	disabled, err := w.webhookRepo.RecordEndpointFailure(ctx, e.ID, w.policy.DisableAfter, now)
+	if err != nil {
+		return err
+	}
+
+	if disabled {
+		slog.Warn("webhook endpoint disabled after repeated failures", "endpoint_id", e.ID, "user_id", e.UserID)
+	}
+
+	return nil
+}
+
+// recordAttempt saves the attempt unless the lease was lost to another worker,
+// in which case that worker's outcome wins and the endpoint counters are left
+// alone.
+func (w *DeliveryWorker) recordAttempt(ctx context.Context, d Delivery, lease time.Time) (bool, error) {
+	recorded, err := w.webhookRepo.RecordAttempt(ctx, d, lease)
+	if err != nil {
+		return false, err
+	}
+	if !recorded {
+		slog.Warn("webhook delivery lease lost before the attempt was recorded", "delivery_id", d.ID)
+	}
+	return recorded, nil
+}
+
+func (w *DeliveryWorker) send(ctx context.Context, e *Endpoint, d Delivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "PicPay-Webhooks/1.0")
+	req.Header.Set("X-Webhook-ID", d.EventID)
+	req.Header.Set("X-Webhook-Event", string(d.EventType))
+	req.Header.Set("X-Webhook-Delivery", strconv.Itoa(d.ID))
+	req.Header.Set(signature.HeaderWebhookSignature, signature.SignWebhook(endpointSecret(w.signingKey, e.ID), time.Now(), d.Payload))
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	// drain a little of the body so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(res.Body, 4096))
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return res.StatusCode, fmt.Errorf("unexpected status code %d", res.StatusCode)
+	}
+
+	return res.StatusCode, nil
+}
+
+// lease is how long a claimed delivery is hidden from other workers. It must
+// outlive a request and the queries around it.
+func (w *DeliveryWorker) lease() time.Duration {
+	if w.client.Timeout > 0 {
+		return w.client.Timeout + time.Second*30
+	}
+	return time.Minute * 5
+}
+
+// NewDeliveryWorker returns a worker that sends requests with client. Callers
+// should give client a timeout and disable redirects.
+func NewDeliveryWorker(webhookRepo WebhookRepository, client *http.Client, signingKey string, policy RetryPolicy) *DeliveryWorker {
+	return &DeliveryWorker{
+		webhookRepo: webhookRepo,
+		client:      client,
+		signingKey:  signingKey,
+		policy:      policy,
+	}
+}
@@ -0,0 +1,243 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/pkg/signature"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const signingKey = "test-signing-key"
+
+var testPolicy = RetryPolicy{
+	MaxAttempts:  3,
+	BaseDelay:    time.Second * 30,
+	MaxDelay:     time.Hour,
+	DisableAfter: 5,
+}
+
+type received struct {
+	body    []byte
+	headers http.Header
+}
+
+func newReceiver(t *testing.T, status int) (*httptest.Server, chan received) {
+	t.Helper()
+
+	requests := make(chan received, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{body: body, headers: r.Header.Clone()}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, requests
+}
+
+func newPendingDelivery() Delivery {
+	return Delivery{
+		ID:         9,
+		EndpointID: 1,
+		EventID:    "evt_1",
+		EventType:  PaymentReceived,
+		Payload:    []byte(`{"id":"evt_1","type":"payment.received"}`),
+		Status:     DeliveryPending,
+	}
+}
+
+// claimOnce makes ClaimDue hand out each delivery once and then report that
+// nothing else is due.
+func claimOnce(m *MockWebhookRepository, deliveries ...Delivery) {
+	for _, d := range deliveries {
+		m.On("ClaimDue", mock.Anything, mock.Anything, mock.Anything, 1).Return([]Delivery{d}, nil).Once()
+	}
+	m.On("ClaimDue", mock.Anything, mock.Anything, mock.Anything, 1).Return([]Delivery{}, nil)
+}
+
+func TestDeliveryWorker_ProcessDue(t *testing.T) {
+	t.Run("should POST signed JSON to the endpoint", func(t *testing.T) {
+		srv, requests := newReceiver(t, http.StatusNoContent)
+		mockRepo := new(MockWebhookRepository)
+		claimOnce(mockRepo, newPendingDelivery())
+		mockRepo.On("FindEndpoint", mock.Anything, 1).Return(&Endpoint{ID: 1, UserID: 3, URL: srv.URL}, nil)
+		mockRepo.On("RecordAttempt", mock.Anything, mock.MatchedBy(func(d Delivery) bool {
+			return d.Status == DeliverySucceeded && d.Attempts == 1 && *d.LastStatusCode == http.StatusNoContent && d.DeliveredAt != nil
+		}), mock.Anything).Return(true, nil)
+
+		worker := NewDeliveryWorker(mockRepo, srv.Client(), signingKey, testPolicy)
+		n, err := worker.ProcessDue(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+
+		req := <-requests
+		assert.Equal(t, "application/json", req.headers.Get("Content-Type"))
+		assert.Equal(t, "evt_1", req.headers.Get("X-Webhook-ID"))
+		assert.Equal(t, string(PaymentReceived), req.headers.Get("X-Webhook-Event"))
+		assert.NoError(t, signature.VerifyWebhook(
+			endpointSecret(signingKey, 1),
+			req.headers.Get(signature.HeaderWebhookSignature),
+			req.body,
+			time.Minute,
+			time.Now(),
+		))
+		mockRepo.AssertExpectations(t)
+		mockRepo.AssertNotCalled(t, "RecordEndpointFailure", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should finish the delivery in flight and stop claiming on shutdown", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			cancel()
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		defer srv.Close()
+
+		first := newPendingDelivery()
+
+		mockRepo := new(MockWebhookRepository)
+		mockRepo.On("ClaimDue", mock.Anything, mock.Anything, mock.Anything, 1).Return([]Delivery{first}, nil).Once()
+		mockRepo.On("FindEndpoint", mock.Anything, 1).Return(&Endpoint{ID: 1, URL: srv.URL}, nil).Once()
+		mockRepo.On("RecordAttempt", mock.Anything, mock.MatchedBy(func(d Delivery) bool {
+			return d.ID == first.ID && d.Status == DeliverySucceeded
+		}), mock.Anything).Return(true, nil).Once()
+
+		n, err := NewDeliveryWorker(mockRepo, srv.Client(), signingKey, testPolicy).ProcessDue(ctx)
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should schedule a retry with backoff when the endpoint fails", func(t *testing.T) {
+		srv, _ := newReceiver(t, http.StatusInternalServerError)
+		d := newPendingDelivery()
+		d.Attempts = 1
+
+		mockRepo := new(MockWebhookRepository)
+		claimOnce(mockRepo, d)
+		mockRepo.On("FindEndpoint", mock.Anything, 1).Return(&Endpoint{ID: 1, URL: srv.URL}, nil)
+		mockRepo.On("RecordAttempt", mock.Anything, mock.MatchedBy(func(d Delivery) bool {
+			wait := time.Until(d.NextAttemptAt)
+			return d.Status == DeliveryPending &&
+				d.Attempts == 2 &&
+				*d.LastStatusCode == http.StatusInternalServerError &&
+				wait > time.Second*55 && wait <= time.Minute
+		}), mock.Anything).Return(true, nil)
+		mockRepo.On("RecordEndpointFailure", mock.Anything, 1, testPolicy.DisableAfter, mock.Anything).Return(false, nil)
+
+		_, err := NewDeliveryWorker(mockRepo, srv.Client(), signingKey, testPolicy).ProcessDue(context.Background())
+
+		require.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should give up after the last attempt", func(t *testing.T) {
+		srv, _ := newReceiver(t, http.StatusBadGateway)
+		d := newPendingDelivery()
+		d.Attempts = testPolicy.MaxAttempts - 1
+
+		mockRepo := new(MockWebhookRepository)
+		claimOnce(mockRepo, d)
+		mockRepo.On("FindEndpoint", mock.Anything, 1).Return(&Endpoint{ID: 1, URL: srv.URL}, nil)
+		mockRepo.On("RecordAttempt", mock.Anything, mock.MatchedBy(func(d Delivery) bool {
+			return d.Status == DeliveryFailed && d.Attempts == testPolicy.MaxAttempts
+		}), mock.Anything).Return(true, nil)
+		mockRepo.On("RecordEndpointFailure", mock.Anything, 1, testPolicy.DisableAfter, mock.Anything).Return(true, nil)
+
+		_, err := NewDeliveryWorker(mockRepo, srv.Client(), signingKey, testPolicy).ProcessDue(context.Background())
+
+		require.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should not call disabled endpoints", func(t *testing.T) {
+		srv, requests := newReceiver(t, http.StatusOK)
+		disabledAt := time.Now()
+
+		mockRepo := new(MockWebhookRepository)
+		claimOnce(mockRepo, newPendingDelivery())
+		mockRepo.On("FindEndpoint", mock.Anything, 1).Return(&Endpoint{ID: 1, URL: srv.URL, DisabledAt: &disabledAt}, nil)
+		mockRepo.On("RecordAttempt", mock.Anything, mock.MatchedBy(func(d Delivery) bool {
+			return d.Status == DeliveryFailed && d.Attempts == 0
+		}), mock.Anything).Return(true, nil)
+
+		_, err := NewDeliveryWorker(mockRepo, srv.Client(), signingKey, testPolicy).ProcessDue(context.Background())
+
+		require.NoError(t, err)
+		assert.Empty(t, requests)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should reset the failure counter after a success", func(t *testing.T) {
+		srv, _ := newReceiver(t, http.StatusOK)
+
+		mockRepo := new(MockWebhookRepository)
+		claimOnce(mockRepo, newPendingDelivery())
+		mockRepo.On("FindEndpoint", mock.Anything, 1).Return(&Endpoint{ID: 1, URL: srv.URL, ConsecutiveFailures: 3}, nil)
+		mockRepo.On("RecordAttempt", mock.Anything, mock.Anything, mock.Anything).Return(true, nil)
+		mockRepo.On("ResetEndpointFailures", mock.Anything, 1).Return(nil)
+
+		_, err := NewDeliveryWorker(mockRepo, srv.Client(), signingKey, testPolicy).ProcessDue(context.Background())
+
+		require.NoError(t, err)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestDeliveryWorker_Lease(t *testing.T) {
+	t.Run("should record the attempt under the lease it was claimed with", func(t *testing.T) {
+		srv, _ := newReceiver(t, http.StatusOK)
+		var lease time.Time
+
+		mockRepo := new(MockWebhookRepository)
+		mockRepo.On("ClaimDue", mock.Anything, mock.Anything, mock.Anything, 1).
+			Run(func(args mock.Arguments) { lease = args.Get(2).(time.Time) }).
+			Return([]Delivery{newPendingDelivery()}, nil).Once()
+		mockRepo.On("ClaimDue", mock.Anything, mock.Anything, mock.Anything, 1).Return([]Delivery{}, nil)
+		mockRepo.On("FindEndpoint", mock.Anything, 1).Return(&Endpoint{ID: 1, URL: srv.URL}, nil)
+		mockRepo.On("RecordAttempt", mock.Anything, mock.Anything, mock.MatchedBy(func(until time.Time) bool {
+			return until.Equal(lease)
+		})).Return(true, nil)
+
+		_, err := NewDeliveryWorker(mockRepo, srv.Client(), signingKey, testPolicy).ProcessDue(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, lease, lease.Truncate(time.Microsecond))
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("should leave the endpoint alone once the lease is lost", func(t *testing.T) {
+		srv, _ := newReceiver(t, http.StatusInternalServerError)
+
+		mockRepo := new(MockWebhookRepository)
+		claimOnce(mockRepo, newPendingDelivery())
+		mockRepo.On("FindEndpoint", mock.Anything, 1).Return(&Endpoint{ID: 1, URL: srv.URL}, nil)
+		mockRepo.On("RecordAttempt", mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+
+		n, err := NewDeliveryWorker(mockRepo, srv.Client(), signingKey, testPolicy).ProcessDue(context.Background())
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, n)
+		mockRepo.AssertNotCalled(t, "RecordEndpointFailure", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	policy := RetryPolicy{BaseDelay: time.Second * 30, MaxDelay: time.Minute * 5}
+
+	assert.Equal(t, time.Second*30, policy.backoff(1))
+	assert.Equal(t, time.Minute, policy.backoff(2))
+	assert.Equal(t, time.Minute*2, policy.backoff(3))
+	assert.Equal(t, time.Minute*5, policy.backoff(10))
+}
@@ -3,6 +3,7 @@ package apperror
 type HttpError struct {
 	Code    int    `json:"code"`
 	Message string `json:"message"`
+	Details any    `json:"details,omitempty"`
 }
 
 func (e *HttpError) Error() string {
@@ -15,3 +16,13 @@ func NewHttpError(code int, msg string) error {
 		Message: msg,
 	}
 }
+
+// NewHttpErrorWithDetails returns an error whose details, such as a list of
+// failed validation rules, are sent to the client along with the message.
+func NewHttpErrorWithDetails(code int, msg string, details any) error {
+	return &HttpError{
+		Code:    code,
+		Message: msg,
+		Details: details,
+	}
+}
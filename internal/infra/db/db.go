@@ -3,37 +3,31 @@ package db
 import (
 	"context"
 	"database/sql"
-	"errors"
 	"time"
 
+	"github.com/XSAM/otelsql"
 	_ "github.com/lib/pq"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
 )
 
 const QueryDuration = time.Second * 5
 
-var dbRef *sql.DB
-
-func Get() (*sql.DB, error) {
-	if dbRef == nil {
-		return nil, errors.New("calling Get before initialization")
-	}
-	return dbRef, nil
-}
-
-func Connect(url string, maxOpenConns, maxIdleConns int, maxIdleTime string) (*sql.DB, error) {
-	db, err := sql.Open("postgres", url)
+func Connect(url string, maxOpenConns, maxIdleConns int, maxIdleTime time.Duration) (*sql.DB, error) {
+	// every query gets a span under the one in its context
+	db, err := otelsql.Open("postgres", url,
+		otelsql.WithAttributes(semconv.DBSystemNamePostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitRows:             true,
+		}),
+	)
 	if err != nil {
 		return nil, err
 	}
 
 	db.SetMaxOpenConns(maxOpenConns)
 	db.SetMaxIdleConns(maxIdleConns)
-
-	duration, err := time.ParseDuration(maxIdleTime)
-	if err != nil {
-		return nil, err
-	}
-	db.SetConnMaxIdleTime(duration)
+	db.SetConnMaxIdleTime(maxIdleTime)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -42,7 +36,5 @@ func Connect(url string, maxOpenConns, maxIdleConns int, maxIdleTime string) (*s
 		return nil, err
 	}
 
-	dbRef = db
-
-	return dbRef, nil
+	return db, nil
 }
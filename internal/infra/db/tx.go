@@ -0,0 +1,104 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Executor runs queries on the pool or on a transaction.
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Transactor lets a service run calls to several repositories, possibly of
+// other domains, in one transaction. Repositories join it through Conn and
+// RunInTx, so their methods keep working on their own when ctx has none.
+type Transactor interface {
+	// WithTx runs fn in a transaction carried by the ctx it is given, and
+	// commits when fn returns nil. Inside another WithTx it joins the outer
+	// transaction instead.
+	WithTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type txKey struct{}
+
+type txState struct {
+	tx          *sql.Tx
+	afterCommit []func()
+}
+
+type transactor struct {
+	database *sql.DB
+}
+
+func (t *transactor) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if _, ok := ctx.Value(txKey{}).(*txState); ok {
+		return fn(ctx)
+	}
+
+	tx, err := t.database.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	state := &txState{tx: tx}
+	if err := fn(context.WithValue(ctx, txKey{}, state)); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for _, f := range state.afterCommit {
+		f()
+	}
+	return nil
+}
+
+// Conn returns the transaction started by WithTx on ctx, so the query joins
+// it, or database when there is none.
+func Conn(ctx context.Context, database *sql.DB) Executor {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		return state.tx
+	}
+	return database
+}
+
+// RunInTx runs fn in the transaction started by WithTx on ctx. Without one,
+// fn gets a transaction of its own that is committed when it returns nil.
+func RunInTx(ctx context.Context, database *sql.DB, fn func(tx *sql.Tx) error) error {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		return fn(state.tx)
+	}
+
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// AfterCommit runs f once the transaction started by WithTx on ctx commits,
+// and never if it rolls back. Without a transaction f runs right away. It is
+// meant for side effects that cannot be undone, such as deleting files.
+func AfterCommit(ctx context.Context, f func()) {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		state.afterCommit = append(state.afterCommit, f)
+		return
+	}
+	f()
+}
+
+func NewTransactor(database *sql.DB) Transactor {
+	return &transactor{database}
+}
@@ -0,0 +1,13 @@
+package db
+
+import "context"
+
+// MockTransactor runs fn without a transaction and counts the calls.
+type MockTransactor struct {
+	Calls int
+}
+
+func (m *MockTransactor) WithTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	m.Calls++
+	return fn(ctx)
+}
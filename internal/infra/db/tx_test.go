@@ -0,0 +1,144 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// countingDB is a database/sql driver that counts transactions and the
+// statements run outside of them.
+type countingDB struct {
+	begins    int
+	commits   int
+	rollbacks int
+	outsideTx int
+	inTx      bool
+}
+
+func (d *countingDB) Connect(context.Context) (driver.Conn, error) { return &countingConn{d}, nil }
+func (d *countingDB) Driver() driver.Driver                        { return nil }
+
+type countingConn struct {
+	db *countingDB
+}
+
+func (c *countingConn) Prepare(string) (driver.Stmt, error) { return nil, driver.ErrSkip }
+func (c *countingConn) Close() error                        { return nil }
+
+func (c *countingConn) Begin() (driver.Tx, error) {
+	c.db.begins++
+	c.db.inTx = true
+	return c, nil
+}
+
+func (c *countingConn) Commit() error {
+	c.db.commits++
+	c.db.inTx = false
+	return nil
+}
+
+func (c *countingConn) Rollback() error {
+	c.db.rollbacks++
+	c.db.inTx = false
+	return nil
+}
+
+func (c *countingConn) ExecContext(context.Context, string, []driver.NamedValue) (driver.Result, error) {
+	if !c.db.inTx {
+		c.db.outsideTx++
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func TestTransactor_WithTx(t *testing.T) {
+	t.Run("should run every repository call in one transaction", func(t *testing.T) {
+		counting := &countingDB{}
+		database := sql.OpenDB(counting)
+		defer database.Close()
+
+		var committed bool
+		err := NewTransactor(database).WithTx(context.Background(), func(ctx context.Context) error {
+			if _, err := Conn(ctx, database).ExecContext(ctx, "UPDATE a"); err != nil {
+				return err
+			}
+
+			err := RunInTx(ctx, database, func(tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, "UPDATE b")
+				return err
+			})
+			if err != nil {
+				return err
+			}
+
+			AfterCommit(ctx, func() { committed = true })
+			assert.False(t, committed)
+			return nil
+		})
+
+		require.NoError(t, err)
+		assert.True(t, committed)
+		assert.Equal(t, 1, counting.begins)
+		assert.Equal(t, 1, counting.commits)
+		assert.Zero(t, counting.outsideTx)
+	})
+
+	t.Run("should join the outer transaction when nested", func(t *testing.T) {
+		counting := &countingDB{}
+		database := sql.OpenDB(counting)
+		defer database.Close()
+
+		transactor := NewTransactor(database)
+		err := transactor.WithTx(context.Background(), func(ctx context.Context) error {
+			return transactor.WithTx(ctx, func(ctx context.Context) error {
+				_, err := Conn(ctx, database).ExecContext(ctx, "UPDATE a")
+				return err
+			})
+		})
+
+		require.NoError(t, err)
+		assert.Equal(t, 1, counting.begins)
+		assert.Equal(t, 1, counting.commits)
+	})
+
+	t.Run("should roll back and skip the after commit hooks on error", func(t *testing.T) {
+		counting := &countingDB{}
+		database := sql.OpenDB(counting)
+		defer database.Close()
+
+		var committed bool
+		err := NewTransactor(database).WithTx(context.Background(), func(ctx context.Context) error {
+			AfterCommit(ctx, func() { committed = true })
+			return errors.New("balance is not zero")
+		})
+
+		require.Error(t, err)
+		assert.False(t, committed)
+		assert.Zero(t, counting.commits)
+		assert.Equal(t, 1, counting.rollbacks)
+	})
+
+	t.Run("should run on its own without a transaction on ctx", func(t *testing.T) {
+		counting := &countingDB{}
+		database := sql.OpenDB(counting)
+		defer database.Close()
+
+		err := RunInTx(context.Background(), database, func(tx *sql.Tx) error {
+			_, err := tx.ExecContext(context.Background(), "UPDATE a")
+			return err
+		})
+
+		var ran bool
+		AfterCommit(context.Background(), func() { ran = true })
+
+		require.NoError(t, err)
+		assert.True(t, ran)
+		assert.Equal(t, 1, counting.begins)
+		assert.Equal(t, 1, counting.commits)
+	})
+}
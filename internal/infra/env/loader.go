@@ -0,0 +1,214 @@
+package env
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+// Sources a setting can come from, lowest precedence first.
+const (
+	sourceDefault = "default"
+	sourceFile    = "file"
+	sourceEnv     = "env"
+	sourceFlag    = "flag"
+)
+
+type value struct {
+	key    string
+	val    string
+	source string
+	secret bool
+}
+
+// Loader layers defaults, an optional YAML config file, environment
+// variables (a .env file included, when present) and flags, each overriding
+// the previous one.
+type Loader struct {
+	flags      *flag.FlagSet
+	configFile *string
+}
+
+// NewLoader registers --config and a flag per setting on fs; Load must run
+// after fs is parsed. With a nil fs no flag is read.
+func NewLoader(fs *flag.FlagSet) *Loader {
+	l := &Loader{flags: fs}
+	if fs == nil {
+		return l
+	}
+
+	l.configFile = fs.String("config", "", "YAML config file, overrides CONFIG_FILE")
+	for _, s := range settings(new(Config)) {
+		fs.String(flagName(s.key), "", "overrides "+s.key)
+	}
+
+	return l
+}
+
+// Load reads the configuration from every source but flags.
+func Load() (*Config, error) {
+	return NewLoader(nil).Load()
+}
+
+// Load reports every invalid setting at once rather than stopping at the
+// first.
+func (l *Loader) Load() (*Config, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("reading .env: %w", err)
+	}
+
+	cfg := new(Config)
+	all := settings(cfg)
+
+	values := make(map[string]value, len(all))
+	for _, s := range all {
+		values[s.key] = value{key: s.key, val: s.def, source: sourceDefault, secret: s.secret}
+	}
+
+	var errs []error
+
+	path := os.Getenv("CONFIG_FILE")
+	if l.configFile != nil && *l.configFile != "" {
+		path = *l.configFile
+	}
+	if path != "" {
+		errs = append(errs, readFile(path, all, values)...)
+	}
+
+	for _, s := range all {
+		if val, ok := os.LookupEnv(s.key); ok {
+			values[s.key] = value{key: s.key, val: val, source: sourceEnv, secret: s.secret}
+		}
+	}
+
+	if l.flags != nil {
+		byFlag := make(map[string]setting, len(all))
+		for _, s := range all {
+			byFlag[flagName(s.key)] = s
+		}
+		l.flags.Visit(func(f *flag.Flag) {
+			if s, ok := byFlag[f.Name]; ok {
+				values[s.key] = value{key: s.key, val: f.Value.String(), source: sourceFlag, secret: s.secret}
+			}
+		})
+	}
+
+	for _, s := range all {
+		v := values[s.key]
+		if err := s.set(v.val); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w (from %s)", s.key, err, v.source))
+		}
+		cfg.effective = append(cfg.effective, v)
+	}
+
+	errs = append(errs, validate(cfg, all)...)
+
+	if len(errs) > 0 {
+		return nil, fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+
+	return cfg, nil
+}
+
+// readFile sets the values found in the YAML file at path. Unknown keys are
+// errors so a typo does not go unnoticed.
+func readFile(path string, all []setting, values map[string]value) []error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return []error{fmt.Errorf("reading config file: %w", err)}
+	}
+
+	var doc map[string]any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return []error{fmt.Errorf("parsing config file %s: %w", path, err)}
+	}
+
+	byPath := make(map[string]setting, len(all))
+	for _, s := range all {
+		byPath[s.path] = s
+	}
+
+	flat := make(map[string]string)
+	flatten("", doc, flat)
+
+	var errs []error
+	for _, p := range sortedKeys(flat) {
+		s, ok := byPath[p]
+		if !ok {
+			errs = append(errs, fmt.Errorf("config file %s: unknown key %q", path, p))
+			continue
+		}
+		values[s.key] = value{key: s.key, val: flat[p], source: sourceFile, secret: s.secret}
+	}
+
+	return errs
+}
+
+func flatten(prefix string, node map[string]any, out map[string]string) {
+	for k, v := range node {
+		p := k
+		if prefix != "" {
+			p = prefix + "." + k
+		}
+
+		switch v := v.(type) {
+		case map[string]any:
+			flatten(p, v, out)
+		case []any:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			out[p] = strings.Join(items, ",")
+		case nil:
+			out[p] = ""
+		default:
+			out[p] = fmt.Sprint(v)
+		}
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func flagName(key string) string {
+	return strings.ToLower(strings.ReplaceAll(key, "_", "-"))
+}
+
+func (c *Config) lookup(key string) value {
+	for _, v := range c.effective {
+		if v.key == key {
+			return v
+		}
+	}
+	return value{}
+}
+
+// Print writes every setting with the source it came from. Secrets are
+// redacted.
+func (c *Config) Print(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, v := range c.effective {
+		val := v.val
+		if v.secret && val != "" {
+			val = "[redacted]"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t(%s)\n", v.key, val, v.source)
+	}
+	return tw.Flush()
+}
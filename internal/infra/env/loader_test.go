@@ -0,0 +1,211 @@
+package env
+
+import (
+	"bytes"
+	"flag"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const strongSecret = "0123456789abcdef0123456789abcdef"
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func loadWithFlags(t *testing.T, args ...string) (*Config, error) {
+	t.Helper()
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	loader := NewLoader(fs)
+	require.NoError(t, fs.Parse(args))
+	return loader.Load()
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("should load defaults", func(t *testing.T) {
+		t.Setenv("JWT_SECRET", "dev-secret")
+
+		cfg, err := Load()
+
+		require.NoError(t, err)
+		assert.Equal(t, ModeDevelopment, cfg.Mode)
+		assert.Equal(t, "8080", cfg.ServerPort)
+		assert.Equal(t, 15*time.Minute, cfg.DB.MaxIdleTime)
+		assert.Equal(t, int64(20000), cfg.Transfer.NoneMaxPerTx)
+		assert.True(t, cfg.Password.RequireUpper)
+	})
+
+	t.Run("should not default the JWT secret", func(t *testing.T) {
+		_, err := Load()
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "JWT_SECRET: required when JWT_ALG is HS256")
+	})
+
+	t.Run("should layer file, environment and flags", func(t *testing.T) {
+		path := writeConfig(t, `
+server:
+  port: 9000
+db:
+  max_open_conns: 10
+jwt:
+  secret: from-file
+  key_files: [a.pem, b.pem]
+transfer:
+  limits:
+    basic: { daily: 123 }
+`)
+		t.Setenv("DB_MAX_OPEN_CONNS", "20")
+		t.Setenv("SERVER_PORT", "9001")
+
+		cfg, err := loadWithFlags(t, "--config", path, "--server-port", "9002")
+
+		require.NoError(t, err)
+		assert.Equal(t, "9002", cfg.ServerPort)
+		assert.Equal(t, 20, cfg.DB.MaxOpenConns)
+		assert.Equal(t, "from-file", cfg.JWT.Secret)
+		assert.Equal(t, []string{"a.pem", "b.pem"}, cfg.JWT.KeyFiles)
+		assert.Equal(t, int64(123), cfg.Transfer.BasicDailyLimit)
+		assert.Equal(t, sourceFlag, cfg.lookup("SERVER_PORT").source)
+		assert.Equal(t, sourceEnv, cfg.lookup("DB_MAX_OPEN_CONNS").source)
+		assert.Equal(t, sourceFile, cfg.lookup("JWT_SECRET").source)
+	})
+
+	t.Run("should parse trusted proxies", func(t *testing.T) {
+		t.Setenv("JWT_SECRET", "dev-secret")
+		t.Setenv("TRUSTED_PROXIES", "10.0.0.0/8, 192.168.1.10")
+
+		cfg, err := Load()
+
+		require.NoError(t, err)
+		assert.Equal(t, []netip.Prefix{
+			netip.MustParsePrefix("10.0.0.0/8"),
+			netip.MustParsePrefix("192.168.1.10/32"),
+		}, cfg.TrustedProxies)
+	})
+
+	t.Run("should read the config file from CONFIG_FILE", func(t *testing.T) {
+		t.Setenv("CONFIG_FILE", writeConfig(t, "jwt:\n  secret: from-file\n"))
+
+		cfg, err := Load()
+
+		require.NoError(t, err)
+		assert.Equal(t, "from-file", cfg.JWT.Secret)
+	})
+
+	t.Run("should report every invalid setting at once", func(t *testing.T) {
+		t.Setenv("JWT_SECRET", "dev-secret")
+		t.Setenv("SERVER_PORT", "70000")
+		t.Setenv("DB_MAX_OPEN_CONNS", "many")
+		t.Setenv("WEBHOOK_TIMEOUT", "10")
+		t.Setenv("PASSWORD_REQUIRE_UPPER", "sometimes")
+		t.Setenv("MAIL_DRIVER", "pigeon")
+		t.Setenv("LOGIN_BASE_DELAY", "1m")
+		t.Setenv("TRUSTED_PROXIES", "proxy.internal")
+
+		_, err := Load()
+
+		require.Error(t, err)
+		for _, key := range []string{
+			"SERVER_PORT",
+			"DB_MAX_OPEN_CONNS",
+			"WEBHOOK_TIMEOUT",
+			"PASSWORD_REQUIRE_UPPER",
+			"MAIL_DRIVER",
+			"LOGIN_BASE_DELAY",
+			"TRUSTED_PROXIES",
+		} {
+			assert.Contains(t, err.Error(), key+":")
+		}
+	})
+
+	t.Run("should reject unknown keys in the config file", func(t *testing.T) {
+		t.Setenv("JWT_SECRET", "dev-secret")
+		path := writeConfig(t, "db:\n  max_open_conn: 10\n")
+
+		_, err := loadWithFlags(t, "--config", path)
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `unknown key "db.max_open_conn"`)
+	})
+
+	t.Run("should require strong secrets in production", func(t *testing.T) {
+		t.Setenv("APP_ENV", ModeProduction)
+		t.Setenv("JWT_SECRET", "short")
+
+		_, err := Load()
+
+		require.Error(t, err)
+		for _, key := range []string{"JWT_SECRET", "MFA_ENCRYPTION_KEY", "API_KEY_SIGNING_KEY", "WEBHOOK_SIGNING_KEY", "AUDIT_PII_KEY"} {
+			assert.Contains(t, err.Error(), key+": must be at least 32 characters in production")
+		}
+		assert.Contains(t, err.Error(), "METRICS_TOKEN: required in production")
+	})
+
+	t.Run("should accept strong secrets in production", func(t *testing.T) {
+		t.Setenv("APP_ENV", ModeProduction)
+		t.Setenv("JWT_ALG", "EdDSA")
+		t.Setenv("MFA_ENCRYPTION_KEY", strongSecret)
+		t.Setenv("API_KEY_SIGNING_KEY", strongSecret)
+		t.Setenv("WEBHOOK_SIGNING_KEY", strongSecret)
+		t.Setenv("AUDIT_PII_KEY", strongSecret)
+		t.Setenv("METRICS_TOKEN", "scraper")
+
+		cfg, err := Load()
+
+		require.NoError(t, err)
+		assert.True(t, cfg.IsProduction())
+	})
+
+	t.Run("should load the example config file", func(t *testing.T) {
+		t.Setenv("MFA_ENCRYPTION_KEY", strongSecret)
+		t.Setenv("API_KEY_SIGNING_KEY", strongSecret)
+		t.Setenv("WEBHOOK_SIGNING_KEY", strongSecret)
+		t.Setenv("AUDIT_PII_KEY", strongSecret)
+		t.Setenv("METRICS_TOKEN", "scraper")
+
+		_, err := loadWithFlags(t, "--config", "../../../config.example.yaml")
+
+		require.NoError(t, err)
+	})
+}
+
+func TestConfigPrint(t *testing.T) {
+	t.Setenv("JWT_SECRET", "dev-secret")
+	t.Setenv("SMTP_USERNAME", "mailer")
+
+	cfg, err := Load()
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	require.NoError(t, cfg.Print(&out))
+
+	assert.NotContains(t, out.String(), "dev-secret")
+	assert.NotContains(t, out.String(), "admin:admin")
+	for _, line := range strings.Split(out.String(), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "JWT_SECRET":
+			assert.Equal(t, []string{"JWT_SECRET", "[redacted]", "(env)"}, fields)
+		case "SMTP_USERNAME":
+			assert.Equal(t, []string{"SMTP_USERNAME", "mailer", "(env)"}, fields)
+		case "ADMIN_PASSWORD":
+			assert.Equal(t, []string{"ADMIN_PASSWORD", "(default)"}, fields)
+		}
+	}
+}
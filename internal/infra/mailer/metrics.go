@@ -0,0 +1,54 @@
+package mailer
+
+import (
+	"context"
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/metrics"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	sendDuration = metrics.NewHistogram(
+		"picpay_mail_send_duration_seconds",
+		"Time to hand an email to the mail driver.",
+		metrics.DefaultBuckets,
+		"driver",
+	)
+	sendErrors = metrics.NewCounter(
+		"picpay_mail_send_errors_total",
+		"Emails the mail driver failed to send.",
+		"driver",
+	)
+)
+
+type instrumentedMailer struct {
+	next   Mailer
+	driver string
+}
+
+func (m *instrumentedMailer) Send(ctx context.Context, msg Message) error {
+	ctx, span := tracing.Start(ctx, "mail.send", trace.WithAttributes(
+		attribute.String("mail.driver", m.driver),
+	))
+	defer span.End()
+
+	start := time.Now()
+	err := m.next.Send(ctx, msg)
+	sendDuration.Observe(time.Since(start).Seconds(), m.driver)
+	if err != nil {
+		sendErrors.Inc(m.driver)
+		span.RecordError(err)
+		span.SetStatus(codes.Error, "sending failed")
+	}
+	return err
+}
+
+// NewInstrumentedMailer records the latency and errors of next under driver
+// and traces every send.
+func NewInstrumentedMailer(next Mailer, driver string) Mailer {
+	return &instrumentedMailer{next: next, driver: driver}
+}
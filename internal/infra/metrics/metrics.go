@@ -0,0 +1,379 @@
+// Package metrics keeps counters, gauges and histograms and writes them in
+// the Prometheus text format. It has no HTTP code, so domain packages record
+// business metrics with it directly.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets suit latencies in seconds, from 5ms to 10s.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Default is the registry the package level constructors register on.
+var Default = NewRegistry()
+
+type metric interface {
+	describe() desc
+	write(w io.Writer) error
+}
+
+type desc struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+}
+
+type Registry struct {
+	mu      sync.Mutex
+	metrics map[string]metric
+}
+
+func NewRegistry() *Registry {
+	return &Registry{metrics: make(map[string]metric)}
+}
+
+func (r *Registry) register(m metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	name := m.describe().name
+	if _, ok := r.metrics[name]; ok {
+		panic(fmt.Sprintf("metrics: %s registered twice", name))
+	}
+	r.metrics[name] = m
+}
+
+// Write writes every metric, sorted by name.
+func (r *Registry) Write(w io.Writer) error {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.metrics))
+	for name := range r.metrics {
+		names = append(names, name)
+	}
+	metrics := make([]metric, len(names))
+	sort.Strings(names)
+	for i, name := range names {
+		metrics[i] = r.metrics[name]
+	}
+	r.mu.Unlock()
+
+	for _, m := range metrics {
+		d := m.describe()
+		if _, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", d.name, escapeHelp(d.help), d.name, d.kind); err != nil {
+			return err
+		}
+		if err := m.write(w); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// series are the values of a metric, one per set of label values.
+type series[T any] struct {
+	desc
+	mu     sync.Mutex
+	values map[string]*labeled[T]
+}
+
+type labeled[T any] struct {
+	labelValues []string
+	value       T
+}
+
+func newSeries[T any](d desc) series[T] {
+	return series[T]{desc: d, values: make(map[string]*labeled[T])}
+}
+
+func (s *series[T]) describe() desc {
+	return s.desc
+}
+
+// with returns the value for labelValues, creating it with init. The caller
+// must hold s.mu.
+func (s *series[T]) with(labelValues []string, init func() T) *T {
+	if len(labelValues) != len(s.labels) {
+		panic(fmt.Sprintf("metrics: %s takes %d label values, got %d", s.name, len(s.labels), len(labelValues)))
+	}
+
+	key := strings.Join(labelValues, "\xff")
+	l, ok := s.values[key]
+	if !ok {
+		l = &labeled[T]{labelValues: append([]string(nil), labelValues...), value: init()}
+		s.values[key] = l
+	}
+	return &l.value
+}
+
+// sorted returns a snapshot of the values, sorted by label values.
+func (s *series[T]) sorted(snapshot func(T) T) []labeled[T] {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.values))
+	for k := range s.values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	out := make([]labeled[T], len(keys))
+	for i, k := range keys {
+		l := s.values[k]
+		out[i] = labeled[T]{labelValues: l.labelValues, value: snapshot(l.value)}
+	}
+	return out
+}
+
+type Counter struct {
+	series[float64]
+}
+
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{newSeries[float64](desc{name: name, help: help, kind: "counter", labels: labels})}
+	r.register(c)
+	return c
+}
+
+func NewCounter(name, help string, labels ...string) *Counter {
+	return Default.NewCounter(name, help, labels...)
+}
+
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add panics on a negative v: counters only go up.
+func (c *Counter) Add(v float64, labelValues ...string) {
+	if v < 0 {
+		panic(fmt.Sprintf("metrics: %s decreased", c.name))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	*c.with(labelValues, zero) += v
+}
+
+func (c *Counter) write(w io.Writer) error {
+	for _, l := range c.sorted(same) {
+		if err := writeSample(w, c.name, c.labels, l.labelValues, "", "", l.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type Gauge struct {
+	series[float64]
+}
+
+func (r *Registry) NewGauge(name, help string, labels ...string) *Gauge {
+	g := &Gauge{newSeries[float64](desc{name: name, help: help, kind: "gauge", labels: labels})}
+	r.register(g)
+	return g
+}
+
+func NewGauge(name, help string, labels ...string) *Gauge {
+	return Default.NewGauge(name, help, labels...)
+}
+
+func (g *Gauge) Set(v float64, labelValues ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	*g.with(labelValues, zero) = v
+}
+
+func (g *Gauge) Add(v float64, labelValues ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	*g.with(labelValues, zero) += v
+}
+
+func (g *Gauge) write(w io.Writer) error {
+	for _, l := range g.sorted(same) {
+		if err := writeSample(w, g.name, g.labels, l.labelValues, "", "", l.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type Histogram struct {
+	series[histogramValue]
+	buckets []float64
+}
+
+type histogramValue struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewHistogram counts observations into buckets, which must be sorted
+// ascending. The +Inf bucket is implied.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if !sort.Float64sAreSorted(buckets) {
+		panic(fmt.Sprintf("metrics: %s buckets are not sorted", name))
+	}
+
+	h := &Histogram{
+		series:  newSeries[histogramValue](desc{name: name, help: help, kind: "histogram", labels: labels}),
+		buckets: buckets,
+	}
+	r.register(h)
+	return h
+}
+
+func NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	return Default.NewHistogram(name, help, buckets, labels...)
+}
+
+func (h *Histogram) Observe(v float64, labelValues ...string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	hv := h.with(labelValues, func() histogramValue {
+		return histogramValue{counts: make([]uint64, len(h.buckets))}
+	})
+
+	if i := sort.SearchFloat64s(h.buckets, v); i < len(h.buckets) {
+		hv.counts[i]++
+	}
+	hv.sum += v
+	hv.count++
+}
+
+func (h *Histogram) write(w io.Writer) error {
+	snapshot := func(hv histogramValue) histogramValue {
+		hv.counts = append([]uint64(nil), hv.counts...)
+		return hv
+	}
+
+	for _, l := range h.sorted(snapshot) {
+		var cumulative uint64
+		for i, upper := range h.buckets {
+			cumulative += l.value.counts[i]
+			if err := writeSample(w, h.name+"_bucket", h.labels, l.labelValues, "le", formatFloat(upper), float64(cumulative)); err != nil {
+				return err
+			}
+		}
+		if err := writeSample(w, h.name+"_bucket", h.labels, l.labelValues, "le", "+Inf", float64(l.value.count)); err != nil {
+			return err
+		}
+		if err := writeSample(w, h.name+"_sum", h.labels, l.labelValues, "", "", l.value.sum); err != nil {
+			return err
+		}
+		if err := writeSample(w, h.name+"_count", h.labels, l.labelValues, "", "", float64(l.value.count)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// funcMetric reads its value when written, for values kept elsewhere such as
+// connection pool stats.
+type funcMetric struct {
+	desc
+	fn func() float64
+}
+
+func (f *funcMetric) describe() desc {
+	return f.desc
+}
+
+func (f *funcMetric) write(w io.Writer) error {
+	return writeSample(w, f.name, nil, nil, "", "", f.fn())
+}
+
+// SetGaugeFunc exposes fn as a gauge, replacing the function registered
+// under name before, if any.
+func (r *Registry) SetGaugeFunc(name, help string, fn func() float64) {
+	r.setFunc(&funcMetric{desc: desc{name: name, help: help, kind: "gauge"}, fn: fn})
+}
+
+// SetCounterFunc is SetGaugeFunc for values that only go up.
+func (r *Registry) SetCounterFunc(name, help string, fn func() float64) {
+	r.setFunc(&funcMetric{desc: desc{name: name, help: help, kind: "counter"}, fn: fn})
+}
+
+func (r *Registry) setFunc(f *funcMetric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if m, ok := r.metrics[f.name]; ok {
+		if _, isFunc := m.(*funcMetric); !isFunc {
+			panic(fmt.Sprintf("metrics: %s registered twice", f.name))
+		}
+	}
+	r.metrics[f.name] = f
+}
+
+func zero() float64 {
+	return 0
+}
+
+func same(v float64) float64 {
+	return v
+}
+
+func writeSample(w io.Writer, name string, labels, labelValues []string, extraLabel, extraValue string, v float64) error {
+	var b strings.Builder
+	b.WriteString(name)
+
+	if len(labels) > 0 || extraLabel != "" {
+		b.WriteByte('{')
+		for i, label := range labels {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(&b, `%s="%s"`, label, escapeLabel(labelValues[i]))
+		}
+		if extraLabel != "" {
+			if len(labels) > 0 {
+				b.WriteByte(',')
+			}
+			fmt.Fprintf(&b, `%s="%s"`, extraLabel, extraValue)
+		}
+		b.WriteByte('}')
+	}
+
+	b.WriteByte(' ')
+	b.WriteString(formatFloat(v))
+	b.WriteByte('\n')
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+var (
+	labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+)
+
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
+
+func escapeHelp(s string) string {
+	return helpEscaper.Replace(s)
+}
@@ -0,0 +1,96 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func write(t *testing.T, r *Registry) string {
+	t.Helper()
+
+	var b strings.Builder
+	require.NoError(t, r.Write(&b))
+	return b.String()
+}
+
+func TestCounter(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("transfers_total", "Transfers by outcome.", "outcome")
+
+	c.Inc("completed")
+	c.Add(2, "completed")
+	c.Inc("denied")
+
+	assert.Equal(t, `# HELP transfers_total Transfers by outcome.
+# TYPE transfers_total counter
+transfers_total{outcome="completed"} 3
+transfers_total{outcome="denied"} 1
+`, write(t, r))
+
+	assert.Panics(t, func() { c.Add(-1, "completed") })
+	assert.Panics(t, func() { c.Inc() })
+}
+
+func TestGauge(t *testing.T) {
+	r := NewRegistry()
+	g := r.NewGauge("queue_size", "Items queued.")
+
+	g.Set(5)
+	g.Add(-2)
+
+	assert.Equal(t, "# HELP queue_size Items queued.\n# TYPE queue_size gauge\nqueue_size 3\n", write(t, r))
+}
+
+func TestHistogram(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogram("latency_seconds", "Latency.", []float64{0.1, 1}, "route")
+
+	h.Observe(0.05, "/a")
+	h.Observe(0.1, "/a")
+	h.Observe(0.5, "/a")
+	h.Observe(3, "/a")
+
+	assert.Equal(t, `# HELP latency_seconds Latency.
+# TYPE latency_seconds histogram
+latency_seconds_bucket{route="/a",le="0.1"} 2
+latency_seconds_bucket{route="/a",le="1"} 3
+latency_seconds_bucket{route="/a",le="+Inf"} 4
+latency_seconds_sum{route="/a"} 3.65
+latency_seconds_count{route="/a"} 4
+`, write(t, r))
+}
+
+func TestRegistry(t *testing.T) {
+	t.Run("should sort metrics by name and escape label values", func(t *testing.T) {
+		r := NewRegistry()
+		r.NewCounter("b_total", "B.", "path").Inc(`/a"b\c`)
+		r.NewCounter("a_total", "A.\nmore").Inc()
+
+		assert.Equal(t, `# HELP a_total A.\nmore
+# TYPE a_total counter
+a_total 1
+# HELP b_total B.
+# TYPE b_total counter
+b_total{path="/a\"b\\c"} 1
+`, write(t, r))
+	})
+
+	t.Run("should refuse a name registered twice", func(t *testing.T) {
+		r := NewRegistry()
+		r.NewCounter("a_total", "A.")
+
+		assert.Panics(t, func() { r.NewGauge("a_total", "A.") })
+		assert.Panics(t, func() { r.SetGaugeFunc("a_total", "A.", func() float64 { return 0 }) })
+	})
+
+	t.Run("should replace a function metric", func(t *testing.T) {
+		r := NewRegistry()
+		r.SetGaugeFunc("open", "Open.", func() float64 { return 1 })
+		r.SetGaugeFunc("open", "Open.", func() float64 { return 2 })
+
+		assert.Equal(t, "# HELP open Open.\n# TYPE open gauge\nopen 2\n", write(t, r))
+	})
+}
@@ -0,0 +1,344 @@
+package migrator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// The bookkeeping table is the one the migrate CLI uses, so databases it
+// migrated keep working: a single row holding the current version and
+// whether the last migration failed halfway.
+const createVersionTable = `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT NOT NULL PRIMARY KEY,
+		dirty BOOLEAN NOT NULL
+	)
+`
+
+var fileName = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// lockID identifies the advisory lock held while migrating, so concurrent
+// deploys run migrations one at a time.
+var lockID = func() int64 {
+	h := fnv.New64a()
+	h.Write([]byte("schema_migrations"))
+	return int64(h.Sum64() >> 1)
+}()
+
+var ErrDirty = errors.New("database is dirty, fix the failed migration by hand and force a version")
+
+type Migration struct {
+	Version uint
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Version uint   `json:"version"`
+	Name    string `json:"name"`
+	Applied bool   `json:"applied"`
+}
+
+type Migrator struct {
+	database   *sql.DB
+	migrations []Migration
+}
+
+// Version returns the current version, 0 when no migration was applied.
+func (m *Migrator) Version(ctx context.Context) (uint, bool, error) {
+	var version uint
+	var dirty bool
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		var err error
+		version, dirty, err = readVersion(ctx, conn)
+		return err
+	})
+	return version, dirty, err
+}
+
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	version, _, err := m.Version(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]Status, len(m.migrations))
+	for i, mig := range m.migrations {
+		status[i] = Status{Version: mig.Version, Name: mig.Name, Applied: mig.Version <= version}
+	}
+
+	return status, nil
+}
+
+// Up applies every pending migration and returns how many ran.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	applied := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		version, dirty, err := readVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("%w (version %d)", ErrDirty, version)
+		}
+
+		for _, mig := range m.pending(version) {
+			slog.Info("applying migration", "version", mig.Version, "name", mig.Name)
+			if err := run(ctx, conn, mig.Version, mig.Up, mig.Version); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", mig.Version, mig.Name, err)
+			}
+			applied++
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down rolls back the last n applied migrations and returns how many ran.
+func (m *Migrator) Down(ctx context.Context, n int) (int, error) {
+	if n <= 0 {
+		return 0, errors.New("number of migrations to roll back must be positive")
+	}
+
+	reverted := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		version, dirty, err := readVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if dirty {
+			return fmt.Errorf("%w (version %d)", ErrDirty, version)
+		}
+
+		steps, err := m.rollback(version, n)
+		if err != nil {
+			return err
+		}
+
+		for _, step := range steps {
+			slog.Info("reverting migration", "version", step.migration.Version, "name", step.migration.Name)
+			if err := run(ctx, conn, step.migration.Version, step.migration.Down, step.previous); err != nil {
+				return fmt.Errorf("migration %d_%s: %w", step.migration.Version, step.migration.Name, err)
+			}
+			reverted++
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// Force sets the version without running anything and clears the dirty
+// flag, after a failed migration was fixed by hand. 0 means no migration
+// applied.
+func (m *Migrator) Force(ctx context.Context, version uint) error {
+	if version != 0 && m.find(version) < 0 {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		return setVersion(ctx, conn, version, false)
+	})
+}
+
+func (m *Migrator) pending(version uint) []Migration {
+	i := sort.Search(len(m.migrations), func(i int) bool { return m.migrations[i].Version > version })
+	return m.migrations[i:]
+}
+
+type rollbackStep struct {
+	migration Migration
+	previous  uint
+}
+
+func (m *Migrator) rollback(version uint, n int) ([]rollbackStep, error) {
+	if version == 0 {
+		return nil, nil
+	}
+
+	i := m.find(version)
+	if i < 0 {
+		return nil, fmt.Errorf("database is at version %d, which this binary does not know", version)
+	}
+
+	steps := []rollbackStep{}
+	for ; i >= 0 && len(steps) < n; i-- {
+		var previous uint
+		if i > 0 {
+			previous = m.migrations[i-1].Version
+		}
+		steps = append(steps, rollbackStep{m.migrations[i], previous})
+	}
+
+	return steps, nil
+}
+
+func (m *Migrator) find(version uint) int {
+	for i, mig := range m.migrations {
+		if mig.Version == version {
+			return i
+		}
+	}
+	return -1
+}
+
+// withLock runs fn on a single connection holding the advisory lock;
+// session level advisory locks belong to the connection that took them.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.database.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, lockID); err != nil {
+		return fmt.Errorf("acquiring migration lock: %w", err)
+	}
+	defer func() {
+		// the lock dies with the session anyway if this fails
+		if _, err := conn.ExecContext(context.WithoutCancel(ctx), `SELECT pg_advisory_unlock($1)`, lockID); err != nil {
+			slog.Error("failed to release migration lock", "err", err.Error())
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, createVersionTable); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+// run marks the database dirty at version, executes body and records
+// target. A failure leaves the dirty flag set, as the statements before the
+// failing one may have been applied.
+func run(ctx context.Context, conn *sql.Conn, version uint, body string, target uint) error {
+	if err := setVersion(ctx, conn, version, true); err != nil {
+		return err
+	}
+
+	if strings.TrimSpace(body) != "" {
+		if _, err := conn.ExecContext(ctx, body); err != nil {
+			return err
+		}
+	}
+
+	return setVersion(ctx, conn, target, false)
+}
+
+func readVersion(ctx context.Context, conn *sql.Conn) (uint, bool, error) {
+	var version int64
+	var dirty bool
+	err := conn.QueryRowContext(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).Scan(&version, &dirty)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+
+	// the migrate CLI stores -1 for "no version" after forcing it
+	if version < 0 {
+		return 0, dirty, nil
+	}
+
+	return uint(version), dirty, nil
+}
+
+func setVersion(ctx context.Context, conn *sql.Conn, version uint, dirty bool) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `TRUNCATE schema_migrations`); err != nil {
+		return err
+	}
+
+	if version > 0 || dirty {
+		_, err := tx.ExecContext(ctx, `INSERT INTO schema_migrations (version, dirty) VALUES ($1, $2)`, int64(version), dirty)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Load reads "<version>_<name>.up.sql" and "<version>_<name>.down.sql" pairs
+// from the root of fsys, sorted by version.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[uint]*Migration{}
+	files := map[uint]int{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+
+		v, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil || v == 0 {
+			return nil, fmt.Errorf("%s: invalid version", entry.Name())
+		}
+		version := uint(v)
+
+		body, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[version]
+		if !ok {
+			mig = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = mig
+		}
+		if mig.Name != match[2] {
+			return nil, fmt.Errorf("version %d is used by both %q and %q", version, mig.Name, match[2])
+		}
+
+		files[version]++
+		if match[3] == "up" {
+			mig.Up = string(body)
+		} else {
+			mig.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if files[mig.Version] != 2 {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", mig.Version, mig.Name)
+		}
+		migrations = append(migrations, *mig)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+func New(database *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := Load(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Migrator{
+		database:   database,
+		migrations: migrations,
+	}, nil
+}
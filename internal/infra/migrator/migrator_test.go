@@ -0,0 +1,91 @@
+package migrator
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/DevVictor19/pic-pay-challenge/cmd/migrate/migrations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func file(body string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(body)}
+}
+
+func TestLoad(t *testing.T) {
+	t.Run("should pair files and sort by version", func(t *testing.T) {
+		migs, err := Load(fstest.MapFS{
+			"000010_add_index.up.sql":     file("CREATE INDEX"),
+			"000010_add_index.down.sql":   file("DROP INDEX"),
+			"000002_create_user.up.sql":   file("CREATE TABLE"),
+			"000002_create_user.down.sql": file(""),
+			"README.md":                   file("ignored"),
+		})
+
+		require.NoError(t, err)
+		require.Len(t, migs, 2)
+		assert.Equal(t, Migration{Version: 2, Name: "create_user", Up: "CREATE TABLE"}, migs[0])
+		assert.Equal(t, uint(10), migs[1].Version)
+		assert.Equal(t, "DROP INDEX", migs[1].Down)
+	})
+
+	t.Run("should reject a migration without its down file", func(t *testing.T) {
+		_, err := Load(fstest.MapFS{"000001_create_user.up.sql": file("CREATE TABLE")})
+		assert.Error(t, err)
+	})
+
+	t.Run("should reject two migrations sharing a version", func(t *testing.T) {
+		_, err := Load(fstest.MapFS{
+			"000001_a.up.sql":   file(""),
+			"000001_a.down.sql": file(""),
+			"000001_b.up.sql":   file(""),
+			"000001_b.down.sql": file(""),
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("should load the embedded migrations", func(t *testing.T) {
+		migs, err := Load(migrations.FS)
+
+		require.NoError(t, err)
+		require.NotEmpty(t, migs)
+		for i, mig := range migs {
+			assert.Equal(t, uint(i+1), mig.Version, "migrations must be numbered without gaps")
+		}
+	})
+}
+
+func TestMigrator_Plan(t *testing.T) {
+	m := &Migrator{migrations: []Migration{{Version: 1}, {Version: 2}, {Version: 5}}}
+
+	t.Run("should list migrations after the current version as pending", func(t *testing.T) {
+		assert.Len(t, m.pending(0), 3)
+		assert.Equal(t, uint(5), m.pending(2)[0].Version)
+		assert.Empty(t, m.pending(5))
+	})
+
+	t.Run("should roll back newest first down to the previous version", func(t *testing.T) {
+		steps, err := m.rollback(5, 2)
+
+		require.NoError(t, err)
+		require.Len(t, steps, 2)
+		assert.Equal(t, uint(5), steps[0].migration.Version)
+		assert.Equal(t, uint(2), steps[0].previous)
+		assert.Equal(t, uint(2), steps[1].migration.Version)
+		assert.Equal(t, uint(1), steps[1].previous)
+	})
+
+	t.Run("should stop at the first migration", func(t *testing.T) {
+		steps, err := m.rollback(2, 10)
+
+		require.NoError(t, err)
+		require.Len(t, steps, 2)
+		assert.Equal(t, uint(0), steps[1].previous)
+	})
+
+	t.Run("should refuse to roll back an unknown version", func(t *testing.T) {
+		_, err := m.rollback(4, 1)
+		assert.Error(t, err)
+	})
+}
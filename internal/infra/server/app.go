@@ -0,0 +1,239 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/auth"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/transaction"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/db"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/env"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/tracing"
+)
+
+type Options struct {
+	// MigrateOnStart applies pending migrations before serving.
+	MigrateOnStart bool
+}
+
+// worker runs until ctx is cancelled.
+type worker func(ctx context.Context)
+
+// tasks runs work detached from the request that started it, such as sending
+// a password reset email, so Stop can wait for it.
+type tasks struct {
+	running sync.WaitGroup
+}
+
+func (t *tasks) Go(f func()) {
+	t.running.Add(1)
+	go func() {
+		defer t.running.Done()
+		f()
+	}()
+}
+
+// App owns everything the server is made of: the database handle, the
+// services behind the router and the background workers.
+type App struct {
+	cfg      *env.Config
+	database *sql.DB
+	services Services
+	handler  http.Handler
+	workers  []worker
+	tasks    *tasks
+	// stopTracing flushes the spans not exported yet
+	stopTracing func(context.Context) error
+
+	server      *http.Server
+	serveErr    chan error
+	stopWorkers context.CancelFunc
+	running     sync.WaitGroup
+}
+
+// New connects to the database and builds the App from cfg. Nothing is
+// served and no worker runs until Start.
+func New(cfg *env.Config, opts Options) (*App, error) {
+	stopTracing, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:     cfg.Tracing.Exporter,
+		ServiceName:  cfg.Tracing.ServiceName,
+		OTLPEndpoint: cfg.Tracing.OTLPEndpoint,
+		SampleRatio:  cfg.Tracing.SampleRatio,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	database, err := db.Connect(
+		cfg.DB.URL,
+		cfg.DB.MaxOpenConns,
+		cfg.DB.MaxIdleConns,
+		cfg.DB.MaxIdleTime,
+	)
+	if err != nil {
+		stopTracing(context.Background())
+		return nil, err
+	}
+
+	app, err := newApp(cfg, database, opts)
+	if err != nil {
+		database.Close()
+		stopTracing(context.Background())
+		return nil, err
+	}
+	app.stopTracing = stopTracing
+
+	return app, nil
+}
+
+func newApp(cfg *env.Config, database *sql.DB, opts Options) (*App, error) {
+	if opts.MigrateOnStart {
+		if err := migrate(database); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.Admin.Email != "" {
+		if err := bootstrapAdmin(database, cfg.Admin, cfg.PasswordHash); err != nil {
+			return nil, err
+		}
+	}
+
+	keyManager, err := auth.NewKeyManager(newKeySource(cfg.JWT), cfg.JWT.KeyOverlap)
+	if err != nil {
+		return nil, err
+	}
+
+	riskEvaluator, err := newRiskEvaluator(cfg.Risk, transaction.NewTransactionRepository(database, db.QueryDuration))
+	if err != nil {
+		return nil, err
+	}
+
+	background := &tasks{}
+	services := newServices(cfg, database, keyManager, riskEvaluator, background)
+	registerDBStats(database)
+
+	var workers []worker
+	if cfg.JWT.RotationInterval > 0 {
+		workers = append(workers, func(ctx context.Context) {
+			keyManager.Run(ctx, cfg.JWT.RotationInterval)
+		})
+	}
+
+	deliveryWorker := newDeliveryWorker(database, cfg.Webhook)
+	workers = append(workers, func(ctx context.Context) {
+		deliveryWorker.Run(ctx, cfg.Webhook.PollInterval)
+	})
+
+	return &App{
+		cfg:      cfg,
+		database: database,
+		services: services,
+		handler:  NewRouter(cfg, services),
+		workers:  workers,
+		tasks:    background,
+	}, nil
+}
+
+func (a *App) Handler() http.Handler {
+	return a.handler
+}
+
+// Start runs the workers and serves on the configured port. It returns once
+// the listener is bound; a server that fails later reports it through Err.
+func (a *App) Start() error {
+	ln, err := net.Listen("tcp", fmt.Sprintf(":%s", a.cfg.ServerPort))
+	if err != nil {
+		return err
+	}
+
+	a.serve(ln)
+
+	return nil
+}
+
+func (a *App) serve(ln net.Listener) {
+	// workers get their own context: they keep running while requests drain
+	// and are stopped once no request can enqueue more work
+	ctx, cancel := context.WithCancel(context.Background())
+	a.stopWorkers = cancel
+
+	for _, w := range a.workers {
+		a.running.Add(1)
+		go func() {
+			defer a.running.Done()
+			w(ctx)
+		}()
+	}
+
+	a.server = &http.Server{
+		Handler:      a.handler,
+		WriteTimeout: time.Second * 30,
+		ReadTimeout:  time.Second * 10,
+		IdleTimeout:  time.Minute,
+	}
+
+	a.serveErr = make(chan error, 1)
+	go func() {
+		a.serveErr <- a.server.Serve(ln)
+	}()
+}
+
+// Err receives the error of a server that stopped serving before Stop.
+func (a *App) Err() <-chan error {
+	return a.serveErr
+}
+
+// Stop stops accepting connections and waits up to the shutdown grace period
+// for in-flight requests, so a deploy does not cut a transfer in half.
+// Requests still running after it are aborted. The work they left running in
+// the background is waited for, then the workers are stopped and the database
+// closed.
+func (a *App) Stop() error {
+	var err error
+
+	if a.server != nil {
+		grace := a.cfg.ShutdownGracePeriod
+		slog.Info("shutting down, draining in-flight requests", "grace_period", grace)
+
+		ctx, cancel := context.WithTimeout(context.Background(), grace)
+		defer cancel()
+
+		if shutdownErr := a.server.Shutdown(ctx); shutdownErr != nil {
+			a.server.Close()
+			err = fmt.Errorf("requests still running after the grace period were aborted: %w", shutdownErr)
+		} else {
+			slog.Info("all requests drained")
+		}
+	}
+
+	if a.tasks != nil {
+		a.tasks.running.Wait()
+		slog.Info("background tasks finished")
+	}
+
+	if a.stopWorkers != nil {
+		a.stopWorkers()
+		a.running.Wait()
+		slog.Info("background workers stopped")
+	}
+
+	if a.database != nil {
+		err = errors.Join(err, a.database.Close())
+	}
+
+	if a.stopTracing != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		err = errors.Join(err, a.stopTracing(ctx))
+	}
+
+	return err
+}
@@ -1,104 +1,368 @@
 package server
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
 	"time"
 
+	"github.com/DevVictor19/pic-pay-challenge/cmd/migrate/migrations"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/admin"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/apikey"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/audit"
 	"github.com/DevVictor19/pic-pay-challenge/internal/domain/auth"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/kyc"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/mfa"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/paymentrequest"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/privacy"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/transaction"
 	"github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
 	"github.com/DevVictor19/pic-pay-challenge/internal/domain/wallet"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/webhook"
 	"github.com/DevVictor19/pic-pay-challenge/internal/infra/db"
 	"github.com/DevVictor19/pic-pay-challenge/internal/infra/env"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/mailer"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/migrator"
 	"github.com/DevVictor19/pic-pay-challenge/internal/infra/utils"
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 )
 
-func Start() error {
-	cfg, err := env.LoadEnv()
+// Run builds the App from cfg and serves until the process gets SIGINT or
+// SIGTERM.
+func Run(cfg *env.Config, opts Options) error {
+	app, err := New(cfg, opts)
 	if err != nil {
 		return err
 	}
 
-	database, err := db.Connect(
-		cfg.DB.URL,
-		cfg.DB.MaxOpenConns,
-		cfg.DB.MaxIdleConns,
-		cfg.DB.MaxIdleTime,
+	if err := app.Start(); err != nil {
+		return errors.Join(err, app.Stop())
+	}
+
+	ctx, stop := shutdownContext()
+	defer stop()
+
+	return runUntil(ctx, app)
+}
+
+func newServices(
+	cfg *env.Config,
+	database *sql.DB,
+	keys auth.KeyProvider,
+	riskEvaluator transaction.RiskEvaluator,
+	background auth.Runner) Services {
+
+	transactor := db.NewTransactor(database)
+	kycStorage := kyc.NewDiskStorage(cfg.KYC.StorageDir)
+
+	userRepo := user.NewUserRepository(database, db.QueryDuration)
+	userService := user.NewUserService(userRepo)
+
+	walletRepo := wallet.NewWalletRepository(database, db.QueryDuration)
+	walletService := wallet.NewWalletService(walletRepo)
+
+	auditRepo := audit.NewAuditRepository(database, db.QueryDuration)
+	auditService := audit.NewAuditService(auditRepo)
+	fingerprinter := audit.NewFingerprinter(cfg.Audit.PIIKey)
+
+	mfaRepo := mfa.NewMFARepository(database, db.QueryDuration)
+	mfaService := mfa.NewMFAService(
+		mfaRepo,
+		cfg.MFA.EncryptionKey,
+		cfg.MFA.Issuer,
+		cfg.MFA.ChallengeTTL,
+		cfg.MFA.MaxFailures,
+		cfg.MFA.LockDuration,
 	)
+
+	jwtService := auth.NewJWTService(keys, cfg.JWT.Aud, cfg.JWT.Iss)
+	passwordHasher := auth.NewPasswordHasher(newHashParams(cfg.PasswordHash), cfg.PasswordHash.MaxConcurrent)
+	passwordPolicy := newPasswordPolicy(cfg.Password)
+	refreshTokenRepo := auth.NewRefreshTokenRepository(database, db.QueryDuration)
+	refreshTokenService := auth.NewRefreshTokenService(refreshTokenRepo, cfg.JWT.RefreshTTL)
+	revocationRepo := auth.NewRevocationRepository(database, db.QueryDuration)
+	revocationStore := auth.NewRevocationStore(revocationRepo, auth.RevocationCacheTTL)
+	passwordResetRepo := auth.NewPasswordResetRepository(database, db.QueryDuration)
+	requestThrottleRepo := auth.NewRequestThrottleRepository(database, db.QueryDuration)
+	passwordResetService := auth.NewPasswordResetService(
+		passwordResetRepo,
+		requestThrottleRepo,
+		cfg.Mail.PasswordResetTTL,
+		auth.ResendThrottle{
+			Cooldown: cfg.Mail.ResetCooldown,
+			Window:   time.Hour,
+			Max:      cfg.Mail.ResetPerHour,
+		},
+		auth.ResendThrottle{
+			Window: time.Hour,
+			Max:    cfg.Mail.ResetIPPerHour,
+		},
+	)
+	loginThrottleRepo := auth.NewLoginThrottleRepository(database, db.QueryDuration)
+	loginGuard := auth.NewLoginGuard(
+		loginThrottleRepo,
+		auth.ThrottlePolicy{
+			FreeAttempts: cfg.Login.EmailFreeAttempts,
+			BaseDelay:    cfg.Login.BaseDelay,
+			MaxDelay:     cfg.Login.MaxDelay,
+			LockAfter:    cfg.Login.EmailLockAfter,
+			LockFor:      cfg.Login.LockDuration,
+			Window:       cfg.Login.FailureWindow,
+		},
+		auth.ThrottlePolicy{
+			FreeAttempts: cfg.Login.IPFreeAttempts,
+			BaseDelay:    cfg.Login.BaseDelay,
+			MaxDelay:     cfg.Login.MaxDelay,
+			LockAfter:    cfg.Login.IPLockAfter,
+			LockFor:      cfg.Login.LockDuration,
+			Window:       cfg.Login.FailureWindow,
+		},
+	)
+	emailVerificationRepo := auth.NewEmailVerificationRepository(database, db.QueryDuration)
+	emailVerificationService := auth.NewEmailVerificationService(
+		emailVerificationRepo,
+		requestThrottleRepo,
+		cfg.Mail.VerifyEmailTTL,
+		auth.ResendThrottle{
+			Cooldown: cfg.Mail.ResendCooldown,
+			Window:   time.Hour,
+			Max:      cfg.Mail.ResendPerHour,
+		},
+	)
+	webhookRepo := webhook.NewWebhookRepository(database, db.QueryDuration)
+	webhookService := webhook.NewWebhookService(webhookRepo, cfg.Webhook.SigningKey, webhook.TargetPolicy{
+		RequireHTTPS: cfg.IsProduction(),
+		AllowPrivate: cfg.Webhook.AllowPrivateTargets,
+	})
+
+	transactionRepo := transaction.NewTransactionRepository(database, db.QueryDuration)
+	transactionService := transaction.NewTransactionService(
+		transactionRepo,
+		transactor,
+		mfaService,
+		webhookService,
+		auditService,
+		riskEvaluator,
+		cfg.Transfer.StepUpThreshold,
+		newTransferLimits(cfg.Transfer),
+	)
+
+	apiKeyRepo := apikey.NewAPIKeyRepository(database, db.QueryDuration)
+	nonceRepo := apikey.NewNonceRepository(database, db.QueryDuration)
+	apiKeyService := apikey.NewAPIKeyService(apiKeyRepo, nonceRepo, auditService, cfg.APIKey.SigningKey)
+
+	kycRepo := kyc.NewKYCRepository(database, db.QueryDuration)
+	kycService := kyc.NewKYCService(
+		kycRepo,
+		kycStorage,
+		userService,
+		auditService,
+		cfg.KYC.MaxUploadSize,
+	)
+
+	authService := auth.NewAuthService(
+		auth.Deps{
+			UserService:    userService,
+			WalletService:  walletService,
+			KYCService:     kycService,
+			APIKeyService:  apiKeyService,
+			WebhookService: webhookService,
+			Transactor:     transactor,
+			PasswordHasher: passwordHasher,
+			PasswordPolicy: passwordPolicy,
+			JWTService:     jwtService,
+			AuditService:   auditService,
+			Fingerprinter:  fingerprinter,
+			RefreshService: refreshTokenService,
+			Revocations:    revocationStore,
+			MFAService:     mfaService,
+			LoginGuard:     loginGuard,
+			ResetService:   passwordResetService,
+			VerifyService:  emailVerificationService,
+			Mailer:         newMailer(cfg.Mail),
+			Background:     background,
+		},
+		auth.Config{
+			PasswordResetURL: cfg.Mail.PasswordResetURL,
+			VerifyEmailURL:   cfg.Mail.VerifyEmailURL,
+			UnlockURL:        cfg.Mail.UnlockURL,
+			AccessTTL:        cfg.JWT.AccessTTL,
+		},
+	)
+
+	privacyService := privacy.NewPrivacyService(
+		walletService,
+		transactionService,
+		mfaService,
+		apiKeyService,
+		webhookService,
+		kycService,
+		auditService,
+	)
+
+	paymentRequestRepo := paymentrequest.NewPaymentRequestRepository(database, db.QueryDuration)
+	paymentRequestService := paymentrequest.NewPaymentRequestService(paymentRequestRepo)
+
+	adminService := admin.NewAdminService(userService, walletService, transactionService, auditService, fingerprinter, transactor)
+
+	return Services{
+		Users:           userService,
+		Auth:            authService,
+		JWT:             jwtService,
+		Revocations:     revocationStore,
+		MFA:             mfaService,
+		APIKeys:         apiKeyService,
+		Transactions:    transactionService,
+		PaymentRequests: paymentRequestService,
+		Webhooks:        webhookService,
+		KYC:             kycService,
+		Privacy:         privacyService,
+		Admin:           adminService,
+	}
+}
+
+// migrate applies the embedded migrations. Replicas starting together wait
+// on each other through the migrator's advisory lock.
+func migrate(database *sql.DB) error {
+	m, err := migrator.New(database, migrations.FS)
 	if err != nil {
 		return err
 	}
-	defer database.Close()
 
-	srv := &http.Server{
-		Addr:         fmt.Sprintf(":%s", cfg.ServerPort),
-		Handler:      mount(),
-		WriteTimeout: time.Second * 30,
-		ReadTimeout:  time.Second * 10,
-		IdleTimeout:  time.Minute,
+	n, err := m.Up(context.Background())
+	if err != nil {
+		return fmt.Errorf("migrating on start: %w", err)
 	}
 
-	if err := srv.ListenAndServe(); !errors.Is(err, http.ErrServerClosed) {
-		return err
-	}
+	slog.Info("migrations applied", "count", n)
 
 	return nil
 }
 
-func mount() http.Handler {
-	cfg, err := env.GetEnv()
-	if err != nil {
-		panic(err)
+func bootstrapAdmin(database *sql.DB, adminCfg env.AdminConfig, hashCfg env.PasswordHashConfig) error {
+	userRepo := user.NewUserRepository(database, db.QueryDuration)
+	userService := user.NewUserService(userRepo)
+
+	passwordHasher := auth.NewPasswordHasher(newHashParams(hashCfg), 0)
+	bootstrapper := auth.NewAdminBootstrapper(userService, passwordHasher)
+
+	dto := user.AdminUserDTO{
+		Fullname: adminCfg.Fullname,
+		Email:    adminCfg.Email,
+		Password: adminCfg.Password,
+	}
+	if err := utils.Validate.Struct(dto); err != nil {
+		return fmt.Errorf("invalid bootstrap admin config: %w", err)
 	}
 
-	database, err := db.Get()
-	if err != nil {
-		panic(err)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+
+	return bootstrapper.BootstrapAdmin(ctx, dto)
+}
+
+func newKeySource(cfg env.JWTConfig) auth.KeySource {
+	switch {
+	case cfg.Alg == auth.AlgHS256:
+		return auth.NewSecretKeySource(cfg.Secret)
+	case cfg.KeysDir != "":
+		return auth.NewDirKeySource(cfg.KeysDir)
+	case len(cfg.KeyFiles) > 0:
+		return auth.NewFileKeySource(cfg.KeyFiles)
+	default:
+		return auth.NewGeneratedKeySource(cfg.Alg)
 	}
+}
 
-	userRepo := user.NewUserRepository(database, db.QueryDuration)
-	userService := user.NewUserService(userRepo)
+func newHashParams(cfg env.PasswordHashConfig) auth.HashParams {
+	return auth.HashParams{
+		Algorithm:     auth.HashAlgorithm(cfg.Algorithm),
+		BcryptCost:    cfg.BcryptCost,
+		Argon2Time:    uint32(cfg.Argon2Time),
+		Argon2Memory:  uint32(cfg.Argon2Memory),
+		Argon2Threads: uint8(cfg.Argon2Threads),
+	}
+}
 
-	walletRepo := wallet.NewWalletRepository(database, db.QueryDuration)
-	walletService := wallet.NewWalletService(walletRepo)
+func newTransferLimits(cfg env.TransferConfig) map[user.KYCLevel]transaction.TransferLimit {
+	return map[user.KYCLevel]transaction.TransferLimit{
+		user.KYCNone:  {PerTransaction: cfg.NoneMaxPerTx, Daily: cfg.NoneDailyLimit},
+		user.KYCBasic: {PerTransaction: cfg.BasicMaxPerTx, Daily: cfg.BasicDailyLimit},
+		user.KYCFull:  {PerTransaction: cfg.FullMaxPerTx, Daily: cfg.FullDailyLimit},
+	}
+}
 
-	jwtService := auth.NewJWTService(cfg.JWT.Secret, cfg.JWT.Aud, cfg.JWT.Iss)
-	bcryptService := auth.NewBcryptService()
-	authService := auth.NewAuthService(userService, walletService, bcryptService, jwtService)
-	authHandler := auth.NewAuthHandler(authService)
-
-	r := chi.NewRouter()
-
-	r.Use(middleware.RequestID)
-	r.Use(middleware.RealIP)
-	r.Use(middleware.Logger)
-	r.Use(middleware.Recoverer)
-	r.Use(middleware.Timeout(60 * time.Second))
-
-	r.Route("/v1", func(r chi.Router) {
-		r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
-			utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "ok"})
-		})
-
-		r.Route("/auth", func(r chi.Router) {
-			r.Post("/signup", utils.MakeHandler(authHandler.Signup))
-			r.Post("/login", utils.MakeHandler(authHandler.Login))
-		})
-
-		// protected routes
-		r.Group(func(r chi.Router) {
-			r.Use(MakeJWTAuthMiddleware(jwtService, userService))
-
-			r.Route("/transactions", func(r chi.Router) {
-				r.Post("/", func(w http.ResponseWriter, r *http.Request) {
-					utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "ok"})
-				})
-			})
-		})
-	})
+// newRiskEvaluator returns nil, which lets every transfer through, when no
+// rules file is configured.
+func newRiskEvaluator(cfg env.RiskConfig, history transaction.RiskHistory) (transaction.RiskEvaluator, error) {
+	if cfg.RulesFile == "" {
+		return nil, nil
+	}
+
+	return transaction.NewRulesEvaluator(history, cfg.RulesFile)
+}
+
+func newPasswordPolicy(cfg env.PasswordPolicyConfig) auth.PasswordPolicy {
+	rules := auth.PasswordRules{
+		MinLength:          cfg.MinLength,
+		MaxLength:          cfg.MaxLength,
+		RequireUpper:       cfg.RequireUpper,
+		RequireLower:       cfg.RequireLower,
+		RequireDigit:       cfg.RequireDigit,
+		RequireSymbol:      cfg.RequireSymbol,
+		ForbidPersonalInfo: cfg.ForbidPersonalInfo,
+	}
+
+	var breach auth.BreachChecker
+	if cfg.BreachedDir != "" {
+		breach = auth.NewFileBreachChecker(cfg.BreachedDir)
+	}
+
+	return auth.NewPasswordPolicy(rules, breach)
+}
 
-	return r
+func newMailer(cfg env.MailConfig) mailer.Mailer {
+	if cfg.Driver == "smtp" {
+		return mailer.NewInstrumentedMailer(
+			mailer.NewSMTPMailer(cfg.SMTPAddr, cfg.From, cfg.SMTPUsername, cfg.SMTPPassword),
+			cfg.Driver,
+		)
+	}
+	return mailer.NewInstrumentedMailer(mailer.NewFileMailer(cfg.Dir, cfg.From), cfg.Driver)
+}
+
+func newDeliveryWorker(database *sql.DB, cfg env.WebhookConfig) *webhook.DeliveryWorker {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	// a proxy would make the connection to itself instead of the target
+	transport.Proxy = nil
+	if !cfg.AllowPrivateTargets {
+		dialer := &net.Dialer{Timeout: cfg.Timeout, Control: webhook.DialControl}
+		transport.DialContext = dialer.DialContext
+	}
+
+	client := &http.Client{
+		Timeout: cfg.Timeout,
+		// sends the traceparent header so receivers can join the trace
+		Transport: otelhttp.NewTransport(transport),
+		// a redirect could point the request at an internal address
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	return webhook.NewDeliveryWorker(
+		webhook.NewWebhookRepository(database, db.QueryDuration),
+		client,
+		cfg.SigningKey,
+		webhook.RetryPolicy{
+			MaxAttempts:  cfg.MaxAttempts,
+			BaseDelay:    cfg.BaseDelay,
+			MaxDelay:     cfg.MaxDelay,
+			DisableAfter: cfg.DisableAfter,
+		},
+	)
 }
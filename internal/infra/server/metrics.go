@@ -0,0 +1,104 @@
+package server
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/metrics"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+var (
+	httpRequests = metrics.NewCounter(
+		"picpay_http_requests_total",
+		"HTTP requests by method, route pattern and status code.",
+		"method", "route", "status",
+	)
+	httpDuration = metrics.NewHistogram(
+		"picpay_http_request_duration_seconds",
+		"HTTP request latency by method and route pattern.",
+		metrics.DefaultBuckets,
+		"method", "route",
+	)
+)
+
+// HTTPMetrics records requests under their chi route pattern rather than
+// their path, so IDs in paths do not turn into one series each.
+func HTTPMetrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+
+		next.ServeHTTP(ww, r)
+
+		route := routePattern(r)
+		status := responseStatus(ww)
+
+		httpRequests.Inc(r.Method, route, strconv.Itoa(status))
+		httpDuration.Observe(time.Since(start).Seconds(), r.Method, route)
+	})
+}
+
+// routePattern must be called once the request went through the router.
+func routePattern(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.RoutePattern() != "" {
+		return rctx.RoutePattern()
+	}
+	return "unmatched"
+}
+
+func responseStatus(ww middleware.WrapResponseWriter) int {
+	if ww.Status() == 0 {
+		return http.StatusOK
+	}
+	return ww.Status()
+}
+
+// MakeMetricsHandler serves the metrics in the Prometheus text format. With
+// a token, scrapers must send it as a Bearer credential.
+func MakeMetricsHandler(token string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token != "" {
+			got := []byte(r.Header.Get("Authorization"))
+			if subtle.ConstantTimeCompare(got, []byte("Bearer "+token)) != 1 {
+				code := http.StatusUnauthorized
+				utils.WriteJSON(w, code, apperror.NewHttpError(code, "invalid metrics token"))
+				return
+			}
+		}
+
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		metrics.Default.Write(w)
+	}
+}
+
+// registerDBStats exposes the connection pool of database, replacing the
+// pool registered before.
+func registerDBStats(database *sql.DB) {
+	stat := func(read func(sql.DBStats) float64) func() float64 {
+		return func() float64 {
+			return read(database.Stats())
+		}
+	}
+
+	metrics.Default.SetGaugeFunc("picpay_db_max_open_connections", "Maximum number of open connections to the database.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxOpenConnections) }))
+	metrics.Default.SetGaugeFunc("picpay_db_open_connections", "Established connections, in use and idle.",
+		stat(func(s sql.DBStats) float64 { return float64(s.OpenConnections) }))
+	metrics.Default.SetGaugeFunc("picpay_db_in_use_connections", "Connections currently in use.",
+		stat(func(s sql.DBStats) float64 { return float64(s.InUse) }))
+	metrics.Default.SetGaugeFunc("picpay_db_idle_connections", "Idle connections.",
+		stat(func(s sql.DBStats) float64 { return float64(s.Idle) }))
+	metrics.Default.SetCounterFunc("picpay_db_wait_count_total", "Connections waited for because the pool was exhausted.",
+		stat(func(s sql.DBStats) float64 { return float64(s.WaitCount) }))
+	metrics.Default.SetCounterFunc("picpay_db_wait_duration_seconds_total", "Time spent waiting for a connection.",
+		stat(func(s sql.DBStats) float64 { return s.WaitDuration.Seconds() }))
+	metrics.Default.SetCounterFunc("picpay_db_max_idle_time_closed_total", "Connections closed for staying idle too long.",
+		stat(func(s sql.DBStats) float64 { return float64(s.MaxIdleTimeClosed) }))
+}
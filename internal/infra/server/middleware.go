@@ -1,22 +1,99 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/netip"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/apikey"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/audit"
 	"github.com/DevVictor19/pic-pay-challenge/internal/domain/auth"
 	"github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
 	"github.com/DevVictor19/pic-pay-challenge/internal/infra/apperror"
 	"github.com/DevVictor19/pic-pay-challenge/internal/infra/utils"
+	"github.com/DevVictor19/pic-pay-challenge/pkg/signature"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/golang-jwt/jwt/v5"
 )
 
 type Middleware func(http.Handler) http.Handler
 
-func MakeJWTAuthMiddleware(jwtService auth.JWTService, userService user.UserService) Middleware {
+// ClientIP replaces r.RemoteAddr with the client address from X-Forwarded-For
+// or X-Real-IP, but only when the connection comes from a trusted proxy.
+// Anyone else could set those headers to dodge the per-IP login throttle.
+func ClientIP(trusted []netip.Prefix) Middleware {
+	isTrusted := func(addr netip.Addr) bool {
+		return slices.ContainsFunc(trusted, func(p netip.Prefix) bool { return p.Contains(addr.Unmap()) })
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			peer, err := netip.ParseAddrPort(r.RemoteAddr)
+			if err != nil || !isTrusted(peer.Addr()) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Walk the chain from the nearest hop and stop at the first
+			// address that is not one of our proxies.
+			var client netip.Addr
+			hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+			for i := len(hops) - 1; i >= 0; i-- {
+				addr, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+				if err != nil {
+					break
+				}
+				client = addr
+				if !isTrusted(addr) {
+					break
+				}
+			}
+			if !client.IsValid() {
+				client, _ = netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP")))
+			}
+
+			if client.IsValid() {
+				r.RemoteAddr = client.Unmap().String()
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// AuditMetadata exposes the client IP and request ID to the audit log. It must
+// run after middleware.RequestID and ClientIP.
+func AuditMetadata(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip := r.RemoteAddr
+		if host, _, err := net.SplitHostPort(ip); err == nil {
+			ip = host
+		}
+
+		ctx := audit.WithMetadata(r.Context(), audit.Metadata{
+			IP:        ip,
+			RequestID: middleware.GetReqID(r.Context()),
+		})
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// MakeJWTAuthMiddleware authenticates requests with either a Bearer JWT or,
+// for shopkeeper integrations, an "ApiKey" credential.
+func MakeJWTAuthMiddleware(
+	jwtService auth.JWTService,
+	userService user.UserService,
+	revocations auth.RevocationStore,
+	apiKeys apikey.APIKeyService) Middleware {
+
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			authHeader := r.Header.Get("Authorization")
@@ -30,7 +107,7 @@ func MakeJWTAuthMiddleware(jwtService auth.JWTService, userService user.UserServ
 			}
 
 			parts := strings.Split(authHeader, " ")
-			if len(parts) != 2 || parts[0] != "Bearer" {
+			if len(parts) != 2 || (parts[0] != "Bearer" && parts[0] != "ApiKey") {
 				code := http.StatusUnauthorized
 				utils.WriteJSON(w, code, apperror.NewHttpError(
 					code,
@@ -39,8 +116,13 @@ func MakeJWTAuthMiddleware(jwtService auth.JWTService, userService user.UserServ
 				return
 			}
 
-			token := parts[1]
-			jwtToken, err := jwtService.ValidateToken(token)
+			var ctx context.Context
+			var err error
+			if parts[0] == "ApiKey" {
+				ctx, err = authenticateAPIKey(r.Context(), apiKeys, userService, parts[1])
+			} else {
+				ctx, err = authenticateBearer(r.Context(), jwtService, userService, revocations, parts[1])
+			}
 			if err != nil {
 				code := http.StatusUnauthorized
 				utils.WriteJSON(w, code, apperror.NewHttpError(
@@ -50,22 +132,221 @@ func MakeJWTAuthMiddleware(jwtService auth.JWTService, userService user.UserServ
 				return
 			}
 
-			claims, _ := jwtToken.Claims.(jwt.MapClaims)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+func authenticateBearer(
+	ctx context.Context,
+	jwtService auth.JWTService,
+	userService user.UserService,
+	revocations auth.RevocationStore,
+	token string) (context.Context, error) {
 
-			userId, err := strconv.ParseInt(fmt.Sprintf("%.f", claims["sub"]), 10, 32)
-			if err != nil {
+	jwtToken, err := jwtService.ValidateToken(token)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, _ := jwtToken.Claims.(jwt.MapClaims)
+
+	userId, err := strconv.ParseInt(fmt.Sprintf("%.f", claims["sub"]), 10, 32)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkRevocation(ctx, revocations, claims, int(userId)); err != nil {
+		return nil, err
+	}
+
+	user, err := userService.FindByID(ctx, int(userId))
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = context.WithValue(ctx, utils.UserKey, user)
+	ctx = context.WithValue(ctx, utils.ClaimsKey, claims)
+	return ctx, nil
+}
+
+func authenticateAPIKey(
+	ctx context.Context,
+	apiKeys apikey.APIKeyService,
+	userService user.UserService,
+	key string) (context.Context, error) {
+
+	k, err := apiKeys.Authenticate(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	usr, err := userService.FindByID(ctx, k.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if usr.Role != user.Shopkeeper {
+		return nil, apikey.ErrInvalidAPIKey
+	}
+
+	ctx = context.WithValue(ctx, utils.UserKey, usr)
+	ctx = context.WithValue(ctx, utils.APIKeyKey, k)
+	return ctx, nil
+}
+
+func checkRevocation(ctx context.Context, revocations auth.RevocationStore, claims jwt.MapClaims, userId int) error {
+	jti, ok := claims["jti"].(string)
+	if !ok || jti == "" {
+		return errors.New("token is missing jti")
+	}
+
+	revoked, err := revocations.IsRevoked(ctx, jti)
+	if err != nil {
+		return err
+	}
+	if revoked {
+		return errors.New("token has been revoked")
+	}
+
+	issuedAt, err := issuedAtMillis(claims)
+	if err != nil {
+		return err
+	}
+
+	validAfter, err := revocations.ValidAfter(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if issuedAt <= validAfter.UnixMilli() {
+		return errors.New("token has been revoked")
+	}
+
+	return nil
+}
+
+// issuedAtMillis prefers the millisecond claim. Tokens issued without it only
+// have iat, so anything from the same second as a revocation is rejected.
+func issuedAtMillis(claims jwt.MapClaims) (int64, error) {
+	if ms, ok := claims[auth.IssuedAtMsClaim].(float64); ok {
+		return int64(ms), nil
+	}
+
+	iat, err := claims.GetIssuedAt()
+	if err != nil || iat == nil {
+		return 0, errors.New("token is missing iat")
+	}
+	return iat.UnixMilli(), nil
+}
+
+func MakeRoleMiddleware(roles ...user.UserRole) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			usr, ok := r.Context().Value(utils.UserKey).(*user.User)
+			if !ok {
 				code := http.StatusUnauthorized
 				utils.WriteJSON(w, code, apperror.NewHttpError(
 					code,
-					err.Error(),
+					"user not authenticated",
 				))
 				return
 			}
 
-			ctx := r.Context()
+			if !slices.Contains(roles, usr.Role) {
+				code := http.StatusForbidden
+				utils.WriteJSON(w, code, apperror.NewHttpError(
+					code,
+					"insufficient permissions",
+				))
+				return
+			}
 
-			user, err := userService.FindByID(ctx, int(userId))
-			if err != nil {
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireVerifiedEmail blocks users that did not confirm their email yet. It
+// must run after the JWT auth middleware.
+func RequireVerifiedEmail(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		usr, ok := r.Context().Value(utils.UserKey).(*user.User)
+		if !ok {
+			code := http.StatusUnauthorized
+			utils.WriteJSON(w, code, apperror.NewHttpError(
+				code,
+				"user not authenticated",
+			))
+			return
+		}
+
+		if !usr.IsEmailVerified() {
+			code := http.StatusForbidden
+			utils.WriteJSON(w, code, apperror.NewHttpError(
+				code,
+				"email not verified",
+			))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequireSession rejects requests authenticated with an API key, keeping
+// account management and money movement behind an interactive login.
+func RequireSession(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := r.Context().Value(utils.APIKeyKey).(*apikey.APIKey); ok {
+			code := http.StatusForbidden
+			utils.WriteJSON(w, code, apperror.NewHttpError(
+				code,
+				"this endpoint does not accept api keys",
+			))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// MakeScopeMiddleware lets API keys through only if they carry every scope.
+// Requests authenticated with a session are not restricted by scopes.
+func MakeScopeMiddleware(scopes ...apikey.Scope) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if k, ok := r.Context().Value(utils.APIKeyKey).(*apikey.APIKey); ok {
+				for _, scope := range scopes {
+					if !k.HasScope(scope) {
+						code := http.StatusForbidden
+						utils.WriteJSON(w, code, apperror.NewHttpError(
+							code,
+							"api key is missing the "+string(scope)+" scope",
+						))
+						return
+					}
+				}
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// MakeSignatureMiddleware verifies the HMAC signature of requests made with
+// an API key. Keys with SignedRequests must sign every request; other keys
+// are only checked when the request carries a signature. It must run after
+// the auth middleware.
+func MakeSignatureMiddleware(apiKeys apikey.APIKeyService, skew time.Duration) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			k, ok := r.Context().Value(utils.APIKeyKey).(*apikey.APIKey)
+			if !ok || (!k.SignedRequests && r.Header.Get(signature.HeaderSignature) == "") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if err := verifySignature(w, r, apiKeys, k, skew); err != nil {
 				code := http.StatusUnauthorized
 				utils.WriteJSON(w, code, apperror.NewHttpError(
 					code,
@@ -74,8 +355,53 @@ func MakeJWTAuthMiddleware(jwtService auth.JWTService, userService user.UserServ
 				return
 			}
 
-			ctx = context.WithValue(ctx, utils.UserKey, user)
-			next.ServeHTTP(w, r.WithContext(ctx))
+			next.ServeHTTP(w, r)
 		})
 	}
 }
+
+func verifySignature(w http.ResponseWriter, r *http.Request, apiKeys apikey.APIKeyService, k *apikey.APIKey, skew time.Duration) error {
+	sig := r.Header.Get(signature.HeaderSignature)
+	if sig == "" {
+		return errors.New("request signature is required")
+	}
+
+	timestamp := r.Header.Get(signature.HeaderTimestamp)
+	unix, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return errors.New("request timestamp is malformed")
+	}
+
+	signedAt := time.Unix(unix, 0)
+	if d := time.Since(signedAt); d > skew || d < -skew {
+		return errors.New("request timestamp is outside the allowed window")
+	}
+
+	nonce := r.Header.Get(signature.HeaderNonce)
+	if len(nonce) < 16 || len(nonce) > 128 {
+		return errors.New("request nonce is malformed")
+	}
+
+	maxBytes := 1_048_578 // 1mb
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, int64(maxBytes)))
+	if err != nil {
+		return errors.New("error reading request body")
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+
+	message := signature.StringToSign(r.Method, r.URL.RequestURI(), timestamp, nonce, body)
+	if !signature.Equal(signature.Compute(apiKeys.SigningSecret(k), message), sig) {
+		return errors.New("request signature is invalid")
+	}
+
+	// the nonce only has to be remembered while its timestamp is accepted
+	fresh, err := apiKeys.UseNonce(r.Context(), k.ID, nonce, signedAt.Add(skew))
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return errors.New("request nonce was already used")
+	}
+
+	return nil
+}
@@ -0,0 +1,220 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/apikey"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/auth"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/utils"
+	"github.com/DevVictor19/pic-pay-challenge/pkg/signature"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+const signingSecret = "secret"
+
+func serveSigned(t *testing.T, apiKeys apikey.APIKeyService, k *apikey.APIKey, req *http.Request) (*httptest.ResponseRecorder, string) {
+	t.Helper()
+
+	var body string
+	handler := MakeSignatureMiddleware(apiKeys, time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		body = string(b)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	if k != nil {
+		req = req.WithContext(context.WithValue(req.Context(), utils.APIKeyKey, k))
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec, body
+}
+
+func newSignedRequest(t *testing.T, body string, now time.Time) *http.Request {
+	t.Helper()
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/transactions/?limit=10", strings.NewReader(body))
+	require.NoError(t, signature.Sign(req, "pk_key", signingSecret, now))
+	return req
+}
+
+func newAPIKeyServiceMock(k *apikey.APIKey) *apikey.MockAPIKeyService {
+	m := new(apikey.MockAPIKeyService)
+	m.On("SigningSecret", k).Return(signingSecret)
+	return m
+}
+
+func TestSignatureMiddleware(t *testing.T) {
+	t.Run("should accept a request signed by the client package", func(t *testing.T) {
+		k := &apikey.APIKey{ID: 1}
+		apiKeys := newAPIKeyServiceMock(k)
+		apiKeys.On("UseNonce", mock.Anything, 1, mock.Anything, mock.Anything).Return(true, nil)
+
+		rec, body := serveSigned(t, apiKeys, k, newSignedRequest(t, `{"amount":10}`, time.Now()))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `{"amount":10}`, body)
+	})
+
+	t.Run("should reject a tampered body", func(t *testing.T) {
+		k := &apikey.APIKey{ID: 1}
+		apiKeys := newAPIKeyServiceMock(k)
+
+		req := newSignedRequest(t, `{"amount":10}`, time.Now())
+		req.Body = io.NopCloser(strings.NewReader(`{"amount":9999}`))
+
+		rec, _ := serveSigned(t, apiKeys, k, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		apiKeys.AssertNotCalled(t, "UseNonce", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should reject a timestamp outside the allowed skew", func(t *testing.T) {
+		k := &apikey.APIKey{ID: 1}
+		apiKeys := newAPIKeyServiceMock(k)
+
+		rec, _ := serveSigned(t, apiKeys, k, newSignedRequest(t, `{}`, time.Now().Add(-time.Minute*2)))
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Contains(t, rec.Body.String(), "outside the allowed window")
+	})
+
+	t.Run("should reject a replayed nonce", func(t *testing.T) {
+		k := &apikey.APIKey{ID: 1}
+		apiKeys := newAPIKeyServiceMock(k)
+		apiKeys.On("UseNonce", mock.Anything, 1, mock.Anything, mock.Anything).Return(false, nil)
+
+		rec, _ := serveSigned(t, apiKeys, k, newSignedRequest(t, `{}`, time.Now()))
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+		assert.Contains(t, rec.Body.String(), "already used")
+	})
+
+	t.Run("should require a signature for keys with signed requests", func(t *testing.T) {
+		k := &apikey.APIKey{ID: 1, SignedRequests: true}
+		apiKeys := newAPIKeyServiceMock(k)
+
+		rec, _ := serveSigned(t, apiKeys, k, httptest.NewRequest(http.MethodGet, "/v1/transactions/", nil))
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("should let unsigned requests through for other keys and sessions", func(t *testing.T) {
+		apiKeys := new(apikey.MockAPIKeyService)
+
+		rec, _ := serveSigned(t, apiKeys, &apikey.APIKey{ID: 1}, httptest.NewRequest(http.MethodGet, "/v1/transactions/", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		rec, _ = serveSigned(t, apiKeys, nil, httptest.NewRequest(http.MethodGet, "/v1/transactions/", nil))
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
+
+func TestCheckRevocation(t *testing.T) {
+	validAfter := time.Unix(1_700_000_000, 600_000_000)
+
+	newRevocations := func() *auth.MockRevocationStore {
+		m := new(auth.MockRevocationStore)
+		m.On("IsRevoked", mock.Anything, "jti").Return(false, nil)
+		m.On("ValidAfter", mock.Anything, 1).Return(validAfter, nil)
+		return m
+	}
+
+	t.Run("should accept a token issued later in the same second as the revocation", func(t *testing.T) {
+		claims := jwt.MapClaims{
+			"jti":                "jti",
+			"iat":                float64(validAfter.Unix()),
+			auth.IssuedAtMsClaim: float64(validAfter.UnixMilli() + 1),
+		}
+
+		err := checkRevocation(context.Background(), newRevocations(), claims, 1)
+
+		assert.NoError(t, err)
+	})
+
+	t.Run("should reject a token issued earlier in the same second as the revocation", func(t *testing.T) {
+		claims := jwt.MapClaims{
+			"jti":                "jti",
+			"iat":                float64(validAfter.Unix()),
+			auth.IssuedAtMsClaim: float64(validAfter.UnixMilli() - 100),
+		}
+
+		err := checkRevocation(context.Background(), newRevocations(), claims, 1)
+
+		assert.EqualError(t, err, "token has been revoked")
+	})
+
+	t.Run("should reject a token without iat_ms issued in the same second as the revocation", func(t *testing.T) {
+		claims := jwt.MapClaims{"jti": "jti", "iat": float64(validAfter.Unix())}
+
+		err := checkRevocation(context.Background(), newRevocations(), claims, 1)
+
+		assert.EqualError(t, err, "token has been revoked")
+	})
+
+	t.Run("should accept a token without iat_ms issued after the revocation", func(t *testing.T) {
+		claims := jwt.MapClaims{"jti": "jti", "iat": float64(validAfter.Unix() + 1)}
+
+		err := checkRevocation(context.Background(), newRevocations(), claims, 1)
+
+		assert.NoError(t, err)
+	})
+}
+
+func TestClientIP(t *testing.T) {
+	trusted := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}
+
+	serve := func(remoteAddr string, header http.Header) string {
+		var got string
+		handler := ClientIP(trusted)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = r.RemoteAddr
+		}))
+
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		for k, v := range header {
+			req.Header[k] = v
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+		return got
+	}
+
+	t.Run("should ignore forwarding headers from an untrusted peer", func(t *testing.T) {
+		got := serve("203.0.113.7:4000", http.Header{
+			"X-Forwarded-For": {"198.51.100.1"},
+			"X-Real-Ip":       {"198.51.100.2"},
+		})
+
+		assert.Equal(t, "203.0.113.7:4000", got)
+	})
+
+	t.Run("should take the first untrusted hop behind a trusted proxy", func(t *testing.T) {
+		got := serve("10.0.0.1:4000", http.Header{
+			"X-Forwarded-For": {"198.51.100.1, 203.0.113.7", "10.0.0.2"},
+		})
+
+		assert.Equal(t, "203.0.113.7", got)
+	})
+
+	t.Run("should fall back to X-Real-IP behind a trusted proxy", func(t *testing.T) {
+		got := serve("10.0.0.1:4000", http.Header{"X-Real-Ip": {"203.0.113.7"}})
+
+		assert.Equal(t, "203.0.113.7", got)
+	})
+
+	t.Run("should keep the socket address without forwarding headers", func(t *testing.T) {
+		assert.Equal(t, "10.0.0.1:4000", serve("10.0.0.1:4000", nil))
+	})
+}
@@ -0,0 +1,167 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/admin"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/apikey"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/auth"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/kyc"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/mfa"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/paymentrequest"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/privacy"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/transaction"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/webhook"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/env"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/utils"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// Services are what the router depends on. Tests fill in only the ones the
+// routes they exercise reach, usually with the packages' mocks.
+type Services struct {
+	Users           user.UserService
+	Auth            auth.AuthService
+	JWT             auth.JWTService
+	Revocations     auth.RevocationStore
+	MFA             mfa.MFAService
+	APIKeys         apikey.APIKeyService
+	Transactions    transaction.TransactionService
+	PaymentRequests paymentrequest.PaymentRequestService
+	Webhooks        webhook.WebhookService
+	KYC             kyc.KYCService
+	Privacy         privacy.PrivacyService
+	Admin           admin.AdminService
+}
+
+func NewRouter(cfg *env.Config, svc Services) http.Handler {
+	jwksHandler := auth.NewJWKSHandler(svc.JWT)
+	authHandler := auth.NewAuthHandler(svc.Auth)
+	mfaHandler := mfa.NewMFAHandler(svc.MFA)
+	userHandler := user.NewUserHandler()
+	webhookHandler := webhook.NewWebhookHandler(svc.Webhooks)
+	transactionHandler := transaction.NewTransactionHandler(svc.Transactions)
+	paymentRequestHandler := paymentrequest.NewPaymentRequestHandler(svc.PaymentRequests)
+	apiKeyHandler := apikey.NewAPIKeyHandler(svc.APIKeys)
+	kycHandler := kyc.NewKYCHandler(svc.KYC, cfg.KYC.MaxUploadSize)
+	privacyHandler := privacy.NewPrivacyHandler(svc.Privacy)
+	adminHandler := admin.NewAdminHandler(svc.Admin)
+
+	r := chi.NewRouter()
+
+	r.Use(middleware.RequestID)
+	r.Use(ClientIP(cfg.TrustedProxies))
+	r.Use(Tracing)
+	r.Use(HTTPMetrics)
+	r.Use(AuditMetadata)
+	r.Use(middleware.Logger)
+	r.Use(middleware.Recoverer)
+	r.Use(middleware.Timeout(60 * time.Second))
+
+	r.Get("/.well-known/jwks.json", utils.MakeHandler(jwksHandler.Get))
+	r.Get("/metrics", MakeMetricsHandler(cfg.Metrics.Token))
+
+	r.Route("/v1", func(r chi.Router) {
+		r.Get("/health", func(w http.ResponseWriter, r *http.Request) {
+			utils.WriteJSON(w, http.StatusOK, map[string]string{"message": "ok"})
+		})
+
+		r.Route("/auth", func(r chi.Router) {
+			r.Post("/signup", utils.MakeHandler(authHandler.Signup))
+			r.Post("/login", utils.MakeHandler(authHandler.Login))
+			r.Post("/refresh", utils.MakeHandler(authHandler.Refresh))
+			r.Post("/mfa/verify", utils.MakeHandler(authHandler.VerifyMFA))
+			r.Post("/password/forgot", utils.MakeHandler(authHandler.ForgotPassword))
+			r.Post("/password/reset", utils.MakeHandler(authHandler.ResetPassword))
+			r.Get("/verify-email", utils.MakeHandler(authHandler.VerifyEmail))
+			r.Get("/unlock", utils.MakeHandler(authHandler.Unlock))
+		})
+
+		// protected routes
+		r.Group(func(r chi.Router) {
+			r.Use(MakeJWTAuthMiddleware(svc.JWT, svc.Users, svc.Revocations, svc.APIKeys))
+			r.Use(MakeSignatureMiddleware(svc.APIKeys, cfg.APIKey.SignatureSkew))
+
+			r.Route("/transactions", func(r chi.Router) {
+				r.Use(RequireVerifiedEmail)
+
+				r.With(MakeScopeMiddleware(apikey.ScopePaymentsRead)).Get("/", utils.MakeHandler(transactionHandler.List))
+				r.With(RequireSession).Post("/", utils.MakeHandler(transactionHandler.Transfer))
+			})
+
+			r.Route("/payment-requests", func(r chi.Router) {
+				r.Use(RequireVerifiedEmail)
+				r.Use(MakeRoleMiddleware(user.Shopkeeper))
+
+				r.With(MakeScopeMiddleware(apikey.ScopePaymentRequestsWrite)).Post("/", utils.MakeHandler(paymentRequestHandler.Create))
+				r.With(MakeScopeMiddleware(apikey.ScopePaymentsRead)).Get("/", utils.MakeHandler(paymentRequestHandler.List))
+				r.With(MakeScopeMiddleware(apikey.ScopePaymentsRead)).Get("/{requestID}", utils.MakeHandler(paymentRequestHandler.Get))
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(RequireSession)
+
+				r.Post("/auth/logout", utils.MakeHandler(authHandler.Logout))
+				r.Post("/auth/logout-all", utils.MakeHandler(authHandler.LogoutAll))
+				r.Post("/auth/verify-email/resend", utils.MakeHandler(authHandler.ResendVerification))
+				r.Post("/auth/mfa/enroll", utils.MakeHandler(mfaHandler.Enroll))
+				r.Post("/auth/mfa/confirm", utils.MakeHandler(mfaHandler.Confirm))
+
+				r.Route("/users/me", func(r chi.Router) {
+					r.Get("/", utils.MakeHandler(userHandler.Me))
+					r.Patch("/", utils.MakeHandler(authHandler.UpdateProfile))
+					r.Delete("/", utils.MakeHandler(authHandler.CloseAccount))
+					r.Post("/password", utils.MakeHandler(authHandler.ChangePassword))
+					r.Get("/export", utils.MakeHandler(privacyHandler.Export))
+				})
+
+				r.Route("/webhooks", func(r chi.Router) {
+					r.Post("/", utils.MakeHandler(webhookHandler.Create))
+					r.Get("/", utils.MakeHandler(webhookHandler.List))
+					r.Delete("/{webhookID}", utils.MakeHandler(webhookHandler.Delete))
+					r.Post("/{webhookID}/enable", utils.MakeHandler(webhookHandler.Enable))
+					r.Get("/{webhookID}/deliveries", utils.MakeHandler(webhookHandler.ListDeliveries))
+					r.Post("/{webhookID}/deliveries/{deliveryID}/redeliver", utils.MakeHandler(webhookHandler.Redeliver))
+				})
+
+				r.Route("/kyc", func(r chi.Router) {
+					r.Use(MakeRoleMiddleware(user.Common, user.Shopkeeper))
+
+					r.Get("/", utils.MakeHandler(kycHandler.Status))
+					r.Post("/documents", utils.MakeHandler(kycHandler.Upload))
+				})
+
+				r.Route("/api-keys", func(r chi.Router) {
+					r.Use(MakeRoleMiddleware(user.Shopkeeper))
+
+					r.Post("/", utils.MakeHandler(apiKeyHandler.Create))
+					r.Get("/", utils.MakeHandler(apiKeyHandler.List))
+					r.Delete("/{keyID}", utils.MakeHandler(apiKeyHandler.Revoke))
+				})
+
+				r.Route("/admin", func(r chi.Router) {
+					r.Use(MakeRoleMiddleware(user.Admin))
+
+					r.Get("/users", utils.MakeHandler(adminHandler.SearchUsers))
+					r.Get("/users/{userID}/wallet", utils.MakeHandler(adminHandler.GetWallet))
+					r.Get("/users/{userID}/transactions", utils.MakeHandler(adminHandler.ListTransactions))
+					r.Post("/users/{userID}/freeze", utils.MakeHandler(adminHandler.FreezeAccount))
+					r.Post("/users/{userID}/unfreeze", utils.MakeHandler(adminHandler.UnfreezeAccount))
+					r.Post("/transactions/{transactionID}/refund", utils.MakeHandler(adminHandler.RefundTransaction))
+					r.Get("/transfers/pending", utils.MakeHandler(adminHandler.ListPendingTransfers))
+					r.Post("/transfers/pending/{transferID}/approve", utils.MakeHandler(adminHandler.ApprovePendingTransfer))
+					r.Post("/transfers/pending/{transferID}/reject", utils.MakeHandler(adminHandler.RejectPendingTransfer))
+					r.Get("/kyc/documents", utils.MakeHandler(kycHandler.Queue))
+					r.Get("/kyc/documents/{documentID}/file", utils.MakeHandler(kycHandler.File))
+					r.Post("/kyc/documents/{documentID}/approve", utils.MakeHandler(kycHandler.Approve))
+					r.Post("/kyc/documents/{documentID}/reject", utils.MakeHandler(kycHandler.Reject))
+				})
+			})
+		})
+	})
+
+	return r
+}
@@ -0,0 +1,191 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/apikey"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/auth"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/paymentrequest"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/transaction"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/webhook"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/env"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// newSessionServices authenticates the "token" bearer token as usr.
+func newSessionServices(usr *user.User) Services {
+	jwtService := new(auth.MockJWTService)
+	jwtService.On("ValidateToken", "token").Return(&jwt.Token{
+		Claims: jwt.MapClaims{
+			"sub": float64(usr.ID),
+			"jti": "jti",
+			"iat": float64(time.Now().Unix()),
+		},
+	}, nil)
+
+	revocations := new(auth.MockRevocationStore)
+	revocations.On("IsRevoked", mock.Anything, "jti").Return(false, nil)
+	revocations.On("ValidAfter", mock.Anything, usr.ID).Return(time.Time{}, nil)
+
+	users := new(user.MockUserService)
+	users.On("FindByID", mock.Anything, usr.ID).Return(usr, nil)
+
+	return Services{
+		Users:       users,
+		JWT:         jwtService,
+		Revocations: revocations,
+	}
+}
+
+func request(router http.Handler, method, target string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, nil)
+	req.Header.Set("Authorization", "Bearer token")
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestRouter(t *testing.T) {
+	verified := time.Now()
+
+	t.Run("should answer health checks without any service", func(t *testing.T) {
+		router := NewRouter(&env.Config{}, Services{})
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/health", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("should route an authenticated request to the service", func(t *testing.T) {
+		usr := &user.User{ID: 7, Role: user.Common, EmailVerifiedAt: &verified}
+		svc := newSessionServices(usr)
+		transactions := new(transaction.MockTransactionService)
+		transactions.On("ListByUser", mock.Anything, 7, 10, 0).Return([]transaction.Transaction{}, nil)
+		svc.Transactions = transactions
+
+		rec := request(NewRouter(&env.Config{}, svc), http.MethodGet, "/v1/transactions/?limit=10")
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		transactions.AssertExpectations(t)
+	})
+
+	t.Run("should reject a request without credentials", func(t *testing.T) {
+		router := NewRouter(&env.Config{}, Services{})
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/transactions/", nil))
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("should keep admin routes from other roles", func(t *testing.T) {
+		usr := &user.User{ID: 7, Role: user.Common, EmailVerifiedAt: &verified}
+
+		rec := request(NewRouter(&env.Config{}, newSessionServices(usr)), http.MethodGet, "/v1/admin/users")
+
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
+
+func TestPaymentRequestRoutes(t *testing.T) {
+	verified := time.Now()
+	shopkeeper := &user.User{ID: 3, Role: user.Shopkeeper, EmailVerifiedAt: &verified}
+
+	withAPIKey := func(scopes ...apikey.Scope) Services {
+		apiKeys := new(apikey.MockAPIKeyService)
+		apiKeys.On("Authenticate", mock.Anything, "key").Return(&apikey.APIKey{ID: 1, UserID: 3, Scopes: scopes}, nil)
+
+		users := new(user.MockUserService)
+		users.On("FindByID", mock.Anything, 3).Return(shopkeeper, nil)
+
+		return Services{APIKeys: apiKeys, Users: users}
+	}
+
+	create := func(router http.Handler) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/payment-requests/", strings.NewReader(`{"amount":1500}`))
+		req.Header.Set("Authorization", "ApiKey key")
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec
+	}
+
+	t.Run("should create payment requests with the write scope", func(t *testing.T) {
+		svc := withAPIKey(apikey.ScopePaymentRequestsWrite)
+		paymentRequests := new(paymentrequest.MockPaymentRequestService)
+		paymentRequests.On("Create", mock.Anything, 3, paymentrequest.CreateDTO{Amount: 1500}).
+			Return(&paymentrequest.PaymentRequest{ID: 8, PayeeID: 3, Amount: 1500}, nil)
+		svc.PaymentRequests = paymentRequests
+
+		rec := create(NewRouter(&env.Config{}, svc))
+
+		assert.Equal(t, http.StatusCreated, rec.Code)
+		paymentRequests.AssertExpectations(t)
+	})
+
+	t.Run("should refuse api keys without the write scope", func(t *testing.T) {
+		svc := withAPIKey(apikey.ScopePaymentsRead)
+		paymentRequests := new(paymentrequest.MockPaymentRequestService)
+		svc.PaymentRequests = paymentRequests
+
+		rec := create(NewRouter(&env.Config{}, svc))
+
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+		paymentRequests.AssertNotCalled(t, "Create", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("should keep common users out", func(t *testing.T) {
+		usr := &user.User{ID: 7, Role: user.Common, EmailVerifiedAt: &verified}
+
+		rec := request(NewRouter(&env.Config{}, newSessionServices(usr)), http.MethodGet, "/v1/payment-requests/")
+
+		assert.Equal(t, http.StatusForbidden, rec.Code)
+	})
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	t.Run("should record requests by route pattern", func(t *testing.T) {
+		usr := &user.User{ID: 7, Role: user.Common}
+		svc := newSessionServices(usr)
+		webhooks := new(webhook.MockWebhookService)
+		webhooks.On("ListDeliveries", mock.Anything, 7, 42, 0, 0).Return([]webhook.Delivery{}, nil)
+		svc.Webhooks = webhooks
+		router := NewRouter(&env.Config{}, svc)
+
+		request(router, http.MethodGet, "/v1/webhooks/42/deliveries")
+		request(router, http.MethodGet, "/nowhere/42")
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.True(t, strings.HasPrefix(rec.Header().Get("Content-Type"), "text/plain"))
+		assert.Contains(t, rec.Body.String(), `picpay_http_requests_total{method="GET",route="/v1/webhooks/{webhookID}/deliveries",status="200"}`)
+		assert.Contains(t, rec.Body.String(), `picpay_http_requests_total{method="GET",route="unmatched",status="404"}`)
+		assert.NotContains(t, rec.Body.String(), "/42")
+	})
+
+	t.Run("should require the configured token", func(t *testing.T) {
+		cfg := &env.Config{Metrics: env.MetricsConfig{Token: "scraper"}}
+		router := NewRouter(cfg, Services{})
+
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+		req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		req.Header.Set("Authorization", "Bearer scraper")
+		rec = httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
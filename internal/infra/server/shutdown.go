@@ -0,0 +1,31 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// shutdownContext is cancelled on the first SIGINT or SIGTERM. Once it is,
+// signals are no longer caught, so a second one kills the process.
+func shutdownContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
+
+// runUntil stops app when ctx is cancelled, or when its server fails on its
+// own.
+func runUntil(ctx context.Context, app *App) error {
+	select {
+	case <-ctx.Done():
+		return app.Stop()
+	case err := <-app.Err():
+		return errors.Join(err, app.Stop())
+	}
+}
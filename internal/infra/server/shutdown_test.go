@@ -0,0 +1,173 @@
+//go:build unix
+
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/env"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type slowResult struct {
+	status int
+	body   string
+	err    error
+}
+
+// startSlow serves a handler that takes delay to answer until ctx is
+// cancelled. It returns the server address, a channel closed once the request
+// reached the handler and the result of stopping the app.
+func startSlow(t *testing.T, ctx context.Context, delay, grace time.Duration) (string, <-chan struct{}, <-chan error) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	started := make(chan struct{})
+	app := &App{
+		cfg: &env.Config{ShutdownGracePeriod: grace},
+		handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			close(started)
+			time.Sleep(delay)
+			w.Write([]byte("settled"))
+		}),
+	}
+	app.serve(ln)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- runUntil(ctx, app)
+	}()
+
+	return "http://" + ln.Addr().String(), started, done
+}
+
+func get(url string) <-chan slowResult {
+	res := make(chan slowResult, 1)
+	go func() {
+		resp, err := http.Get(url)
+		if err != nil {
+			res <- slowResult{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		res <- slowResult{status: resp.StatusCode, body: string(body), err: err}
+	}()
+	return res
+}
+
+func TestApp_DrainsInFlightRequestsOnSignal(t *testing.T) {
+	ctx, stop := shutdownContext()
+	defer stop()
+
+	url, started, done := startSlow(t, ctx, 300*time.Millisecond, 5*time.Second)
+
+	res := get(url)
+	<-started
+
+	require.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGTERM))
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("signal did not cancel the shutdown context")
+	}
+
+	// new connections are refused while the slow request drains
+	require.Eventually(t, func() bool {
+		conn, err := net.DialTimeout("tcp", url[len("http://"):], 50*time.Millisecond)
+		if err == nil {
+			conn.Close()
+			return false
+		}
+		return true
+	}, time.Second, 10*time.Millisecond)
+
+	r := <-res
+	require.NoError(t, r.err)
+	assert.Equal(t, http.StatusOK, r.status)
+	assert.Equal(t, "settled", r.body)
+
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("app did not stop after draining")
+	}
+}
+
+func TestApp_AbortsRequestsOverGracePeriod(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	url, started, done := startSlow(t, ctx, 5*time.Second, 100*time.Millisecond)
+
+	res := get(url)
+	<-started
+	cancel()
+
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	case <-time.After(3 * time.Second):
+		t.Fatal("app did not give up after the grace period")
+	}
+
+	assert.Error(t, (<-res).err)
+}
+
+func TestApp_StopsWorkersAfterDraining(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	running := make(chan struct{})
+	stopped := false
+	app := &App{
+		cfg:     &env.Config{ShutdownGracePeriod: time.Second},
+		handler: http.NotFoundHandler(),
+		workers: []worker{func(ctx context.Context) {
+			close(running)
+			<-ctx.Done()
+			stopped = true
+		}},
+	}
+	app.serve(ln)
+
+	select {
+	case <-running:
+	case <-time.After(time.Second):
+		t.Fatal("worker did not start")
+	}
+
+	require.NoError(t, app.Stop())
+	assert.True(t, stopped)
+}
+
+func TestApp_WaitsForBackgroundTasks(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	app := &App{
+		cfg:     &env.Config{ShutdownGracePeriod: time.Second},
+		handler: http.NotFoundHandler(),
+		tasks:   &tasks{},
+	}
+	app.serve(ln)
+
+	sent := false
+	app.tasks.Go(func() {
+		time.Sleep(100 * time.Millisecond)
+		sent = true
+	})
+
+	require.NoError(t, app.Stop())
+	assert.True(t, sent)
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/user"
+	"github.com/DevVictor19/pic-pay-challenge/internal/domain/webhook"
+	"github.com/DevVictor19/pic-pay-challenge/internal/infra/env"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	prevProvider, prevPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(prevProvider)
+		otel.SetTextMapPropagator(prevPropagator)
+	})
+	return recorder
+}
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+func TestTracing(t *testing.T) {
+	t.Run("should name the root span after the route and carry the request id", func(t *testing.T) {
+		recorder := recordSpans(t)
+		usr := &user.User{ID: 7, Role: user.Common}
+		svc := newSessionServices(usr)
+		webhooks := new(webhook.MockWebhookService)
+		webhooks.On("ListDeliveries", mock.Anything, 7, 42, 0, 0).Return([]webhook.Delivery{}, nil)
+		svc.Webhooks = webhooks
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/webhooks/42/deliveries", nil)
+		req.Header.Set("Authorization", "Bearer token")
+		req.Header.Set("X-Request-Id", "req-1")
+		NewRouter(&env.Config{}, svc).ServeHTTP(httptest.NewRecorder(), req)
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+		assert.Equal(t, "GET /v1/webhooks/{webhookID}/deliveries", spans[0].Name())
+		assert.Equal(t, trace.SpanKindServer, spans[0].SpanKind())
+		assert.Equal(t, "req-1", spanAttribute(spans[0], "http.request_id").AsString())
+		assert.Equal(t, int64(http.StatusOK), spanAttribute(spans[0], "http.response.status_code").AsInt64())
+	})
+
+	t.Run("should continue the caller's trace", func(t *testing.T) {
+		recorder := recordSpans(t)
+		const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+		req := httptest.NewRequest(http.MethodGet, "/v1/health", nil)
+		req.Header.Set("traceparent", traceparent)
+		NewRouter(&env.Config{}, Services{}).ServeHTTP(httptest.NewRecorder(), req)
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", spans[0].SpanContext().TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+	})
+
+	t.Run("should mark server errors", func(t *testing.T) {
+		recorder := recordSpans(t)
+		handler := Tracing(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		spans := recorder.Ended()
+		require.Len(t, spans, 1)
+		assert.Equal(t, "GET unmatched", spans[0].Name())
+		assert.Equal(t, codes.Error, spans[0].Status().Code)
+	})
+}
@@ -3,3 +3,11 @@ package utils
 type userKey string
 
 const UserKey userKey = "user"
+
+type claimsKey string
+
+const ClaimsKey claimsKey = "claims"
+
+type apiKeyKey string
+
+const APIKeyKey apiKeyKey = "api_key"
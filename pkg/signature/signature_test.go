@@ -0,0 +1,68 @@
+package signature
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSign(t *testing.T) {
+	t.Run("should sign method, path, timestamp, nonce and body", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/v1/payments?ref=1", strings.NewReader(`{"amount":10}`))
+		now := time.Unix(1700000000, 0)
+
+		require.NoError(t, Sign(req, "pk_key", "secret", now))
+
+		assert.Equal(t, "ApiKey pk_key", req.Header.Get("Authorization"))
+		assert.Equal(t, "1700000000", req.Header.Get(HeaderTimestamp))
+		assert.Len(t, req.Header.Get(HeaderNonce), 32)
+
+		message := StringToSign("POST", "/v1/payments?ref=1", "1700000000", req.Header.Get(HeaderNonce), []byte(`{"amount":10}`))
+		assert.Equal(t, Compute("secret", message), req.Header.Get(HeaderSignature))
+
+		body, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+		assert.Equal(t, `{"amount":10}`, string(body))
+	})
+
+	t.Run("should use a new nonce for every request", func(t *testing.T) {
+		a := httptest.NewRequest(http.MethodGet, "/", nil)
+		b := httptest.NewRequest(http.MethodGet, "/", nil)
+
+		require.NoError(t, Sign(a, "pk_key", "secret", time.Now()))
+		require.NoError(t, Sign(b, "pk_key", "secret", time.Now()))
+
+		assert.NotEqual(t, a.Header.Get(HeaderNonce), b.Header.Get(HeaderNonce))
+	})
+}
+
+func TestStringToSign(t *testing.T) {
+	got := StringToSign("get", "/v1/transactions", "1", "abc", nil)
+
+	assert.Equal(t, "GET\n/v1/transactions\n1\nabc\ne3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855", got)
+}
+
+func TestTransport(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		message := StringToSign(r.Method, r.URL.RequestURI(), r.Header.Get(HeaderTimestamp), r.Header.Get(HeaderNonce), body)
+		if !Equal(Compute("secret", message), r.Header.Get(HeaderSignature)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	res, err := NewClient("pk_key", "secret").Post(srv.URL+"/v1/payments", "application/json", strings.NewReader(`{}`))
+	require.NoError(t, err)
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+}
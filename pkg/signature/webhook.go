@@ -0,0 +1,50 @@
+package signature
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const HeaderWebhookSignature = "X-Webhook-Signature"
+
+var ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+
+// SignWebhook returns the X-Webhook-Signature header value for body, in the
+// form "t=<unix timestamp>,v1=<hex HMAC-SHA256 of "<timestamp>.<body>">".
+func SignWebhook(secret string, timestamp time.Time, body []byte) string {
+	ts := strconv.FormatInt(timestamp.Unix(), 10)
+	return fmt.Sprintf("t=%s,v1=%s", ts, Compute(secret, ts+"."+string(body)))
+}
+
+// VerifyWebhook checks a X-Webhook-Signature header against body. Receivers
+// should reject deliveries signed more than tolerance away from now.
+func VerifyWebhook(secret, header string, body []byte, tolerance time.Duration, now time.Time) error {
+	var ts, sig string
+	for _, part := range strings.Split(header, ",") {
+		key, val, _ := strings.Cut(part, "=")
+		switch key {
+		case "t":
+			ts = val
+		case "v1":
+			sig = val
+		}
+	}
+
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || sig == "" {
+		return ErrInvalidWebhookSignature
+	}
+
+	if d := now.Sub(time.Unix(unix, 0)); d > tolerance || d < -tolerance {
+		return ErrInvalidWebhookSignature
+	}
+
+	if !Equal(Compute(secret, ts+"."+string(body)), sig) {
+		return ErrInvalidWebhookSignature
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package signature
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyWebhook(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"type":"payment.received"}`)
+	header := SignWebhook("secret", now, body)
+
+	t.Run("should accept a valid signature", func(t *testing.T) {
+		assert.NoError(t, VerifyWebhook("secret", header, body, time.Minute, now))
+	})
+
+	t.Run("should reject a tampered body", func(t *testing.T) {
+		err := VerifyWebhook("secret", header, []byte(`{"type":"refund.created"}`), time.Minute, now)
+		assert.ErrorIs(t, err, ErrInvalidWebhookSignature)
+	})
+
+	t.Run("should reject another secret", func(t *testing.T) {
+		assert.ErrorIs(t, VerifyWebhook("other", header, body, time.Minute, now), ErrInvalidWebhookSignature)
+	})
+
+	t.Run("should reject old deliveries", func(t *testing.T) {
+		err := VerifyWebhook("secret", header, body, time.Minute, now.Add(time.Minute*2))
+		assert.ErrorIs(t, err, ErrInvalidWebhookSignature)
+	})
+
+	t.Run("should reject malformed headers", func(t *testing.T) {
+		assert.ErrorIs(t, VerifyWebhook("secret", "v1=abc", body, time.Minute, now), ErrInvalidWebhookSignature)
+	})
+}